@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
@@ -9,16 +11,23 @@ import (
 	"syscall"
 	"time"
 
+	"ride-sharing/services/api-gateway/internal/auth"
 	"ride-sharing/services/api-gateway/internal/clients"
 	httpHandlers "ride-sharing/services/api-gateway/internal/handlers/http"
 	wsHandlers "ride-sharing/services/api-gateway/internal/handlers/websocket"
+	"ride-sharing/services/api-gateway/internal/ratelimit"
 	"ride-sharing/services/api-gateway/internal/websocket"
+	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/env"
 	"ride-sharing/shared/messaging"
+
+	"github.com/google/uuid"
 )
 
 var (
 	httpAddr = env.GetString("HTTP_ADDR", ":8081")
+	// metricsAddr serves /debug/vars apart from the public routes, empty disables it
+	metricsAddr = env.GetString("METRICS_ADDR", ":9091")
 )
 
 func main() {
@@ -37,8 +46,31 @@ func main() {
 	defer rabbitMq.Close()
 	log.Println("RabbitMQ connection established")
 
-	wsUpgrader := websocket.NewWebSocketUpgrader()
-	connManager := websocket.NewConnectionManager()
+	originValidator, err := messaging.OriginValidatorFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure the WebSocket origins: %v", err)
+	}
+	wsUpgrader := websocket.NewWebSocketUpgrader(originValidator, httpHandlers.WriteUpgradeError)
+	sessionConfig := websocket.DefaultSessionConfig()
+	sessionConfig.MaxSessions = env.GetInt("WS_MAX_SESSIONS_PER_USER", sessionConfig.MaxSessions)
+	sessionConfig.OnLimit, err = websocket.ParseSessionLimitPolicy(env.GetString("WS_SESSION_LIMIT_POLICY", string(sessionConfig.OnLimit)))
+	if err != nil {
+		log.Fatalf("Failed to parse WS_SESSION_LIMIT_POLICY: %v", err)
+	}
+	writerConfig := websocket.DefaultWriterConfig()
+	writerConfig.BufferSize = env.GetInt("WS_SEND_BUFFER_SIZE", writerConfig.BufferSize)
+	if writerConfig.BufferSize < 1 {
+		log.Fatalf("WS_SEND_BUFFER_SIZE must be at least 1, got %d", writerConfig.BufferSize)
+	}
+	writerConfig.WriteTimeout = time.Duration(env.GetInt("WS_WRITE_TIMEOUT_MS", int(writerConfig.WriteTimeout.Milliseconds()))) * time.Millisecond
+	writerConfig.OnFull, err = websocket.ParseBackpressurePolicy(env.GetString("WS_BACKPRESSURE_POLICY", string(writerConfig.OnFull)))
+	if err != nil {
+		log.Fatalf("Failed to parse WS_BACKPRESSURE_POLICY: %v", err)
+	}
+	heartbeatConfig := websocket.DefaultHeartbeatConfig()
+	heartbeatConfig.PingInterval = time.Duration(env.GetInt("WS_PING_INTERVAL_MS", int(heartbeatConfig.PingInterval.Milliseconds()))) * time.Millisecond
+	heartbeatConfig.PongTimeout = time.Duration(env.GetInt("WS_PONG_TIMEOUT_MS", int(heartbeatConfig.PongTimeout.Milliseconds()))) * time.Millisecond
+	connManager := websocket.NewConnectionManager(sessionConfig, writerConfig, heartbeatConfig)
 
 	tripClient, err := clients.NewTripServiceClient()
 	if err != nil {
@@ -51,27 +83,105 @@ func main() {
 	}
 
 	tripHandler := httpHandlers.NewTripHandler(tripClient)
-	wsHandler := wsHandlers.NewWebSocketHandler(connManager, wsUpgrader, driverClient, rabbitMq)
+	wsConfig := wsHandlers.DefaultConfig()
+	wsConfig.LocationUpdateInterval = time.Duration(env.GetInt("DRIVER_LOCATION_MIN_INTERVAL_MS", 1000)) * time.Millisecond
+	wsConfig.MessageRateLimit, err = ratelimit.ParsePolicy(env.GetString("WS_MESSAGE_RATE_LIMIT", wsConfig.MessageRateLimit.String()))
+	if err != nil {
+		log.Fatalf("Failed to parse WS_MESSAGE_RATE_LIMIT: %v", err)
+	}
+
+	driverReconnectGrace := time.Duration(env.GetInt("DRIVER_RECONNECT_GRACE_MS", 30000)) * time.Millisecond
+	sessions := websocket.NewSessionRegistry(driverReconnectGrace)
+
+	mailboxSize := env.GetInt("WS_MAILBOX_SIZE", 100)
+	if mailboxSize < 1 {
+		log.Fatalf("WS_MAILBOX_SIZE must be at least 1, got %d", mailboxSize)
+	}
+	mailboxTTL := time.Duration(env.GetInt("WS_MAILBOX_TTL_MS", 120000)) * time.Millisecond
+	mailbox := websocket.NewMailbox(connManager.SendMessage, mailboxSize, mailboxTTL)
+
+	// Every instance needs a unique ID, messages for its users are forwarded to its own queue
+	gatewayID := env.GetString("GATEWAY_ID", "")
+	if gatewayID == "" {
+		gatewayID = defaultGatewayID()
+	}
+	presenceInterval := time.Duration(env.GetInt("GATEWAY_PRESENCE_INTERVAL_MS", 5000)) * time.Millisecond
+	// Instances that missed several announcements in a row are assumed to be gone
+	presence := websocket.NewPresenceTable(3 * presenceInterval)
+	router := wsHandlers.NewMessageRouter(gatewayID, connManager, sessions, mailbox, presence, rabbitMq, presenceInterval)
+
+	routerCtx, stopRouter := context.WithCancel(context.Background())
+	defer stopRouter()
+	if err := router.Start(routerCtx); err != nil {
+		log.Fatalf("Failed to start message router: %v", err)
+	}
+
+	authenticator, err := newAuthenticator()
+	if err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
+	}
+
+	wsHandler := wsHandlers.NewWebSocketHandler(connManager, sessions, mailbox, router, wsUpgrader, driverClient, rabbitMq, wsConfig)
+
+	// Every route has its own limits, the previews are the most expensive requests
+	trustedProxies := env.GetInt("RATE_LIMIT_TRUSTED_PROXIES", 0)
+	previewLimiter := newRateLimiter("trip_preview", "RATE_LIMIT_TRIP_PREVIEW", "30/1m", trustedProxies)
+	startLimiter := newRateLimiter("trip_start", "RATE_LIMIT_TRIP_START", "10/1m", trustedProxies)
+	// Shared by the WebSocket and event stream endpoints, it limits reconnect loops
+	connectLimiter := newRateLimiter("ws_connect", "RATE_LIMIT_WS_CONNECT", "30/1m", trustedProxies)
+	// Commands and acks of riders on the event stream, who can't send them over a WebSocket
+	commandLimiter := newRateLimiter("rider_commands", "RATE_LIMIT_RIDER_COMMANDS", "30/1m", trustedProxies)
+	ackLimiter := newRateLimiter("sse_ack", "RATE_LIMIT_SSE_ACK", "120/1m", trustedProxies)
+	chatLimiter := newRateLimiter("chat", "RATE_LIMIT_CHAT", "60/1m", trustedProxies)
 
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("POST /trip/preview", httpHandlers.EnableCORS(tripHandler.HandleTripPreview))
-	mux.HandleFunc("POST /trip/start", httpHandlers.EnableCORS(tripHandler.HandleCreateTrip))
-	mux.HandleFunc("/ws/drivers", wsHandler.HandleDriverConnection)
-	mux.HandleFunc("/ws/riders", wsHandler.HandleRiderConnection)
+	// Rate limits come after authentication, so authenticated users are limited by user ID
+	mux.HandleFunc("POST /trip/preview", httpHandlers.EnableCORS(httpHandlers.Authenticate(authenticator, httpHandlers.RateLimit(previewLimiter, tripHandler.HandleTripPreview), auth.RoleRider)))
+	mux.HandleFunc("POST /trip/start", httpHandlers.EnableCORS(httpHandlers.Authenticate(authenticator, httpHandlers.RateLimit(startLimiter, tripHandler.HandleCreateTrip), auth.RoleRider)))
+	mux.HandleFunc("/ws/drivers", httpHandlers.AuthenticateStream(authenticator, httpHandlers.RateLimit(connectLimiter, wsHandler.HandleDriverConnection), auth.RoleDriver))
+	mux.HandleFunc("/ws/riders", httpHandlers.AuthenticateStream(authenticator, httpHandlers.RateLimit(connectLimiter, wsHandler.HandleRiderConnection), auth.RoleRider))
+	mux.HandleFunc("GET /sse/riders", httpHandlers.AuthenticateStream(authenticator, httpHandlers.RateLimit(connectLimiter, wsHandler.HandleRiderEvents), auth.RoleRider))
+	mux.HandleFunc("POST /sse/riders/ack", httpHandlers.EnableCORS(httpHandlers.Authenticate(authenticator, httpHandlers.RateLimit(ackLimiter, wsHandler.HandleRiderEventsAck), auth.RoleRider)))
+	mux.HandleFunc("POST /trip/cancel", httpHandlers.EnableCORS(httpHandlers.Authenticate(authenticator, httpHandlers.RateLimit(commandLimiter, wsHandler.HandleRiderCommand(contracts.RiderCmdCancelTrip)), auth.RoleRider)))
+	mux.HandleFunc("POST /trip/pickup", httpHandlers.EnableCORS(httpHandlers.Authenticate(authenticator, httpHandlers.RateLimit(commandLimiter, wsHandler.HandleRiderCommand(contracts.RiderCmdUpdatePickup)), auth.RoleRider)))
+	mux.HandleFunc("POST /trip/stops", httpHandlers.EnableCORS(httpHandlers.Authenticate(authenticator, httpHandlers.RateLimit(commandLimiter, wsHandler.HandleRiderCommand(contracts.RiderCmdAddStop)), auth.RoleRider)))
+	mux.HandleFunc("GET /trip/chat", httpHandlers.EnableCORS(httpHandlers.Authenticate(authenticator, httpHandlers.RateLimit(chatLimiter, tripHandler.HandleChatHistory), auth.RoleRider, auth.RoleDriver)))
+	mux.HandleFunc("POST /trip/chat", httpHandlers.EnableCORS(httpHandlers.Authenticate(authenticator, httpHandlers.RateLimit(chatLimiter, wsHandler.HandleChatCommand(contracts.ChatCmdSend)), auth.RoleRider, auth.RoleDriver)))
+	mux.HandleFunc("POST /trip/chat/receipt", httpHandlers.EnableCORS(httpHandlers.Authenticate(authenticator, httpHandlers.RateLimit(chatLimiter, wsHandler.HandleChatCommand(contracts.ChatCmdReceipt)), auth.RoleRider, auth.RoleDriver)))
+	// Browsers check with a preflight request before sending an Authorization header
+	mux.HandleFunc("OPTIONS /trip/", httpHandlers.EnableCORS(http.NotFound))
+	mux.HandleFunc("OPTIONS /sse/", httpHandlers.EnableCORS(http.NotFound))
 
 	server := &http.Server{
 		Addr:    httpAddr,
-		Handler: mux,
+		Handler: httpHandlers.RequestID(mux.ServeHTTP),
 	}
 
-	serverErrors := make(chan error, 1)
+	serverErrors := make(chan error, 2)
 
 	go func() {
 		log.Printf("Server listening on port: %v", httpAddr)
 		serverErrors <- server.ListenAndServe()
 	}()
 
+	// The metrics include the command line and memory stats, so they get their own
+	// listener that isn't exposed with the public port
+	var metricsServer *http.Server
+	if metricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /debug/vars", expvar.Handler())
+		metricsServer = &http.Server{
+			Addr:    metricsAddr,
+			Handler: metricsMux,
+		}
+
+		go func() {
+			log.Printf("Metrics listening on port: %v", metricsAddr)
+			serverErrors <- metricsServer.ListenAndServe()
+		}()
+	}
+
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
@@ -82,6 +192,9 @@ func main() {
 	case sig := <-shutdown:
 		log.Printf("Server is shutting down due to : %v signal", sig)
 
+		// Tell the other instances this one is gone
+		stopRouter()
+
 		// Close gRPC connections
 		tripClient.Close()
 		driverClient.Close()
@@ -92,6 +205,70 @@ func main() {
 			log.Printf("Server could not shutdown gracefully: %v", err)
 			server.Close()
 		}
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
 
 	}
 }
+
+// defaultGatewayID combines the hostname, which is the pod name in Kubernetes, with a
+// random suffix so restarted instances don't receive their predecessor's messages
+func defaultGatewayID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "api-gateway"
+	}
+	return hostname + "-" + uuid.NewString()[:8]
+}
+
+// newRateLimiter creates the rate limiter of a route from the policy in envKey, nil if it is "off"
+func newRateLimiter(name, envKey, fallback string, trustedProxies int) *httpHandlers.RateLimiter {
+	policy, err := ratelimit.ParsePolicy(env.GetString(envKey, fallback))
+	if err != nil {
+		log.Fatalf("Failed to parse %s: %v", envKey, err)
+	}
+
+	return httpHandlers.NewRateLimiter(name, httpHandlers.RateLimitConfig{
+		Policy:         policy,
+		TrustedProxies: trustedProxies,
+	})
+}
+
+// newAuthenticator verifies tokens with the HMAC secret or the keys of the JWKS file.
+// Authentication is only disabled, trusting clients with their user IDs, when
+// AUTH_DISABLED is set explicitly, so a missing secret can't open up a deployment.
+func newAuthenticator() (auth.Authenticator, error) {
+	secret := env.GetString("JWT_HMAC_SECRET", "")
+	jwksFile := env.GetString("JWT_JWKS_FILE", "")
+	disabled := env.GetBool("AUTH_DISABLED", false)
+
+	var keys *auth.KeySet
+	switch {
+	case disabled && (secret != "" || jwksFile != ""):
+		return nil, errors.New("AUTH_DISABLED can't be combined with JWT_HMAC_SECRET or JWT_JWKS_FILE")
+	case disabled:
+		log.Println("WARNING: AUTH_DISABLED is set, authentication is disabled")
+		return nil, nil
+	case secret != "" && jwksFile != "":
+		return nil, errors.New("set only one of JWT_HMAC_SECRET and JWT_JWKS_FILE")
+	case secret != "":
+		keys = auth.NewHMACKeySet([]byte(secret))
+	case jwksFile != "":
+		var err error
+		if keys, err = auth.LoadJWKS(jwksFile); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("set JWT_HMAC_SECRET or JWT_JWKS_FILE, or AUTH_DISABLED=true to run without authentication")
+	}
+
+	return auth.NewJWTAuthenticator(auth.JWTConfig{
+		Keys: keys,
+		Options: auth.VerifyOptions{
+			Issuer:   env.GetString("JWT_ISSUER", ""),
+			Audience: env.GetString("JWT_AUDIENCE", ""),
+			Leeway:   time.Duration(env.GetInt("JWT_LEEWAY_MS", 30000)) * time.Millisecond,
+		},
+	}), nil
+}
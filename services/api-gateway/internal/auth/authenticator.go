@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoCredentials is returned when a request carries no token
+	ErrNoCredentials = errors.New("no credentials")
+)
+
+// TokenSubprotocol is the WebSocket subprotocol announcing that the next requested
+// subprotocol is a token, for browsers which can't set headers on WebSocket requests:
+// new WebSocket(url, ["access_token", token])
+const TokenSubprotocol = "access_token"
+
+type streamKey struct{}
+
+// WithStreamCredentials returns a copy of ctx that lets the request carry its token
+// in the token query parameter or the subprotocol header. Only WebSocket and event
+// stream routes allow it, since browsers can't set headers on those requests and
+// tokens in URLs end up in logs.
+func WithStreamCredentials(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamKey{}, true)
+}
+
+func allowsStreamCredentials(ctx context.Context) bool {
+	allowed, _ := ctx.Value(streamKey{}).(bool)
+	return allowed
+}
+
+// Authenticator verifies the identity behind a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// JWTConfig holds the settings of the JWT authenticator
+type JWTConfig struct {
+	Keys    *KeySet
+	Options VerifyOptions
+}
+
+type jwtAuthenticator struct {
+	config JWTConfig
+}
+
+// NewJWTAuthenticator creates an authenticator for signed JWTs carrying the user ID
+// in the sub claim and the role in the role claim
+func NewJWTAuthenticator(config JWTConfig) Authenticator {
+	return &jwtAuthenticator{config: config}
+}
+
+func (a *jwtAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	token, err := tokenFromRequest(r)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	claims, err := Verify(token, a.config.Keys, a.config.Options, time.Now())
+	if err != nil {
+		return Principal{}, err
+	}
+
+	role, err := ParseRole(claims.Role)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	return Principal{UserID: claims.Subject, Role: role}, nil
+}
+
+// tokenFromRequest reads a bearer token from the Authorization header, or for
+// WebSocket upgrades and event streams, which browsers open without custom headers,
+// from the token query parameter or the subprotocol header
+func tokenFromRequest(r *http.Request) (string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return "", fmt.Errorf("%w: malformed Authorization header", ErrInvalidToken)
+		}
+		return token, nil
+	}
+
+	if !allowsStreamCredentials(r.Context()) {
+		return "", ErrNoCredentials
+	}
+
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token, nil
+	}
+
+	protocols := websocketSubprotocols(r)
+	for i, protocol := range protocols {
+		if protocol == TokenSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1], nil
+		}
+	}
+
+	return "", ErrNoCredentials
+}
+
+func websocketSubprotocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			protocols = append(protocols, strings.TrimSpace(protocol))
+		}
+	}
+	return protocols
+}
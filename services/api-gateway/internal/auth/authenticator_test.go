@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"ride-sharing/shared/jwt"
+	"testing"
+	"time"
+)
+
+func TestAuthenticatorTokenSources(t *testing.T) {
+	secret := []byte("secret")
+	token, err := jwt.SignHS256(Claims{
+		Subject:   "rider-1",
+		Role:      string(RoleRider),
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := NewJWTAuthenticator(JWTConfig{Keys: NewHMACKeySet(secret)})
+
+	tests := []struct {
+		name    string
+		url     string
+		header  map[string]string
+		stream  bool
+		wantErr error
+	}{
+		{
+			name:   "header",
+			url:    "/trip/start",
+			header: map[string]string{"Authorization": "Bearer " + token},
+		},
+		{
+			name:   "header on a stream route",
+			url:    "/ws/riders",
+			header: map[string]string{"Authorization": "Bearer " + token},
+			stream: true,
+		},
+		{
+			name:   "query parameter on a stream route",
+			url:    "/ws/riders?token=" + token,
+			stream: true,
+		},
+		{
+			name:   "subprotocol on a stream route",
+			url:    "/ws/riders",
+			header: map[string]string{"Sec-WebSocket-Protocol": TokenSubprotocol + ", " + token},
+			stream: true,
+		},
+		{
+			name:    "query parameter elsewhere",
+			url:     "/trip/start?token=" + token,
+			wantErr: ErrNoCredentials,
+		},
+		{
+			name:    "subprotocol elsewhere",
+			url:     "/trip/start",
+			header:  map[string]string{"Sec-WebSocket-Protocol": TokenSubprotocol + ", " + token},
+			wantErr: ErrNoCredentials,
+		},
+		{
+			name:    "malformed header",
+			url:     "/trip/start",
+			header:  map[string]string{"Authorization": "Basic " + token},
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.url, nil)
+			for name, value := range tt.header {
+				r.Header.Set(name, value)
+			}
+			if tt.stream {
+				r = r.WithContext(WithStreamCredentials(r.Context()))
+			}
+
+			principal, err := authenticator.Authenticate(r)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if principal.UserID != "rider-1" || principal.Role != RoleRider {
+				t.Errorf("Authenticate() = %+v, want rider-1 as rider", principal)
+			}
+		})
+	}
+}
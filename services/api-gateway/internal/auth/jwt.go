@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"ride-sharing/shared/jwt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	algHS256 = jwt.AlgHS256
+	algRS256 = jwt.AlgRS256
+)
+
+var (
+	// ErrInvalidToken is returned for malformed tokens and tokens with bad signatures or claims
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenExpired is returned for tokens past their expiry
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// Claims are the registered claims checked by the gateway plus the role
+type Claims = jwt.Claims
+
+// VerifyOptions are the checks a token must pass besides its signature
+type VerifyOptions struct {
+	// Issuer must match the iss claim if set
+	Issuer string
+	// Audience must be one of the aud claim's values if set
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway time.Duration
+}
+
+// Verify checks a compact JWT's signature against the key set and its claims
+// against the options, and returns the claims. Tokens must expire.
+func Verify(token string, keys *KeySet, options VerifyOptions, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var h jwt.Header
+	if err := jwt.DecodeSegment(parts[0], &h); err != nil {
+		return Claims{}, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+
+	key, err := keys.Find(h.Kid, h.Alg)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: signature encoding", ErrInvalidToken)
+	}
+	if err := verifySignature(key, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, err
+	}
+
+	var claims Claims
+	if err := jwt.DecodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+
+	if err := validateClaims(claims, options, now); err != nil {
+		return Claims{}, err
+	}
+	return claims, nil
+}
+
+func validateClaims(c Claims, options VerifyOptions, now time.Time) error {
+	if c.Subject == "" {
+		return fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	if c.ExpiresAt == 0 {
+		return fmt.Errorf("%w: missing expiry", ErrInvalidToken)
+	}
+	if now.Add(-options.Leeway).After(time.Unix(c.ExpiresAt, 0)) {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != 0 && now.Add(options.Leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+	if options.Issuer != "" && c.Issuer != options.Issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, c.Issuer)
+	}
+	if options.Audience != "" && !slices.Contains(c.Audience, options.Audience) {
+		return fmt.Errorf("%w: not meant for audience %q", ErrInvalidToken, options.Audience)
+	}
+	return nil
+}
+
+func verifySignature(key Key, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch key.Algorithm {
+	case algHS256:
+		mac := hmac.New(sha256.New, key.Secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	case algRS256:
+		if err := rsa.VerifyPKCS1v15(key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, key.Algorithm)
+	}
+	return nil
+}
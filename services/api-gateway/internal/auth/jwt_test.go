@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"ride-sharing/shared/jwt"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Unix(1_700_000_000, 0)
+
+func validClaims() Claims {
+	return Claims{
+		Subject:   "user-1",
+		Role:      string(RoleRider),
+		Issuer:    "ride-sharing",
+		Audience:  jwt.Audience{"api-gateway"},
+		ExpiresAt: testNow.Add(time.Hour).Unix(),
+	}
+}
+
+func signHS256(t *testing.T, claims Claims, secret string) string {
+	t.Helper()
+	token, err := jwt.SignHS256(claims, []byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func signRS256(t *testing.T, claims Claims, key *rsa.PrivateKey, keyID string) string {
+	t.Helper()
+	headerSegment, err := jwt.EncodeSegment(jwt.Header{Alg: algRS256, Kid: keyID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimsSegment, err := jwt.EncodeSegment(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingInput := headerSegment + "." + claimsSegment
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVerifyHS256(t *testing.T) {
+	keys := NewHMACKeySet([]byte("secret"))
+	options := VerifyOptions{Issuer: "ride-sharing", Audience: "api-gateway", Leeway: 30 * time.Second}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr error
+	}{
+		{"valid", func() string { return signHS256(t, validClaims(), "secret") }, nil},
+		{"wrong secret", func() string { return signHS256(t, validClaims(), "other") }, ErrInvalidToken},
+		{"expired", func() string {
+			claims := validClaims()
+			claims.ExpiresAt = testNow.Add(-time.Minute).Unix()
+			return signHS256(t, claims, "secret")
+		}, ErrTokenExpired},
+		{"expired within leeway", func() string {
+			claims := validClaims()
+			claims.ExpiresAt = testNow.Add(-10 * time.Second).Unix()
+			return signHS256(t, claims, "secret")
+		}, nil},
+		{"not valid yet", func() string {
+			claims := validClaims()
+			claims.NotBefore = testNow.Add(time.Minute).Unix()
+			return signHS256(t, claims, "secret")
+		}, ErrInvalidToken},
+		{"missing expiry", func() string {
+			claims := validClaims()
+			claims.ExpiresAt = 0
+			return signHS256(t, claims, "secret")
+		}, ErrInvalidToken},
+		{"missing subject", func() string {
+			claims := validClaims()
+			claims.Subject = ""
+			return signHS256(t, claims, "secret")
+		}, ErrInvalidToken},
+		{"wrong issuer", func() string {
+			claims := validClaims()
+			claims.Issuer = "someone-else"
+			return signHS256(t, claims, "secret")
+		}, ErrInvalidToken},
+		{"one of several audiences", func() string {
+			claims := validClaims()
+			claims.Audience = jwt.Audience{"web", "api-gateway"}
+			return signHS256(t, claims, "secret")
+		}, nil},
+		{"wrong audience", func() string {
+			claims := validClaims()
+			claims.Audience = jwt.Audience{"web"}
+			return signHS256(t, claims, "secret")
+		}, ErrInvalidToken},
+		{"malformed", func() string { return "not-a-token" }, ErrInvalidToken},
+		{"tampered claims", func() string {
+			token := signHS256(t, validClaims(), "secret")
+			parts := strings.Split(token, ".")
+			claims := validClaims()
+			claims.Subject = "user-2"
+			parts[1], _ = jwt.EncodeSegment(claims)
+			return strings.Join(parts, ".")
+		}, ErrInvalidToken},
+		{"unsigned", func() string {
+			headerSegment, _ := jwt.EncodeSegment(jwt.Header{Alg: "none"})
+			claimsSegment, _ := jwt.EncodeSegment(validClaims())
+			return headerSegment + "." + claimsSegment + "."
+		}, ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := Verify(tt.token(), keys, options, testNow)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.Subject != "user-1" || claims.Role != string(RoleRider) {
+				t.Errorf("Verify() = %+v, want the signed claims", claims)
+			}
+		})
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key, other := generateRSAKey(t), generateRSAKey(t)
+	keys := NewKeySet(Key{ID: "k1", Algorithm: algRS256, PublicKey: &key.PublicKey})
+
+	if _, err := Verify(signRS256(t, validClaims(), key, "k1"), keys, VerifyOptions{}, testNow); err != nil {
+		t.Errorf("Verify() of a valid token error = %v", err)
+	}
+	if _, err := Verify(signRS256(t, validClaims(), other, "k1"), keys, VerifyOptions{}, testNow); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() of a token signed with another key error = %v, want ErrInvalidToken", err)
+	}
+
+	// An HS256 token must not be checked against the RSA key, whatever it is signed with
+	hmacToken := signHS256(t, validClaims(), string(key.PublicKey.N.Bytes()))
+	if _, err := Verify(hmacToken, keys, VerifyOptions{}, testNow); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() of an HS256 token error = %v, want ErrInvalidToken", err)
+	}
+}
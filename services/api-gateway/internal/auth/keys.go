@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+var (
+	// ErrUnknownKey is returned when no key matches a token's key ID and algorithm
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+// Key is a key tokens may be signed with
+type Key struct {
+	// ID matches the kid header of tokens, empty matches tokens without one
+	ID        string
+	Algorithm string
+	// Secret is set for HMAC keys
+	Secret []byte
+	// PublicKey is set for RSA keys
+	PublicKey *rsa.PublicKey
+}
+
+// KeySet holds the keys tokens are verified with
+type KeySet struct {
+	keys []Key
+}
+
+// NewKeySet creates a key set of the given keys
+func NewKeySet(keys ...Key) *KeySet {
+	return &KeySet{keys: keys}
+}
+
+// NewHMACKeySet creates a key set of a single HS256 secret
+func NewHMACKeySet(secret []byte) *KeySet {
+	return NewKeySet(Key{Algorithm: algHS256, Secret: secret})
+}
+
+// Find returns the key for a token's kid and alg headers
+func (s *KeySet) Find(keyID, algorithm string) (Key, error) {
+	for _, key := range s.keys {
+		// A lone key without ID, like an HMAC secret, matches any kid
+		if key.Algorithm == algorithm && (key.ID == keyID || (len(s.keys) == 1 && (keyID == "" || key.ID == ""))) {
+			return key, nil
+		}
+	}
+	return Key{}, fmt.Errorf("%w: kid %q, alg %q", ErrUnknownKey, keyID, algorithm)
+}
+
+// jwk is a JSON Web Key of a JWKS document, see RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA public keys
+	N string `json:"n"`
+	E string `json:"e"`
+	// Symmetric keys
+	K string `json:"k"`
+}
+
+// LoadJWKS reads a key set from a local JWKS file. RSA keys are used for RS256 and
+// symmetric ("oct") keys for HS256. Encryption keys are skipped.
+func LoadJWKS(path string) (*KeySet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	var keys []Key
+	for _, k := range document.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+
+		key, err := k.toKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in JWKS: %w", k.Kid, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no signing keys")
+	}
+	return NewKeySet(keys...), nil
+}
+
+func (k jwk) toKey() (Key, error) {
+	switch k.Kty {
+	case "RSA":
+		if k.Alg != "" && k.Alg != algRS256 {
+			return Key{}, fmt.Errorf("unsupported algorithm %q", k.Alg)
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 {
+			return Key{}, errors.New("invalid exponent")
+		}
+		return Key{
+			ID:        k.Kid,
+			Algorithm: algRS256,
+			PublicKey: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())},
+		}, nil
+
+	case "oct":
+		if k.Alg != "" && k.Alg != algHS256 {
+			return Key{}, fmt.Errorf("unsupported algorithm %q", k.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return Key{}, errors.New("invalid secret")
+		}
+		return Key{ID: k.Kid, Algorithm: algHS256, Secret: secret}, nil
+
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"ride-sharing/shared/jwt"
+	"testing"
+)
+
+func rsaJWK(keyID string, key *rsa.PublicKey) jwk {
+	return jwk{
+		Kty: "RSA",
+		Kid: keyID,
+		Alg: algRS256,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func writeJWKS(t *testing.T, keys ...jwk) string {
+	t.Helper()
+	raw, err := json.Marshal(map[string][]jwk{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKeySetFind(t *testing.T) {
+	keys := NewKeySet(
+		Key{ID: "k1", Algorithm: algRS256},
+		Key{ID: "k2", Algorithm: algRS256},
+		Key{ID: "k3", Algorithm: algHS256},
+	)
+
+	tests := []struct {
+		keyID, algorithm string
+		wantID           string
+	}{
+		{"k1", algRS256, "k1"},
+		{"k2", algRS256, "k2"},
+		{"k3", algHS256, "k3"},
+		// The algorithm has to match the key, not only the ID
+		{"k1", algHS256, ""},
+		{"k4", algRS256, ""},
+		// Without a kid, several keys are ambiguous
+		{"", algRS256, ""},
+	}
+
+	for _, tt := range tests {
+		key, err := keys.Find(tt.keyID, tt.algorithm)
+		if tt.wantID == "" {
+			if !errors.Is(err, ErrUnknownKey) {
+				t.Errorf("Find(%q, %q) = %+v, %v, want ErrUnknownKey", tt.keyID, tt.algorithm, key, err)
+			}
+			continue
+		}
+		if err != nil || key.ID != tt.wantID {
+			t.Errorf("Find(%q, %q) = %+v, %v, want key %s", tt.keyID, tt.algorithm, key, err, tt.wantID)
+		}
+	}
+
+	// A lone key without ID matches any kid
+	single := NewHMACKeySet([]byte("secret"))
+	for _, keyID := range []string{"", "any"} {
+		if _, err := single.Find(keyID, algHS256); err != nil {
+			t.Errorf("Find(%q) on a single HMAC key error = %v", keyID, err)
+		}
+	}
+}
+
+func TestLoadJWKS(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	path := writeJWKS(t,
+		rsaJWK("rsa-1", &rsaKey.PublicKey),
+		jwk{Kty: "oct", Kid: "hmac-1", Alg: algHS256, K: base64.RawURLEncoding.EncodeToString([]byte("secret"))},
+		// Encryption keys are skipped, even of unsupported types
+		jwk{Kty: "EC", Kid: "enc-1", Use: "enc"},
+	)
+
+	keys, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatalf("LoadJWKS() error = %v", err)
+	}
+
+	if _, err := Verify(signRS256(t, validClaims(), rsaKey, "rsa-1"), keys, VerifyOptions{}, testNow); err != nil {
+		t.Errorf("Verify() of an RS256 token error = %v", err)
+	}
+
+	hmacToken, err := signHS256WithKeyID(validClaims(), "secret", "hmac-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(hmacToken, keys, VerifyOptions{}, testNow); err != nil {
+		t.Errorf("Verify() of an HS256 token error = %v", err)
+	}
+
+	if _, err := Verify(signRS256(t, validClaims(), rsaKey, "unknown"), keys, VerifyOptions{}, testNow); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with an unknown kid error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestLoadJWKSRejectsInvalidKeys(t *testing.T) {
+	rsaKey := generateRSAKey(t)
+	wrongAlgorithm := rsaJWK("rsa-1", &rsaKey.PublicKey)
+	wrongAlgorithm.Alg = "RS512"
+	weakExponent := rsaJWK("rsa-1", &rsaKey.PublicKey)
+	weakExponent.E = base64.RawURLEncoding.EncodeToString([]byte{1})
+
+	tests := []struct {
+		name string
+		keys []jwk
+	}{
+		{"no keys", nil},
+		{"only encryption keys", []jwk{{Kty: "RSA", Use: "enc"}}},
+		{"unsupported key type", []jwk{{Kty: "EC", Kid: "ec-1"}}},
+		{"unsupported algorithm", []jwk{wrongAlgorithm}},
+		{"invalid exponent", []jwk{weakExponent}},
+		{"empty secret", []jwk{{Kty: "oct", Kid: "hmac-1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadJWKS(writeJWKS(t, tt.keys...)); err == nil {
+				t.Error("LoadJWKS() succeeded, want an error")
+			}
+		})
+	}
+
+	if _, err := LoadJWKS(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadJWKS() of a missing file succeeded, want an error")
+	}
+}
+
+// signHS256WithKeyID signs like jwt.SignHS256, with a kid header to pick the key of a JWKS
+func signHS256WithKeyID(claims Claims, secret, keyID string) (string, error) {
+	headerSegment, err := jwt.EncodeSegment(jwt.Header{Alg: algHS256, Kid: keyID})
+	if err != nil {
+		return "", err
+	}
+	claimsSegment, err := jwt.EncodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := headerSegment + "." + claimsSegment
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"ride-sharing/shared/jwt"
+	"slices"
+)
+
+// Role is what a principal is allowed to act as
+type Role string
+
+const (
+	RoleRider  Role = jwt.RoleRider
+	RoleDriver Role = jwt.RoleDriver
+	// RoleAdmin may act on every endpoint
+	RoleAdmin Role = jwt.RoleAdmin
+)
+
+// ParseRole parses a role claim
+func ParseRole(name string) (Role, error) {
+	switch role := Role(name); role {
+	case RoleRider, RoleDriver, RoleAdmin:
+		return role, nil
+	default:
+		return "", fmt.Errorf("%w: unknown role %q", ErrInvalidToken, name)
+	}
+}
+
+// Principal is the verified identity behind a request
+type Principal struct {
+	UserID string
+	Role   Role
+}
+
+// HasRole reports whether the principal may act as one of the roles. Admins may act as anyone.
+func (p Principal) HasRole(roles ...Role) bool {
+	return p.Role == RoleAdmin || slices.Contains(roles, p.Role)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal of an authenticated request
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// ResolveUserID returns the ID of the authenticated user, ignoring the client-supplied
+// one. Without a principal, i.e. with authentication disabled, it trusts the client.
+func ResolveUserID(ctx context.Context, claimed string) string {
+	if principal, ok := PrincipalFrom(ctx); ok {
+		return principal.UserID
+	}
+	return claimed
+}
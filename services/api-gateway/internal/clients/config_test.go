@@ -0,0 +1,41 @@
+package clients
+
+import (
+	"ride-sharing/shared/grpcclient"
+	pbd "ride-sharing/shared/proto/driver"
+	pb "ride-sharing/shared/proto/trip"
+	"testing"
+)
+
+// policyOf returns the policy a method is called with
+func policyOf(config grpcclient.Config, method string) grpcclient.MethodPolicy {
+	if policy, ok := config.Methods[method]; ok {
+		return policy
+	}
+	return config.DefaultPolicy
+}
+
+func TestIdempotentMethods(t *testing.T) {
+	tests := []struct {
+		config grpcclient.Config
+		method string
+		want   bool
+	}{
+		// Previews save new fares, creating trips and registering drivers add them again
+		{tripServiceConfig(), pb.TripService_PreviewTrip_FullMethodName, false},
+		{tripServiceConfig(), pb.TripService_CreateTrip_FullMethodName, false},
+		{driverServiceConfig(), pbd.DriverService_RegisterDriver_FullMethodName, false},
+
+		{tripServiceConfig(), pb.TripService_ListChatMessages_FullMethodName, true},
+		{driverServiceConfig(), pbd.DriverService_GetDriver_FullMethodName, true},
+		{driverServiceConfig(), pbd.DriverService_GetDriverAvailability_FullMethodName, true},
+		{driverServiceConfig(), pbd.DriverService_UnregisterDriver_FullMethodName, true},
+		{driverServiceConfig(), pbd.DriverService_UpdateDriverConnection_FullMethodName, true},
+	}
+
+	for _, tt := range tests {
+		if got := policyOf(tt.config, tt.method).Idempotent; got != tt.want {
+			t.Errorf("%s idempotent = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
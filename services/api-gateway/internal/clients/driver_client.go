@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"ride-sharing/shared/grpcclient"
 	pb "ride-sharing/shared/proto/driver"
+	"time"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 type driverServiceClient struct {
@@ -16,13 +17,33 @@ type driverServiceClient struct {
 	conn   *grpc.ClientConn
 }
 
+// driverServiceConfig returns the call policies of driver-service. Registering adds
+// the driver to the dispatch pool and is not repeated, the other calls are.
+func driverServiceConfig() grpcclient.Config {
+	config := grpcclient.DefaultConfig("driver-service")
+	config.DefaultPolicy = grpcclient.MethodPolicy{Timeout: 3 * time.Second}
+	config.Methods = map[string]grpcclient.MethodPolicy{
+		pb.DriverService_RegisterDriver_FullMethodName:         {Timeout: 3 * time.Second},
+		pb.DriverService_UnregisterDriver_FullMethodName:       {Timeout: 3 * time.Second, Idempotent: true},
+		pb.DriverService_GetDriver_FullMethodName:              {Timeout: 2 * time.Second, Idempotent: true},
+		pb.DriverService_UpdateDriverConnection_FullMethodName: {Timeout: 2 * time.Second, Idempotent: true},
+		pb.DriverService_GetDriverAvailability_FullMethodName:  {Timeout: 2 * time.Second, Idempotent: true},
+	}
+	return config
+}
+
 func NewDriverServiceClient() (DriverServiceClient, error) {
 	driverServiceURL := os.Getenv("DRIVER_SERVICE_URL")
 	if driverServiceURL == "" {
 		driverServiceURL = "driver-service:9092"
 	}
 
-	conn, err := grpc.NewClient(driverServiceURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	dialOptions, err := forwardPrincipal()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpcclient.Dial(driverServiceURL, driverServiceConfig(), dialOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -64,3 +85,33 @@ func (c *driverServiceClient) UnRegisterDriver(ctx context.Context, unRegisterDr
 
 	return resp, nil
 }
+
+// GetDriver implements DriverServiceClient.
+func (c *driverServiceClient) GetDriver(ctx context.Context, getDriverRequest *pb.GetDriverRequest) (*pb.GetDriverResponse, error) {
+	resp, err := c.client.GetDriver(ctx, getDriverRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver: %w", err)
+	}
+
+	return resp, nil
+}
+
+// GetDriverAvailability implements DriverServiceClient.
+func (c *driverServiceClient) GetDriverAvailability(ctx context.Context, getDriverRequest *pb.GetDriverRequest) (*pb.GetDriverAvailabilityResponse, error) {
+	resp, err := c.client.GetDriverAvailability(ctx, getDriverRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver availability: %w", err)
+	}
+
+	return resp, nil
+}
+
+// UpdateDriverConnection implements DriverServiceClient.
+func (c *driverServiceClient) UpdateDriverConnection(ctx context.Context, updateDriverConnectionRequest *pb.UpdateDriverConnectionRequest) (*pb.UpdateDriverConnectionResponse, error) {
+	resp, err := c.client.UpdateDriverConnection(ctx, updateDriverConnectionRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update driver connection: %w", err)
+	}
+
+	return resp, nil
+}
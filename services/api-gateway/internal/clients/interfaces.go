@@ -9,11 +9,15 @@ import (
 type TripServiceClient interface {
 	PreviewTrip(ctx context.Context, previewTripRequest *tripPb.PreviewTripRequest) (*tripPb.PreviewTripResponse, error)
 	CreateTrip(ctx context.Context, createTripRequest *tripPb.CreateTripRequest) (*tripPb.CreateTripResponse, error)
+	ListChatMessages(ctx context.Context, listChatMessagesRequest *tripPb.ListChatMessagesRequest) (*tripPb.ListChatMessagesResponse, error)
 	Close()
 }
 
 type DriverServiceClient interface {
 	RegisterDriver(ctx context.Context, registerDriverRequest *driverPb.RegisterDriverRequest) (*driverPb.RegisterDriverResponse, error)
 	UnRegisterDriver(ctx context.Context, unRegisterDriverRequest *driverPb.RegisterDriverRequest) (*driverPb.RegisterDriverResponse, error)
+	GetDriver(ctx context.Context, getDriverRequest *driverPb.GetDriverRequest) (*driverPb.GetDriverResponse, error)
+	GetDriverAvailability(ctx context.Context, getDriverRequest *driverPb.GetDriverRequest) (*driverPb.GetDriverAvailabilityResponse, error)
+	UpdateDriverConnection(ctx context.Context, updateDriverConnectionRequest *driverPb.UpdateDriverConnectionRequest) (*driverPb.UpdateDriverConnectionResponse, error)
 	Close()
 }
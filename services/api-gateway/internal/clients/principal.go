@@ -0,0 +1,41 @@
+package clients
+
+import (
+	"context"
+	"ride-sharing/services/api-gateway/internal/auth"
+	"ride-sharing/shared/grpcserver"
+
+	"google.golang.org/grpc"
+)
+
+// forwardPrincipal sends the service token and the authenticated principal of a call's
+// context along as metadata, so the services can log it and refuse requests made for
+// another user. The services only trust the principal of callers with the token.
+func forwardPrincipal() ([]grpc.DialOption, error) {
+	serviceToken, err := grpcserver.ServiceTokenFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			ctx = grpcserver.OutgoingServiceContext(withPrincipal(ctx), serviceToken)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			ctx = grpcserver.OutgoingServiceContext(withPrincipal(ctx), serviceToken)
+			return streamer(ctx, desc, cc, method, opts...)
+		}),
+	}, nil
+}
+
+func withPrincipal(ctx context.Context) context.Context {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return ctx
+	}
+	return grpcserver.OutgoingContext(ctx, grpcserver.Principal{
+		UserID: principal.UserID,
+		Role:   string(principal.Role),
+	})
+}
@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"ride-sharing/shared/grpcclient"
 	pb "ride-sharing/shared/proto/trip"
+	"time"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 type tripServiceClient struct {
@@ -16,13 +17,31 @@ type tripServiceClient struct {
 	conn   *grpc.ClientConn
 }
 
+// tripServiceConfig returns the call policies of trip-service. Previews wait for the
+// routing API. They are not retried either, every preview looks up a route and saves
+// new fares, so a retry would pay for the lookup twice and leave duplicate fares.
+func tripServiceConfig() grpcclient.Config {
+	config := grpcclient.DefaultConfig("trip-service")
+	config.Methods = map[string]grpcclient.MethodPolicy{
+		pb.TripService_PreviewTrip_FullMethodName:      {Timeout: 10 * time.Second},
+		pb.TripService_CreateTrip_FullMethodName:       {Timeout: 5 * time.Second},
+		pb.TripService_ListChatMessages_FullMethodName: {Timeout: 3 * time.Second, Idempotent: true},
+	}
+	return config
+}
+
 func NewTripServiceClient() (TripServiceClient, error) {
 	tripServiceURL := os.Getenv("TRIP_SERVICE_URL")
 	if tripServiceURL == "" {
 		tripServiceURL = "trip-service:9093"
 	}
 
-	conn, err := grpc.NewClient(tripServiceURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	dialOptions, err := forwardPrincipal()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpcclient.Dial(tripServiceURL, tripServiceConfig(), dialOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -64,3 +83,13 @@ func (c *tripServiceClient) PreviewTrip(ctx context.Context, previewTripRequest
 
 	return resp, nil
 }
+
+// ListChatMessages implements TripServiceClient.
+func (c *tripServiceClient) ListChatMessages(ctx context.Context, listChatMessagesRequest *pb.ListChatMessagesRequest) (*pb.ListChatMessagesResponse, error) {
+	resp, err := c.client.ListChatMessages(ctx, listChatMessagesRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat messages: %w", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/trip"
+	"time"
+)
+
+// ChatHistoryResponse is the chat of a trip. Messages have the shape of the
+// chat.event.message payloads, oldest first.
+type ChatHistoryResponse struct {
+	Messages []messaging.ChatMessageData `json:"messages"`
+	// Closed is set once the trip ended, no more messages can be sent
+	Closed bool `json:"closed"`
+}
+
+func ChatHistoryFromProto(resp *pb.ListChatMessagesResponse) ChatHistoryResponse {
+	history := ChatHistoryResponse{
+		Messages: make([]messaging.ChatMessageData, 0, len(resp.GetMessages())),
+		Closed:   resp.GetClosed(),
+	}
+
+	for _, m := range resp.GetMessages() {
+		history.Messages = append(history.Messages, messaging.ChatMessageData{
+			ID:          m.GetId(),
+			TripID:      m.GetTripID(),
+			SenderID:    m.GetSenderID(),
+			SenderRole:  m.GetSenderRole(),
+			Text:        m.GetText(),
+			SentAt:      time.UnixMilli(m.GetSentAtUnixMillis()).UTC(),
+			DeliveredAt: optionalUnixMillis(m.GetDeliveredAtUnixMillis()),
+			ReadAt:      optionalUnixMillis(m.GetReadAtUnixMillis()),
+		})
+	}
+
+	return history
+}
+
+// optionalUnixMillis converts a time of a proto message, which is zero when unset
+func optionalUnixMillis(millis int64) *time.Time {
+	if millis == 0 {
+		return nil
+	}
+	t := time.UnixMilli(millis).UTC()
+	return &t
+}
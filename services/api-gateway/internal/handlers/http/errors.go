@@ -0,0 +1,127 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"ride-sharing/shared/contracts"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error is an error with the HTTP status and API error code it is reported with
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// BadRequest returns an Error for an invalid request
+func BadRequest(message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: contracts.ErrCodeBadRequest, Message: message}
+}
+
+// reasonStatuses are the HTTP statuses of the domain specific error codes services
+// send as the reason of their gRPC errors
+var reasonStatuses = map[string]int{
+	contracts.ErrCodeNotFound:     http.StatusNotFound,
+	contracts.ErrCodeFareNotOwned: http.StatusForbidden,
+	contracts.ErrCodeFareExpired:  http.StatusGone,
+}
+
+// codeErrors translate the gRPC status codes without a known reason
+var codeErrors = map[codes.Code]Error{
+	codes.InvalidArgument:    {Status: http.StatusBadRequest, Code: contracts.ErrCodeBadRequest},
+	codes.OutOfRange:         {Status: http.StatusBadRequest, Code: contracts.ErrCodeBadRequest},
+	codes.NotFound:           {Status: http.StatusNotFound, Code: contracts.ErrCodeNotFound},
+	codes.AlreadyExists:      {Status: http.StatusConflict, Code: contracts.ErrCodeConflict},
+	codes.Aborted:            {Status: http.StatusConflict, Code: contracts.ErrCodeConflict},
+	codes.FailedPrecondition: {Status: http.StatusConflict, Code: contracts.ErrCodeConflict},
+	codes.PermissionDenied:   {Status: http.StatusForbidden, Code: contracts.ErrCodeForbidden},
+	codes.Unauthenticated:    {Status: http.StatusUnauthorized, Code: contracts.ErrCodeUnauthorized},
+	codes.ResourceExhausted:  {Status: http.StatusTooManyRequests, Code: contracts.ErrCodeRateLimited},
+	codes.Unavailable:        {Status: http.StatusServiceUnavailable, Code: contracts.ErrCodeServiceUnavailable, Message: "service unavailable, retry later"},
+	codes.DeadlineExceeded:   {Status: http.StatusGatewayTimeout, Code: contracts.ErrCodeTimeout, Message: "the request timed out"},
+}
+
+// internalError hides the details of unexpected errors from clients, they are only logged
+var internalError = Error{Status: http.StatusInternalServerError, Code: contracts.ErrCodeInternal, Message: "internal error"}
+
+// translateError maps an error to what the client is told: an Error as is, gRPC
+// errors by their reason or status code and everything else to an internal error
+func translateError(err error) Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return *apiErr
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return codeErrors[codes.DeadlineExceeded]
+	}
+
+	// The status itself, not status.FromError, whose message includes the wrapping errors
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return internalError
+	}
+	st := grpcErr.GRPCStatus()
+
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok {
+			continue
+		}
+		if httpStatus, known := reasonStatuses[info.Reason]; known {
+			return Error{Status: httpStatus, Code: info.Reason, Message: st.Message()}
+		}
+	}
+
+	translated, known := codeErrors[st.Code()]
+	if !known {
+		return internalError
+	}
+	if translated.Message == "" {
+		translated.Message = st.Message()
+	}
+	return translated
+}
+
+// WriteError replies with err as an APIResponse error carrying the request ID.
+// Server errors are logged with the original error.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	translated := translateError(err)
+	requestID := RequestIDFrom(r.Context())
+
+	if translated.Status >= http.StatusInternalServerError {
+		log.Printf("[%s] %s %s failed: %v", requestID, r.Method, r.URL.Path, err)
+	}
+
+	WriteJSON(w, translated.Status, contracts.APIResponse{
+		Error: &contracts.APIError{
+			Code:      translated.Code,
+			Message:   translated.Message,
+			RequestID: requestID,
+		},
+	})
+}
+
+// WriteUpgradeError reports a failed WebSocket handshake as an APIResponse error.
+// It fits the Error field of websocket.Upgrader.
+func WriteUpgradeError(w http.ResponseWriter, r *http.Request, status int, reason error) {
+	code := contracts.ErrCodeBadRequest
+	switch {
+	case status == http.StatusForbidden:
+		code = contracts.ErrCodeForbidden
+	case status >= http.StatusInternalServerError:
+		code = contracts.ErrCodeInternal
+	}
+
+	WriteError(w, r, &Error{Status: status, Code: code, Message: reason.Error()})
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"ride-sharing/shared/contracts"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// reasonError returns a gRPC error with a reason, the way the services report domain errors
+func reasonError(t *testing.T, code codes.Code, reason, message string) error {
+	t.Helper()
+	st, err := status.New(code, message).WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: "trip-service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.Err()
+}
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		// Reasons win over status codes
+		{"fare not owned", reasonError(t, codes.PermissionDenied, contracts.ErrCodeFareNotOwned, "fare not owned"), http.StatusForbidden, contracts.ErrCodeFareNotOwned, "fare not owned"},
+		{"fare expired", reasonError(t, codes.FailedPrecondition, contracts.ErrCodeFareExpired, "fare expired"), http.StatusGone, contracts.ErrCodeFareExpired, "fare expired"},
+		{"not found reason", reasonError(t, codes.NotFound, contracts.ErrCodeNotFound, "trip not found"), http.StatusNotFound, contracts.ErrCodeNotFound, "trip not found"},
+		{"unknown reason", reasonError(t, codes.PermissionDenied, "SOMETHING_NEW", "denied"), http.StatusForbidden, contracts.ErrCodeForbidden, "denied"},
+		{"wrapped reason", fmt.Errorf("failed to create trip: %w", reasonError(t, codes.PermissionDenied, contracts.ErrCodeFareNotOwned, "fare not owned")), http.StatusForbidden, contracts.ErrCodeFareNotOwned, "fare not owned"},
+
+		{"invalid argument", status.Error(codes.InvalidArgument, "bad fare"), http.StatusBadRequest, contracts.ErrCodeBadRequest, "bad fare"},
+		{"out of range", status.Error(codes.OutOfRange, "too far"), http.StatusBadRequest, contracts.ErrCodeBadRequest, "too far"},
+		{"not found", status.Error(codes.NotFound, "no trip"), http.StatusNotFound, contracts.ErrCodeNotFound, "no trip"},
+		{"already exists", status.Error(codes.AlreadyExists, "exists"), http.StatusConflict, contracts.ErrCodeConflict, "exists"},
+		{"aborted", status.Error(codes.Aborted, "conflict"), http.StatusConflict, contracts.ErrCodeConflict, "conflict"},
+		{"failed precondition", status.Error(codes.FailedPrecondition, "wrong state"), http.StatusConflict, contracts.ErrCodeConflict, "wrong state"},
+		{"permission denied", status.Error(codes.PermissionDenied, "denied"), http.StatusForbidden, contracts.ErrCodeForbidden, "denied"},
+		{"unauthenticated", status.Error(codes.Unauthenticated, "who"), http.StatusUnauthorized, contracts.ErrCodeUnauthorized, "who"},
+		{"resource exhausted", status.Error(codes.ResourceExhausted, "slow down"), http.StatusTooManyRequests, contracts.ErrCodeRateLimited, "slow down"},
+		// Infrastructure failures get a fixed message
+		{"unavailable", status.Error(codes.Unavailable, "dial tcp 10.0.0.1:9093"), http.StatusServiceUnavailable, contracts.ErrCodeServiceUnavailable, "service unavailable, retry later"},
+		{"deadline exceeded", status.Error(codes.DeadlineExceeded, "deadline"), http.StatusGatewayTimeout, contracts.ErrCodeTimeout, "the request timed out"},
+		{"context deadline", fmt.Errorf("preview: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, contracts.ErrCodeTimeout, "the request timed out"},
+
+		// Details of unexpected errors stay hidden
+		{"internal", status.Error(codes.Internal, "failed to save fare: pq: connection refused"), http.StatusInternalServerError, contracts.ErrCodeInternal, "internal error"},
+		{"unknown code", status.Error(codes.Unknown, "panic"), http.StatusInternalServerError, contracts.ErrCodeInternal, "internal error"},
+		{"plain error", errors.New("boom"), http.StatusInternalServerError, contracts.ErrCodeInternal, "internal error"},
+
+		{"API error", fmt.Errorf("decode: %w", BadRequest("invalid JSON")), http.StatusBadRequest, contracts.ErrCodeBadRequest, "invalid JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := translateError(tt.err)
+			want := Error{Status: tt.wantStatus, Code: tt.wantCode, Message: tt.wantMessage}
+			if got != want {
+				t.Errorf("translateError() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/trip/start", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+
+	RequestID(func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, r, reasonError(t, codes.PermissionDenied, contracts.ErrCodeFareNotOwned, "fare not owned"))
+	})(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	var response contracts.APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+	}
+	want := contracts.APIError{Code: contracts.ErrCodeFareNotOwned, Message: "fare not owned", RequestID: "req-1"}
+	if response.Error == nil || *response.Error != want {
+		t.Errorf("error = %+v, want %+v", response.Error, want)
+	}
+}
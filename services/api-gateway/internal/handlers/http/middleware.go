@@ -1,12 +1,19 @@
 package http
 
-import "net/http"
+import (
+	"errors"
+	"log"
+	"net/http"
+	"ride-sharing/services/api-gateway/internal/auth"
+	"ride-sharing/shared/contracts"
+)
 
 func EnableCORS(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,DELETE,PUT,OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After, X-Request-ID")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -16,3 +23,50 @@ func EnableCORS(handler http.HandlerFunc) http.HandlerFunc {
 		handler(w, r)
 	}
 }
+
+// Authenticate lets requests through whose principal has one of the roles, and puts
+// the principal into the request context. A nil authenticator disables authentication.
+func Authenticate(authenticator auth.Authenticator, handler http.HandlerFunc, roles ...auth.Role) http.HandlerFunc {
+	if authenticator == nil {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			message := "invalid token"
+			switch {
+			case errors.Is(err, auth.ErrNoCredentials):
+				message = "authentication required"
+			case errors.Is(err, auth.ErrTokenExpired):
+				message = "token expired"
+			}
+
+			log.Printf("Rejected unauthenticated request to %s: %v", r.URL.Path, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ride-sharing"`)
+			WriteError(w, r, &Error{Status: http.StatusUnauthorized, Code: contracts.ErrCodeUnauthorized, Message: message})
+			return
+		}
+
+		if !principal.HasRole(roles...) {
+			log.Printf("Rejected %s %s with role %s from %s", r.Method, r.URL.Path, principal.Role, principal.UserID)
+			WriteError(w, r, &Error{
+				Status:  http.StatusForbidden,
+				Code:    contracts.ErrCodeForbidden,
+				Message: "not allowed for role " + string(principal.Role),
+			})
+			return
+		}
+
+		handler(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// AuthenticateStream is Authenticate for WebSocket and event stream routes, which
+// also accept the token in the URL or the WebSocket subprotocol
+func AuthenticateStream(authenticator auth.Authenticator, handler http.HandlerFunc, roles ...auth.Role) http.HandlerFunc {
+	authenticate := Authenticate(authenticator, handler, roles...)
+	return func(w http.ResponseWriter, r *http.Request) {
+		authenticate(w, r.WithContext(auth.WithStreamCredentials(r.Context())))
+	}
+}
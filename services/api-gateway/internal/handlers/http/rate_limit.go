@@ -0,0 +1,178 @@
+package http
+
+import (
+	"expvar"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"ride-sharing/services/api-gateway/internal/auth"
+	"ride-sharing/services/api-gateway/internal/ratelimit"
+	"ride-sharing/shared/contracts"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitMetrics counts the rejected requests of each rate limiter on /debug/vars
+var rateLimitMetrics = expvar.NewMap("rate_limited")
+
+// RateLimitConfig holds the settings of a rate limiter
+type RateLimitConfig struct {
+	Policy ratelimit.Policy
+	// TrustedProxies is how many proxies in front of the gateway append to the
+	// X-Forwarded-For header. The client IP is the entry the outermost of them
+	// appended, entries left of it are set by clients and can be forged. 0 ignores
+	// the header.
+	TrustedProxies int
+}
+
+// RateLimiter keeps a token bucket per client, keyed by the authenticated user or
+// the client IP for anonymous requests
+type RateLimiter struct {
+	name    string
+	config  RateLimitConfig
+	buckets map[string]*ratelimit.TokenBucket
+	// lastSweep is when full buckets were last dropped, they are the same as new ones
+	lastSweep time.Time
+	mutex     sync.Mutex
+	now       func() time.Time
+}
+
+// NewRateLimiter creates a rate limiter, named for logs and metrics. It returns nil,
+// which RateLimit treats as no limit, if the policy is disabled.
+func NewRateLimiter(name string, config RateLimitConfig) *RateLimiter {
+	if !config.Policy.Enabled() {
+		return nil
+	}
+
+	return &RateLimiter{
+		name:      name,
+		config:    config,
+		buckets:   make(map[string]*ratelimit.TokenBucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// rateLimitResult is the state of a client's bucket after a request
+type rateLimitResult struct {
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration
+	reset      time.Duration
+}
+
+func (l *RateLimiter) allow(key string, now time.Time) rateLimitResult {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if now.Sub(l.lastSweep) > l.config.Policy.Period {
+		l.sweep(now)
+	}
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = ratelimit.NewTokenBucket(l.config.Policy, now)
+		l.buckets[key] = bucket
+	}
+
+	allowed, retryAfter := bucket.Take(now)
+	return rateLimitResult{
+		allowed:    allowed,
+		remaining:  bucket.Remaining(),
+		retryAfter: retryAfter,
+		reset:      bucket.UntilFull(),
+	}
+}
+
+// sweep drops the buckets which refilled completely. The caller must hold the lock.
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.Full(now) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// clientKey identifies the client of a request, the user if authenticated and the IP otherwise
+func (l *RateLimiter) clientKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		return "user:" + principal.UserID
+	}
+
+	if client := forwardedClient(r, l.config.TrustedProxies); client != "" {
+		return "ip:" + client
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// forwardedClient returns the client IP which the outermost of trustedProxies proxies
+// appended to the X-Forwarded-For header, or "" if the header isn't trusted or set
+func forwardedClient(r *http.Request, trustedProxies int) string {
+	if trustedProxies <= 0 {
+		return ""
+	}
+
+	var hops []string
+	// Proxies may append their own header instead of extending the first one
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) == 0 {
+		return ""
+	}
+
+	// A shorter chain than expected was sent by a client close to the proxies, its
+	// first entry was still appended by one of them
+	return hops[max(len(hops)-trustedProxies, 0)]
+}
+
+// RateLimit rejects requests beyond the limiter's policy with 429 Too Many Requests.
+// All responses carry the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers. It must wrap handlers after Authenticate to limit users instead of IPs.
+// A nil limiter lets all requests through.
+func RateLimit(limiter *RateLimiter, handler http.HandlerFunc) http.HandlerFunc {
+	if limiter == nil {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := limiter.clientKey(r)
+		result := limiter.allow(key, limiter.now())
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limiter.config.Policy.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.reset)))
+
+		if !result.allowed {
+			rateLimitMetrics.Add(limiter.name, 1)
+			log.Printf("Rate limited %s on %s", key, limiter.name)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.retryAfter)))
+			WriteError(w, r, &Error{
+				Status:  http.StatusTooManyRequests,
+				Code:    contracts.ErrCodeRateLimited,
+				Message: "too many requests, retry later",
+			})
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds, as the rate limit headers count in seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"ride-sharing/services/api-gateway/internal/auth"
+	"ride-sharing/services/api-gateway/internal/ratelimit"
+	"ride-sharing/shared/contracts"
+	"testing"
+	"time"
+)
+
+var testStart = time.Unix(1_700_000_000, 0)
+
+// testLimiter returns a limiter whose clock is moved by hand through the returned pointer
+func testLimiter(t *testing.T, policy string, trustedProxies int) (*RateLimiter, *time.Time) {
+	t.Helper()
+	parsed, err := ratelimit.ParsePolicy(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := testStart
+	limiter := NewRateLimiter(t.Name(), RateLimitConfig{Policy: parsed, TrustedProxies: trustedProxies})
+	limiter.lastSweep = now
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func limitedRequest(remoteAddr string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/trip/preview", nil)
+	r.RemoteAddr = remoteAddr
+	return r
+}
+
+func serveLimited(limiter *RateLimiter, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	RateLimit(limiter, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})(w, r)
+	return w
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	limiter, now := testLimiter(t, "3/10s", 0)
+
+	tests := []struct {
+		after         time.Duration
+		wantStatus    int
+		wantRemaining string
+		wantReset     string
+		wantRetry     string
+	}{
+		{0, http.StatusNoContent, "2", "4", ""},
+		{0, http.StatusNoContent, "1", "7", ""},
+		{0, http.StatusNoContent, "0", "10", ""},
+		// A token takes 3.33s to refill, the headers round up to whole seconds
+		{0, http.StatusTooManyRequests, "0", "10", "4"},
+		{time.Second, http.StatusTooManyRequests, "0", "9", "3"},
+		{4 * time.Second, http.StatusNoContent, "0", "10", ""},
+	}
+
+	for i, tt := range tests {
+		*now = testStart.Add(tt.after)
+		w := serveLimited(limiter, limitedRequest("192.0.2.1:1234"))
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("request %d: status = %d, want %d", i, w.Code, tt.wantStatus)
+		}
+		headers := map[string]string{
+			"RateLimit-Limit":     "3",
+			"RateLimit-Remaining": tt.wantRemaining,
+			"RateLimit-Reset":     tt.wantReset,
+			"Retry-After":         tt.wantRetry,
+		}
+		for name, want := range headers {
+			if got := w.Header().Get(name); got != want {
+				t.Errorf("request %d: %s = %q, want %q", i, name, got, want)
+			}
+		}
+	}
+}
+
+func TestRateLimitRejection(t *testing.T) {
+	limiter, _ := testLimiter(t, "1/1m", 0)
+	serveLimited(limiter, limitedRequest("192.0.2.1:1234"))
+
+	w := serveLimited(limiter, limitedRequest("192.0.2.1:1234"))
+
+	var response contracts.APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+	}
+	if response.Error == nil || response.Error.Code != contracts.ErrCodeRateLimited {
+		t.Errorf("error = %+v, want %s", response.Error, contracts.ErrCodeRateLimited)
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	withUser := func(r *http.Request, userID string) *http.Request {
+		return r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: userID, Role: auth.RoleRider}))
+	}
+	forwarded := func(r *http.Request, headers ...string) *http.Request {
+		for _, header := range headers {
+			r.Header.Add("X-Forwarded-For", header)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name           string
+		trustedProxies int
+		first, second  *http.Request
+		wantShared     bool
+	}{
+		{"same IP", 0, limitedRequest("192.0.2.1:1234"), limitedRequest("192.0.2.1:5678"), true},
+		{"other IP", 0, limitedRequest("192.0.2.1:1234"), limitedRequest("192.0.2.2:1234"), false},
+		{"users behind one IP", 0, withUser(limitedRequest("192.0.2.1:1234"), "u1"), withUser(limitedRequest("192.0.2.1:1234"), "u2"), false},
+		{"user on two IPs", 0, withUser(limitedRequest("192.0.2.1:1234"), "u1"), withUser(limitedRequest("192.0.2.2:1234"), "u1"), true},
+		{"untrusted forwarded header", 0, forwarded(limitedRequest("192.0.2.1:1234"), "198.51.100.1"), forwarded(limitedRequest("192.0.2.1:1234"), "198.51.100.2"), true},
+		{"trusted forwarded header", 1, forwarded(limitedRequest("10.0.0.1:1234"), "198.51.100.1"), forwarded(limitedRequest("10.0.0.1:1234"), "198.51.100.2"), false},
+		// The proxy appends the address it saw to the entries the client sent, which
+		// change with every request
+		{"client of the forwarded chain", 1, forwarded(limitedRequest("10.0.0.1:1234"), "203.0.113.1, 198.51.100.1"), forwarded(limitedRequest("10.0.0.2:1234"), "203.0.113.2, 198.51.100.1"), true},
+		{"client behind two proxies", 2, forwarded(limitedRequest("10.0.0.1:1234"), "203.0.113.1, 198.51.100.1, 10.0.1.1"), forwarded(limitedRequest("10.0.0.1:1234"), "203.0.113.2, 198.51.100.1, 10.0.1.2"), true},
+		{"chain split across headers", 1, forwarded(limitedRequest("10.0.0.1:1234"), "203.0.113.1", "198.51.100.1"), forwarded(limitedRequest("10.0.0.1:1234"), "198.51.100.1"), true},
+		{"chain shorter than the proxies", 2, forwarded(limitedRequest("10.0.0.1:1234"), "198.51.100.1"), forwarded(limitedRequest("10.0.0.1:1234"), "198.51.100.2"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, _ := testLimiter(t, "1/1m", tt.trustedProxies)
+			serveLimited(limiter, tt.first)
+
+			limited := serveLimited(limiter, tt.second).Code == http.StatusTooManyRequests
+			if limited != tt.wantShared {
+				t.Errorf("second request limited = %v, want %v", limited, tt.wantShared)
+			}
+		})
+	}
+}
+
+func TestRateLimiterSweepsFullBuckets(t *testing.T) {
+	limiter, now := testLimiter(t, "2/1m", 0)
+	serveLimited(limiter, limitedRequest("192.0.2.1:1234"))
+	*now = testStart.Add(50 * time.Second)
+	serveLimited(limiter, limitedRequest("192.0.2.2:1234"))
+	serveLimited(limiter, limitedRequest("192.0.2.2:1234"))
+
+	// A period after the last sweep the first bucket is full, the second one still refilling
+	*now = testStart.Add(70 * time.Second)
+	serveLimited(limiter, limitedRequest("192.0.2.3:1234"))
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if _, ok := limiter.buckets["ip:192.0.2.1"]; ok {
+		t.Error("full bucket was not swept")
+	}
+	if len(limiter.buckets) != 2 {
+		t.Errorf("%d buckets left, want the refilling and the new one", len(limiter.buckets))
+	}
+}
+
+func TestRateLimitOff(t *testing.T) {
+	if limiter := NewRateLimiter("off", RateLimitConfig{}); limiter != nil {
+		t.Fatalf("NewRateLimiter() of a disabled policy = %+v, want nil", limiter)
+	}
+
+	for range 3 {
+		if w := serveLimited(nil, limitedRequest("192.0.2.1:1234")); w.Code != http.StatusNoContent || w.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("request without a limiter: status %d, headers %v", w.Code, w.Header())
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"ride-sharing/shared/requestid"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the ID of a request, set by clients or proxies or generated by the gateway
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs, longer ones are replaced
+const maxRequestIDLength = 128
+
+// RequestID gives every request an ID, keeping a valid one sent by the client, and
+// returns it in the X-Request-ID response header
+func RequestID(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		handler(w, r.WithContext(requestid.NewContext(r.Context(), requestID)))
+	}
+}
+
+// RequestIDFrom returns the ID of the request of a context. The gRPC clients send it
+// along to the services.
+func RequestIDFrom(ctx context.Context) string {
+	return requestid.FromContext(ctx)
+}
+
+// validRequestID only accepts printable ASCII, so IDs can't inject into logs or headers
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
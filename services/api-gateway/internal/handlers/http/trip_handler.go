@@ -2,11 +2,13 @@ package http
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"net/http"
+	"ride-sharing/services/api-gateway/internal/auth"
 	"ride-sharing/services/api-gateway/internal/clients"
 	"ride-sharing/services/api-gateway/internal/dto"
 	"ride-sharing/shared/contracts"
+	pb "ride-sharing/shared/proto/trip"
 )
 
 type TripHandler struct {
@@ -24,19 +26,19 @@ func (h *TripHandler) HandleTripPreview(w http.ResponseWriter, r *http.Request)
 	var reqBody dto.PreviewTripRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		http.Error(w, "failed to parse JSON data", http.StatusBadRequest)
+		WriteError(w, r, BadRequest("failed to parse JSON data"))
 		return
 	}
 
+	reqBody.UserID = auth.ResolveUserID(r.Context(), reqBody.UserID)
 	if reqBody.UserID == "" {
-		http.Error(w, "UserID is required", http.StatusBadRequest)
+		WriteError(w, r, BadRequest("userID is required"))
 		return
 	}
 
 	tripPreview, err := h.tripClient.PreviewTrip(r.Context(), reqBody.ToProto())
 	if err != nil {
-		log.Printf("failed to preview trip: %v", err)
-		http.Error(w, "failed to preview trip", http.StatusInternalServerError)
+		WriteError(w, r, fmt.Errorf("failed to preview trip: %w", err))
 		return
 	}
 
@@ -49,17 +51,56 @@ func (h *TripHandler) HandleCreateTrip(w http.ResponseWriter, r *http.Request) {
 	var reqBody dto.StartTripRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		http.Error(w, "Failed to parse JSON data", http.StatusBadRequest)
+		WriteError(w, r, BadRequest("failed to parse JSON data"))
+		return
+	}
+
+	reqBody.UserID = auth.ResolveUserID(r.Context(), reqBody.UserID)
+	if reqBody.UserID == "" {
+		WriteError(w, r, BadRequest("userID is required"))
+		return
+	}
+
+	if reqBody.RideFareID == "" {
+		WriteError(w, r, BadRequest("rideFareID is required"))
 		return
 	}
 
 	trip, err := h.tripClient.CreateTrip(r.Context(), reqBody.ToProto())
 	if err != nil {
-		log.Printf("Failed to create trip: %v", err)
-		http.Error(w, "Failed to create trip", http.StatusInternalServerError)
+		WriteError(w, r, fmt.Errorf("failed to create trip: %w", err))
 		return
 	}
 
 	response := contracts.APIResponse{Data: trip}
 	WriteJSON(w, http.StatusCreated, response)
 }
+
+// Http handler to list the chat messages of a trip, for its rider or assigned driver
+func (h *TripHandler) HandleChatHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	userID := auth.ResolveUserID(r.Context(), query.Get("userID"))
+	if userID == "" {
+		WriteError(w, r, BadRequest("userID is required"))
+		return
+	}
+
+	tripID := query.Get("tripID")
+	if tripID == "" {
+		WriteError(w, r, BadRequest("tripID is required"))
+		return
+	}
+
+	history, err := h.tripClient.ListChatMessages(r.Context(), &pb.ListChatMessagesRequest{
+		TripID: tripID,
+		UserID: userID,
+	})
+	if err != nil {
+		WriteError(w, r, fmt.Errorf("failed to list chat messages: %w", err))
+		return
+	}
+
+	response := contracts.APIResponse{Data: dto.ChatHistoryFromProto(history)}
+	WriteJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/google/uuid"
+)
+
+// handleChatCommand relays a validated chat command of a rider or driver to
+// trip-service, which checks that they take part in the trip's chat. Messages come
+// back as chat.event.message correlated to the command's ID, refusals as chat.event.error.
+func (h *WebSocketHandler) handleChatCommand(ctx context.Context, userID, sessionID string, msg contracts.WSRawMessage, payload any) {
+	commandID := msg.ID
+	if commandID == "" {
+		commandID = uuid.NewString()
+	}
+
+	command, err := h.publishChatCommand(ctx, userID, msg.Type, commandID, payload)
+	if err != nil {
+		log.Printf("Error publishing %s of user %s: %v", msg.Type, userID, err)
+
+		message := contracts.NewWSMessage(contracts.ChatEventError, contracts.WSCommandResultData{
+			Command: msg.Type,
+			TripID:  command.TripID,
+			Code:    contracts.ErrCodeServiceUnavailable,
+			Message: "failed to relay the command, retry later",
+		})
+		message.CorrelationID = commandID
+		h.sendToSession(userID, sessionID, message)
+	}
+}
+
+// publishChatCommand publishes a chat command with its validated payload. The sender
+// is the authenticated user, whatever the payload says.
+func (h *WebSocketHandler) publishChatCommand(ctx context.Context, userID, commandType, commandID string, payload any) (messaging.ChatCommandData, error) {
+	command := messaging.ChatCommandData{CommandID: commandID}
+
+	switch payload := payload.(type) {
+	case *contracts.WSChatSendData:
+		command.TripID = payload.TripID
+		command.Text = payload.Text
+	case *contracts.WSChatReceiptData:
+		command.TripID = payload.TripID
+		command.MessageID = payload.MessageID
+		command.Status = payload.Status
+	}
+
+	marshalledCommand, err := json.Marshal(command)
+	if err != nil {
+		return command, err
+	}
+
+	return command, h.messageBroker.Publish(ctx, commandType, contracts.AmqpMessage{
+		OwnerID: userID,
+		Data:    marshalledCommand,
+	})
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	httpHandlers "ride-sharing/services/api-gateway/internal/handlers/http"
+	gatewayws "ride-sharing/services/api-gateway/internal/websocket"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
 	pb "ride-sharing/shared/proto/driver"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -16,7 +19,13 @@ import (
 func (h *WebSocketHandler) HandleDriverConnection(w http.ResponseWriter, r *http.Request) {
 	userID, packageSlug, err := validateDriverParams(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpHandlers.WriteError(w, r, httpHandlers.BadRequest(err.Error()))
+		return
+	}
+
+	lastSeq, err := validateLastSeq(r)
+	if err != nil {
+		httpHandlers.WriteError(w, r, httpHandlers.BadRequest(err.Error()))
 		return
 	}
 
@@ -28,94 +37,173 @@ func (h *WebSocketHandler) HandleDriverConnection(w http.ResponseWriter, r *http
 	defer conn.Close()
 
 	ctx := r.Context()
-	h.connManager.Add(userID, conn)
+	sessionID, err := h.connManager.Add(userID, conn)
+	if err != nil {
+		log.Printf("Rejected connection of driver %s: %v", userID, err)
+		gatewayws.CloseConnection(conn, err.Error())
+		return
+	}
+
+	// A driver reconnecting within the grace period picks up their session again
+	if h.sessions.Resume(userID) {
+		log.Printf("Driver %s resumed their session", userID)
+	}
+	h.router.UserOnline(ctx, userID)
 
 	defer func() {
-		h.connManager.Remove(userID)
-		h.unregisterDriver(ctx, userID, packageSlug)
-		log.Printf("Driver unregistered: %s", userID)
+		h.connManager.Remove(userID, sessionID)
+		h.suspendDriver(userID, packageSlug)
 	}()
 
-	if err := h.registerDriver(ctx, userID, packageSlug); err != nil {
+	// vehicleID is optional, driver-service picks an eligible vehicle when empty
+	vehicleID := r.URL.Query().Get("vehicleID")
+
+	// Registering again also marks a resumed driver as connected
+	if err := h.registerDriver(ctx, userID, sessionID, packageSlug, vehicleID); err != nil {
 		log.Printf("Failed to register driver: %v", err)
 		return
 	}
 
-	queues := []string{
-		messaging.DriverCmdTripRequestQueue,
-	}
+	// Messages missed while disconnected follow the registration
+	h.openMailbox(userID, sessionID, lastSeq)
+	defer h.mailbox.Close(userID)
 
-	// Use the common message handler to forward RabbitMQ messages to driver's WebSocket
-	handler := h.createMessageHandler("driver")
-	for _, q := range queues {
-		if err := h.messageBroker.Consume(ctx, q, handler); err != nil {
-			log.Printf("Consumer error for queue %s: %v", q, err)
-		}
-	}
-
-	h.handleDriverMessages(ctx, conn, userID)
+	h.handleDriverMessages(ctx, conn, userID, sessionID)
 }
 
-func (h *WebSocketHandler) handleDriverMessages(ctx context.Context, conn *websocket.Conn, userID string) {
+func (h *WebSocketHandler) handleDriverMessages(ctx context.Context, conn *websocket.Conn, userID, sessionID string) {
+	throttle := newLocationThrottle(h.config.LocationUpdateInterval)
+	messageThrottle := newMessageThrottle(h.config.MessageRateLimit)
+
 	for {
-		_, msg, err := conn.ReadMessage()
+		msg, err := h.connManager.ReadMessage(conn)
 		if err != nil {
 			log.Printf("Error reading message from driver %s: %v", userID, err)
 			break
 		}
 
-		type driverMessage struct {
-			Type string          `json:"type"`
-			Data json.RawMessage `json:"data"`
+		if !messageThrottle.Allow(userID, time.Now()) {
+			continue
 		}
 
-		var driverMsg driverMessage
-		if err := json.Unmarshal(msg, &driverMsg); err != nil {
-			log.Printf("Error unmarshalling driver message: %v", err)
+		driverMsg, payload, ok := h.decodeClientMessage(userID, sessionID, msg)
+		if !ok {
 			continue
 		}
 
 		switch driverMsg.Type {
 
-		case contracts.DriverCmdLocation:
-			continue
+		case contracts.ClientCmdAck:
+			h.handleAck(userID, payload.(*contracts.WSAckData))
 
-		case contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline:
-			// Extract riderID from the message data to use as OwnerID
-			var tripResponse struct {
-				RiderID string `json:"riderID"`
-			}
-			if err := json.Unmarshal(driverMsg.Data, &tripResponse); err != nil {
-				log.Printf("Error unmarshaling trip response data: %v", err)
-				continue
+		case contracts.ClientCmdHeartbeat:
+			h.answerHeartbeat(userID, sessionID, driverMsg)
+
+		case contracts.DriverCmdLocation:
+			if err := h.handleDriverLocation(ctx, userID, payload.(*contracts.WSDriverLocationData), throttle); err != nil {
+				log.Printf("Error handling driver location: %v", err)
 			}
 
-			if err := h.messageBroker.Publish(ctx, driverMsg.Type, contracts.AmqpMessage{
-				OwnerID: tripResponse.RiderID, // Use rider's ID, not driver's ID
-				Data:    driverMsg.Data,
-			}); err != nil {
-				log.Printf("Error publishing message to rabbitmq: %v", err)
+		case contracts.DriverCmdTripAccept, contracts.DriverCmdTripDecline,
+			contracts.DriverCmdTripStart, contracts.DriverCmdTripComplete:
+			if err := h.publishDriverTripCommand(ctx, userID, driverMsg.Type, payload.(*messaging.DriveTripResponseData)); err != nil {
+				log.Printf("Error publishing %s of driver %s: %v", driverMsg.Type, userID, err)
 			}
 
+		case contracts.ChatCmdSend, contracts.ChatCmdReceipt:
+			h.handleChatCommand(ctx, userID, sessionID, driverMsg, payload)
+
 		default:
-			log.Printf("Unknown message type: %v", driverMsg.Type)
+			h.rejectMessage(userID, sessionID, driverMsg)
+		}
+	}
+}
+
+// publishDriverTripCommand relays a driver's answer to an offer, or the progress of
+// their trip, to trip-service. The driver is the authenticated user as registered
+// with driver-service, whatever the payload says, and trips are only accepted while
+// driver-service still holds the driver's offer. trip-service answers the rider of
+// the trip, so the rider named by the client is never trusted either.
+func (h *WebSocketHandler) publishDriverTripCommand(ctx context.Context, userID, commandType string, payload *messaging.DriveTripResponseData) error {
+	resp, err := h.driverClient.GetDriver(ctx, &pb.GetDriverRequest{DriverID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to get driver: %w", err)
+	}
+	if resp.GetDriver() == nil {
+		return fmt.Errorf("driver %s is not registered", userID)
+	}
+
+	if commandType == contracts.DriverCmdTripAccept {
+		// Once the offer expired the driver may be offered another trip, a late
+		// accept would book them twice
+		if err := h.checkOfferOpen(ctx, userID, payload.TripID); err != nil {
+			return err
 		}
 	}
+
+	command := *payload
+	command.Driver = resp.GetDriver()
+	marshalledCommand, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+
+	return h.messageBroker.Publish(ctx, commandType, contracts.AmqpMessage{
+		OwnerID: userID,
+		Data:    marshalledCommand,
+	})
 }
 
-func (h *WebSocketHandler) registerDriver(ctx context.Context, userID string, packageSlug string) error {
+// checkOfferOpen returns an error unless the driver has an unexpired offer for the trip
+func (h *WebSocketHandler) checkOfferOpen(ctx context.Context, userID, tripID string) error {
+	resp, err := h.driverClient.GetDriverAvailability(ctx, &pb.GetDriverRequest{DriverID: userID})
+	if err != nil {
+		return fmt.Errorf("failed to get driver availability: %w", err)
+	}
+
+	if resp.GetAvailability() != pb.DriverAvailability_DRIVER_AVAILABILITY_OFFERED || resp.GetTripID() != tripID {
+		return fmt.Errorf("driver %s has no open offer for trip %s", userID, tripID)
+	}
+	return nil
+}
+
+func (h *WebSocketHandler) registerDriver(ctx context.Context, userID, sessionID, packageSlug, vehicleID string) error {
 	resp, err := h.driverClient.RegisterDriver(ctx, &pb.RegisterDriverRequest{
 		DriverID:    userID,
 		PackageSlug: packageSlug,
+		VehicleID:   vehicleID,
 	})
 
 	if err != nil {
 		return fmt.Errorf("failed to register driver: %w", err)
 	}
 
-	return h.connManager.SendMessage(userID, contracts.WSMessage{
-		Type: contracts.DriverCmdRegister,
-		Data: resp.Driver,
+	return h.connManager.SendToSession(userID, sessionID, contracts.NewWSMessage(contracts.DriverCmdRegister, resp.Driver))
+}
+
+// suspendDriver starts the reconnect grace period of a driver whose connection dropped.
+// The driver keeps their state and assignments but gets no new offers, and is only
+// unregistered if they don't come back in time.
+func (h *WebSocketHandler) suspendDriver(userID, packageSlug string) {
+	if h.connManager.Connected(userID) {
+		// The driver is still connected on another session, or already reconnected
+		return
+	}
+
+	// The request context is done once the connection is gone
+	ctx := context.Background()
+	if _, err := h.driverClient.UpdateDriverConnection(ctx, &pb.UpdateDriverConnectionRequest{
+		DriverID:  userID,
+		Connected: false,
+	}); err != nil {
+		log.Printf("Error marking driver %s as disconnected: %v", userID, err)
+	}
+
+	// The driver stays present on this instance until the grace period ends
+	h.sessions.Suspend(userID, func() {
+		h.unregisterDriver(context.Background(), userID, packageSlug)
+		h.router.UserOffline(context.Background(), userID)
+		log.Printf("Driver unregistered: %s", userID)
 	})
 }
 
@@ -1,71 +1,123 @@
 package websocket
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"log"
 	"ride-sharing/services/api-gateway/internal/clients"
+	"ride-sharing/services/api-gateway/internal/ratelimit"
 	"ride-sharing/services/api-gateway/internal/websocket"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
-
-	"github.com/rabbitmq/amqp091-go"
+	"time"
 )
 
+// Config holds the tunables of the WebSocket handlers
+type Config struct {
+	// LocationUpdateInterval is the minimum time between two forwarded location
+	// updates of the same driver. Updates arriving faster are dropped.
+	LocationUpdateInterval time.Duration
+	// MessageRateLimit limits the messages a single connection may send. Messages
+	// beyond it are dropped.
+	MessageRateLimit ratelimit.Policy
+}
+
+// DefaultConfig returns a Config with sensible default values
+func DefaultConfig() Config {
+	return Config{
+		LocationUpdateInterval: 1 * time.Second,
+		MessageRateLimit:       ratelimit.Policy{Limit: 20, Period: time.Second},
+	}
+}
+
 type WebSocketHandler struct {
 	connManager   *websocket.ConnectionManager
+	sessions      *websocket.SessionRegistry
+	mailbox       *websocket.Mailbox
+	router        *MessageRouter
 	upgrader      *websocket.WebSocketUpgrader
 	driverClient  clients.DriverServiceClient
 	messageBroker messaging.MessageBroker
+	config        Config
 }
 
 func NewWebSocketHandler(
 	connManager *websocket.ConnectionManager,
+	sessions *websocket.SessionRegistry,
+	mailbox *websocket.Mailbox,
+	router *MessageRouter,
 	upgrader *websocket.WebSocketUpgrader,
 	driverClient clients.DriverServiceClient,
-	messageBroker messaging.MessageBroker) *WebSocketHandler {
+	messageBroker messaging.MessageBroker,
+	config Config) *WebSocketHandler {
 	return &WebSocketHandler{
 		connManager:   connManager,
+		sessions:      sessions,
+		mailbox:       mailbox,
+		router:        router,
 		upgrader:      upgrader,
 		driverClient:  driverClient,
 		messageBroker: messageBroker,
+		config:        config,
 	}
 }
 
-// createMessageHandler creates a RabbitMQ message handler that forwards messages to WebSocket connections
-// This is used for consuming from RabbitMQ and forwarding to connected users (rider or driver)
-func (h *WebSocketHandler) createMessageHandler(userType string) messaging.MessageHandler {
-	return func(ctx context.Context, delivery amqp091.Delivery) error {
-		var amqpMsg contracts.AmqpMessage
-		if err := json.Unmarshal(delivery.Body, &amqpMsg); err != nil {
-			log.Printf("Failed to unmarshal AMQP message for %s: %v, body: %s", userType, err, string(delivery.Body))
-			// Don't requeue malformed messages - they'll never succeed
-			return nil
-		}
+// openMailbox starts delivering a user's messages to a new session, after dropping
+// the ones the client reported as seen when reconnecting
+func (h *WebSocketHandler) openMailbox(userID, sessionID string, lastSeq uint64) {
+	if lastSeq > 0 {
+		h.mailbox.Ack(userID, lastSeq)
+	}
 
-		userID := amqpMsg.OwnerID
-		var payload any
-		if amqpMsg.Data != nil {
-			if err := json.Unmarshal(amqpMsg.Data, &payload); err != nil {
-				log.Printf("Failed to unmarshal payload for %s %s: %v", userType, userID, err)
-				// Don't requeue malformed messages
-				return nil
-			}
-		}
+	replayed := h.mailbox.Open(userID, func(message contracts.WSMessage) error {
+		return h.connManager.SendToSession(userID, sessionID, message)
+	})
+	if replayed > 0 {
+		log.Printf("Replayed %d unacknowledged messages to %s", replayed, userID)
+	}
+}
 
-		wsMsg := contracts.WSMessage{
-			Type: delivery.RoutingKey,
-			Data: payload,
-		}
+// handleAck drops the messages a client acknowledged from its mailbox
+func (h *WebSocketHandler) handleAck(userID string, ack *contracts.WSAckData) {
+	h.mailbox.Ack(userID, ack.Seq)
+}
+
+// answerHeartbeat echoes a client's heartbeat, so clients that can't see pings know the connection is alive
+func (h *WebSocketHandler) answerHeartbeat(userID, sessionID string, heartbeat contracts.WSRawMessage) {
+	message := contracts.NewWSMessage(contracts.ClientCmdHeartbeat, nil)
+	message.CorrelationID = heartbeat.ID
+	h.sendToSession(userID, sessionID, message)
+}
+
+// decodeClientMessage validates a client message against the message registry. Invalid
+// messages are answered with a client.event.error frame and reported as not ok.
+func (h *WebSocketHandler) decodeClientMessage(userID, sessionID string, frame []byte) (contracts.WSRawMessage, any, bool) {
+	msg, payload, err := messaging.DecodeWSMessage(frame)
+	var msgErr *messaging.WSMessageError
+	if errors.As(err, &msgErr) {
+		log.Printf("Rejected message from %s: %v", userID, err)
+		h.sendToSession(userID, sessionID, msgErr.ErrorMessage())
+		return msg, nil, false
+	}
+	return msg, payload, true
+}
 
-		// If sending fails (e.g., user not connected), log but don't requeue
-		if err := h.connManager.SendMessage(userID, wsMsg); err != nil {
-			log.Printf("Failed to send message to %s %s: %v", userType, userID, err)
-			// User might not be connected yet, but message was valid - don't requeue
-			return nil
-		}
+// rejectMessage answers a valid message which isn't accepted on the client's connection,
+// such as a driver command sent by a rider
+func (h *WebSocketHandler) rejectMessage(userID, sessionID string, msg contracts.WSRawMessage) {
+	msgErr := &messaging.WSMessageError{
+		Code:    contracts.ErrCodeUnknownMessageType,
+		Message: "message type not accepted on this connection",
+		Type:    msg.Type,
+		ID:      msg.ID,
+	}
+	log.Printf("Rejected message from %s: %v", userID, msgErr)
+	h.sendToSession(userID, sessionID, msgErr.ErrorMessage())
+}
 
-		log.Printf("Successfully forwarded message to %s %s: %s", userType, userID, delivery.RoutingKey)
-		return nil
+// sendToSession sends a message to one session of a user, outside of the mailbox,
+// for answers which are only meaningful to the session which asked
+func (h *WebSocketHandler) sendToSession(userID, sessionID string, message contracts.WSMessage) {
+	if err := h.connManager.SendToSession(userID, sessionID, message); err != nil {
+		log.Printf("Error sending %s to %s: %v", message.Type, userID, err)
 	}
 }
@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"time"
+)
+
+// locationThrottle rate-limits location updates of a single driver connection
+type locationThrottle struct {
+	interval time.Duration
+	last     time.Time
+}
+
+func newLocationThrottle(interval time.Duration) *locationThrottle {
+	return &locationThrottle{
+		interval: interval,
+	}
+}
+
+// Allow reports whether an update received at now should be forwarded
+func (t *locationThrottle) Allow(now time.Time) bool {
+	if !t.last.IsZero() && now.Sub(t.last) < t.interval {
+		return false
+	}
+
+	t.last = now
+	return true
+}
+
+// handleDriverLocation forwards a validated location update to driver-service
+func (h *WebSocketHandler) handleDriverLocation(ctx context.Context, userID string, locationMsg *contracts.WSDriverLocationData, throttle *locationThrottle) error {
+	if !throttle.Allow(time.Now()) {
+		return nil
+	}
+
+	payload, err := json.Marshal(messaging.DriverLocationData{
+		DriverID: userID,
+		Location: locationMsg.Location,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal location data: %w", err)
+	}
+
+	return h.messageBroker.Publish(ctx, contracts.DriverCmdLocation, contracts.AmqpMessage{
+		OwnerID: userID,
+		Data:    payload,
+	})
+}
@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"context"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"testing"
+	"time"
+)
+
+// publishRecorder keeps the routing keys of published messages
+type publishRecorder struct {
+	messaging.MessageBroker
+	published []string
+}
+
+func (b *publishRecorder) Publish(ctx context.Context, routingKey string, msg contracts.AmqpMessage) error {
+	b.published = append(b.published, routingKey)
+	return nil
+}
+
+func TestLocationThrottle(t *testing.T) {
+	throttle := newLocationThrottle(time.Second)
+	start := time.Now()
+
+	tests := []struct {
+		after time.Duration
+		want  bool
+	}{
+		{0, true},
+		{500 * time.Millisecond, false},
+		{time.Second, true},
+		{1500 * time.Millisecond, false},
+		{2 * time.Second, true},
+	}
+
+	for _, tt := range tests {
+		if got := throttle.Allow(start.Add(tt.after)); got != tt.want {
+			t.Errorf("Allow(+%s) = %v, want %v", tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestDriverLocationValidatedBeforeThrottling(t *testing.T) {
+	broker := &publishRecorder{}
+	h := &WebSocketHandler{messageBroker: broker}
+	throttle := newLocationThrottle(time.Minute)
+
+	frames := []struct {
+		frame     string
+		wantValid bool
+	}{
+		{`{"type":"driver.cmd.location","data":{"location":{"latitude":91,"longitude":0}}}`, false},
+		{`{"type":"driver.cmd.location","data":{}}`, false},
+		// Only the first valid update of the interval is forwarded
+		{`{"type":"driver.cmd.location","data":{"location":{"latitude":37.77,"longitude":-122.42}}}`, true},
+		{`{"type":"driver.cmd.location","data":{"location":{"latitude":37.78,"longitude":-122.41}}}`, true},
+	}
+
+	// Like the driver's read loop, messages are decoded and validated before they reach the throttle
+	for _, tt := range frames {
+		_, payload, err := messaging.DecodeWSMessage([]byte(tt.frame))
+		if (err == nil) != tt.wantValid {
+			t.Fatalf("DecodeWSMessage(%s) error = %v, want valid %v", tt.frame, err, tt.wantValid)
+		}
+		if err != nil {
+			continue
+		}
+		if err := h.handleDriverLocation(context.Background(), "driver-1", payload.(*contracts.WSDriverLocationData), throttle); err != nil {
+			t.Errorf("handleDriverLocation(%s) error = %v", tt.frame, err)
+		}
+	}
+
+	if len(broker.published) != 1 || broker.published[0] != contracts.DriverCmdLocation {
+		t.Errorf("published %v, want a single %s", broker.published, contracts.DriverCmdLocation)
+	}
+}
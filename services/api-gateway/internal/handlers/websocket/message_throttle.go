@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"expvar"
+	"log"
+	"ride-sharing/services/api-gateway/internal/ratelimit"
+	"time"
+)
+
+// throttledMessages counts the messages dropped for exceeding the message rate limit on /debug/vars
+var throttledMessages = expvar.NewInt("ws_throttled_messages")
+
+// messageThrottle rate-limits all messages of a single connection
+type messageThrottle struct {
+	// bucket is nil without a rate limit
+	bucket *ratelimit.TokenBucket
+	// throttling is set while messages are dropped, so each burst is only logged once
+	throttling bool
+}
+
+func newMessageThrottle(policy ratelimit.Policy) *messageThrottle {
+	if !policy.Enabled() {
+		return &messageThrottle{}
+	}
+	return &messageThrottle{
+		bucket: ratelimit.NewTokenBucket(policy, time.Now()),
+	}
+}
+
+// Allow reports whether a message of userID received at now should be handled
+func (t *messageThrottle) Allow(userID string, now time.Time) bool {
+	if t.bucket == nil {
+		return true
+	}
+
+	allowed, retryAfter := t.bucket.Take(now)
+	if allowed {
+		t.throttling = false
+		return true
+	}
+
+	throttledMessages.Add(1)
+	if !t.throttling {
+		t.throttling = true
+		log.Printf("%s exceeds the message rate limit, dropping messages for %v", userID, retryAfter.Round(time.Millisecond))
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/google/uuid"
+)
+
+// handleRiderCommand relays a validated rider command to trip-service, which checks it
+// against the rider's trip and answers with a command ack or error frame correlated
+// to the command's ID
+func (h *WebSocketHandler) handleRiderCommand(ctx context.Context, userID, sessionID string, msg contracts.WSRawMessage, payload any) {
+	commandID := msg.ID
+	if commandID == "" {
+		// Lets the client tell answers apart, even if it can't relate them to its commands
+		commandID = uuid.NewString()
+	}
+
+	command, err := h.publishRiderCommand(ctx, userID, msg.Type, commandID, payload)
+	if err != nil {
+		log.Printf("Error publishing %s of rider %s: %v", msg.Type, userID, err)
+
+		message := contracts.NewWSMessage(contracts.RiderEventCommandError, contracts.WSCommandResultData{
+			Command: msg.Type,
+			TripID:  command.TripID,
+			Code:    contracts.ErrCodeServiceUnavailable,
+			Message: "failed to relay the command, retry later",
+		})
+		message.CorrelationID = commandID
+		h.sendToSession(userID, sessionID, message)
+	}
+}
+
+// publishRiderCommand publishes a rider command with its validated payload. The rider
+// is the authenticated user, whatever the payload says.
+func (h *WebSocketHandler) publishRiderCommand(ctx context.Context, userID, commandType, commandID string, payload any) (messaging.RiderCommandData, error) {
+	command := messaging.RiderCommandData{CommandID: commandID}
+
+	switch payload := payload.(type) {
+	case *contracts.WSCancelTripData:
+		command.TripID = payload.TripID
+		command.Reason = payload.Reason
+	case *contracts.WSUpdatePickupData:
+		command.TripID = payload.TripID
+		command.Location = payload.Pickup
+	case *contracts.WSAddStopData:
+		command.TripID = payload.TripID
+		command.Location = payload.Stop
+	}
+
+	marshalledCommand, err := json.Marshal(command)
+	if err != nil {
+		return command, err
+	}
+
+	return command, h.messageBroker.Publish(ctx, commandType, contracts.AmqpMessage{
+		OwnerID: userID,
+		Data:    marshalledCommand,
+	})
+}
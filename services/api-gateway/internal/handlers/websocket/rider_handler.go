@@ -1,15 +1,25 @@
 package websocket
 
 import (
+	"context"
 	"log"
 	"net/http"
-	"ride-sharing/shared/messaging"
+	httpHandlers "ride-sharing/services/api-gateway/internal/handlers/http"
+	"ride-sharing/services/api-gateway/internal/websocket"
+	"ride-sharing/shared/contracts"
+	"time"
 )
 
 func (h *WebSocketHandler) HandleRiderConnection(w http.ResponseWriter, r *http.Request) {
 	userID, err := validateUserID(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpHandlers.WriteError(w, r, httpHandlers.BadRequest(err.Error()))
+		return
+	}
+
+	lastSeq, err := validateLastSeq(r)
+	if err != nil {
+		httpHandlers.WriteError(w, r, httpHandlers.BadRequest(err.Error()))
 		return
 	}
 
@@ -22,28 +32,57 @@ func (h *WebSocketHandler) HandleRiderConnection(w http.ResponseWriter, r *http.
 	}
 	defer conn.Close()
 
-	h.connManager.Add(userID, conn)
-	defer h.connManager.Remove(userID)
-
-	queues := []string{
-		messaging.NotifyDriverNoDriversFoundQueue,
-		messaging.NotifyDriverAssignedQueue,
+	sessionID, err := h.connManager.Add(userID, conn)
+	if err != nil {
+		log.Printf("Rejected connection of rider %s: %v", userID, err)
+		websocket.CloseConnection(conn, err.Error())
+		return
 	}
+	h.openMailbox(userID, sessionID, lastSeq)
 
-	// Use the common message handler to forward RabbitMQ messages to driver's WebSocket
-	handler := h.createMessageHandler("rider")
-	for _, q := range queues {
-		if err := h.messageBroker.Consume(ctx, q, handler); err != nil {
-			log.Printf("Consumer error for queue %s: %v", q, err)
-		}
-	}
+	h.router.UserOnline(ctx, userID)
+	defer func() {
+		h.mailbox.Close(userID)
+		h.connManager.Remove(userID, sessionID)
+		// The request context is done once the connection is gone
+		h.router.UserOffline(context.Background(), userID)
+	}()
+
+	throttle := newMessageThrottle(h.config.MessageRateLimit)
 
 	for {
-		_, msg, err := conn.ReadMessage()
+		msg, err := h.connManager.ReadMessage(conn)
 		if err != nil {
 			log.Printf("Error reading message: %v", err)
 			break
 		}
-		log.Printf("Received message from rider %s: %s", userID, string(msg))
+
+		if !throttle.Allow(userID, time.Now()) {
+			continue
+		}
+
+		h.handleRiderMessage(ctx, userID, sessionID, msg)
+	}
+}
+
+// handleRiderMessage handles a message from a rider. Invalid messages are answered
+// with an error frame.
+func (h *WebSocketHandler) handleRiderMessage(ctx context.Context, userID, sessionID string, frame []byte) {
+	msg, payload, ok := h.decodeClientMessage(userID, sessionID, frame)
+	if !ok {
+		return
+	}
+
+	switch msg.Type {
+	case contracts.ClientCmdAck:
+		h.handleAck(userID, payload.(*contracts.WSAckData))
+	case contracts.ClientCmdHeartbeat:
+		h.answerHeartbeat(userID, sessionID, msg)
+	case contracts.RiderCmdCancelTrip, contracts.RiderCmdUpdatePickup, contracts.RiderCmdAddStop:
+		h.handleRiderCommand(ctx, userID, sessionID, msg, payload)
+	case contracts.ChatCmdSend, contracts.ChatCmdReceipt:
+		h.handleChatCommand(ctx, userID, sessionID, msg, payload)
+	default:
+		h.rejectMessage(userID, sessionID, msg)
 	}
 }
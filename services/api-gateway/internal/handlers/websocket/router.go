@@ -0,0 +1,330 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"ride-sharing/services/api-gateway/internal/websocket"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// userQueues are the shared queues holding messages for riders and drivers
+var userQueues = []string{
+	messaging.DriverCmdTripRequestQueue,
+	messaging.NotifyDriverNoDriversFoundQueue,
+	messaging.NotifyDriverAssignedQueue,
+	messaging.NotifyDriverLocationQueue,
+	messaging.NotifyTripStatusQueue,
+	messaging.NotifyRiderCommandResultQueue,
+	messaging.NotifyChatQueue,
+}
+
+// MessageRouter delivers RabbitMQ messages to the WebSocket of their owner, whichever
+// API Gateway instance the owner is connected to. Each instance consumes the shared
+// queues once and forwards messages for users connected elsewhere to the exclusive
+// queue of the owner's instance. Instances announce their users to each other so
+// every instance knows where to forward to. Messages for users who aren't connected
+// anywhere are kept in the mailbox of the instance that received them, and handed
+// over once the user connects to any instance.
+type MessageRouter struct {
+	gatewayID     string
+	connManager   *websocket.ConnectionManager
+	sessions      *websocket.SessionRegistry
+	mailbox       *websocket.Mailbox
+	presence      *websocket.PresenceTable
+	messageBroker messaging.MessageBroker
+	// heartbeat is how often the instance announces all of its users
+	heartbeat time.Duration
+}
+
+func NewMessageRouter(
+	gatewayID string,
+	connManager *websocket.ConnectionManager,
+	sessions *websocket.SessionRegistry,
+	mailbox *websocket.Mailbox,
+	presence *websocket.PresenceTable,
+	messageBroker messaging.MessageBroker,
+	heartbeat time.Duration) *MessageRouter {
+	return &MessageRouter{
+		gatewayID:     gatewayID,
+		connManager:   connManager,
+		sessions:      sessions,
+		mailbox:       mailbox,
+		presence:      presence,
+		messageBroker: messageBroker,
+		heartbeat:     heartbeat,
+	}
+}
+
+// Start declares the instance's exclusive queue and starts routing until ctx is done
+func (r *MessageRouter) Start(ctx context.Context) error {
+	gatewayQueue := messaging.GatewayQueue(r.gatewayID)
+	if err := r.messageBroker.DeclareExclusiveQueue(gatewayQueue, []string{
+		contracts.GatewayEventPresence,
+		messaging.GatewayDeliverRoutingKey(r.gatewayID),
+	}); err != nil {
+		return err
+	}
+
+	if err := r.messageBroker.Consume(ctx, gatewayQueue, r.handleGatewayMessage); err != nil {
+		return fmt.Errorf("failed to consume %s: %w", gatewayQueue, err)
+	}
+
+	for _, queue := range userQueues {
+		if err := r.messageBroker.Consume(ctx, queue, r.routeUserMessage); err != nil {
+			return fmt.Errorf("failed to consume %s: %w", queue, err)
+		}
+	}
+
+	go r.announceLoop(ctx)
+
+	log.Printf("Message router of gateway %s started", r.gatewayID)
+	return nil
+}
+
+// UserOnline announces that a user connected to this instance
+func (r *MessageRouter) UserOnline(ctx context.Context, userID string) {
+	r.publishPresence(ctx, messaging.GatewayPresenceEvent{
+		GatewayID: r.gatewayID,
+		Online:    []string{userID},
+	})
+}
+
+// UserOffline announces that a user left this instance, unless they still have a
+// connection or a suspended session here
+func (r *MessageRouter) UserOffline(ctx context.Context, userID string) {
+	if r.isLocal(userID) {
+		return
+	}
+
+	r.publishPresence(ctx, messaging.GatewayPresenceEvent{
+		GatewayID: r.gatewayID,
+		Offline:   []string{userID},
+	})
+}
+
+// routeUserMessage delivers a message from the shared queues locally and forwards
+// it to every other instance its owner is connected to
+func (r *MessageRouter) routeUserMessage(ctx context.Context, delivery amqp091.Delivery) error {
+	var amqpMsg contracts.AmqpMessage
+	if err := json.Unmarshal(delivery.Body, &amqpMsg); err != nil {
+		log.Printf("Failed to unmarshal AMQP message: %v, body: %s", err, string(delivery.Body))
+		// Don't requeue malformed messages - they'll never succeed
+		return nil
+	}
+
+	// The envelope's ID and timestamp are set once, every instance sends the same
+	message := messaging.GatewayDelivery{
+		RoutingKey: delivery.RoutingKey,
+		Message:    amqpMsg,
+		ID:         uuid.NewString(),
+		Timestamp:  time.Now().UTC(),
+	}
+
+	local := r.isLocal(amqpMsg.OwnerID)
+	if local {
+		r.deliverLocal(message)
+	}
+
+	forwarded := false
+	for _, gatewayID := range r.presence.Lookup(amqpMsg.OwnerID) {
+		if gatewayID == r.gatewayID {
+			continue
+		}
+
+		if err := r.forward(ctx, gatewayID, message); err != nil {
+			// Requeueing would deliver the message twice to the users that already got it
+			log.Printf("Failed to forward %s for %s to gateway %s: %v", delivery.RoutingKey, amqpMsg.OwnerID, gatewayID, err)
+			continue
+		}
+		forwarded = true
+	}
+
+	if !local && !forwarded {
+		// Keep the message until the user connects
+		log.Printf("Storing %s: %s is not connected to any gateway", delivery.RoutingKey, amqpMsg.OwnerID)
+		r.deliverLocal(message)
+	}
+
+	return nil
+}
+
+// handleGatewayMessage processes presence updates and messages forwarded by other instances
+func (r *MessageRouter) handleGatewayMessage(ctx context.Context, delivery amqp091.Delivery) error {
+	var amqpMsg contracts.AmqpMessage
+	if err := json.Unmarshal(delivery.Body, &amqpMsg); err != nil {
+		log.Printf("Failed to unmarshal gateway message: %v", err)
+		return nil
+	}
+
+	if delivery.RoutingKey == contracts.GatewayEventPresence {
+		var event messaging.GatewayPresenceEvent
+		if err := json.Unmarshal(amqpMsg.Data, &event); err != nil {
+			log.Printf("Failed to unmarshal presence event: %v", err)
+			return nil
+		}
+
+		if event.GatewayID == r.gatewayID {
+			return nil
+		}
+		if event.Snapshot {
+			r.presence.Replace(event.GatewayID, event.Online)
+		} else {
+			r.presence.Update(event.GatewayID, event.Online, event.Offline)
+		}
+
+		for _, userID := range event.Online {
+			r.handOver(ctx, userID, event.GatewayID)
+		}
+		return nil
+	}
+
+	var forwarded messaging.GatewayDelivery
+	if err := json.Unmarshal(amqpMsg.Data, &forwarded); err != nil {
+		log.Printf("Failed to unmarshal forwarded message: %v", err)
+		return nil
+	}
+
+	// Forwarded messages are never forwarded again, which rules out loops while
+	// presence information is settling. Users who left meanwhile find them in the mailbox.
+	r.deliverLocal(forwarded)
+	return nil
+}
+
+// deliverLocal hands a message to the mailbox of its owner on this instance, which
+// sends it right away if the owner is connected. A zero seq numbers the message anew.
+func (r *MessageRouter) deliverLocal(delivery messaging.GatewayDelivery) {
+	routingKey, amqpMsg := delivery.RoutingKey, delivery.Message
+	userID := amqpMsg.OwnerID
+
+	var payload any
+	if amqpMsg.Data != nil {
+		if err := json.Unmarshal(amqpMsg.Data, &payload); err != nil {
+			// A malformed payload will never succeed anywhere
+			log.Printf("Failed to unmarshal payload for %s: %v", userID, err)
+			return
+		}
+	}
+
+	message := contracts.NewWSMessage(routingKey, payload)
+	if delivery.ID != "" {
+		// Forwarded by an instance of an older version otherwise
+		message.ID = delivery.ID
+		message.Timestamp = delivery.Timestamp
+	}
+	message.CorrelationID = amqpMsg.CorrelationID
+	message.Seq = delivery.Seq
+	seq := r.mailbox.Deliver(userID, message)
+	log.Printf("Delivered message %d to %s: %s", seq, userID, routingKey)
+}
+
+// isLocal reports whether a user is connected to this instance or within their
+// reconnect grace period here
+func (r *MessageRouter) isLocal(userID string) bool {
+	return r.connManager.Connected(userID) || r.sessions.Suspended(userID)
+}
+
+// handOver forwards the stored messages of a user who connected to another instance
+func (r *MessageRouter) handOver(ctx context.Context, userID, gatewayID string) {
+	if r.isLocal(userID) || !r.mailbox.Has(userID) {
+		return
+	}
+
+	messages := r.mailbox.Take(userID)
+	for _, msg := range messages {
+		data, err := json.Marshal(msg.Data)
+		if err != nil {
+			log.Printf("Failed to marshal stored message for %s: %v", userID, err)
+			continue
+		}
+
+		// Keeping the ID and number lets the client recognize messages it has already seen
+		if err := r.forward(ctx, gatewayID, messaging.GatewayDelivery{
+			RoutingKey: msg.Type,
+			Message: contracts.AmqpMessage{
+				OwnerID:       userID,
+				Data:          data,
+				CorrelationID: msg.CorrelationID,
+			},
+			ID:        msg.ID,
+			Timestamp: msg.Timestamp,
+			Seq:       msg.Seq,
+		}); err != nil {
+			log.Printf("Failed to hand over stored %s for %s to gateway %s: %v", msg.Type, userID, gatewayID, err)
+		}
+	}
+	log.Printf("Handed over %d stored messages for %s to gateway %s", len(messages), userID, gatewayID)
+}
+
+// forward hands a message to the instance of another gateway
+func (r *MessageRouter) forward(ctx context.Context, gatewayID string, delivery messaging.GatewayDelivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	return r.messageBroker.Publish(ctx, messaging.GatewayDeliverRoutingKey(gatewayID), contracts.AmqpMessage{
+		OwnerID: delivery.Message.OwnerID,
+		Data:    data,
+	})
+}
+
+// announceLoop periodically announces every user of this instance, so instances
+// that started later or missed an update catch up, and forgets silent instances.
+// When ctx is done it announces that all users are gone.
+func (r *MessageRouter) announceLoop(ctx context.Context) {
+	ticker := time.NewTicker(r.heartbeat)
+	defer ticker.Stop()
+
+	r.announceAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			// The instance is shutting down, its users will reconnect elsewhere
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+			r.publishPresence(shutdownCtx, messaging.GatewayPresenceEvent{
+				GatewayID: r.gatewayID,
+				Snapshot:  true,
+			})
+			cancel()
+			return
+		case <-ticker.C:
+			r.announceAll(ctx)
+			r.presence.Expire()
+			r.mailbox.Expire()
+		}
+	}
+}
+
+func (r *MessageRouter) announceAll(ctx context.Context) {
+	users := append(r.connManager.IDs(), r.sessions.IDs()...)
+	slices.Sort(users)
+
+	r.publishPresence(ctx, messaging.GatewayPresenceEvent{
+		GatewayID: r.gatewayID,
+		Online:    slices.Compact(users),
+		Snapshot:  true,
+	})
+}
+
+func (r *MessageRouter) publishPresence(ctx context.Context, event messaging.GatewayPresenceEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal presence event: %v", err)
+		return
+	}
+
+	if err := r.messageBroker.Publish(ctx, contracts.GatewayEventPresence, contracts.AmqpMessage{
+		OwnerID: r.gatewayID,
+		Data:    data,
+	}); err != nil {
+		log.Printf("Failed to publish presence of gateway %s: %v", r.gatewayID, err)
+	}
+}
@@ -0,0 +1,338 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"ride-sharing/services/api-gateway/internal/websocket"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// memoryBus stands in for the RabbitMQ exchange. Messages published with a routing
+// key go to every exclusive queue bound to it, each queue is consumed in order.
+type memoryBus struct {
+	mutex    sync.Mutex
+	bindings map[string][]string
+	queues   map[string]chan amqp091.Delivery
+}
+
+func newMemoryBus() *memoryBus {
+	return &memoryBus{
+		bindings: make(map[string][]string),
+		queues:   make(map[string]chan amqp091.Delivery),
+	}
+}
+
+// memoryBroker is one gateway instance's connection to the bus. Handlers of the shared
+// queues are kept so the test decides which instance receives a message.
+type memoryBroker struct {
+	bus    *memoryBus
+	mutex  sync.Mutex
+	shared map[string]messaging.MessageHandler
+}
+
+func (b *memoryBroker) Publish(ctx context.Context, routingKey string, msg contracts.AmqpMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	b.bus.mutex.Lock()
+	defer b.bus.mutex.Unlock()
+	for _, queue := range b.bus.bindings[routingKey] {
+		b.bus.queues[queue] <- amqp091.Delivery{RoutingKey: routingKey, Body: body}
+	}
+	return nil
+}
+
+func (b *memoryBroker) Consume(ctx context.Context, queue string, handler messaging.MessageHandler) error {
+	b.bus.mutex.Lock()
+	deliveries, exclusive := b.bus.queues[queue]
+	b.bus.mutex.Unlock()
+
+	if !exclusive {
+		b.mutex.Lock()
+		b.shared[queue] = handler
+		b.mutex.Unlock()
+		return nil
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case delivery := <-deliveries:
+				handler(ctx, delivery)
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *memoryBroker) ConsumeDeferred(ctx context.Context, queue string, prefetch int, handler messaging.MessageHandler) error {
+	return b.Consume(ctx, queue, handler)
+}
+
+func (b *memoryBroker) DeclareExclusiveQueue(queue string, routingKeys []string) error {
+	b.bus.mutex.Lock()
+	defer b.bus.mutex.Unlock()
+	b.bus.queues[queue] = make(chan amqp091.Delivery, 256)
+	for _, routingKey := range routingKeys {
+		b.bus.bindings[routingKey] = append(b.bus.bindings[routingKey], queue)
+	}
+	return nil
+}
+
+func (b *memoryBroker) HealthCheck(ctx context.Context) error { return nil }
+
+func (b *memoryBroker) Close() error { return nil }
+
+// receive hands a message of a shared queue to this instance, like RabbitMQ
+// picking one of the consumers
+func (b *memoryBroker) receive(t *testing.T, queue, routingKey string, msg contracts.AmqpMessage) {
+	t.Helper()
+
+	b.mutex.Lock()
+	handler := b.shared[queue]
+	b.mutex.Unlock()
+	if handler == nil {
+		t.Fatalf("no consumer for %s", queue)
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handler(context.Background(), amqp091.Delivery{RoutingKey: routingKey, Body: body}); err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+}
+
+type testGateway struct {
+	id          string
+	router      *MessageRouter
+	broker      *memoryBroker
+	connManager *websocket.ConnectionManager
+	mailbox     *websocket.Mailbox
+	presence    *websocket.PresenceTable
+	sent        chan contracts.WSMessage
+}
+
+func startGateway(t *testing.T, bus *memoryBus, id string) *testGateway {
+	t.Helper()
+	return startGatewayWithSessions(t, bus, id, websocket.DefaultSessionConfig())
+}
+
+func startGatewayWithSessions(t *testing.T, bus *memoryBus, id string, sessions websocket.SessionConfig) *testGateway {
+	t.Helper()
+
+	g := &testGateway{
+		id:          id,
+		broker:      &memoryBroker{bus: bus, shared: make(map[string]messaging.MessageHandler)},
+		connManager: websocket.NewConnectionManager(sessions, websocket.DefaultWriterConfig(), websocket.DefaultHeartbeatConfig()),
+		presence:    websocket.NewPresenceTable(time.Minute),
+		sent:        make(chan contracts.WSMessage, 16),
+	}
+	g.mailbox = websocket.NewMailbox(g.connManager.SendMessage, 16, time.Minute)
+	g.router = NewMessageRouter(id, g.connManager, websocket.NewSessionRegistry(time.Minute), g.mailbox, g.presence, g.broker, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := g.router.Start(ctx); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	return g
+}
+
+// connect opens a WebSocket for a user and replays their mailbox, like the connection
+// handlers. Messages the client receives are passed on to g.sent.
+func (g *testGateway) connect(t *testing.T, userID string) {
+	t.Helper()
+
+	upgrader := websocket.NewWebSocketUpgrader(messaging.AllowAllOrigins(), nil)
+	added := make(chan string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		sessionID, err := g.connManager.Add(userID, conn)
+		if err != nil {
+			return
+		}
+		added <- sessionID
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	sessionID := <-added
+	t.Cleanup(func() { g.connManager.Remove(userID, sessionID) })
+
+	go func() {
+		for {
+			var message contracts.WSMessage
+			if err := client.ReadJSON(&message); err != nil {
+				return
+			}
+			g.sent <- message
+		}
+	}()
+
+	g.router.UserOnline(context.Background(), userID)
+	g.mailbox.Open(userID, func(message contracts.WSMessage) error {
+		return g.connManager.SendToSession(userID, sessionID, message)
+	})
+}
+
+func (g *testGateway) expectMessage(t *testing.T) contracts.WSMessage {
+	t.Helper()
+
+	select {
+	case message := <-g.sent:
+		return message
+	case <-time.After(time.Second):
+		t.Fatalf("gateway %s sent no message", g.id)
+		return contracts.WSMessage{}
+	}
+}
+
+func (g *testGateway) expectNoMessage(t *testing.T) {
+	t.Helper()
+
+	select {
+	case message := <-g.sent:
+		t.Fatalf("gateway %s sent %+v, want nothing", g.id, message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func tripStatus(userID string) contracts.AmqpMessage {
+	return contracts.AmqpMessage{
+		OwnerID:       userID,
+		Data:          []byte(`{"tripID":"trip-1"}`),
+		CorrelationID: "corr-1",
+	}
+}
+
+func TestMessageRouterDeliversLocally(t *testing.T) {
+	bus := newMemoryBus()
+	a, b := startGateway(t, bus, "a"), startGateway(t, bus, "b")
+	a.connect(t, "rider-1")
+
+	a.broker.receive(t, messaging.NotifyTripStatusQueue, contracts.TripEventStarted, tripStatus("rider-1"))
+
+	message := a.expectMessage(t)
+	if message.Type != contracts.TripEventStarted {
+		t.Errorf("Type = %q, want %q", message.Type, contracts.TripEventStarted)
+	}
+	if message.CorrelationID != "corr-1" {
+		t.Errorf("CorrelationID = %q, want corr-1", message.CorrelationID)
+	}
+	if message.Seq == 0 {
+		t.Error("message was not numbered")
+	}
+	b.expectNoMessage(t)
+}
+
+func TestMessageRouterForwardsToOwningGateway(t *testing.T) {
+	bus := newMemoryBus()
+	a, b := startGateway(t, bus, "a"), startGateway(t, bus, "b")
+	b.connect(t, "rider-1")
+	eventually(t, func() bool { return slices.Contains(a.presence.Lookup("rider-1"), "b") })
+
+	a.broker.receive(t, messaging.NotifyTripStatusQueue, contracts.TripEventStarted, tripStatus("rider-1"))
+
+	message := b.expectMessage(t)
+	if message.Type != contracts.TripEventStarted {
+		t.Errorf("Type = %q, want %q", message.Type, contracts.TripEventStarted)
+	}
+	if message.CorrelationID != "corr-1" {
+		t.Errorf("CorrelationID = %q, want corr-1", message.CorrelationID)
+	}
+	a.expectNoMessage(t)
+	if a.mailbox.Has("rider-1") {
+		t.Error("forwarded message was kept on gateway a")
+	}
+}
+
+func TestMessageRouterHandsOverMailboxOnReconnect(t *testing.T) {
+	bus := newMemoryBus()
+	a, b := startGateway(t, bus, "a"), startGateway(t, bus, "b")
+
+	// Nobody is connected, gateway a keeps the message
+	a.broker.receive(t, messaging.NotifyTripStatusQueue, contracts.TripEventStarted, tripStatus("rider-1"))
+	if !a.mailbox.Has("rider-1") {
+		t.Fatal("message was not stored")
+	}
+
+	var stored contracts.WSMessage
+	a.mailbox.Open("rider-1", func(message contracts.WSMessage) error {
+		stored = message
+		return nil
+	})
+	a.mailbox.Close("rider-1")
+
+	b.connect(t, "rider-1")
+
+	message := b.expectMessage(t)
+	if message.Seq == 0 {
+		t.Error("handed over message lost its number")
+	}
+	if message.ID != stored.ID || !message.Timestamp.Equal(stored.Timestamp) {
+		t.Errorf("handed over message %s of %v, want the stored %s of %v", message.ID, message.Timestamp, stored.ID, stored.Timestamp)
+	}
+	if message.Type != contracts.TripEventStarted {
+		t.Errorf("Type = %q, want %q", message.Type, contracts.TripEventStarted)
+	}
+	if message.CorrelationID != "corr-1" {
+		t.Errorf("CorrelationID = %q, want corr-1", message.CorrelationID)
+	}
+	eventually(t, func() bool { return !a.mailbox.Has("rider-1") })
+	a.expectNoMessage(t)
+}
+
+func TestMessageRouterKeepsIDAcrossGateways(t *testing.T) {
+	bus := newMemoryBus()
+	a, b := startGateway(t, bus, "a"), startGateway(t, bus, "b")
+	a.connect(t, "rider-1")
+	b.connect(t, "rider-1")
+	eventually(t, func() bool { return slices.Contains(a.presence.Lookup("rider-1"), "b") })
+
+	a.broker.receive(t, messaging.NotifyTripStatusQueue, contracts.TripEventStarted, tripStatus("rider-1"))
+
+	// The sessions on both gateways get the same message
+	local, forwarded := a.expectMessage(t), b.expectMessage(t)
+	if local.ID == "" || forwarded.ID != local.ID {
+		t.Errorf("forwarded message ID = %q, want %q", forwarded.ID, local.ID)
+	}
+	if !forwarded.Timestamp.Equal(local.Timestamp) {
+		t.Errorf("forwarded message timestamp = %v, want %v", forwarded.Timestamp, local.Timestamp)
+	}
+}
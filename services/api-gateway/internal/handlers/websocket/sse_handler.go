@@ -0,0 +1,163 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	httpHandlers "ride-sharing/services/api-gateway/internal/handlers/http"
+	"ride-sharing/services/api-gateway/internal/websocket"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+)
+
+// maxCommandBodySize bounds the body of rider commands sent over HTTP
+const maxCommandBodySize = 1 << 16
+
+// HandleRiderEvents streams a rider's messages as server-sent events, for riders whose
+// network blocks WebSocket upgrades. The stream carries the same messages as the
+// rider WebSocket, commands and acks are sent over HTTP instead.
+func (h *WebSocketHandler) HandleRiderEvents(w http.ResponseWriter, r *http.Request) {
+	userID, err := validateUserID(r)
+	if err != nil {
+		httpHandlers.WriteError(w, r, httpHandlers.BadRequest(err.Error()))
+		return
+	}
+
+	lastSeq, err := validateLastEventID(r)
+	if err != nil {
+		httpHandlers.WriteError(w, r, httpHandlers.BadRequest(err.Error()))
+		return
+	}
+
+	if !h.upgrader.CheckOrigin(r) {
+		httpHandlers.WriteError(w, r, &httpHandlers.Error{Status: http.StatusForbidden, Code: contracts.ErrCodeForbidden, Message: "origin not allowed"})
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+	}
+
+	stream := websocket.NewEventStream(w)
+	defer stream.Close()
+
+	sessionID, err := h.connManager.AddStream(userID, stream)
+	if err != nil {
+		log.Printf("Rejected event stream of rider %s: %v", userID, err)
+		// EventSource gives up on error statuses instead of reconnecting in a loop
+		httpHandlers.WriteError(w, r, &httpHandlers.Error{Status: http.StatusConflict, Code: contracts.ErrCodeConflict, Message: err.Error()})
+		return
+	}
+
+	// Removing the session stops its writer before the deferred Close ends the stream
+	defer h.connManager.Remove(userID, sessionID)
+
+	if err := stream.Open(); err != nil {
+		log.Printf("Failed to open event stream of rider %s: %v", userID, err)
+		return
+	}
+	h.openMailbox(userID, sessionID, lastSeq)
+
+	ctx := r.Context()
+	h.router.UserOnline(ctx, userID)
+	defer func() {
+		h.mailbox.Close(userID)
+		// The request context is done once the client is gone
+		h.router.UserOffline(context.Background(), userID)
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-stream.Done():
+	}
+}
+
+// HandleRiderEventsAck acknowledges the messages a rider received on their event
+// stream, like a client.cmd.ack sent over the WebSocket
+func (h *WebSocketHandler) HandleRiderEventsAck(w http.ResponseWriter, r *http.Request) {
+	userID, err := validateUserID(r)
+	if err != nil {
+		httpHandlers.WriteError(w, r, httpHandlers.BadRequest(err.Error()))
+		return
+	}
+
+	payload, ok := decodeCommandBody(w, r, contracts.ClientCmdAck)
+	if !ok {
+		return
+	}
+
+	h.handleAck(userID, payload.(*contracts.WSAckData))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRiderCommand returns the HTTP handler of a rider command, for riders on the
+// event stream. The body is the command's payload. The command is answered with its
+// ID, which is the request ID, and the result arrives on the rider's stream as a
+// command ack or error correlated to it.
+func (h *WebSocketHandler) HandleRiderCommand(commandType string) http.HandlerFunc {
+	return h.handleHTTPCommand(commandType, func(ctx context.Context, userID, commandID string, payload any) error {
+		_, err := h.publishRiderCommand(ctx, userID, commandType, commandID, payload)
+		return err
+	})
+}
+
+// HandleChatCommand returns the HTTP handler of a chat command, like HandleRiderCommand.
+// Messages and refusals arrive on the user's stream correlated to the command's ID.
+func (h *WebSocketHandler) HandleChatCommand(commandType string) http.HandlerFunc {
+	return h.handleHTTPCommand(commandType, func(ctx context.Context, userID, commandID string, payload any) error {
+		_, err := h.publishChatCommand(ctx, userID, commandType, commandID, payload)
+		return err
+	})
+}
+
+// handleHTTPCommand validates the body of a command and publishes it, answering with
+// the command's ID once it is relayed
+func (h *WebSocketHandler) handleHTTPCommand(commandType string, publish func(ctx context.Context, userID, commandID string, payload any) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := validateUserID(r)
+		if err != nil {
+			httpHandlers.WriteError(w, r, httpHandlers.BadRequest(err.Error()))
+			return
+		}
+
+		payload, ok := decodeCommandBody(w, r, commandType)
+		if !ok {
+			return
+		}
+
+		commandID := httpHandlers.RequestIDFrom(r.Context())
+		if err := publish(r.Context(), userID, commandID, payload); err != nil {
+			log.Printf("Error publishing %s of user %s: %v", commandType, userID, err)
+			httpHandlers.WriteError(w, r, &httpHandlers.Error{
+				Status:  http.StatusServiceUnavailable,
+				Code:    contracts.ErrCodeServiceUnavailable,
+				Message: "failed to relay the command, retry later",
+			})
+			return
+		}
+
+		httpHandlers.WriteJSON(w, http.StatusAccepted, contracts.APIResponse{
+			Data: contracts.CommandAccepted{CommandID: commandID},
+		})
+	}
+}
+
+// decodeCommandBody validates a request body against the payload registered for a
+// message type. Invalid bodies are answered with a 400 and reported as not ok.
+func decodeCommandBody(w http.ResponseWriter, r *http.Request, messageType string) (any, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCommandBodySize))
+	if err != nil {
+		httpHandlers.WriteError(w, r, httpHandlers.BadRequest("failed to read the body"))
+		return nil, false
+	}
+
+	payload, err := messaging.DecodeWSPayload(messageType, body)
+	var msgErr *messaging.WSMessageError
+	if errors.As(err, &msgErr) {
+		httpHandlers.WriteError(w, r, &httpHandlers.Error{Status: http.StatusBadRequest, Code: msgErr.Code, Message: msgErr.Message})
+		return nil, false
+	}
+	return payload, true
+}
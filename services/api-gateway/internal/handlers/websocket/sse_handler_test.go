@@ -0,0 +1,307 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	httpHandlers "ride-sharing/services/api-gateway/internal/handlers/http"
+	"ride-sharing/services/api-gateway/internal/websocket"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseHandler returns the handler of a gateway's rider event streams, publishing
+// commands to broker
+func sseHandler(g *testGateway, broker messaging.MessageBroker) *WebSocketHandler {
+	return &WebSocketHandler{
+		connManager:   g.connManager,
+		mailbox:       g.mailbox,
+		router:        g.router,
+		upgrader:      websocket.NewWebSocketUpgrader(messaging.AllowAllOrigins(), nil),
+		messageBroker: broker,
+	}
+}
+
+// sseServer serves a gateway's rider event streams
+func sseServer(t *testing.T, g *testGateway) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(sseHandler(g, g.broker).HandleRiderEvents))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func openEventStream(t *testing.T, server *httptest.Server, lastEventID string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"?userID=rider-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	return resp
+}
+
+// readEventIDs reads events until it has count of them and returns their IDs
+func readEventIDs(t *testing.T, body io.Reader, count int) []string {
+	t.Helper()
+
+	events := make(chan string)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(body)
+		var id string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if value, ok := strings.CutPrefix(line, "id: "); ok {
+				id = value
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var message contracts.WSMessage
+				if err := json.Unmarshal([]byte(data), &message); err != nil {
+					t.Errorf("invalid event data %q: %v", data, err)
+				}
+				events <- id
+				id = ""
+			}
+		}
+	}()
+
+	var ids []string
+	timeout := time.After(time.Second)
+	for len(ids) < count {
+		select {
+		case id, ok := <-events:
+			if !ok {
+				t.Fatalf("stream ended after events %v", ids)
+			}
+			ids = append(ids, id)
+		case <-timeout:
+			t.Fatalf("got events %v, want %d", ids, count)
+		}
+	}
+	return ids
+}
+
+func TestRiderEventsReplaysAfterLastEventID(t *testing.T) {
+	g := startGateway(t, newMemoryBus(), "a")
+	for range 3 {
+		g.broker.receive(t, messaging.NotifyTripStatusQueue, contracts.TripEventStarted, tripStatus("rider-1"))
+	}
+	server := sseServer(t, g)
+
+	first := openEventStream(t, server, "")
+	ids := readEventIDs(t, first.Body, 3)
+	first.Body.Close()
+	eventually(t, func() bool { return !g.connManager.Connected("rider-1") })
+
+	// The browser reconnects after the first event, the others are still unacknowledged
+	resp := openEventStream(t, server, ids[0])
+
+	if got := readEventIDs(t, resp.Body, 2); !slices.Equal(got, ids[1:]) {
+		t.Errorf("replayed events %v, want %v", got, ids[1:])
+	}
+}
+
+func TestRiderEventsRejectsInvalidLastEventID(t *testing.T) {
+	server := sseServer(t, startGateway(t, newMemoryBus(), "a"))
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"?userID=rider-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", "latest")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestRiderEventsEndWhenStreamIsClosed(t *testing.T) {
+	g := startGatewayWithSessions(t, newMemoryBus(), "a", websocket.SessionConfig{MaxSessions: 1, OnLimit: websocket.SessionLimitKickOldest})
+	server := sseServer(t, g)
+
+	first := openEventStream(t, server, "")
+	eventually(t, func() bool { return g.connManager.Connected("rider-1") })
+
+	// The newer stream replaces the first one, whose response ends
+	openEventStream(t, server, "")
+
+	body, err := io.ReadAll(first.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(body), ": closed: replaced by a newer session\n\n") {
+		t.Errorf("first stream ended with %q, want the close reason", body)
+	}
+}
+
+// commandBroker records the published commands, or fails to publish them with err
+type commandBroker struct {
+	messaging.MessageBroker
+	err       error
+	published []string
+	commands  []messaging.RiderCommandData
+}
+
+func (b *commandBroker) Publish(ctx context.Context, routingKey string, msg contracts.AmqpMessage) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	var command messaging.RiderCommandData
+	if err := json.Unmarshal(msg.Data, &command); err != nil {
+		return err
+	}
+	b.published = append(b.published, routingKey+" -> "+msg.OwnerID)
+	b.commands = append(b.commands, command)
+	return nil
+}
+
+// commandResponse is the APIResponse of a command endpoint
+type commandResponse struct {
+	Data  contracts.CommandAccepted `json:"data"`
+	Error *contracts.APIError       `json:"error"`
+}
+
+// postCommand posts body to handler as rider-1 with the request ID requestID and
+// decodes the response
+func postCommand(t *testing.T, handler http.HandlerFunc, requestID, body string) (int, commandResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/?userID=rider-1", strings.NewReader(body))
+	req.Header.Set(httpHandlers.RequestIDHeader, requestID)
+	rec := httptest.NewRecorder()
+	httpHandlers.RequestID(handler)(rec, req)
+
+	var resp commandResponse
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", rec.Body, err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestRiderCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		publishErr    error
+		wantStatus    int
+		wantCode      string
+		wantPublished []string
+	}{
+		{
+			name:          "accepted",
+			body:          `{"tripID":"trip-1","reason":"changed plans"}`,
+			wantStatus:    http.StatusAccepted,
+			wantPublished: []string{contracts.RiderCmdCancelTrip + " -> rider-1"},
+		},
+		{
+			name:       "missing trip",
+			body:       `{"reason":"changed plans"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   contracts.ErrCodeInvalidPayload,
+		},
+		{
+			name:       "unknown field",
+			body:       `{"tripID":"trip-1","userID":"rider-2"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   contracts.ErrCodeInvalidPayload,
+		},
+		{
+			name:       "empty body",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   contracts.ErrCodeInvalidPayload,
+		},
+		{
+			name:       "publishing fails",
+			body:       `{"tripID":"trip-1"}`,
+			publishErr: errors.New("channel closed"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   contracts.ErrCodeServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := &commandBroker{err: tt.publishErr}
+			h := sseHandler(startGateway(t, newMemoryBus(), "a"), broker)
+
+			status, resp := postCommand(t, h.HandleRiderCommand(contracts.RiderCmdCancelTrip), "req-1", tt.body)
+
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if !slices.Equal(broker.published, tt.wantPublished) {
+				t.Errorf("published %v, want %v", broker.published, tt.wantPublished)
+			}
+			if tt.wantCode != "" {
+				if resp.Error == nil || resp.Error.Code != tt.wantCode {
+					t.Errorf("error = %+v, want code %s", resp.Error, tt.wantCode)
+				}
+				return
+			}
+
+			// The command ID is the request ID, its ack on the stream is correlated to it
+			if got := resp.Data; got != (contracts.CommandAccepted{CommandID: "req-1"}) {
+				t.Errorf("data = %+v, want command ID req-1", got)
+			}
+			if got := broker.commands[0]; got.CommandID != "req-1" || got.TripID != "trip-1" || got.Reason != "changed plans" {
+				t.Errorf("published command = %+v", got)
+			}
+		})
+	}
+}
+
+func TestRiderEventsAckStopsReplay(t *testing.T) {
+	g := startGateway(t, newMemoryBus(), "a")
+	for range 3 {
+		g.broker.receive(t, messaging.NotifyTripStatusQueue, contracts.TripEventStarted, tripStatus("rider-1"))
+	}
+	server := sseServer(t, g)
+
+	first := openEventStream(t, server, "")
+	ids := readEventIDs(t, first.Body, 3)
+	first.Body.Close()
+	eventually(t, func() bool { return !g.connManager.Connected("rider-1") })
+
+	// Event IDs are the sequence numbers of the messages
+	h := sseHandler(g, g.broker)
+	if status, _ := postCommand(t, h.HandleRiderEventsAck, "req-1", `{"seq":`+ids[1]+`}`); status != http.StatusNoContent {
+		t.Fatalf("ack status = %d, want 204", status)
+	}
+	if status, resp := postCommand(t, h.HandleRiderEventsAck, "req-2", `{"seq":0}`); status != http.StatusBadRequest || resp.Error.Code != contracts.ErrCodeInvalidPayload {
+		t.Errorf("invalid ack status = %d, error = %+v, want 400 %s", status, resp.Error, contracts.ErrCodeInvalidPayload)
+	}
+
+	// Without Last-Event-ID only the unacknowledged event is replayed
+	resp := openEventStream(t, server, "")
+	if got := readEventIDs(t, resp.Body, 1); !slices.Equal(got, ids[2:]) {
+		t.Errorf("replayed events %v, want %v", got, ids[2:])
+	}
+}
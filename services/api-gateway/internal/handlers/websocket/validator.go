@@ -3,10 +3,14 @@ package websocket
 import (
 	"errors"
 	"net/http"
+	"ride-sharing/services/api-gateway/internal/auth"
+	"strconv"
 )
 
+// validateUserID returns the authenticated user, or the userID query parameter when
+// authentication is disabled
 func validateUserID(r *http.Request) (string, error) {
-	userID := r.URL.Query().Get("userID")
+	userID := auth.ResolveUserID(r.Context(), r.URL.Query().Get("userID"))
 	if userID == "" {
 		return "", errors.New("userID is required")
 	}
@@ -27,3 +31,35 @@ func validateDriverParams(r *http.Request) (userID, packageSlug string, err erro
 
 	return userID, packageSlug, nil
 }
+
+// validateLastSeq parses the optional sequence number of the last message a
+// reconnecting client has seen
+func validateLastSeq(r *http.Request) (uint64, error) {
+	lastSeq := r.URL.Query().Get("lastSeq")
+	if lastSeq == "" {
+		return 0, nil
+	}
+
+	seq, err := strconv.ParseUint(lastSeq, 10, 64)
+	if err != nil {
+		return 0, errors.New("lastSeq must be a non-negative integer")
+	}
+
+	return seq, nil
+}
+
+// validateLastEventID parses the seq of the last event a reconnecting event stream
+// has seen. Browsers send it in the Last-Event-ID header, other clients may pass lastSeq.
+func validateLastEventID(r *http.Request) (uint64, error) {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		return validateLastSeq(r)
+	}
+
+	seq, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil {
+		return 0, errors.New("Last-Event-ID must be a non-negative integer")
+	}
+
+	return seq, nil
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidPolicy is returned for malformed rate limit policies
+	ErrInvalidPolicy = errors.New("invalid rate limit policy")
+)
+
+// Policy allows Limit requests per Period. Up to Limit requests may be
+// made at once, after that they are spread evenly over the period.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParsePolicy parses a policy like "30/1m", or "off" for no limit
+func ParsePolicy(policy string) (Policy, error) {
+	if policy == "off" {
+		return Policy{}, nil
+	}
+
+	limit, period, ok := strings.Cut(policy, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("%w: %q, expected requests/period like 30/1m", ErrInvalidPolicy, policy)
+	}
+
+	parsed := Policy{}
+	var err error
+	if parsed.Limit, err = strconv.Atoi(limit); err != nil || parsed.Limit <= 0 {
+		return Policy{}, fmt.Errorf("%w: %q, limit must be a positive integer", ErrInvalidPolicy, policy)
+	}
+	if parsed.Period, err = time.ParseDuration(period); err != nil || parsed.Period <= 0 {
+		return Policy{}, fmt.Errorf("%w: %q, period must be a positive duration", ErrInvalidPolicy, policy)
+	}
+
+	return parsed, nil
+}
+
+// String formats the policy the way ParsePolicy parses it
+func (p Policy) String() string {
+	if !p.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%v", p.Limit, p.Period)
+}
+
+// Enabled reports whether the policy limits anything
+func (p Policy) Enabled() bool {
+	return p.Limit > 0 && p.Period > 0
+}
+
+// TokenBucket limits a single client to a policy. It starts full and refills
+// continuously. It is not safe for concurrent use.
+type TokenBucket struct {
+	policy  Policy
+	tokens  float64
+	updated time.Time
+}
+
+// NewTokenBucket creates a full bucket for a policy
+func NewTokenBucket(policy Policy, now time.Time) *TokenBucket {
+	return &TokenBucket{
+		policy:  policy,
+		tokens:  float64(policy.Limit),
+		updated: now,
+	}
+}
+
+// Take takes a token at now. Without a token left it returns false and how long
+// until the next one is available.
+func (b *TokenBucket) Take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false, b.timeFor(1 - b.tokens)
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// Remaining returns the number of whole tokens left
+func (b *TokenBucket) Remaining() int {
+	return int(b.tokens)
+}
+
+// UntilFull returns how long until the bucket is full again
+func (b *TokenBucket) UntilFull() time.Duration {
+	return b.timeFor(float64(b.policy.Limit) - b.tokens)
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		perSecond := float64(b.policy.Limit) / b.policy.Period.Seconds()
+		b.tokens = min(float64(b.policy.Limit), b.tokens+elapsed.Seconds()*perSecond)
+		b.updated = now
+	}
+}
+
+// Full reports whether the bucket refilled completely at now, which makes it the same as a new one
+func (b *TokenBucket) Full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= float64(b.policy.Limit)
+}
+
+// timeFor returns how long refilling the given number of tokens takes
+func (b *TokenBucket) timeFor(tokens float64) time.Duration {
+	return time.Duration(tokens * float64(b.policy.Period) / float64(b.policy.Limit))
+}
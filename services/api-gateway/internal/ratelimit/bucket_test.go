@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var testStart = time.Unix(1_700_000_000, 0)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   Policy
+	}{
+		{"30/1m", Policy{Limit: 30, Period: time.Minute}},
+		{"1/500ms", Policy{Limit: 1, Period: 500 * time.Millisecond}},
+		{"off", Policy{}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePolicy(tt.policy)
+		if err != nil || got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %+v, %v, want %+v", tt.policy, got, err, tt.want)
+		}
+		// String formats what ParsePolicy parses
+		if again, err := ParsePolicy(got.String()); err != nil || again != got {
+			t.Errorf("ParsePolicy(%q) = %+v, %v, want %+v", got.String(), again, err, got)
+		}
+	}
+
+	for _, policy := range []string{"", "30", "0/1m", "-1/1m", "x/1m", "30/0s", "30/-1m", "30/minute", "Off"} {
+		if _, err := ParsePolicy(policy); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("ParsePolicy(%q) error = %v, want ErrInvalidPolicy", policy, err)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := NewTokenBucket(Policy{Limit: 2, Period: time.Second}, testStart)
+
+	// The bucket starts full
+	for range 2 {
+		if ok, _ := bucket.Take(testStart); !ok {
+			t.Fatal("Take() of a full bucket = false")
+		}
+	}
+
+	steps := []struct {
+		after      time.Duration
+		wantOK     bool
+		wantRetry  time.Duration
+		wantRemain int
+	}{
+		// A token refills every 500ms
+		{0, false, 500 * time.Millisecond, 0},
+		{200 * time.Millisecond, false, 300 * time.Millisecond, 0},
+		{500 * time.Millisecond, true, 0, 0},
+		{600 * time.Millisecond, false, 400 * time.Millisecond, 0},
+		// Refilling stops at the limit
+		{time.Hour, true, 0, 1},
+	}
+
+	for _, step := range steps {
+		ok, retry := bucket.Take(testStart.Add(step.after))
+		if ok != step.wantOK || retry != step.wantRetry || bucket.Remaining() != step.wantRemain {
+			t.Errorf("Take() after %v = %v, %v with %d left, want %v, %v with %d left",
+				step.after, ok, retry, bucket.Remaining(), step.wantOK, step.wantRetry, step.wantRemain)
+		}
+	}
+
+	if got := bucket.UntilFull(); got != 500*time.Millisecond {
+		t.Errorf("UntilFull() = %v, want 500ms", got)
+	}
+
+	// Time going backwards doesn't add tokens
+	bucket.Take(testStart)
+	if ok, _ := bucket.Take(testStart); ok {
+		t.Error("Take() of an empty bucket succeeded after the clock went back")
+	}
+
+	if bucket.Full(testStart) {
+		t.Error("Full() of an empty bucket = true")
+	}
+	if !bucket.Full(testStart.Add(2 * time.Hour)) {
+		t.Error("Full() = false after refilling")
+	}
+}
@@ -2,76 +2,244 @@ package websocket
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"ride-sharing/shared/contracts"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 var (
 	ErrConnectionNotFound = errors.New("connection not found")
+	// ErrTooManySessions is returned when a user exceeds the session limit and the policy rejects new sessions
+	ErrTooManySessions = errors.New("too many sessions")
+	// ErrInvalidSessionLimitPolicy is returned for an unknown session limit policy
+	ErrInvalidSessionLimitPolicy = errors.New("invalid session limit policy")
 )
 
+// closeTimeout bounds how long closing a kicked session waits for the close frame to be written
+const closeTimeout = time.Second
+
+// SessionLimitPolicy decides what happens when a user opens more sessions than allowed
+type SessionLimitPolicy string
+
+const (
+	// SessionLimitKickOldest closes the user's oldest session to make room for the new one
+	SessionLimitKickOldest SessionLimitPolicy = "kick_oldest"
+	// SessionLimitReject refuses the new session
+	SessionLimitReject SessionLimitPolicy = "reject"
+)
+
+// ParseSessionLimitPolicy parses a session limit policy name
+func ParseSessionLimitPolicy(name string) (SessionLimitPolicy, error) {
+	switch policy := SessionLimitPolicy(name); policy {
+	case SessionLimitKickOldest, SessionLimitReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidSessionLimitPolicy, name)
+	}
+}
+
+// SessionConfig limits the concurrent sessions of a user, e.g. a phone and a browser
+type SessionConfig struct {
+	// MaxSessions is the maximum number of sessions per user, zero for no limit
+	MaxSessions int
+	OnLimit     SessionLimitPolicy
+}
+
+// DefaultSessionConfig returns a SessionConfig with sensible default values
+func DefaultSessionConfig() SessionConfig {
+	return SessionConfig{
+		MaxSessions: 5,
+		OnLimit:     SessionLimitKickOldest,
+	}
+}
+
 type connWrapper struct {
-	conn  *websocket.Conn
-	mutex sync.Mutex
+	sessionID string
+	transport transport
+	writer    *connWriter
 }
 
+// ConnectionManager keeps the WebSocket connections and event streams of each user.
+// A user may have several sessions at once, messages to the user are sent to all of them.
 type ConnectionManager struct {
-	connections map[string]*connWrapper
+	config       SessionConfig
+	writerConfig WriterConfig
+	heartbeat    HeartbeatConfig
+	// connections holds each user's sessions, oldest first
+	connections map[string][]*connWrapper
 	mutex       sync.RWMutex
 }
 
 // constructor for connection manager
-func NewConnectionManager() *ConnectionManager {
+func NewConnectionManager(config SessionConfig, writerConfig WriterConfig, heartbeat HeartbeatConfig) *ConnectionManager {
 	return &ConnectionManager{
-		connections: make(map[string]*connWrapper),
+		config:       config,
+		writerConfig: writerConfig,
+		heartbeat:    heartbeat,
+		connections:  make(map[string][]*connWrapper),
 	}
 }
 
-// Add connection for user
-func (cm *ConnectionManager) Add(id string, conn *websocket.Conn) {
+// Add a session for user and return its ID. When the user is at the session limit,
+// the oldest session is closed or ErrTooManySessions returned, depending on the policy.
+// The connection is pinged from now on and must be read with ReadMessage.
+func (cm *ConnectionManager) Add(id string, conn *websocket.Conn) (string, error) {
+	return cm.add(id, &wsTransport{conn: conn}, func() {
+		cm.heartbeat.watch(conn)
+	})
+}
+
+// AddStream adds a session for user whose messages are written to an event stream,
+// and returns its ID. The session limit applies like to Add. Keep-alive comments are
+// sent each ping interval.
+func (cm *ConnectionManager) AddStream(id string, stream *EventStream) (string, error) {
+	return cm.add(id, stream, nil)
+}
+
+// add registers a session writing to transport. onAdded runs once the session limit
+// let the session in.
+func (cm *ConnectionManager) add(id string, transport transport, onAdded func()) (string, error) {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
-	cm.connections[id] = &connWrapper{
-		conn:  conn,
-		mutex: sync.Mutex{},
+
+	sessions := cm.connections[id]
+	if cm.config.MaxSessions > 0 && len(sessions) >= cm.config.MaxSessions {
+		if cm.config.OnLimit == SessionLimitReject {
+			return "", ErrTooManySessions
+		}
+
+		// The kicked session's handler notices the closed connection and removes it
+		oldest := sessions[0]
+		sessions = sessions[1:]
+		oldest.writer.stop()
+		go oldest.transport.close("replaced by a newer session")
+		log.Printf("Session limit of user %s reached, closed session %s", id, oldest.sessionID)
 	}
 
-	log.Printf("Added connection for user %s", id)
+	if onAdded != nil {
+		onAdded()
+	}
+
+	sessionID := uuid.NewString()
+	cm.connections[id] = append(sessions, &connWrapper{
+		sessionID: sessionID,
+		transport: transport,
+		writer:    newConnWriter(transport, cm.writerConfig, cm.heartbeat.PingInterval),
+	})
+
+	log.Printf("Added session %s for user %s", sessionID, id)
+	return sessionID, nil
 }
 
-// Remove connection for user
-func (cm *ConnectionManager) Remove(id string) {
+// Remove a session of user, leaving their other sessions in place
+func (cm *ConnectionManager) Remove(id string, sessionID string) {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
-	delete(cm.connections, id)
+
+	sessions := cm.connections[id]
+	for i, wrapper := range sessions {
+		if wrapper.sessionID != sessionID {
+			continue
+		}
+
+		wrapper.writer.stop()
+		sessions = append(sessions[:i:i], sessions[i+1:]...)
+		if len(sessions) == 0 {
+			delete(cm.connections, id)
+		} else {
+			cm.connections[id] = sessions
+		}
+		return
+	}
 }
 
-// Get connection for user
-func (cm *ConnectionManager) Get(id string) (*websocket.Conn, bool) {
+// ReadMessage reads the next message of a connection. Every message shows the client is
+// alive, like a pong. An error means the connection is closed or dead.
+func (cm *ConnectionManager) ReadMessage(conn *websocket.Conn) ([]byte, error) {
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cm.heartbeat.extendReadDeadline(conn); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// Connected reports whether user has at least one session
+func (cm *ConnectionManager) Connected(id string) bool {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+	return len(cm.connections[id]) > 0
+}
+
+// IDs returns the users with a connection
+func (cm *ConnectionManager) IDs() []string {
 	cm.mutex.RLock()
 	defer cm.mutex.RUnlock()
-	wrapper, exists := cm.connections[id]
-	if !exists {
-		return nil, false
+	ids := make([]string, 0, len(cm.connections))
+	for id := range cm.connections {
+		ids = append(ids, id)
 	}
-	return wrapper.conn, true
+	return ids
 }
 
-// Send message
+// SendMessage queues a message for every session of user without waiting for the writes.
+// It only fails if no session accepted the message.
 func (cm *ConnectionManager) SendMessage(id string, message contracts.WSMessage) error {
 	cm.mutex.RLock()
-	wrapper, exists := cm.connections[id]
+	sessions := cm.connections[id]
 	cm.mutex.RUnlock()
 
-	if !exists {
+	if len(sessions) == 0 {
 		return ErrConnectionNotFound
 	}
 
-	wrapper.mutex.Lock()
-	defer wrapper.mutex.Unlock()
+	var errs []error
+	for _, wrapper := range sessions {
+		if err := wrapper.writer.enqueue(message); err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", wrapper.sessionID, err))
+		}
+	}
 
-	return wrapper.conn.WriteJSON(message)
+	if len(errs) == len(sessions) {
+		return errors.Join(errs...)
+	}
+	for _, err := range errs {
+		log.Printf("Failed to send message to user %s: %v", id, err)
+	}
+	return nil
+}
+
+// SendToSession queues a message for a single session of user
+func (cm *ConnectionManager) SendToSession(id, sessionID string, message contracts.WSMessage) error {
+	cm.mutex.RLock()
+	var session *connWrapper
+	for _, wrapper := range cm.connections[id] {
+		if wrapper.sessionID == sessionID {
+			session = wrapper
+			break
+		}
+	}
+	cm.mutex.RUnlock()
+
+	if session == nil {
+		return ErrConnectionNotFound
+	}
+	return session.writer.enqueue(message)
+}
+
+// CloseConnection tells the client why its connection is closed, then closes it
+func CloseConnection(conn *websocket.Conn, reason string) {
+	message := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	if err := conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(closeTimeout)); err != nil {
+		log.Printf("Failed to send close message: %v", err)
+	}
+	conn.Close()
 }
@@ -0,0 +1,156 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connPair returns the server and client side of a WebSocket connection
+func connPair(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+
+	upgrader := NewWebSocketUpgrader(messaging.AllowAllOrigins(), nil)
+	accepted := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		accepted <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	conn := <-accepted
+	t.Cleanup(func() { conn.Close() })
+	return conn, client
+}
+
+// addSessions adds count sessions of user and returns their IDs and client sides
+func addSessions(t *testing.T, cm *ConnectionManager, user string, count int) ([]string, []*websocket.Conn) {
+	t.Helper()
+
+	var sessionIDs []string
+	var clients []*websocket.Conn
+	for range count {
+		conn, client := connPair(t)
+		sessionID, err := cm.Add(user, conn)
+		if err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+		clients = append(clients, client)
+	}
+	return sessionIDs, clients
+}
+
+// readType returns the type of the next message of client, or the error reading it
+func readType(client *websocket.Conn) (string, error) {
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	var message contracts.WSMessage
+	if err := client.ReadJSON(&message); err != nil {
+		return "", err
+	}
+	return message.Type, nil
+}
+
+func TestParseSessionLimitPolicy(t *testing.T) {
+	for _, name := range []string{"kick_oldest", "reject"} {
+		if policy, err := ParseSessionLimitPolicy(name); err != nil || string(policy) != name {
+			t.Errorf("ParseSessionLimitPolicy(%q) = %q, %v", name, policy, err)
+		}
+	}
+	if _, err := ParseSessionLimitPolicy("queue"); !errors.Is(err, ErrInvalidSessionLimitPolicy) {
+		t.Errorf("ParseSessionLimitPolicy(queue) error = %v, want ErrInvalidSessionLimitPolicy", err)
+	}
+}
+
+func TestConnectionManagerKicksOldestSession(t *testing.T) {
+	cm := NewConnectionManager(SessionConfig{MaxSessions: 2, OnLimit: SessionLimitKickOldest}, DefaultWriterConfig(), DefaultHeartbeatConfig())
+	_, clients := addSessions(t, cm, "rider-1", 3)
+
+	_, err := readType(clients[0])
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.ClosePolicyViolation || closeErr.Text != "replaced by a newer session" {
+		t.Fatalf("oldest session read %v, want it closed as replaced", err)
+	}
+
+	if err := cm.SendMessage("rider-1", contracts.WSMessage{Type: contracts.TripEventCreated}); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	for i, client := range clients[1:] {
+		if messageType, err := readType(client); err != nil || messageType != contracts.TripEventCreated {
+			t.Errorf("session %d read %q, %v, want the message", i+1, messageType, err)
+		}
+	}
+}
+
+func TestConnectionManagerRejectsSessionsOverLimit(t *testing.T) {
+	cm := NewConnectionManager(SessionConfig{MaxSessions: 2, OnLimit: SessionLimitReject}, DefaultWriterConfig(), DefaultHeartbeatConfig())
+	_, clients := addSessions(t, cm, "rider-1", 2)
+
+	conn, _ := connPair(t)
+	if _, err := cm.Add("rider-1", conn); !errors.Is(err, ErrTooManySessions) {
+		t.Fatalf("Add() over the limit = %v, want ErrTooManySessions", err)
+	}
+	// Other users have their own limit
+	if _, err := cm.Add("rider-2", conn); err != nil {
+		t.Fatalf("Add() for another user = %v", err)
+	}
+
+	// The existing sessions are left alone
+	if err := cm.SendMessage("rider-1", contracts.WSMessage{Type: contracts.TripEventCreated}); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	for i, client := range clients {
+		if messageType, err := readType(client); err != nil || messageType != contracts.TripEventCreated {
+			t.Errorf("session %d read %q, %v, want the message", i, messageType, err)
+		}
+	}
+}
+
+func TestConnectionManagerRemovesSingleSessions(t *testing.T) {
+	cm := NewConnectionManager(SessionConfig{}, DefaultWriterConfig(), DefaultHeartbeatConfig())
+	sessionIDs, clients := addSessions(t, cm, "rider-1", 2)
+
+	cm.Remove("rider-1", sessionIDs[0])
+	if !cm.Connected("rider-1") {
+		t.Fatal("Connected() = false with a session left")
+	}
+	if err := cm.SendToSession("rider-1", sessionIDs[0], contracts.WSMessage{Type: contracts.TripEventCreated}); !errors.Is(err, ErrConnectionNotFound) {
+		t.Errorf("SendToSession() of the removed session = %v, want ErrConnectionNotFound", err)
+	}
+	if err := cm.SendToSession("rider-1", sessionIDs[1], contracts.WSMessage{Type: contracts.TripEventCreated}); err != nil {
+		t.Fatalf("SendToSession() error = %v", err)
+	}
+	if messageType, err := readType(clients[1]); err != nil || messageType != contracts.TripEventCreated {
+		t.Errorf("remaining session read %q, %v, want the message", messageType, err)
+	}
+
+	// Removing an unknown session changes nothing
+	cm.Remove("rider-1", "unknown")
+	if !cm.Connected("rider-1") {
+		t.Fatal("Connected() = false after removing an unknown session")
+	}
+
+	cm.Remove("rider-1", sessionIDs[1])
+	if cm.Connected("rider-1") {
+		t.Error("Connected() = true after removing every session")
+	}
+	if err := cm.SendMessage("rider-1", contracts.WSMessage{Type: contracts.TripEventCreated}); !errors.Is(err, ErrConnectionNotFound) {
+		t.Errorf("SendMessage() without sessions = %v, want ErrConnectionNotFound", err)
+	}
+}
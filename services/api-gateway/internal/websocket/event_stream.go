@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"ride-sharing/shared/contracts"
+	"sync"
+	"time"
+)
+
+// defaultRetryInterval is how long browsers wait before reconnecting a dropped event stream
+const defaultRetryInterval = 3 * time.Second
+
+// EventStream writes a session's messages as server-sent events, for clients that
+// can't open a WebSocket. Each event's data is the message as it would be sent over
+// the WebSocket, and messages with a seq use it as the event ID, so reconnecting
+// browsers report the last one they received in the Last-Event-ID header.
+type EventStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+
+	// mutex serializes the writes, and makes Close wait for the one in progress
+	mutex  sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewEventStream prepares the response of a request for streaming events. Nothing
+// is written until Open, so the request can still be answered with an error.
+func NewEventStream(w http.ResponseWriter) *EventStream {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Keeps nginx from buffering the events
+	header.Set("X-Accel-Buffering", "no")
+
+	return &EventStream{
+		w:          w,
+		controller: http.NewResponseController(w),
+		done:       make(chan struct{}),
+	}
+}
+
+// Open sends the response headers and the reconnect interval to the client
+func (s *EventStream) Open() error {
+	return s.send(time.Time{}, fmt.Sprintf("retry: %d\n\n", defaultRetryInterval.Milliseconds()))
+}
+
+// Done is closed once the stream is closed, by Close or because a write failed
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends the stream. It must be called before the request's handler returns.
+func (s *EventStream) Close() {
+	s.close("")
+}
+
+func (s *EventStream) write(message contracts.WSMessage, deadline time.Time) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	event := fmt.Sprintf("data: %s\n\n", data)
+	if message.Seq > 0 {
+		event = fmt.Sprintf("id: %d\n%s", message.Seq, event)
+	}
+	return s.send(deadline, event)
+}
+
+// keepAlive sends a comment, which clients ignore but keeps proxies from closing the idle stream
+func (s *EventStream) keepAlive(deadline time.Time) error {
+	return s.send(deadline, ": keep-alive\n\n")
+}
+
+// close ends the stream. The reason is sent as a comment, browsers reconnect on their own.
+func (s *EventStream) close(reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return
+	}
+	if reason != "" {
+		s.sendLocked(time.Now().Add(closeTimeout), fmt.Sprintf(": closed: %s\n\n", reason))
+	}
+	s.closed = true
+	close(s.done)
+}
+
+func (s *EventStream) send(deadline time.Time, event string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrConnectionClosed
+	}
+	return s.sendLocked(deadline, event)
+}
+
+// sendLocked writes and flushes an event. The caller must hold the lock.
+func (s *EventStream) sendLocked(deadline time.Time, event string) error {
+	if err := s.controller.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := s.w.Write([]byte(event)); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
@@ -0,0 +1,125 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"ride-sharing/shared/contracts"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStreamFraming(t *testing.T) {
+	withSeq := contracts.WSMessage{Type: contracts.TripEventStarted, Seq: 7}
+	withoutSeq := contracts.WSMessage{Type: contracts.ClientCmdHeartbeat}
+
+	writeErr := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream := NewEventStream(w)
+		if err := stream.Open(); err != nil {
+			t.Error(err)
+			return
+		}
+		for _, message := range []contracts.WSMessage{withSeq, withoutSeq} {
+			if err := stream.write(message, time.Now().Add(time.Second)); err != nil {
+				t.Error(err)
+			}
+		}
+		stream.close("going away")
+		writeErr <- stream.write(withSeq, time.Now().Add(time.Second))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := func(message contracts.WSMessage) string {
+		encoded, err := json.Marshal(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(encoded)
+	}
+	want := "retry: 3000\n\n" +
+		"id: 7\ndata: " + data(withSeq) + "\n\n" +
+		"data: " + data(withoutSeq) + "\n\n" +
+		": closed: going away\n\n"
+	if string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+
+	if err := <-writeErr; !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("write after close = %v, want ErrConnectionClosed", err)
+	}
+}
+
+func TestEventStreamKeepAlive(t *testing.T) {
+	cm := NewConnectionManager(DefaultSessionConfig(), DefaultWriterConfig(), HeartbeatConfig{PingInterval: 10 * time.Millisecond})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream := NewEventStream(w)
+		defer stream.Close()
+
+		sessionID, err := cm.AddStream("rider-1", stream)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer cm.Remove("rider-1", sessionID)
+
+		if err := stream.Open(); err != nil {
+			t.Error(err)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-stream.Done():
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream ended without a keep-alive comment")
+			}
+			if strings.HasPrefix(line, ": keep-alive") {
+				return
+			}
+		case <-timeout:
+			t.Fatal("no keep-alive comment within a second")
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// HeartbeatConfig holds the tunables of dead connection detection. The gateway pings
+// every connection each PingInterval, and a connection that sends neither a pong nor
+// a message for PingInterval plus PongTimeout is considered dead and closed.
+type HeartbeatConfig struct {
+	// PingInterval is the time between two pings, zero disables the heartbeat
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+}
+
+// DefaultHeartbeatConfig returns a HeartbeatConfig with sensible default values
+func DefaultHeartbeatConfig() HeartbeatConfig {
+	return HeartbeatConfig{
+		PingInterval: 25 * time.Second,
+		PongTimeout:  10 * time.Second,
+	}
+}
+
+func (c HeartbeatConfig) enabled() bool {
+	return c.PingInterval > 0
+}
+
+// extendReadDeadline gives the client another ping interval plus pong timeout to show it's alive
+func (c HeartbeatConfig) extendReadDeadline(conn *websocket.Conn) error {
+	if !c.enabled() {
+		return nil
+	}
+	return conn.SetReadDeadline(time.Now().Add(c.PingInterval + c.PongTimeout))
+}
+
+// watch sets the initial read deadline of a connection and extends it on every pong
+func (c HeartbeatConfig) watch(conn *websocket.Conn) {
+	if !c.enabled() {
+		return
+	}
+
+	c.extendReadDeadline(conn)
+	conn.SetPongHandler(func(string) error {
+		return c.extendReadDeadline(conn)
+	})
+}
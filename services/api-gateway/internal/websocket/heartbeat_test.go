@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatClosesSilentPeers(t *testing.T) {
+	heartbeat := HeartbeatConfig{PingInterval: 20 * time.Millisecond, PongTimeout: 20 * time.Millisecond}
+
+	tests := []struct {
+		name      string
+		answering bool
+	}{
+		{name: "peer not answering pings", answering: false},
+		{name: "peer answering pings", answering: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, client := connPair(t)
+			heartbeat.watch(conn)
+			w := newConnWriter(&wsTransport{conn: conn}, DefaultWriterConfig(), heartbeat.PingInterval)
+			defer w.stop()
+
+			// gorilla answers pings while reading, a client that never reads never answers
+			if tt.answering {
+				go func() {
+					for {
+						if _, _, err := client.ReadMessage(); err != nil {
+							return
+						}
+					}
+				}()
+			}
+
+			readErr := make(chan error, 1)
+			go func() {
+				for {
+					if _, _, err := conn.ReadMessage(); err != nil {
+						readErr <- err
+						return
+					}
+				}
+			}()
+
+			select {
+			case err := <-readErr:
+				if tt.answering {
+					t.Fatalf("connection of an answering peer closed: %v", err)
+				}
+				if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+					t.Errorf("read error = %v, want a timeout", err)
+				}
+			case <-time.After(10 * (heartbeat.PingInterval + heartbeat.PongTimeout)):
+				if !tt.answering {
+					t.Fatal("connection of a silent peer is still open")
+				}
+			}
+		})
+	}
+}
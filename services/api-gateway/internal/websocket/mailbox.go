@@ -0,0 +1,211 @@
+package websocket
+
+import (
+	"log"
+	"ride-sharing/shared/contracts"
+	"slices"
+	"sync"
+	"time"
+)
+
+type mailboxEntry struct {
+	message  contracts.WSMessage
+	storedAt time.Time
+}
+
+// userMailbox holds the unacknowledged messages of a user
+type userMailbox struct {
+	mutex   sync.Mutex
+	nextSeq uint64
+	entries []mailboxEntry
+	// open counts the connections the user's messages are sent to
+	open int
+	// removed is set once the mailbox was dropped from the Mailbox
+	removed bool
+}
+
+// Mailbox keeps the messages of each user until the user acknowledges them, so
+// messages sent while a user is offline, or lost with a dropped connection, are
+// replayed in order when the user reconnects. Each mailbox holds at most
+// maxMessages messages, dropping the oldest, and messages expire after the TTL.
+//
+// Messages are numbered per user. Sequences start at the time the mailbox was
+// created in microseconds, so they keep increasing when a user's mailbox is
+// recreated, e.g. on another gateway instance.
+type Mailbox struct {
+	send        func(id string, message contracts.WSMessage) error
+	maxMessages int
+	ttl         time.Duration
+	boxes       map[string]*userMailbox
+	mutex       sync.Mutex
+}
+
+// NewMailbox creates a mailbox that delivers messages of open users with send.
+// Each user's mailbox holds at least one message, whatever maxMessages says.
+func NewMailbox(send func(id string, message contracts.WSMessage) error, maxMessages int, ttl time.Duration) *Mailbox {
+	return &Mailbox{
+		send:        send,
+		maxMessages: max(maxMessages, 1),
+		ttl:         ttl,
+		boxes:       make(map[string]*userMailbox),
+	}
+}
+
+// Deliver numbers and stores a message for a user and sends it if the user is open.
+// Messages that already carry a Seq, e.g. handed over by another gateway instance,
+// keep it. A failed send is not an error, the message is replayed once the user reconnects.
+func (m *Mailbox) Deliver(id string, message contracts.WSMessage) uint64 {
+	box := m.lockBox(id)
+	defer box.mutex.Unlock()
+
+	m.prune(box)
+
+	if message.Seq == 0 {
+		message.Seq = box.nextSeq
+	}
+	box.nextSeq = max(box.nextSeq, message.Seq+1)
+
+	if len(box.entries) >= m.maxMessages {
+		log.Printf("Mailbox of %s is full, dropping the oldest message", id)
+		box.entries = box.entries[1:]
+	}
+	// Handed over messages may be numbered lower than the ones already stored
+	position := len(box.entries)
+	for position > 0 && box.entries[position-1].message.Seq > message.Seq {
+		position--
+	}
+	box.entries = slices.Insert(box.entries, position, mailboxEntry{message: message, storedAt: time.Now()})
+
+	if box.open > 0 {
+		if err := m.send(id, message); err != nil {
+			log.Printf("Failed to send message %d to %s, keeping it for replay: %v", message.Seq, id, err)
+		}
+	}
+
+	return message.Seq
+}
+
+// Open starts sending a user's messages for a new connection and replays the
+// unacknowledged ones to it with replay, oldest first. It returns the number of
+// replayed messages. Messages delivered in the meantime wait for the replay, so
+// they are never sent out of order.
+func (m *Mailbox) Open(id string, replay func(message contracts.WSMessage) error) int {
+	box := m.lockBox(id)
+	defer box.mutex.Unlock()
+
+	m.prune(box)
+	box.open++
+
+	replayed := 0
+	for _, entry := range box.entries {
+		if err := replay(entry.message); err != nil {
+			log.Printf("Failed to replay messages to %s: %v", id, err)
+			break
+		}
+		replayed++
+	}
+
+	return replayed
+}
+
+// Close stops sending a user's messages for one of the connections opened with Open
+func (m *Mailbox) Close(id string) {
+	box := m.lockBox(id)
+	defer box.mutex.Unlock()
+
+	if box.open > 0 {
+		box.open--
+	}
+}
+
+// Ack drops the messages of a user numbered up to and including seq
+func (m *Mailbox) Ack(id string, seq uint64) {
+	box := m.lockBox(id)
+	defer box.mutex.Unlock()
+
+	acked := 0
+	for acked < len(box.entries) && box.entries[acked].message.Seq <= seq {
+		acked++
+	}
+	box.entries = box.entries[acked:]
+}
+
+// Has reports whether a user has unacknowledged messages
+func (m *Mailbox) Has(id string) bool {
+	m.mutex.Lock()
+	box, exists := m.boxes[id]
+	m.mutex.Unlock()
+	if !exists {
+		return false
+	}
+
+	box.mutex.Lock()
+	defer box.mutex.Unlock()
+	m.prune(box)
+	return len(box.entries) > 0
+}
+
+// Take removes and returns the unacknowledged messages of a user who isn't open,
+// e.g. to hand them to the gateway instance the user connected to
+func (m *Mailbox) Take(id string) []contracts.WSMessage {
+	box := m.lockBox(id)
+	defer box.mutex.Unlock()
+
+	if box.open > 0 {
+		return nil
+	}
+
+	m.prune(box)
+	messages := make([]contracts.WSMessage, 0, len(box.entries))
+	for _, entry := range box.entries {
+		messages = append(messages, entry.message)
+	}
+	box.entries = nil
+	return messages
+}
+
+// Expire drops expired messages and forgets the empty mailboxes of users who aren't open
+func (m *Mailbox) Expire() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for id, box := range m.boxes {
+		// Busy mailboxes are pruned on their next use
+		if !box.mutex.TryLock() {
+			continue
+		}
+		m.prune(box)
+		if len(box.entries) == 0 && box.open == 0 {
+			box.removed = true
+			delete(m.boxes, id)
+		}
+		box.mutex.Unlock()
+	}
+}
+
+// lockBox returns the locked mailbox of a user, creating it if needed
+func (m *Mailbox) lockBox(id string) *userMailbox {
+	for {
+		m.mutex.Lock()
+		box, exists := m.boxes[id]
+		if !exists {
+			box = &userMailbox{nextSeq: uint64(time.Now().UnixMicro())}
+			m.boxes[id] = box
+		}
+		m.mutex.Unlock()
+
+		box.mutex.Lock()
+		if !box.removed {
+			return box
+		}
+		// Expire dropped the mailbox between the two locks
+		box.mutex.Unlock()
+	}
+}
+
+// prune drops the expired messages of a mailbox. The caller must hold the mailbox lock.
+func (m *Mailbox) prune(box *userMailbox) {
+	box.entries = slices.DeleteFunc(box.entries, func(entry mailboxEntry) bool {
+		return time.Since(entry.storedAt) > m.ttl
+	})
+}
@@ -0,0 +1,220 @@
+package websocket
+
+import (
+	"errors"
+	"ride-sharing/shared/contracts"
+	"slices"
+	"testing"
+	"time"
+)
+
+// recorder collects the messages a mailbox sends
+type recorder struct {
+	messages []contracts.WSMessage
+	err      error
+}
+
+func (r *recorder) send(_ string, message contracts.WSMessage) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func (r *recorder) replay(message contracts.WSMessage) error {
+	return r.send("", message)
+}
+
+func (r *recorder) seqs() []uint64 {
+	seqs := make([]uint64, 0, len(r.messages))
+	for _, message := range r.messages {
+		seqs = append(seqs, message.Seq)
+	}
+	return seqs
+}
+
+func wsMessage(messageType string) contracts.WSMessage {
+	return contracts.WSMessage{Type: messageType}
+}
+
+func TestMailboxNumbersMessages(t *testing.T) {
+	m := NewMailbox((&recorder{}).send, 10, time.Minute)
+
+	first := m.Deliver("u1", wsMessage("a"))
+	second := m.Deliver("u1", wsMessage("b"))
+	if first == 0 {
+		t.Fatal("first message was not numbered")
+	}
+	if second != first+1 {
+		t.Errorf("second seq = %d, want %d", second, first+1)
+	}
+
+	// Numbered messages keep their number and move the next one past it
+	if seq := m.Deliver("u1", contracts.WSMessage{Type: "c", Seq: second + 10}); seq != second+10 {
+		t.Errorf("handed over seq = %d, want %d", seq, second+10)
+	}
+	if seq := m.Deliver("u1", wsMessage("d")); seq != second+11 {
+		t.Errorf("seq after handover = %d, want %d", seq, second+11)
+	}
+
+	// Users are numbered independently
+	other := m.Deliver("u2", wsMessage("a"))
+	if next := m.Deliver("u2", wsMessage("b")); next != other+1 {
+		t.Errorf("seq of u2 = %d, want %d", next, other+1)
+	}
+}
+
+func TestMailboxSendsOnlyToOpenUsers(t *testing.T) {
+	sent := &recorder{}
+	m := NewMailbox(sent.send, 10, time.Minute)
+
+	first := m.Deliver("u1", wsMessage("a"))
+	if len(sent.messages) != 0 {
+		t.Fatalf("sent %d messages to a closed user", len(sent.messages))
+	}
+
+	replayed := &recorder{}
+	if n := m.Open("u1", replayed.replay); n != 1 {
+		t.Errorf("Open() replayed %d, want 1", n)
+	}
+	if got := replayed.seqs(); len(got) != 1 || got[0] != first {
+		t.Errorf("replayed %v, want [%d]", got, first)
+	}
+
+	second := m.Deliver("u1", wsMessage("b"))
+	if got := sent.seqs(); len(got) != 1 || got[0] != second {
+		t.Errorf("sent %v, want [%d]", got, second)
+	}
+
+	m.Close("u1")
+	m.Deliver("u1", wsMessage("c"))
+	if len(sent.messages) != 1 {
+		t.Errorf("sent %d messages after close, want 1", len(sent.messages))
+	}
+}
+
+func TestMailboxKeepsMessagesOfFailedSends(t *testing.T) {
+	sent := &recorder{err: errors.New("connection closed")}
+	m := NewMailbox(sent.send, 10, time.Minute)
+	m.Open("u1", sent.replay)
+
+	seq := m.Deliver("u1", wsMessage("a"))
+
+	replayed := &recorder{}
+	m.Open("u1", replayed.replay)
+	if got := replayed.seqs(); len(got) != 1 || got[0] != seq {
+		t.Errorf("replayed %v, want [%d]", got, seq)
+	}
+}
+
+func TestMailboxAck(t *testing.T) {
+	m := NewMailbox((&recorder{}).send, 10, time.Minute)
+	first := m.Deliver("u1", wsMessage("a"))
+	second := m.Deliver("u1", wsMessage("b"))
+	third := m.Deliver("u1", wsMessage("c"))
+
+	m.Ack("u1", second)
+
+	replayed := &recorder{}
+	m.Open("u1", replayed.replay)
+	if got := replayed.seqs(); len(got) != 1 || got[0] != third {
+		t.Errorf("replayed %v after ack of %d, want [%d]", got, second, third)
+	}
+
+	// Acknowledging an older message again changes nothing
+	m.Ack("u1", first)
+	if !m.Has("u1") {
+		t.Error("stale ack dropped unacknowledged messages")
+	}
+
+	m.Ack("u1", third)
+	if m.Has("u1") {
+		t.Error("messages left after acknowledging all of them")
+	}
+}
+
+func TestMailboxOrdersHandedOverMessages(t *testing.T) {
+	m := NewMailbox((&recorder{}).send, 10, time.Minute)
+	local := m.Deliver("u1", wsMessage("local"))
+
+	// Messages handed over by another instance may be older than the local ones
+	m.Deliver("u1", contracts.WSMessage{Type: "old", Seq: local - 2})
+	m.Deliver("u1", contracts.WSMessage{Type: "older", Seq: local - 3})
+
+	replayed := &recorder{}
+	m.Open("u1", replayed.replay)
+	want := []uint64{local - 3, local - 2, local}
+	if got := replayed.seqs(); !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestMailboxDropsOldestWhenFull(t *testing.T) {
+	m := NewMailbox((&recorder{}).send, 2, time.Minute)
+	m.Deliver("u1", wsMessage("a"))
+	second := m.Deliver("u1", wsMessage("b"))
+	third := m.Deliver("u1", wsMessage("c"))
+
+	replayed := &recorder{}
+	m.Open("u1", replayed.replay)
+	if got := replayed.seqs(); len(got) != 2 || got[0] != second || got[1] != third {
+		t.Errorf("replayed %v, want [%d %d]", got, second, third)
+	}
+}
+
+func TestMailboxSizes(t *testing.T) {
+	// Sizes below one hold a single message like size one, instead of panicking
+	for _, size := range []int{1, 0, -1} {
+		m := NewMailbox((&recorder{}).send, size, time.Minute)
+		m.Deliver("u1", wsMessage("a"))
+		last := m.Deliver("u1", wsMessage("b"))
+
+		replayed := &recorder{}
+		m.Open("u1", replayed.replay)
+		if got := replayed.seqs(); !slices.Equal(got, []uint64{last}) {
+			t.Errorf("size %d: replayed %v, want [%d]", size, got, last)
+		}
+	}
+}
+
+func TestMailboxTake(t *testing.T) {
+	m := NewMailbox((&recorder{}).send, 10, time.Minute)
+	seq := m.Deliver("u1", wsMessage("a"))
+
+	m.Open("u1", (&recorder{}).replay)
+	if taken := m.Take("u1"); taken != nil {
+		t.Errorf("Take() of an open user = %v, want nil", taken)
+	}
+	m.Close("u1")
+
+	taken := m.Take("u1")
+	if len(taken) != 1 || taken[0].Seq != seq {
+		t.Errorf("Take() = %v, want the message %d", taken, seq)
+	}
+	if m.Has("u1") {
+		t.Error("taken messages are still stored")
+	}
+}
+
+func TestMailboxExpiresMessages(t *testing.T) {
+	m := NewMailbox((&recorder{}).send, 10, 10*time.Millisecond)
+	m.Deliver("u1", wsMessage("a"))
+	time.Sleep(20 * time.Millisecond)
+
+	if m.Has("u1") {
+		t.Error("expired message is still stored")
+	}
+
+	m.Expire()
+	if len(m.boxes) != 0 {
+		t.Errorf("%d mailboxes left after Expire, want 0", len(m.boxes))
+	}
+
+	// Users keep their mailbox while open
+	m.Open("u1", (&recorder{}).replay)
+	m.Expire()
+	if _, exists := m.boxes["u1"]; !exists {
+		t.Error("Expire dropped the mailbox of an open user")
+	}
+}
@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+type gatewayPresence struct {
+	users    map[string]struct{}
+	lastSeen time.Time
+}
+
+// PresenceTable tracks which API Gateway instances each user is connected to.
+// Gateways that stop sending presence updates for longer than the TTL are
+// assumed to be gone, together with their users.
+type PresenceTable struct {
+	ttl      time.Duration
+	gateways map[string]*gatewayPresence
+	mutex    sync.RWMutex
+}
+
+// NewPresenceTable creates a presence table that forgets silent gateways after ttl
+func NewPresenceTable(ttl time.Duration) *PresenceTable {
+	return &PresenceTable{
+		ttl:      ttl,
+		gateways: make(map[string]*gatewayPresence),
+	}
+}
+
+// Update records users going online or offline on a gateway
+func (p *PresenceTable) Update(gatewayID string, online, offline []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	gateway := p.gateway(gatewayID)
+	for _, id := range online {
+		gateway.users[id] = struct{}{}
+	}
+	for _, id := range offline {
+		delete(gateway.users, id)
+	}
+}
+
+// Replace sets the complete list of users connected to a gateway
+func (p *PresenceTable) Replace(gatewayID string, users []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(users) == 0 {
+		delete(p.gateways, gatewayID)
+		return
+	}
+
+	gateway := p.gateway(gatewayID)
+	gateway.users = make(map[string]struct{}, len(users))
+	for _, id := range users {
+		gateway.users[id] = struct{}{}
+	}
+}
+
+// Lookup returns the gateways a user is connected to
+func (p *PresenceTable) Lookup(userID string) []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	var gatewayIDs []string
+	for gatewayID, gateway := range p.gateways {
+		if time.Since(gateway.lastSeen) > p.ttl {
+			continue
+		}
+		if _, ok := gateway.users[userID]; ok {
+			gatewayIDs = append(gatewayIDs, gatewayID)
+		}
+	}
+	return gatewayIDs
+}
+
+// Expire forgets the gateways that were silent for longer than the TTL
+func (p *PresenceTable) Expire() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for gatewayID, gateway := range p.gateways {
+		if time.Since(gateway.lastSeen) > p.ttl {
+			delete(p.gateways, gatewayID)
+		}
+	}
+}
+
+// gateway returns the presence of a gateway, marking it as seen. The caller must hold the write lock.
+func (p *PresenceTable) gateway(gatewayID string) *gatewayPresence {
+	gateway, exists := p.gateways[gatewayID]
+	if !exists {
+		gateway = &gatewayPresence{users: make(map[string]struct{})}
+		p.gateways[gatewayID] = gateway
+	}
+	gateway.lastSeen = time.Now()
+	return gateway
+}
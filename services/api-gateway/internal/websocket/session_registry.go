@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// suspendedSession is a session whose connection dropped less than a grace period ago
+type suspendedSession struct {
+	timer *time.Timer
+}
+
+// SessionRegistry keeps the sessions of users whose connection dropped alive for a
+// grace period, so they can pick up where they left off when they reconnect.
+type SessionRegistry struct {
+	grace    time.Duration
+	sessions map[string]*suspendedSession
+	mutex    sync.Mutex
+}
+
+// NewSessionRegistry creates a registry that keeps sessions for grace.
+// A zero grace ends sessions as soon as they drop.
+func NewSessionRegistry(grace time.Duration) *SessionRegistry {
+	return &SessionRegistry{
+		grace:    grace,
+		sessions: make(map[string]*suspendedSession),
+	}
+}
+
+// Suspend starts the grace period of a user whose connection dropped. onExpire runs
+// once the grace period ends without a reconnect.
+func (r *SessionRegistry) Suspend(id string, onExpire func()) {
+	if r.grace <= 0 {
+		onExpire()
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if previous, exists := r.sessions[id]; exists {
+		previous.timer.Stop()
+	}
+
+	session := &suspendedSession{}
+	session.timer = time.AfterFunc(r.grace, func() {
+		r.mutex.Lock()
+		// The session may have been resumed or suspended again in the meantime
+		current, exists := r.sessions[id]
+		if !exists || current != session {
+			r.mutex.Unlock()
+			return
+		}
+		delete(r.sessions, id)
+		r.mutex.Unlock()
+
+		log.Printf("Grace period of %s expired", id)
+		onExpire()
+	})
+	r.sessions[id] = session
+}
+
+// Resume ends the grace period of a reconnecting user. It returns false if the
+// user had no suspended session.
+func (r *SessionRegistry) Resume(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	session, exists := r.sessions[id]
+	if !exists {
+		return false
+	}
+
+	session.timer.Stop()
+	delete(r.sessions, id)
+	return true
+}
+
+// Suspended reports whether a user is within their grace period
+func (r *SessionRegistry) Suspended(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, exists := r.sessions[id]
+	return exists
+}
+
+// IDs returns the users within their grace period
+func (r *SessionRegistry) IDs() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ids := make([]string, 0, len(r.sessions))
+	for id := range r.sessions {
+		ids = append(ids, id)
+	}
+	return ids
+}
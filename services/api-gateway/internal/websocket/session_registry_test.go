@@ -0,0 +1,86 @@
+package websocket
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSessionRegistryExpires(t *testing.T) {
+	r := NewSessionRegistry(10 * time.Millisecond)
+
+	expired := make(chan struct{})
+	r.Suspend("driver-1", func() { close(expired) })
+
+	select {
+	case <-expired:
+	case <-time.After(time.Second):
+		t.Fatal("onExpire wasn't called after the grace period")
+	}
+	if r.Resume("driver-1") {
+		t.Error("Resume() after the grace period = true, want false")
+	}
+}
+
+func TestSessionRegistryResumeCancelsExpiry(t *testing.T) {
+	r := NewSessionRegistry(20 * time.Millisecond)
+
+	var expired atomic.Bool
+	r.Suspend("driver-1", func() { expired.Store(true) })
+	if !r.Suspended("driver-1") {
+		t.Fatal("Suspended() = false during the grace period")
+	}
+
+	if !r.Resume("driver-1") {
+		t.Fatal("Resume() = false, want the suspended session resumed")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if expired.Load() {
+		t.Error("onExpire was called for a resumed session")
+	}
+	if r.Suspended("driver-1") {
+		t.Error("Suspended() after Resume() = true, want false")
+	}
+}
+
+func TestSessionRegistryZeroGraceExpiresAtOnce(t *testing.T) {
+	r := NewSessionRegistry(0)
+
+	expired := false
+	r.Suspend("driver-1", func() { expired = true })
+
+	if !expired {
+		t.Error("onExpire wasn't called without a grace period")
+	}
+}
+
+// A reconnect racing the end of the grace period either resumes the session or
+// finds it expired, never both and never neither
+func TestSessionRegistryResumeRacesExpiry(t *testing.T) {
+	const grace = time.Millisecond
+	r := NewSessionRegistry(grace)
+
+	for i := range 100 {
+		var expired atomic.Bool
+		done := make(chan struct{})
+		r.Suspend("driver-1", func() {
+			expired.Store(true)
+			close(done)
+		})
+
+		time.Sleep(grace)
+		if !r.Resume("driver-1") {
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatalf("round %d: session neither resumed nor expired", i)
+			}
+			continue
+		}
+		time.Sleep(5 * grace)
+		if expired.Load() {
+			t.Fatalf("round %d: session both resumed and expired", i)
+		}
+	}
+}
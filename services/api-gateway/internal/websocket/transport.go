@@ -0,0 +1,43 @@
+package websocket
+
+import (
+	"ride-sharing/shared/contracts"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// transport is the connection a session's writer writes to
+type transport interface {
+	// write writes a message, giving up at deadline
+	write(message contracts.WSMessage, deadline time.Time) error
+	// keepAlive shows the client, and the proxies in between, that the connection is in use
+	keepAlive(deadline time.Time) error
+	// close closes the connection, telling the client the reason unless it is empty
+	close(reason string)
+}
+
+// wsTransport writes to a WebSocket connection
+type wsTransport struct {
+	conn *websocket.Conn
+}
+
+func (t *wsTransport) write(message contracts.WSMessage, deadline time.Time) error {
+	if err := t.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	return t.conn.WriteJSON(message)
+}
+
+// keepAlive pings the client, the pong extends the read deadline of the connection
+func (t *wsTransport) keepAlive(deadline time.Time) error {
+	return t.conn.WriteControl(websocket.PingMessage, nil, deadline)
+}
+
+func (t *wsTransport) close(reason string) {
+	if reason == "" {
+		t.conn.Close()
+		return
+	}
+	CloseConnection(t.conn, reason)
+}
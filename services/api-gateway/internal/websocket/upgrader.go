@@ -2,6 +2,8 @@ package websocket
 
 import (
 	"net/http"
+	"ride-sharing/services/api-gateway/internal/auth"
+	"ride-sharing/shared/messaging"
 
 	"github.com/gorilla/websocket"
 )
@@ -10,12 +12,15 @@ type WebSocketUpgrader struct {
 	upgrader websocket.Upgrader
 }
 
-func NewWebSocketUpgrader() *WebSocketUpgrader {
+// NewWebSocketUpgrader creates an upgrader accepting the origins allowed by originValidator.
+// Failed handshakes are answered with onError.
+func NewWebSocketUpgrader(originValidator messaging.OriginValidator, onError func(w http.ResponseWriter, r *http.Request, status int, reason error)) *WebSocketUpgrader {
 	return &WebSocketUpgrader{
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true //TODO: Fix security issue - allows any origin
-			},
+			CheckOrigin: messaging.CheckOrigin(originValidator),
+			Error:       onError,
+			// Lets browsers pass their token as a subprotocol
+			Subprotocols: []string{auth.TokenSubprotocol},
 		},
 	}
 }
@@ -24,3 +29,9 @@ func (u *WebSocketUpgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*we
 	conn, err := u.upgrader.Upgrade(w, r, nil)
 	return conn, err
 }
+
+// CheckOrigin reports whether the request's origin may connect, for endpoints that
+// stream to browsers without a WebSocket upgrade
+func (u *WebSocketUpgrader) CheckOrigin(r *http.Request) bool {
+	return u.upgrader.CheckOrigin(r)
+}
@@ -0,0 +1,230 @@
+package websocket
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"ride-sharing/shared/contracts"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrConnectionClosed is returned when sending to a connection whose writer stopped
+	ErrConnectionClosed = errors.New("connection closed")
+	// ErrInvalidBackpressurePolicy is returned for an unknown backpressure policy
+	ErrInvalidBackpressurePolicy = errors.New("invalid backpressure policy")
+)
+
+// sendQueueMetrics exposes the outbound queues of all connections on /debug/vars:
+// depth is the number of queued messages, the others count how full queues were handled
+var sendQueueMetrics = expvar.NewMap("ws_send_queue")
+
+// coalescableTypes are the messages sent to clients that only matter until a newer one
+// of the same type arrives
+var coalescableTypes = map[string]bool{
+	contracts.TripEventDriverLocation: true,
+}
+
+// BackpressurePolicy decides what happens when a connection's send buffer is full
+type BackpressurePolicy string
+
+const (
+	// BackpressureDropOldest drops the oldest queued message
+	BackpressureDropOldest BackpressurePolicy = "drop_oldest"
+	// BackpressureCoalesce replaces a queued location update by a newer one of the same
+	// type, and drops the oldest message if there is none
+	BackpressureCoalesce BackpressurePolicy = "coalesce"
+	// BackpressureDisconnect closes the connection of the slow client
+	BackpressureDisconnect BackpressurePolicy = "disconnect"
+)
+
+// ParseBackpressurePolicy parses a backpressure policy name
+func ParseBackpressurePolicy(name string) (BackpressurePolicy, error) {
+	switch policy := BackpressurePolicy(name); policy {
+	case BackpressureDropOldest, BackpressureCoalesce, BackpressureDisconnect:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidBackpressurePolicy, name)
+	}
+}
+
+// WriterConfig holds the tunables of the per-connection writers
+type WriterConfig struct {
+	// BufferSize is the maximum number of messages queued per connection
+	BufferSize int
+	// WriteTimeout bounds each write, a client that doesn't keep up is disconnected
+	WriteTimeout time.Duration
+	OnFull       BackpressurePolicy
+}
+
+// DefaultWriterConfig returns a WriterConfig with sensible default values
+func DefaultWriterConfig() WriterConfig {
+	return WriterConfig{
+		BufferSize:   64,
+		WriteTimeout: 10 * time.Second,
+		OnFull:       BackpressureDropOldest,
+	}
+}
+
+// connWriter owns the writes to a connection. Messages are queued without blocking
+// and written by a dedicated goroutine, so a slow client only delays its own messages.
+type connWriter struct {
+	transport transport
+	config    WriterConfig
+	// pingInterval is the time between two keep-alives, zero for none
+	pingInterval time.Duration
+
+	mutex  sync.Mutex
+	queue  []contracts.WSMessage
+	closed bool
+	// wake signals the writer goroutine that messages were queued
+	wake chan struct{}
+	done chan struct{}
+}
+
+// newConnWriter starts the writer of a connection. Its queue holds at least one
+// message, a full queue must have a message to drop.
+func newConnWriter(transport transport, config WriterConfig, pingInterval time.Duration) *connWriter {
+	config.BufferSize = max(config.BufferSize, 1)
+	w := &connWriter{
+		transport:    transport,
+		config:       config,
+		pingInterval: pingInterval,
+		wake:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// enqueue queues a message for writing, applying the backpressure policy if the queue is full
+func (w *connWriter) enqueue(message contracts.WSMessage) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return ErrConnectionClosed
+	}
+
+	if len(w.queue) >= w.config.BufferSize {
+		switch w.config.OnFull {
+		case BackpressureDisconnect:
+			sendQueueMetrics.Add("disconnects", 1)
+			w.closeLocked()
+			go w.transport.close("client too slow")
+			return fmt.Errorf("%w: send buffer full", ErrConnectionClosed)
+
+		case BackpressureCoalesce:
+			if w.coalesceLocked(message) {
+				sendQueueMetrics.Add("coalesced", 1)
+				return nil
+			}
+			fallthrough
+
+		default:
+			w.queue = w.queue[1:]
+			sendQueueMetrics.Add("depth", -1)
+			sendQueueMetrics.Add("dropped", 1)
+		}
+	}
+
+	w.queue = append(w.queue, message)
+	sendQueueMetrics.Add("depth", 1)
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// coalesceLocked replaces the last queued message of the same type by message if
+// the type is coalescable. The caller must hold the lock.
+func (w *connWriter) coalesceLocked(message contracts.WSMessage) bool {
+	if !coalescableTypes[message.Type] {
+		return false
+	}
+
+	for i := len(w.queue) - 1; i >= 0; i-- {
+		if w.queue[i].Type == message.Type {
+			w.queue[i] = message
+			return true
+		}
+	}
+	return false
+}
+
+// stop ends the writer goroutine, dropping the queued messages
+func (w *connWriter) stop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closeLocked()
+}
+
+func (w *connWriter) closeLocked() {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	sendQueueMetrics.Add("depth", -int64(len(w.queue)))
+	w.queue = nil
+	close(w.done)
+}
+
+func (w *connWriter) run() {
+	var ping <-chan time.Time
+	if w.pingInterval > 0 {
+		ticker := time.NewTicker(w.pingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ping:
+			if err := w.transport.keepAlive(time.Now().Add(w.config.WriteTimeout)); err != nil {
+				log.Printf("Failed to ping connection, closing it: %v", err)
+				w.stop()
+				w.transport.close("")
+				return
+			}
+			continue
+		case <-w.wake:
+		}
+
+		for {
+			message, ok := w.next()
+			if !ok {
+				break
+			}
+
+			if err := w.transport.write(message, time.Now().Add(w.config.WriteTimeout)); err != nil {
+				// The session's handler notices the closed connection and cleans up
+				log.Printf("Failed to write to connection, closing it: %v", err)
+				w.stop()
+				w.transport.close("")
+				return
+			}
+		}
+	}
+}
+
+// next pops the oldest queued message. Messages stay queued until they are written,
+// so the queue length is the client's actual backlog.
+func (w *connWriter) next() (contracts.WSMessage, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed || len(w.queue) == 0 {
+		return contracts.WSMessage{}, false
+	}
+
+	message := w.queue[0]
+	w.queue = w.queue[1:]
+	sendQueueMetrics.Add("depth", -1)
+	return message, true
+}
@@ -0,0 +1,206 @@
+package websocket
+
+import (
+	"errors"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// idleWriter returns a writer without its goroutine, so queued messages stay queued
+func idleWriter(conn *websocket.Conn, policy BackpressurePolicy, bufferSize int) *connWriter {
+	return &connWriter{
+		transport: &wsTransport{conn: conn},
+		config:    WriterConfig{BufferSize: bufferSize, WriteTimeout: time.Second, OnFull: policy},
+		wake:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+func typedMessage(messageType string, seq uint64) contracts.WSMessage {
+	return contracts.WSMessage{Type: messageType, Seq: seq}
+}
+
+func queuedSeqs(w *connWriter) []uint64 {
+	seqs := make([]uint64, 0, len(w.queue))
+	for _, message := range w.queue {
+		seqs = append(seqs, message.Seq)
+	}
+	return seqs
+}
+
+func TestParseBackpressurePolicy(t *testing.T) {
+	for _, policy := range []BackpressurePolicy{BackpressureDropOldest, BackpressureCoalesce, BackpressureDisconnect} {
+		got, err := ParseBackpressurePolicy(string(policy))
+		if err != nil || got != policy {
+			t.Errorf("ParseBackpressurePolicy(%q) = %q, %v", policy, got, err)
+		}
+	}
+
+	for _, name := range []string{"", "block", "DROP_OLDEST"} {
+		if _, err := ParseBackpressurePolicy(name); !errors.Is(err, ErrInvalidBackpressurePolicy) {
+			t.Errorf("ParseBackpressurePolicy(%q) error = %v, want ErrInvalidBackpressurePolicy", name, err)
+		}
+	}
+}
+
+func TestCoalescableTypesAreSentToClients(t *testing.T) {
+	for messageType := range coalescableTypes {
+		spec, ok := messaging.LookupWSMessage(messageType)
+		if !ok || spec.Direction&messaging.WSServerToClient == 0 {
+			t.Errorf("coalescable type %s is not sent to clients", messageType)
+		}
+	}
+}
+
+func TestConnWriterBackpressure(t *testing.T) {
+	location := contracts.TripEventDriverLocation
+	status := contracts.TripEventStarted
+
+	tests := []struct {
+		name    string
+		policy  BackpressurePolicy
+		queued  []contracts.WSMessage
+		message contracts.WSMessage
+		want    []uint64
+	}{
+		{
+			name:    "drop oldest",
+			policy:  BackpressureDropOldest,
+			queued:  []contracts.WSMessage{typedMessage(location, 1), typedMessage(status, 2)},
+			message: typedMessage(location, 3),
+			want:    []uint64{2, 3},
+		},
+		{
+			name:    "coalesce replaces the latest location update",
+			policy:  BackpressureCoalesce,
+			queued:  []contracts.WSMessage{typedMessage(location, 1), typedMessage(status, 2)},
+			message: typedMessage(location, 3),
+			want:    []uint64{3, 2},
+		},
+		{
+			name:    "coalesce drops the oldest without a location update",
+			policy:  BackpressureCoalesce,
+			queued:  []contracts.WSMessage{typedMessage(status, 1), typedMessage(status, 2)},
+			message: typedMessage(location, 3),
+			want:    []uint64{2, 3},
+		},
+		{
+			name:    "coalesce never merges other messages",
+			policy:  BackpressureCoalesce,
+			queued:  []contracts.WSMessage{typedMessage(location, 1), typedMessage(status, 2)},
+			message: typedMessage(status, 3),
+			want:    []uint64{2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := idleWriter(nil, tt.policy, len(tt.queued))
+			for _, message := range tt.queued {
+				if err := w.enqueue(message); err != nil {
+					t.Fatalf("enqueue(%d) = %v", message.Seq, err)
+				}
+			}
+
+			if err := w.enqueue(tt.message); err != nil {
+				t.Fatalf("enqueue on a full queue = %v", err)
+			}
+			if got := queuedSeqs(w); !slices.Equal(got, tt.want) {
+				t.Errorf("queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnWriterDisconnectsSlowClients(t *testing.T) {
+	conn, client := connPair(t)
+	w := idleWriter(conn, BackpressureDisconnect, 1)
+	if err := w.enqueue(typedMessage(contracts.TripEventStarted, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.enqueue(typedMessage(contracts.TripEventStarted, 2)); !errors.Is(err, ErrConnectionClosed) {
+		t.Fatalf("enqueue on a full queue = %v, want ErrConnectionClosed", err)
+	}
+
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := client.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("client read = %v, want a policy violation close", err)
+	}
+
+	if err := w.enqueue(typedMessage(contracts.TripEventStarted, 3)); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("enqueue after disconnect = %v, want ErrConnectionClosed", err)
+	}
+}
+
+func TestConnWriterWritesInOrder(t *testing.T) {
+	conn, client := connPair(t)
+	w := newConnWriter(&wsTransport{conn: conn}, WriterConfig{BufferSize: 10, WriteTimeout: time.Second, OnFull: BackpressureDropOldest}, 0)
+	defer w.stop()
+
+	want := []uint64{1, 2, 3}
+	for _, seq := range want {
+		if err := w.enqueue(typedMessage(contracts.TripEventStarted, seq)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	var got []uint64
+	for range want {
+		var message contracts.WSMessage
+		if err := client.ReadJSON(&message); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, message.Seq)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("written = %v, want %v", got, want)
+	}
+}
+
+func TestConnWriterClosesOnWriteError(t *testing.T) {
+	conn, _ := connPair(t)
+	conn.Close()
+	w := newConnWriter(&wsTransport{conn: conn}, DefaultWriterConfig(), 0)
+
+	if err := w.enqueue(typedMessage(contracts.TripEventStarted, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := w.enqueue(typedMessage(contracts.TripEventStarted, 2))
+		if errors.Is(err, ErrConnectionClosed) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("enqueue after a failed write = %v, want ErrConnectionClosed", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConnWriterHoldsAtLeastOneMessage(t *testing.T) {
+	for _, policy := range []BackpressurePolicy{BackpressureDropOldest, BackpressureCoalesce} {
+		for _, size := range []int{0, -1} {
+			conn, _ := connPair(t)
+			w := newConnWriter(&wsTransport{conn: conn}, WriterConfig{BufferSize: size, WriteTimeout: time.Second, OnFull: policy}, 0)
+			if w.config.BufferSize != 1 {
+				t.Errorf("%s, size %d: BufferSize = %d, want 1", policy, size, w.config.BufferSize)
+			}
+			for _, seq := range []uint64{1, 2, 3} {
+				if err := w.enqueue(typedMessage(contracts.TripEventStarted, seq)); err != nil {
+					t.Errorf("%s, size %d: enqueue(%d) = %v", policy, size, seq, err)
+				}
+			}
+			w.stop()
+		}
+	}
+}
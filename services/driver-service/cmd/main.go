@@ -9,15 +9,17 @@ import (
 	"ride-sharing/services/driver-service/internal/domain"
 	grpcHandler "ride-sharing/services/driver-service/internal/infrastructure/grpc"
 	messagingInfra "ride-sharing/services/driver-service/internal/infrastructure/messaging"
+	"ride-sharing/services/driver-service/internal/infrastructure/repository"
 	"ride-sharing/shared/env"
+	"ride-sharing/shared/grpcserver"
 	"ride-sharing/shared/messaging"
 	"syscall"
-
-	grpcserver "google.golang.org/grpc"
+	"time"
 )
 
 const (
-	defaultGrpcAddr = ":9092"
+	defaultGrpcAddr      = ":9092"
+	defaultBatchWindowMs = 2000
 )
 
 func main() {
@@ -28,6 +30,18 @@ func main() {
 	}
 
 	grpcAddr := env.GetString("GRPC_ADDR", defaultGrpcAddr)
+	// Generated profiles are for development, drivers need a real profile otherwise
+	autoProvisionProfiles := env.GetBool("AUTO_PROVISION_PROFILES", false)
+
+	dispatchPolicy, err := domain.ParseDispatchPolicy(env.GetString("DISPATCH_POLICY", ""))
+	if err != nil {
+		log.Fatalf("failed to parse DISPATCH_POLICY: %v", err)
+	}
+	matchingPolicy, err := domain.ParseMatchingPolicy(env.GetString("MATCHING_POLICY", ""))
+	if err != nil {
+		log.Fatalf("failed to parse MATCHING_POLICY: %v", err)
+	}
+	batchWindow := time.Duration(env.GetInt("DISPATCH_BATCH_WINDOW_MS", defaultBatchWindowMs)) * time.Millisecond
 
 	// Setup context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -60,14 +74,28 @@ func main() {
 	// Initialize domain service
 	// Following Dependency Inversion Principle (DIP):
 	// All components depend on interfaces, not concrete implementations
-	driverService := domain.NewDriverService()
+	profileRepo := repository.NewInmemProfileRepository()
+	profileService := domain.NewProfileService(profileRepo, autoProvisionProfiles)
+	driverService := domain.NewDriverService(profileService, dispatchPolicy, matchingPolicy)
+
+	driverPublisher := messagingInfra.NewDriverEventPublisher(rabbitMq)
 
 	// Initialize gRPC server and register handlers
-	grpcServer := grpcserver.NewServer()
-	grpcHandler.NewDriverHandler(grpcServer, driverService)
+	serviceToken, err := grpcserver.ServiceTokenFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure the gRPC server: %v", err)
+	}
+	if serviceToken == "" {
+		log.Printf("GRPC_SERVICE_TOKEN is not set, every caller is trusted")
+	}
+
+	grpcServer := grpcserver.NewServer(serviceToken)
+	grpcHandler.NewDriverHandler(grpcServer, driverService, profileService, driverPublisher)
 
 	// Initialize RabbitMQ consumer
-	tripConsumer := messagingInfra.NewTripConsumer(rabbitMq, driverService)
+	tripConsumer := messagingInfra.NewTripConsumer(rabbitMq, driverService, batchWindow)
+	locationConsumer := messagingInfra.NewLocationConsumer(rabbitMq, driverService, driverPublisher)
+	availabilityConsumer := messagingInfra.NewAvailabilityConsumer(rabbitMq, driverService)
 
 	// Start RabbitMQ consumer in background
 	go func() {
@@ -80,6 +108,22 @@ func main() {
 		}
 	}()
 
+	go func() {
+		log.Printf("Starting RabbitMQ consumer for queue: %s", messaging.DriverLocationQueue)
+		if err := locationConsumer.ConsumeDriverLocation(ctx, messaging.DriverLocationQueue, nil); err != nil {
+			log.Printf("Consumer error: %v", err)
+			cancel()
+		}
+	}()
+
+	go func() {
+		log.Printf("Starting RabbitMQ consumer for queue: %s", messaging.DriverAvailabilityQueue)
+		if err := availabilityConsumer.ConsumeAvailabilityEvents(ctx, messaging.DriverAvailabilityQueue, nil); err != nil {
+			log.Printf("Consumer error: %v", err)
+			cancel()
+		}
+	}()
+
 	// Start gRPC server in background
 	go func() {
 		log.Printf("Starting gRPC server DriverService on %s", lis.Addr().String())
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"ride-sharing/services/driver-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/driver"
+	pbt "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/util"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// reconnectDelay is how long a virtual driver waits before reconnecting after a dropped connection
+const reconnectDelay = 3 * time.Second
+
+// maxSeenMessages bounds how many message sequence numbers a driver remembers
+const maxSeenMessages = 1000
+
+type point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// leg is a part of the driver's journey with an action to perform on arrival
+type leg struct {
+	path     []point
+	onArrive func()
+}
+
+type virtualDriver struct {
+	id         string
+	routeIndex int
+	cfg        simulatorConfig
+
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	driver   *pb.Driver
+	position point
+	legs     []leg
+	busy     bool
+	// tripID is the trip the driver accepted, if any
+	tripID string
+	// offers cancels the pending answers of open offers, by trip ID
+	offers map[string]context.CancelFunc
+
+	// seen holds the sequence numbers of the last received messages, so replayed
+	// messages are ignored. Only used by the reading goroutine.
+	seen     map[uint64]struct{}
+	seenList []uint64
+	lastSeq  uint64
+}
+
+func newVirtualDriver(id string, routeIndex int, cfg simulatorConfig) *virtualDriver {
+	route := domain.PredefinedRoutes[routeIndex%len(domain.PredefinedRoutes)]
+
+	return &virtualDriver{
+		id:         id,
+		routeIndex: routeIndex,
+		cfg:        cfg,
+		position:   point{Latitude: route[0][0], Longitude: route[0][1]},
+		offers:     make(map[string]context.CancelFunc),
+		seen:       make(map[uint64]struct{}),
+	}
+}
+
+// run keeps the driver connected until the context is cancelled
+func (d *virtualDriver) run(ctx context.Context) {
+	for {
+		if err := d.session(ctx); err != nil {
+			log.Printf("[%s] session ended: %v", d.id, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// session connects the driver and drives until the connection drops
+func (d *virtualDriver) session(ctx context.Context) error {
+	u, err := url.Parse(d.cfg.GatewayURL + "/ws/drivers")
+	if err != nil {
+		return fmt.Errorf("invalid gateway url: %w", err)
+	}
+	query := u.Query()
+	query.Set("userID", d.id)
+	query.Set("packageSlug", d.cfg.PackageSlug)
+	if d.lastSeq > 0 {
+		query.Set("lastSeq", strconv.FormatUint(d.lastSeq, 10))
+	}
+	u.RawQuery = query.Encode()
+
+	token, err := d.cfg.signDriverToken(d.id, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to sign the driver token: %w", err)
+	}
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	d.mu.Lock()
+	d.conn = conn
+	d.legs = nil
+	d.busy = false
+	d.tripID = ""
+	d.mu.Unlock()
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-sessionCtx.Done()
+		conn.Close()
+	}()
+	go d.drive(sessionCtx)
+
+	return d.readMessages(sessionCtx)
+}
+
+func (d *virtualDriver) readMessages(ctx context.Context) error {
+	for {
+		_, raw, err := d.conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var msg contracts.WSRawMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			log.Printf("[%s] failed to unmarshal message: %v", d.id, err)
+			continue
+		}
+
+		if msg.Seq != 0 {
+			d.send(contracts.ClientCmdAck, contracts.WSAckData{Seq: msg.Seq})
+			if !d.markSeen(msg.Seq) {
+				continue
+			}
+		}
+
+		switch msg.Type {
+		case contracts.DriverCmdRegister:
+			var driver pb.Driver
+			if err := json.Unmarshal(msg.Data, &driver); err != nil {
+				log.Printf("[%s] failed to unmarshal registration: %v", d.id, err)
+				continue
+			}
+			d.mu.Lock()
+			d.driver = &driver
+			d.mu.Unlock()
+			log.Printf("[%s] registered as %s (%s)", d.id, driver.Name, driver.CarPlate)
+
+		case contracts.DriverCmdTripRequest:
+			var event messaging.TripCreatedEvent
+			if err := json.Unmarshal(msg.Data, &event); err != nil || event.Trip == nil {
+				log.Printf("[%s] failed to unmarshal trip request: %v", d.id, err)
+				continue
+			}
+			offerCtx, cancel := context.WithCancel(ctx)
+			d.mu.Lock()
+			d.offers[event.Trip.Id] = cancel
+			d.mu.Unlock()
+			go d.answerOffer(offerCtx, event.Trip)
+
+		case contracts.DriverCmdTripOfferRevoked, contracts.DriverCmdTripAlreadyTaken, contracts.DriverCmdTripCancelled:
+			var closed messaging.TripOfferClosedData
+			if err := json.Unmarshal(msg.Data, &closed); err != nil {
+				log.Printf("[%s] failed to unmarshal closed offer: %v", d.id, err)
+				continue
+			}
+			log.Printf("[%s] offer for trip %s closed: %s", d.id, closed.TripID, closed.Reason)
+			d.closeOffer(closed.TripID)
+		}
+	}
+}
+
+// markSeen records a message sequence number and reports whether it is new
+func (d *virtualDriver) markSeen(seq uint64) bool {
+	if _, ok := d.seen[seq]; ok {
+		return false
+	}
+
+	d.seen[seq] = struct{}{}
+	d.seenList = append(d.seenList, seq)
+	if len(d.seenList) > maxSeenMessages {
+		delete(d.seen, d.seenList[0])
+		d.seenList = d.seenList[1:]
+	}
+	d.lastSeq = max(d.lastSeq, seq)
+	return true
+}
+
+// closeOffer drops an offer that went to another driver or was cancelled by the
+// rider, abandoning the trip if this driver already took it
+func (d *virtualDriver) closeOffer(tripID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if cancel, ok := d.offers[tripID]; ok {
+		cancel()
+		delete(d.offers, tripID)
+	}
+
+	if d.tripID == tripID {
+		d.legs = nil
+		d.busy = false
+		d.tripID = ""
+	}
+}
+
+// answerOffer accepts or declines a trip after a random delay
+func (d *virtualDriver) answerOffer(ctx context.Context, trip *pbt.Trip) {
+	defer func() {
+		d.mu.Lock()
+		delete(d.offers, trip.Id)
+		d.mu.Unlock()
+	}()
+
+	latency := d.cfg.MinResponseLatency
+	if spread := d.cfg.MaxResponseLatency - d.cfg.MinResponseLatency; spread > 0 {
+		latency += rand.N(spread)
+	}
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(latency):
+	}
+
+	d.mu.Lock()
+	accept := !d.busy && rand.Float64() < d.cfg.AcceptProbability
+	if accept {
+		d.busy = true
+		d.tripID = trip.Id
+	}
+	driver := d.driver
+	d.mu.Unlock()
+
+	response := messaging.DriveTripResponseData{
+		Driver:  driver,
+		TripID:  trip.Id,
+		RiderID: trip.UserID,
+	}
+
+	if !accept {
+		log.Printf("[%s] declining trip %s", d.id, trip.Id)
+		d.send(contracts.DriverCmdTripDecline, response)
+		return
+	}
+
+	log.Printf("[%s] accepting trip %s", d.id, trip.Id)
+	if err := d.send(contracts.DriverCmdTripAccept, response); err != nil {
+		d.finishTrip()
+		return
+	}
+
+	route := tripRoute(trip)
+	if len(route) == 0 {
+		log.Printf("[%s] trip %s has no route, completing immediately", d.id, trip.Id)
+		d.send(contracts.DriverCmdTripStart, response)
+		d.send(contracts.DriverCmdTripComplete, response)
+		d.finishTrip()
+		return
+	}
+
+	d.mu.Lock()
+	if d.tripID != trip.Id {
+		// The trip was taken by another driver in the meantime
+		d.mu.Unlock()
+		return
+	}
+	d.legs = []leg{
+		{
+			path: []point{route[0]},
+			onArrive: func() {
+				log.Printf("[%s] picked up rider of trip %s", d.id, trip.Id)
+				d.send(contracts.DriverCmdTripStart, response)
+			},
+		},
+		{
+			path: route,
+			onArrive: func() {
+				log.Printf("[%s] completed trip %s", d.id, trip.Id)
+				d.send(contracts.DriverCmdTripComplete, response)
+				d.finishTrip()
+			},
+		},
+	}
+	d.mu.Unlock()
+}
+
+func (d *virtualDriver) finishTrip() {
+	d.mu.Lock()
+	d.busy = false
+	d.tripID = ""
+	d.mu.Unlock()
+}
+
+// drive moves the driver along its legs and reports the position every tick.
+// Without a trip it loops over its predefined route.
+func (d *virtualDriver) drive(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.UpdateInterval)
+	defer ticker.Stop()
+
+	step := d.cfg.SpeedMps * d.cfg.UpdateInterval.Seconds()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		d.mu.Lock()
+		if len(d.legs) == 0 && !d.busy {
+			d.legs = []leg{{path: d.cruiseRoute()}}
+		}
+		arrived := d.advance(step)
+		position := d.position
+		d.mu.Unlock()
+
+		for _, onArrive := range arrived {
+			onArrive()
+		}
+
+		d.send(contracts.DriverCmdLocation, contracts.WSDriverLocationData{
+			Location: &pb.Location{
+				Latitude:  position.Latitude,
+				Longitude: position.Longitude,
+			},
+		})
+	}
+}
+
+// advance moves the driver up to distance meters along its legs and returns the
+// arrival callbacks of completed legs. The caller must hold d.mu.
+func (d *virtualDriver) advance(distance float64) []func() {
+	var arrived []func()
+
+	for distance > 0 && len(d.legs) > 0 {
+		current := &d.legs[0]
+		if len(current.path) == 0 {
+			if current.onArrive != nil {
+				arrived = append(arrived, current.onArrive)
+			}
+			d.legs = d.legs[1:]
+			continue
+		}
+
+		target := current.path[0]
+		remaining := util.HaversineDistance(d.position.Latitude, d.position.Longitude, target.Latitude, target.Longitude)
+		if remaining <= distance {
+			d.position = target
+			current.path = current.path[1:]
+			distance -= remaining
+			continue
+		}
+
+		ratio := distance / remaining
+		d.position = point{
+			Latitude:  d.position.Latitude + (target.Latitude-d.position.Latitude)*ratio,
+			Longitude: d.position.Longitude + (target.Longitude-d.position.Longitude)*ratio,
+		}
+		distance = 0
+	}
+
+	return arrived
+}
+
+// cruiseRoute returns the driver's predefined route, starting from its current position
+func (d *virtualDriver) cruiseRoute() []point {
+	route := domain.PredefinedRoutes[d.routeIndex%len(domain.PredefinedRoutes)]
+	path := make([]point, 0, len(route)+1)
+	for _, coord := range route {
+		path = append(path, point{Latitude: coord[0], Longitude: coord[1]})
+	}
+
+	// Loop back to the start so the next lap is continuous
+	return append(path, path[0])
+}
+
+// send writes a message to the gateway; writes are serialized per connection
+func (d *virtualDriver) send(messageType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	conn := d.conn
+	d.mu.Unlock()
+
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	if err := conn.WriteJSON(contracts.WSRawMessage{
+		Type:    messageType,
+		Version: contracts.WSProtocolVersion,
+		ID:      uuid.NewString(),
+		Data:    payload,
+	}); err != nil {
+		log.Printf("[%s] failed to send %s: %v", d.id, messageType, err)
+		return err
+	}
+	return nil
+}
+
+// tripRoute extracts the trip's route. Route coordinates carry OSRM's
+// [longitude, latitude] pairs in their latitude and longitude fields.
+func tripRoute(trip *pbt.Trip) []point {
+	route := trip.GetRoute()
+	if route == nil || len(route.Geometry) == 0 {
+		return nil
+	}
+
+	coordinates := route.Geometry[0].GetCoordinates()
+	path := make([]point, 0, len(coordinates))
+	for _, coord := range coordinates {
+		path = append(path, point{Latitude: coord.Longitude, Longitude: coord.Latitude})
+	}
+
+	return path
+}
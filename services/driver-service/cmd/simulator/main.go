@@ -0,0 +1,98 @@
+// Command simulator connects virtual drivers to the API Gateway for local development.
+//
+// Each virtual driver registers through the driver WebSocket, drives along one of the
+// predefined routes and answers trip offers. Accepted trips are driven to the pickup
+// and along the trip's route before the driver goes back to cruising.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"ride-sharing/shared/env"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func main() {
+	cfg := simulatorConfig{}
+
+	flag.StringVar(&cfg.GatewayURL, "gateway", env.GetString("GATEWAY_WS_URL", "ws://localhost:8081"), "API Gateway WebSocket base URL")
+	flag.IntVar(&cfg.Drivers, "drivers", 5, "Number of virtual drivers")
+	flag.StringVar(&cfg.PackageSlug, "package", "sedan", "Package slug of the virtual drivers")
+	flag.Float64Var(&cfg.AcceptProbability, "accept", 0.8, "Probability of accepting a trip offer (0-1)")
+	flag.DurationVar(&cfg.MinResponseLatency, "min-latency", 1*time.Second, "Minimum time before answering an offer")
+	flag.DurationVar(&cfg.MaxResponseLatency, "max-latency", 5*time.Second, "Maximum time before answering an offer")
+	flag.DurationVar(&cfg.UpdateInterval, "interval", 2*time.Second, "Interval between location updates")
+	flag.Float64Var(&cfg.SpeedMps, "speed", 10, "Driving speed in meters per second")
+	flag.StringVar(&cfg.JWTSecret, "jwt-secret", env.GetString("JWT_HMAC_SECRET", ""), "Gateway JWT_HMAC_SECRET to sign driver tokens with, empty for gateways without authentication")
+	flag.StringVar(&cfg.JWTIssuer, "jwt-issuer", env.GetString("JWT_ISSUER", ""), "iss claim of the driver tokens")
+	flag.StringVar(&cfg.JWTAudience, "jwt-audience", env.GetString("JWT_AUDIENCE", ""), "aud claim of the driver tokens")
+	flag.Parse()
+
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		<-sigChan
+		log.Println("Received shutdown signal")
+		cancel()
+	}()
+
+	log.Printf("Starting %d virtual %s drivers against %s", cfg.Drivers, cfg.PackageSlug, cfg.GatewayURL)
+
+	var wg sync.WaitGroup
+	for i := range cfg.Drivers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			newVirtualDriver(fmt.Sprintf("sim-driver-%d", i+1), i, cfg).run(ctx)
+		}()
+	}
+
+	wg.Wait()
+	log.Print("Simulator stopped")
+}
+
+type simulatorConfig struct {
+	GatewayURL         string
+	Drivers            int
+	PackageSlug        string
+	AcceptProbability  float64
+	MinResponseLatency time.Duration
+	MaxResponseLatency time.Duration
+	UpdateInterval     time.Duration
+	SpeedMps           float64
+	// JWTSecret signs a token for each virtual driver, see signDriverToken
+	JWTSecret   string
+	JWTIssuer   string
+	JWTAudience string
+}
+
+func (c simulatorConfig) validate() error {
+	switch {
+	case c.Drivers <= 0:
+		return fmt.Errorf("drivers must be positive")
+	case c.AcceptProbability < 0 || c.AcceptProbability > 1:
+		return fmt.Errorf("accept must be between 0 and 1")
+	case c.MinResponseLatency < 0 || c.MaxResponseLatency < c.MinResponseLatency:
+		return fmt.Errorf("min-latency must be positive and not above max-latency")
+	case c.UpdateInterval <= 0:
+		return fmt.Errorf("interval must be positive")
+	case c.SpeedMps <= 0:
+		return fmt.Errorf("speed must be positive")
+	}
+	return nil
+}
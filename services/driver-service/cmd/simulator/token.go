@@ -0,0 +1,31 @@
+package main
+
+import (
+	"ride-sharing/shared/jwt"
+	"time"
+)
+
+// tokenLifetime is how long the tokens of the virtual drivers are valid. A token is
+// signed for every connection, so reconnects never use an expired one.
+const tokenLifetime = time.Hour
+
+// signDriverToken signs an HS256 token for a virtual driver with the gateway's
+// JWT_HMAC_SECRET. It returns an empty token without a secret, for gateways running
+// without authentication.
+func (c simulatorConfig) signDriverToken(driverID string, now time.Time) (string, error) {
+	if c.JWTSecret == "" {
+		return "", nil
+	}
+
+	claims := jwt.Claims{
+		Subject:   driverID,
+		Role:      jwt.RoleDriver,
+		Issuer:    c.JWTIssuer,
+		ExpiresAt: now.Add(tokenLifetime).Unix(),
+		IssuedAt:  now.Unix(),
+	}
+	if c.JWTAudience != "" {
+		claims.Audience = jwt.Audience{c.JWTAudience}
+	}
+	return jwt.SignHS256(claims, []byte(c.JWTSecret))
+}
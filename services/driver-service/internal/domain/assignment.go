@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"math"
+	pb "ride-sharing/shared/proto/driver"
+	sharedutil "ride-sharing/shared/util"
+)
+
+// averagePickupSpeedMps is the assumed city driving speed (30 km/h) used to estimate pickup ETAs
+const averagePickupSpeedMps = 30 * 1000.0 / 3600
+
+// unknownPickupETA is the cost of drivers without a known location. It is large
+// enough to make them a last resort while keeping the assignment arithmetic finite.
+const unknownPickupETA = 24 * 60 * 60.0
+
+// PickupETASeconds estimates how long a driver at from needs to reach the pickup at to
+func PickupETASeconds(from, to *pb.Location) float64 {
+	if from == nil || to == nil {
+		return unknownPickupETA
+	}
+
+	distance := sharedutil.HaversineDistance(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+	return distance / averagePickupSpeedMps
+}
+
+// MinCostAssignment pairs rows with columns so the total cost is minimal, using the
+// Hungarian algorithm in O(n²·m). cost[i][j] is the cost of giving column j to row i
+// and every row must have the same length. The result holds the column of each row,
+// or -1 for rows left over when there are more rows than columns.
+func MinCostAssignment(cost [][]float64) []int {
+	n := len(cost)
+	if n == 0 {
+		return nil
+	}
+
+	m := len(cost[0])
+	if n > m {
+		// The algorithm needs at least as many columns as rows, so solve the
+		// transposed problem and map the result back
+		transposed := make([][]float64, m)
+		for j := range transposed {
+			transposed[j] = make([]float64, n)
+			for i := range cost {
+				transposed[j][i] = cost[i][j]
+			}
+		}
+
+		assignment := unassigned(n)
+		for col, row := range MinCostAssignment(transposed) {
+			assignment[row] = col
+		}
+		return assignment
+	}
+
+	// Potentials and matching are 1-indexed, index 0 is the algorithm's virtual column
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	match := make([]int, m+1) // row matched to each column
+	way := make([]int, m+1)   // previous column on the augmenting path
+	minv := make([]float64, m+1)
+	used := make([]bool, m+1)
+
+	for i := 1; i <= n; i++ {
+		match[0] = i
+		col := 0
+		for j := range minv {
+			minv[j] = math.Inf(1)
+			used[j] = false
+		}
+
+		for match[col] != 0 {
+			used[col] = true
+			row := match[col]
+			delta := math.Inf(1)
+			next := 0
+
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				if reduced := cost[row-1][j-1] - u[row] - v[j]; reduced < minv[j] {
+					minv[j] = reduced
+					way[j] = col
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					next = j
+				}
+			}
+
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[match[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			col = next
+		}
+
+		// Flip the augmenting path
+		for col != 0 {
+			prev := way[col]
+			match[col] = match[prev]
+			col = prev
+		}
+	}
+
+	assignment := unassigned(n)
+	for j := 1; j <= m; j++ {
+		if match[j] != 0 {
+			assignment[match[j]-1] = j - 1
+		}
+	}
+	return assignment
+}
+
+// GreedyAssignment gives each row, in order, its cheapest column that is still free.
+// This is what matching trips one at a time amounts to.
+func GreedyAssignment(cost [][]float64) []int {
+	assignment := unassigned(len(cost))
+	taken := make(map[int]bool)
+
+	for i, row := range cost {
+		best := -1
+		for j, c := range row {
+			if !taken[j] && (best < 0 || c < row[best]) {
+				best = j
+			}
+		}
+		if best >= 0 {
+			assignment[i] = best
+			taken[best] = true
+		}
+	}
+
+	return assignment
+}
+
+// AssignmentCost returns the total cost of an assignment, ignoring unassigned rows
+func AssignmentCost(cost [][]float64, assignment []int) float64 {
+	var total float64
+	for i, j := range assignment {
+		if j >= 0 {
+			total += cost[i][j]
+		}
+	}
+	return total
+}
+
+func unassigned(n int) []int {
+	assignment := make([]int, n)
+	for i := range assignment {
+		assignment[i] = -1
+	}
+	return assignment
+}
@@ -0,0 +1,212 @@
+package domain
+
+import (
+	"math"
+	"math/rand/v2"
+	pb "ride-sharing/shared/proto/driver"
+	"testing"
+)
+
+// Synthetic positions are drawn from this bounding box around San Francisco
+const (
+	minLatitude  = 37.70
+	maxLatitude  = 37.81
+	minLongitude = -122.51
+	maxLongitude = -122.37
+)
+
+// bruteForceCost returns the lowest total cost of assigning min(n, m) rows to
+// distinct columns, trying every assignment
+func bruteForceCost(cost [][]float64) float64 {
+	n := len(cost)
+	if n == 0 {
+		return 0
+	}
+	m := len(cost[0])
+	assignments := min(n, m)
+
+	taken := make([]bool, m)
+	var search func(row, assigned int) float64
+	search = func(row, assigned int) float64 {
+		if assigned == assignments {
+			return 0
+		}
+		if n-row < assignments-assigned {
+			return math.Inf(1)
+		}
+
+		// Leave the row unassigned, or give it any free column
+		best := search(row+1, assigned)
+		for j := range m {
+			if taken[j] {
+				continue
+			}
+			taken[j] = true
+			best = min(best, cost[row][j]+search(row+1, assigned+1))
+			taken[j] = false
+		}
+		return best
+	}
+
+	return search(0, 0)
+}
+
+func randomCosts(rng *rand.Rand, n, m int) [][]float64 {
+	cost := make([][]float64, n)
+	for i := range cost {
+		cost[i] = make([]float64, m)
+		for j := range cost[i] {
+			cost[i][j] = float64(rng.IntN(100))
+		}
+	}
+	return cost
+}
+
+func TestMinCostAssignment(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	tests := []struct {
+		name string
+		cost [][]float64
+	}{
+		{"empty", nil},
+		{"single", [][]float64{{7}}},
+		{"square greedy trap", [][]float64{{1, 2}, {1, 100}}},
+		{"square 3x3", [][]float64{{4, 1, 3}, {2, 0, 5}, {3, 2, 2}}},
+		{"square random 5x5", randomCosts(rng, 5, 5)},
+		{"square random 7x7", randomCosts(rng, 7, 7)},
+		{"more rows 3x1", [][]float64{{5}, {1}, {3}}},
+		{"more rows 4x2", [][]float64{{1, 9}, {2, 8}, {9, 1}, {3, 3}}},
+		{"more rows random 6x3", randomCosts(rng, 6, 3)},
+		{"more rows random 7x4", randomCosts(rng, 7, 4)},
+		{"more columns 1x3", [][]float64{{5, 1, 3}}},
+		{"more columns 2x4", [][]float64{{1, 2, 9, 9}, {1, 9, 9, 3}}},
+		{"more columns random 3x6", randomCosts(rng, 3, 6)},
+		{"more columns random 4x7", randomCosts(rng, 4, 7)},
+		{"unknown locations", [][]float64{{unknownPickupETA, 10}, {20, unknownPickupETA}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assignment := MinCostAssignment(tt.cost)
+
+			if len(assignment) != len(tt.cost) {
+				t.Fatalf("got %d assignments for %d rows", len(assignment), len(tt.cost))
+			}
+
+			m := 0
+			if len(tt.cost) > 0 {
+				m = len(tt.cost[0])
+			}
+			used := make(map[int]bool)
+			assigned := 0
+			for i, j := range assignment {
+				if j < 0 {
+					continue
+				}
+				if j >= m {
+					t.Fatalf("row %d got column %d of %d", i, j, m)
+				}
+				if used[j] {
+					t.Fatalf("column %d assigned twice in %v", j, assignment)
+				}
+				used[j] = true
+				assigned++
+			}
+			if want := min(len(tt.cost), m); assigned != want {
+				t.Fatalf("assigned %d rows, want %d: %v", assigned, want, assignment)
+			}
+
+			got, want := AssignmentCost(tt.cost, assignment), bruteForceCost(tt.cost)
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("cost of %v = %v, want the optimum %v", assignment, got, want)
+			}
+		})
+	}
+}
+
+func TestMinCostAssignmentRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	for range 200 {
+		n, m := 1+rng.IntN(6), 1+rng.IntN(6)
+		cost := randomCosts(rng, n, m)
+
+		got, want := AssignmentCost(cost, MinCostAssignment(cost)), bruteForceCost(cost)
+		if math.Abs(got-want) > 1e-9 {
+			t.Fatalf("cost of %v = %v, want the optimum %v", cost, got, want)
+		}
+	}
+}
+
+func TestGreedyAssignment(t *testing.T) {
+	cost := [][]float64{{1, 2}, {1, 100}, {5, 5}}
+
+	assignment := GreedyAssignment(cost)
+
+	// Row 0 takes its cheapest column and leaves row 1 the expensive one
+	want := []int{0, 1, -1}
+	for i := range want {
+		if assignment[i] != want[i] {
+			t.Fatalf("GreedyAssignment = %v, want %v", assignment, want)
+		}
+	}
+}
+
+// syntheticCosts returns the pickup ETA of every driver to every trip for random positions
+func syntheticCosts(rng *rand.Rand, trips, drivers int) [][]float64 {
+	driverLocations := make([]*pb.Location, drivers)
+	for j := range driverLocations {
+		driverLocations[j] = randomLocation(rng)
+	}
+
+	cost := make([][]float64, trips)
+	for i := range cost {
+		pickup := randomLocation(rng)
+		cost[i] = make([]float64, drivers)
+		for j, location := range driverLocations {
+			cost[i][j] = PickupETASeconds(location, pickup)
+		}
+	}
+
+	return cost
+}
+
+func randomLocation(rng *rand.Rand) *pb.Location {
+	return &pb.Location{
+		Latitude:  minLatitude + rng.Float64()*(maxLatitude-minLatitude),
+		Longitude: minLongitude + rng.Float64()*(maxLongitude-minLongitude),
+	}
+}
+
+// benchmarkAssignment matches batches of 50 synthetic trips with 60 drivers and
+// reports the mean total pickup ETA next to the time per batch, so greedy per-trip
+// matching and the batched minimum-cost assignment can be compared
+func benchmarkAssignment(b *testing.B, assign func(cost [][]float64) []int) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	batches := make([][][]float64, 20)
+	for i := range batches {
+		batches[i] = syntheticCosts(rng, 50, 60)
+	}
+
+	var total float64
+	for _, cost := range batches {
+		total += AssignmentCost(cost, assign(cost))
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		assign(batches[n%len(batches)])
+	}
+	b.StopTimer()
+
+	b.ReportMetric(total/float64(len(batches))/60, "eta-min/batch")
+}
+
+func BenchmarkGreedyAssignment(b *testing.B) {
+	benchmarkAssignment(b, GreedyAssignment)
+}
+
+func BenchmarkMinCostAssignment(b *testing.B) {
+	benchmarkAssignment(b, MinCostAssignment)
+}
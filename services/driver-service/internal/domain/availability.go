@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"errors"
+	pb "ride-sharing/shared/proto/driver"
+	"time"
+)
+
+var (
+	// ErrInvalidTransition is returned when a driver cannot move to the requested availability
+	ErrInvalidTransition = errors.New("invalid availability transition")
+)
+
+// Availability is the dispatch state of a driver
+type Availability string
+
+const (
+	AvailabilityOnline          Availability = "online"
+	AvailabilityOffered         Availability = "offered"
+	AvailabilityEnRouteToPickup Availability = "en_route_to_pickup"
+	AvailabilityOnTrip          Availability = "on_trip"
+	AvailabilityOffline         Availability = "offline"
+)
+
+// allowedTransitions lists the states a driver may move to from each state.
+// Every state may go offline when the driver disconnects.
+var allowedTransitions = map[Availability][]Availability{
+	AvailabilityOnline:          {AvailabilityOffered, AvailabilityOffline},
+	AvailabilityOffered:         {AvailabilityOnline, AvailabilityEnRouteToPickup, AvailabilityOffline},
+	AvailabilityEnRouteToPickup: {AvailabilityOnTrip, AvailabilityOnline, AvailabilityOffline},
+	AvailabilityOnTrip:          {AvailabilityOnline, AvailabilityOffline},
+	AvailabilityOffline:         {AvailabilityOnline},
+}
+
+// CanTransitionTo reports whether a driver in state a may move to next
+func (a Availability) CanTransitionTo(next Availability) bool {
+	for _, allowed := range allowedTransitions[a] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ToProto converts the availability to its protobuf representation
+func (a Availability) ToProto() pb.DriverAvailability {
+	switch a {
+	case AvailabilityOnline:
+		return pb.DriverAvailability_DRIVER_AVAILABILITY_ONLINE
+	case AvailabilityOffered:
+		return pb.DriverAvailability_DRIVER_AVAILABILITY_OFFERED
+	case AvailabilityEnRouteToPickup:
+		return pb.DriverAvailability_DRIVER_AVAILABILITY_EN_ROUTE_TO_PICKUP
+	case AvailabilityOnTrip:
+		return pb.DriverAvailability_DRIVER_AVAILABILITY_ON_TRIP
+	case AvailabilityOffline:
+		return pb.DriverAvailability_DRIVER_AVAILABILITY_OFFLINE
+	default:
+		return pb.DriverAvailability_DRIVER_AVAILABILITY_UNSPECIFIED
+	}
+}
+
+// DriverAvailability is a driver's current state, when it was entered and the trip it relates to
+type DriverAvailability struct {
+	DriverID string
+	State    Availability
+	Since    time.Time
+	TripID   string
+	// Connected is false while the driver's connection is down
+	Connected bool
+}
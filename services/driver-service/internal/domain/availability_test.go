@@ -0,0 +1,223 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/driver"
+	pbt "ride-sharing/shared/proto/trip"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testPackage = "sedan"
+
+// newTestService returns a driver service with the drivers online for testPackage
+func newTestService(matching MatchingPolicy, drivers ...*pb.Driver) *driverService {
+	s := NewDriverService(nil, DispatchPolicy{Default: DefaultDispatchConfig()}, matching).(*driverService)
+	for _, driver := range drivers {
+		driver.PackageSlug = testPackage
+		s.drivers = append(s.drivers, &driverInMap{
+			Driver:       driver,
+			Availability: DriverAvailability{DriverID: driver.Id, State: AvailabilityOnline, Since: time.Now()},
+		})
+	}
+	return s
+}
+
+// tripAt returns a testPackage trip whose route starts at pickup
+func tripAt(id string, pickup *pb.Location) messaging.TripCreatedEvent {
+	trip := &pbt.Trip{
+		Id:           id,
+		SelectedFare: &pbt.RideFare{PackageSlug: testPackage},
+	}
+	if pickup != nil {
+		// Route coordinates are in OSRM's [longitude, latitude] order
+		trip.Route = &pbt.Route{Geometry: []*pbt.Geometry{{
+			Coordinates: []*pbt.Coordinate{{Latitude: pickup.Longitude, Longitude: pickup.Latitude}},
+		}}}
+	}
+	return messaging.TripCreatedEvent{Trip: trip}
+}
+
+func availabilityOf(t *testing.T, s *driverService, driverID string) DriverAvailability {
+	t.Helper()
+	availability, err := s.GetAvailability(driverID)
+	if err != nil {
+		t.Fatalf("GetAvailability(%s) error = %v", driverID, err)
+	}
+	return *availability
+}
+
+func TestAvailabilityTransitions(t *testing.T) {
+	tests := []struct {
+		from, to Availability
+		want     bool
+	}{
+		{AvailabilityOnline, AvailabilityOffered, true},
+		{AvailabilityOffered, AvailabilityEnRouteToPickup, true},
+		{AvailabilityOffered, AvailabilityOnline, true},
+		{AvailabilityEnRouteToPickup, AvailabilityOnTrip, true},
+		{AvailabilityEnRouteToPickup, AvailabilityOnline, true},
+		{AvailabilityOnTrip, AvailabilityOnline, true},
+		{AvailabilityOffline, AvailabilityOnline, true},
+
+		// Drivers are only assigned trips they were offered, and offered one trip at a time
+		{AvailabilityOnline, AvailabilityEnRouteToPickup, false},
+		{AvailabilityOnline, AvailabilityOnTrip, false},
+		{AvailabilityOffered, AvailabilityOffered, false},
+		{AvailabilityOffered, AvailabilityOnTrip, false},
+		{AvailabilityEnRouteToPickup, AvailabilityOffered, false},
+		{AvailabilityOnTrip, AvailabilityOffered, false},
+		{AvailabilityOnTrip, AvailabilityEnRouteToPickup, false},
+		{AvailabilityOffline, AvailabilityOffered, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	// Every state may go offline when the driver disconnects
+	for state := range allowedTransitions {
+		if state != AvailabilityOffline && !state.CanTransitionTo(AvailabilityOffline) {
+			t.Errorf("%s can't go offline", state)
+		}
+	}
+}
+
+func TestOffersSkipBusyDrivers(t *testing.T) {
+	s := newTestService(MatchingPolicy{}, driverAt("d1", 37.77, -122.42), driverAt("d2", 37.78, -122.42))
+	ctx := context.Background()
+
+	first, err := s.FindAndNotifyDrivers(ctx, tripAt("t1", nil))
+	if err != nil || len(first) != 1 {
+		t.Fatalf("FindAndNotifyDrivers(t1) = %v, %v, want one driver", first, err)
+	}
+	if got := availabilityOf(t, s, first[0]); got.State != AvailabilityOffered || got.TripID != "t1" {
+		t.Errorf("availability of %s = %s for %q, want offered for t1", first[0], got.State, got.TripID)
+	}
+
+	// The offered driver isn't offered the next trip
+	second, err := s.FindAndNotifyDrivers(ctx, tripAt("t2", nil))
+	if err != nil || len(second) != 1 || second[0] == first[0] {
+		t.Fatalf("FindAndNotifyDrivers(t2) = %v, %v, want the other driver than %s", second, err, first[0])
+	}
+
+	// Neither is a driver on a trip
+	if err := s.UpdateAvailability(first[0], AvailabilityEnRouteToPickup, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateAvailability(first[0], AvailabilityOnTrip, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if drivers, err := s.FindAndNotifyDrivers(ctx, tripAt("t3", nil)); err == nil {
+		t.Errorf("FindAndNotifyDrivers(t3) = %v, want no drivers while both are busy", drivers)
+	}
+}
+
+func TestUpdateAvailability(t *testing.T) {
+	s := newTestService(MatchingPolicy{}, driverAt("d1", 37.77, -122.42))
+	if _, err := s.FindAndNotifyDrivers(context.Background(), tripAt("t1", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		state   Availability
+		tripID  string
+		wantErr error
+		want    Availability
+	}{
+		{"redelivered offer", AvailabilityOffered, "t1", nil, AvailabilityOffered},
+		{"event of another trip", AvailabilityEnRouteToPickup, "t2", ErrInvalidTransition, AvailabilityOffered},
+		{"start before assignment", AvailabilityOnTrip, "t1", ErrInvalidTransition, AvailabilityOffered},
+		{"assigned", AvailabilityEnRouteToPickup, "t1", nil, AvailabilityEnRouteToPickup},
+		{"offer while assigned", AvailabilityOffered, "t1", ErrInvalidTransition, AvailabilityEnRouteToPickup},
+		{"started", AvailabilityOnTrip, "t1", nil, AvailabilityOnTrip},
+		{"completed", AvailabilityOnline, "t1", nil, AvailabilityOnline},
+	}
+
+	for _, tt := range tests {
+		err := s.UpdateAvailability("d1", tt.state, tt.tripID)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: UpdateAvailability(%s, %s) error = %v, want %v", tt.name, tt.state, tt.tripID, err, tt.wantErr)
+		}
+		if got := availabilityOf(t, s, "d1").State; got != tt.want {
+			t.Errorf("%s: state = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if err := s.UpdateAvailability("unknown", AvailabilityOnline, "t1"); !errors.Is(err, ErrDriverNotFound) {
+		t.Errorf("UpdateAvailability of an unknown driver error = %v, want ErrDriverNotFound", err)
+	}
+}
+
+func TestDeclinedAndRevokedOffersReleaseTheDriver(t *testing.T) {
+	// Declines, revoked offers and trips taken by someone else all release the driver
+	for _, trip := range []string{"declined", "revoked", "taken"} {
+		s := newTestService(MatchingPolicy{}, driverAt("d1", 37.77, -122.42))
+		if _, err := s.FindAndNotifyDrivers(context.Background(), tripAt(trip, nil)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.UpdateAvailability("d1", AvailabilityOnline, trip); err != nil {
+			t.Fatalf("%s: UpdateAvailability(online) error = %v", trip, err)
+		}
+		if got := availabilityOf(t, s, "d1"); got.State != AvailabilityOnline || got.TripID != "" {
+			t.Errorf("%s: availability = %s for %q, want online without trip", trip, got.State, got.TripID)
+		}
+		if drivers, err := s.FindAndNotifyDrivers(context.Background(), tripAt("next", nil)); err != nil || !slices.Equal(drivers, []string{"d1"}) {
+			t.Errorf("%s: FindAndNotifyDrivers after release = %v, %v, want [d1]", trip, drivers, err)
+		}
+	}
+}
+
+func TestUnansweredOffersExpire(t *testing.T) {
+	s := newTestService(MatchingPolicy{}, driverAt("d1", 37.77, -122.42))
+	if _, err := s.FindAndNotifyDrivers(context.Background(), tripAt("t1", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := s.drivers[0]
+	offeredAt := entry.Availability.Since
+	if entry.isAvailable(offeredAt.Add(offerTimeout)) {
+		t.Error("driver is available before the offer timed out")
+	}
+	if !entry.isAvailable(offeredAt.Add(offerTimeout + time.Millisecond)) {
+		t.Error("driver is still unavailable after the offer timed out")
+	}
+
+	// A timed out driver reports no open offer, so a late accept is refused
+	entry.Availability.Since = time.Now().Add(-offerTimeout - time.Second)
+	if got := availabilityOf(t, s, "d1"); got.State != AvailabilityOnline || got.TripID != "" {
+		t.Errorf("availability after the offer timed out = %s for %q, want online", got.State, got.TripID)
+	}
+
+	// and is offered the next trip
+	if drivers, err := s.FindAndNotifyDrivers(context.Background(), tripAt("t2", nil)); err != nil || !slices.Equal(drivers, []string{"d1"}) {
+		t.Fatalf("FindAndNotifyDrivers(t2) = %v, %v, want [d1]", drivers, err)
+	}
+	if got := availabilityOf(t, s, "d1"); got.TripID != "t2" {
+		t.Errorf("driver is offered %q, want t2", got.TripID)
+	}
+}
+
+func TestDisconnectedDriversAreNotOffered(t *testing.T) {
+	s := newTestService(MatchingPolicy{}, driverAt("d1", 37.77, -122.42))
+	if err := s.SetConnected("d1", false); err != nil {
+		t.Fatal(err)
+	}
+	if drivers, err := s.FindAndNotifyDrivers(context.Background(), tripAt("t1", nil)); err == nil {
+		t.Fatalf("FindAndNotifyDrivers() = %v, want no drivers while disconnected", drivers)
+	}
+
+	if err := s.SetConnected("d1", true); err != nil {
+		t.Fatal(err)
+	}
+	if drivers, err := s.FindAndNotifyDrivers(context.Background(), tripAt("t1", nil)); err != nil || !slices.Equal(drivers, []string{"d1"}) {
+		t.Errorf("FindAndNotifyDrivers() after reconnect = %v, %v, want [d1]", drivers, err)
+	}
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidDispatchPolicy is returned when a dispatch policy spec cannot be parsed
+	ErrInvalidDispatchPolicy = errors.New("invalid dispatch policy")
+)
+
+// DispatchMode decides how drivers are picked for a trip
+type DispatchMode string
+
+const (
+	// DispatchModeSingle offers the trip to one driver at a time
+	DispatchModeSingle DispatchMode = "single"
+	// DispatchModeBroadcast offers the trip to the nearest drivers at once; the first accept wins
+	DispatchModeBroadcast DispatchMode = "broadcast"
+	// DispatchModeBatch collects trips over a short window and matches them together,
+	// minimising the total pickup ETA of the batch
+	DispatchModeBatch DispatchMode = "batch"
+)
+
+// DispatchConfig is the dispatch behaviour of a package
+type DispatchConfig struct {
+	Mode DispatchMode
+	// OfferCount is how many drivers are offered the trip in broadcast mode
+	OfferCount int
+}
+
+// DefaultDispatchConfig returns the single driver dispatch used when a package isn't configured
+func DefaultDispatchConfig() DispatchConfig {
+	return DispatchConfig{
+		Mode:       DispatchModeSingle,
+		OfferCount: 1,
+	}
+}
+
+// offerCount returns how many drivers should be offered a trip
+func (c DispatchConfig) offerCount() int {
+	if c.Mode != DispatchModeBroadcast || c.OfferCount < 1 {
+		return 1
+	}
+	return c.OfferCount
+}
+
+// DispatchPolicy holds the dispatch configuration of every package
+type DispatchPolicy struct {
+	Default  DispatchConfig
+	Packages map[string]DispatchConfig
+}
+
+// ForPackage returns the dispatch configuration of a package
+func (p DispatchPolicy) ForPackage(packageSlug string) DispatchConfig {
+	if config, ok := p.Packages[packageSlug]; ok {
+		return config
+	}
+	return p.Default
+}
+
+// ParseDispatchPolicy parses a comma separated list of package dispatch modes,
+// e.g. "suv=broadcast:3,sedan=batch,luxury=single". Packages not listed use single dispatch.
+func ParseDispatchPolicy(spec string) (DispatchPolicy, error) {
+	policy := DispatchPolicy{
+		Default:  DefaultDispatchConfig(),
+		Packages: make(map[string]DispatchConfig),
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		packageSlug, mode, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(packageSlug) == "" {
+			return DispatchPolicy{}, fmt.Errorf("%w: %q", ErrInvalidDispatchPolicy, entry)
+		}
+
+		config, err := parseDispatchConfig(strings.TrimSpace(mode))
+		if err != nil {
+			return DispatchPolicy{}, fmt.Errorf("%w: %q: %v", ErrInvalidDispatchPolicy, entry, err)
+		}
+
+		policy.Packages[strings.TrimSpace(packageSlug)] = config
+	}
+
+	return policy, nil
+}
+
+func parseDispatchConfig(mode string) (DispatchConfig, error) {
+	name, count, hasCount := strings.Cut(mode, ":")
+
+	switch DispatchMode(name) {
+	case DispatchModeSingle, DispatchModeBatch:
+		if hasCount {
+			return DispatchConfig{}, fmt.Errorf("%s dispatch takes no offer count", name)
+		}
+		return DispatchConfig{Mode: DispatchMode(name), OfferCount: 1}, nil
+
+	case DispatchModeBroadcast:
+		offerCount := 3
+		if hasCount {
+			n, err := strconv.Atoi(count)
+			if err != nil || n < 1 {
+				return DispatchConfig{}, fmt.Errorf("offer count must be a positive number")
+			}
+			offerCount = n
+		}
+		return DispatchConfig{Mode: DispatchModeBroadcast, OfferCount: offerCount}, nil
+
+	default:
+		return DispatchConfig{}, fmt.Errorf("unknown mode %q", name)
+	}
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDispatchPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    map[string]DispatchConfig
+		wantErr bool
+	}{
+		{name: "empty", spec: "", want: map[string]DispatchConfig{}},
+		{
+			name: "every mode",
+			spec: "suv=broadcast:5, sedan=batch ,luxury=single",
+			want: map[string]DispatchConfig{
+				"suv":    {Mode: DispatchModeBroadcast, OfferCount: 5},
+				"sedan":  {Mode: DispatchModeBatch, OfferCount: 1},
+				"luxury": {Mode: DispatchModeSingle, OfferCount: 1},
+			},
+		},
+		{
+			name: "broadcast default count",
+			spec: "suv=broadcast",
+			want: map[string]DispatchConfig{"suv": {Mode: DispatchModeBroadcast, OfferCount: 3}},
+		},
+		{
+			name: "empty entries",
+			spec: ",suv=single,,",
+			want: map[string]DispatchConfig{"suv": {Mode: DispatchModeSingle, OfferCount: 1}},
+		},
+		{name: "missing mode", spec: "suv", wantErr: true},
+		{name: "missing package", spec: "=single", wantErr: true},
+		{name: "unknown mode", spec: "suv=nearest", wantErr: true},
+		{name: "zero offer count", spec: "suv=broadcast:0", wantErr: true},
+		{name: "invalid offer count", spec: "suv=broadcast:many", wantErr: true},
+		{name: "count of single", spec: "suv=single:2", wantErr: true},
+		{name: "count of batch", spec: "suv=batch:2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParseDispatchPolicy(tt.spec)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDispatchPolicy) {
+					t.Fatalf("ParseDispatchPolicy(%q) = %v, want ErrInvalidDispatchPolicy", tt.spec, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDispatchPolicy(%q): %v", tt.spec, err)
+			}
+
+			if len(policy.Packages) != len(tt.want) {
+				t.Fatalf("got packages %v, want %v", policy.Packages, tt.want)
+			}
+			for packageSlug, want := range tt.want {
+				if got := policy.ForPackage(packageSlug); got != want {
+					t.Errorf("ForPackage(%q) = %+v, want %+v", packageSlug, got, want)
+				}
+			}
+			if got := policy.ForPackage("unlisted"); got != DefaultDispatchConfig() {
+				t.Errorf("unlisted package uses %+v, want the default", got)
+			}
+		})
+	}
+}
+
+func TestDispatchConfigOfferCount(t *testing.T) {
+	tests := []struct {
+		config DispatchConfig
+		want   int
+	}{
+		{DispatchConfig{Mode: DispatchModeSingle, OfferCount: 4}, 1},
+		{DispatchConfig{Mode: DispatchModeBatch, OfferCount: 4}, 1},
+		{DispatchConfig{Mode: DispatchModeBroadcast, OfferCount: 4}, 4},
+		{DispatchConfig{Mode: DispatchModeBroadcast}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.config.offerCount(); got != tt.want {
+			t.Errorf("%+v offerCount = %d, want %d", tt.config, got, tt.want)
+		}
+	}
+}
@@ -10,21 +10,63 @@ import (
 var (
 	// ErrDriverNotFound is returned when a driver is not found
 	ErrDriverNotFound = errors.New("driver not found")
+
+	// ErrInvalidLocation is returned when a location is outside valid coordinate ranges
+	ErrInvalidLocation = errors.New("invalid location")
 )
 
 // DriverService defines the contract for driver management operations
 type DriverService interface {
-	// RegisterDriver registers a new driver with the system
-	RegisterDriver(driverID string, packageSlug string) (*pb.Driver, error)
+	// RegisterDriver brings a driver online with their profile and a vehicle eligible for the package
+	RegisterDriver(ctx context.Context, driverID, packageSlug, vehicleID string) (*pb.Driver, error)
 
 	// UnregisterDriver removes a driver from the system
 	UnregisterDriver(driverID string) error
 
+	// GetDriver returns the latest known state of a registered driver
+	GetDriver(driverID string) (*pb.Driver, error)
+
+	// UpdateDriverLocation moves a registered driver to a new location
+	UpdateDriverLocation(driverID string, location *pb.Location) (*pb.Driver, error)
+
+	// WatchDrivers returns the drivers currently matching the filter and a channel of
+	// their subsequent updates. The returned function must be called to stop watching.
+	WatchDrivers(filter DriverFilter) ([]*pb.Driver, <-chan DriverUpdate, func())
+
+	// GetAvailability returns the driver's current availability state
+	GetAvailability(driverID string) (*DriverAvailability, error)
+
+	// UpdateAvailability moves a driver to a new availability state for a trip
+	UpdateAvailability(driverID string, state Availability, tripID string) error
+
+	// SetConnected marks a driver as disconnected while their connection is down, or as
+	// connected again. Disconnected drivers keep their state but aren't offered trips.
+	SetConnected(driverID string, connected bool) error
+
 	// ProcessTripCreatedEvent processes trip creation events
 	ProcessTripCreatedEvent(ctx context.Context, tripID, userID string) error
 
-	// FindAndNotifyDrivers finds available drivers and notifies them of a trip
-	FindAndNotifyDrivers(ctx context.Context, tripEvent messaging.TripCreatedEvent) (string, error)
+	// FindAndNotifyDrivers marks the drivers to offer a trip to as offered and returns their IDs.
+	// Packages in broadcast dispatch mode get several drivers, nearest first.
+	FindAndNotifyDrivers(ctx context.Context, tripEvent messaging.TripCreatedEvent) ([]string, error)
+	// DispatchConfigFor returns how trips of a package are dispatched
+	DispatchConfigFor(packageSlug string) DispatchConfig
+	// AssignTrips matches a batch of trips to available drivers with the lowest total
+	// pickup ETA, following the ranking and exclusions of each trip's matching rule,
+	// and marks the drivers as offered. It returns the driver of each matched trip.
+	AssignTrips(ctx context.Context, trips []messaging.TripCreatedEvent) map[string]string
+}
+
+// DriverAvailabilityConsumer defines the contract for consuming events that change driver availability
+type DriverAvailabilityConsumer interface {
+	// ConsumeAvailabilityEvents starts consuming trip lifecycle events from the queue
+	ConsumeAvailabilityEvents(ctx context.Context, queue string, handler messaging.MessageHandler) error
+}
+
+// DriverEventPublisher defines the contract for publishing driver events
+type DriverEventPublisher interface {
+	// PublishLocationUpdated notifies other services of a driver's new position
+	PublishLocationUpdated(ctx context.Context, driver *pb.Driver) error
 }
 
 // TripEventConsumer defines the contract for consuming trip events
@@ -32,3 +74,9 @@ type TripEventConsumer interface {
 	// ConsumeTripCreated starts consuming trip created events from the queue
 	ConsumeTripCreated(ctx context.Context, queue string, handler messaging.MessageHandler) error
 }
+
+// DriverLocationConsumer defines the contract for consuming driver location updates
+type DriverLocationConsumer interface {
+	// ConsumeDriverLocation starts consuming driver location updates from the queue
+	ConsumeDriverLocation(ctx context.Context, queue string, handler messaging.MessageHandler) error
+}
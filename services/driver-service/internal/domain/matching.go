@@ -0,0 +1,263 @@
+package domain
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	pb "ride-sharing/shared/proto/driver"
+	sharedutil "ride-sharing/shared/util"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcloughlin/geohash"
+)
+
+var (
+	// ErrInvalidMatchingPolicy is returned when a matching policy spec cannot be parsed
+	ErrInvalidMatchingPolicy = errors.New("invalid matching policy")
+)
+
+// regionPrefix marks matching policy keys that apply to pickups within a geohash
+const regionPrefix = "region:"
+
+// MatchCandidate is an available driver that may be offered a trip
+type MatchCandidate struct {
+	// Driver must not be modified by strategies
+	Driver *pb.Driver
+	// LastAssignedAt is when the driver was last assigned a trip, zero if never
+	LastAssignedAt time.Time
+
+	entry *driverInMap
+}
+
+// MatchRequest describes the trip drivers are matched for
+type MatchRequest struct {
+	PackageSlug string
+	// Pickup is nil when the trip has no route
+	Pickup *pb.Location
+}
+
+// MatchingStrategy decides which drivers are offered a trip first
+type MatchingStrategy interface {
+	// Rank orders the candidates best first and may drop unsuitable ones.
+	// It may reorder the given slice in place.
+	Rank(req MatchRequest, candidates []MatchCandidate) []MatchCandidate
+}
+
+type randomStrategy struct{}
+
+// NewRandomStrategy creates a strategy that shuffles the candidates
+func NewRandomStrategy() MatchingStrategy {
+	return randomStrategy{}
+}
+
+func (randomStrategy) Rank(req MatchRequest, candidates []MatchCandidate) []MatchCandidate {
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	return candidates
+}
+
+type nearestStrategy struct{}
+
+// NewNearestStrategy creates a strategy that prefers the drivers with the shortest pickup ETA.
+// Drivers without a known location go last.
+func NewNearestStrategy() MatchingStrategy {
+	return nearestStrategy{}
+}
+
+func (nearestStrategy) Rank(req MatchRequest, candidates []MatchCandidate) []MatchCandidate {
+	if req.Pickup == nil {
+		return candidates
+	}
+
+	slices.SortStableFunc(candidates, func(a, b MatchCandidate) int {
+		return cmp.Compare(PickupETASeconds(a.Driver.Location, req.Pickup), PickupETASeconds(b.Driver.Location, req.Pickup))
+	})
+	return candidates
+}
+
+type leastRecentlyAssignedStrategy struct{}
+
+// NewLeastRecentlyAssignedStrategy creates a strategy that prefers the drivers who waited
+// longest since their last trip, spreading trips fairly
+func NewLeastRecentlyAssignedStrategy() MatchingStrategy {
+	return leastRecentlyAssignedStrategy{}
+}
+
+func (leastRecentlyAssignedStrategy) Rank(req MatchRequest, candidates []MatchCandidate) []MatchCandidate {
+	slices.SortStableFunc(candidates, func(a, b MatchCandidate) int {
+		return a.LastAssignedAt.Compare(b.LastAssignedAt)
+	})
+	return candidates
+}
+
+type highestRatedStrategy struct{}
+
+// NewHighestRatedStrategy creates a strategy that prefers the drivers with the best rating
+func NewHighestRatedStrategy() MatchingStrategy {
+	return highestRatedStrategy{}
+}
+
+func (highestRatedStrategy) Rank(req MatchRequest, candidates []MatchCandidate) []MatchCandidate {
+	slices.SortStableFunc(candidates, func(a, b MatchCandidate) int {
+		return cmp.Compare(b.Driver.Rating, a.Driver.Rating)
+	})
+	return candidates
+}
+
+type maxPickupDistanceStrategy struct {
+	meters float64
+}
+
+// NewMaxPickupDistanceStrategy creates a strategy that drops drivers farther than
+// meters from the pickup. Without a pickup every driver is kept.
+func NewMaxPickupDistanceStrategy(meters float64) MatchingStrategy {
+	return maxPickupDistanceStrategy{meters: meters}
+}
+
+func (s maxPickupDistanceStrategy) Rank(req MatchRequest, candidates []MatchCandidate) []MatchCandidate {
+	if req.Pickup == nil {
+		return candidates
+	}
+
+	return slices.DeleteFunc(candidates, func(c MatchCandidate) bool {
+		location := c.Driver.Location
+		return location == nil ||
+			sharedutil.HaversineDistance(location.Latitude, location.Longitude, req.Pickup.Latitude, req.Pickup.Longitude) > s.meters
+	})
+}
+
+type chainStrategy struct {
+	strategies []MatchingStrategy
+}
+
+// Chain combines strategies in priority order: each strategy only breaks the ties
+// left by the ones before it, and filters apply wherever they appear.
+// Chain(highestRated, nearest) prefers the best rated drivers and, among equally
+// rated ones, the nearest.
+func Chain(strategies ...MatchingStrategy) MatchingStrategy {
+	if len(strategies) == 1 {
+		return strategies[0]
+	}
+	return chainStrategy{strategies: strategies}
+}
+
+func (s chainStrategy) Rank(req MatchRequest, candidates []MatchCandidate) []MatchCandidate {
+	// Ranking with the least important strategy first lets the stable sorts of
+	// the more important ones keep its order among their ties
+	for i := len(s.strategies) - 1; i >= 0; i-- {
+		candidates = s.strategies[i].Rank(req, candidates)
+	}
+	return candidates
+}
+
+// MatchingPolicy picks the matching strategy of a trip by pickup region or package
+type MatchingPolicy struct {
+	// Default applies to trips no other rule matches, nil to use the dispatch mode's default
+	Default  MatchingStrategy
+	Packages map[string]MatchingStrategy
+	// Regions are keyed by geohash prefix
+	Regions map[string]MatchingStrategy
+}
+
+// For returns the strategy for a trip, or nil if the policy has none. The region
+// with the longest geohash prefix containing the pickup wins over package rules.
+func (p MatchingPolicy) For(req MatchRequest) MatchingStrategy {
+	if req.Pickup != nil && len(p.Regions) > 0 {
+		pickupHash := geohash.Encode(req.Pickup.Latitude, req.Pickup.Longitude)
+
+		var best string
+		for prefix := range p.Regions {
+			if strings.HasPrefix(pickupHash, prefix) && len(prefix) > len(best) {
+				best = prefix
+			}
+		}
+		if best != "" {
+			return p.Regions[best]
+		}
+	}
+
+	if strategy, ok := p.Packages[req.PackageSlug]; ok {
+		return strategy
+	}
+
+	return p.Default
+}
+
+// ParseMatchingPolicy parses a comma separated list of matching rules, e.g.
+// "sedan=least_recent>nearest,region:9q8yy=highest_rated>nearest,*=random".
+// Keys are package slugs, "region:" followed by a geohash prefix, or "*" for the default.
+func ParseMatchingPolicy(spec string) (MatchingPolicy, error) {
+	policy := MatchingPolicy{
+		Packages: make(map[string]MatchingStrategy),
+		Regions:  make(map[string]MatchingStrategy),
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, strategySpec, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return MatchingPolicy{}, fmt.Errorf("%w: %q", ErrInvalidMatchingPolicy, entry)
+		}
+
+		strategy, err := ParseMatchingStrategy(strategySpec)
+		if err != nil {
+			return MatchingPolicy{}, fmt.Errorf("%w: %q: %v", ErrInvalidMatchingPolicy, entry, err)
+		}
+
+		switch {
+		case key == "*":
+			policy.Default = strategy
+		case strings.HasPrefix(key, regionPrefix):
+			region := strings.ToLower(strings.TrimPrefix(key, regionPrefix))
+			if region == "" {
+				return MatchingPolicy{}, fmt.Errorf("%w: %q: empty region", ErrInvalidMatchingPolicy, entry)
+			}
+			policy.Regions[region] = strategy
+		default:
+			policy.Packages[key] = strategy
+		}
+	}
+
+	return policy, nil
+}
+
+// ParseMatchingStrategy parses a chain of strategies separated by ">", most important
+// first: random, nearest, least_recent, highest_rated and max_distance:<meters>.
+func ParseMatchingStrategy(spec string) (MatchingStrategy, error) {
+	var strategies []MatchingStrategy
+
+	for _, name := range strings.Split(spec, ">") {
+		name = strings.TrimSpace(name)
+
+		switch name {
+		case "random":
+			strategies = append(strategies, NewRandomStrategy())
+		case "nearest":
+			strategies = append(strategies, NewNearestStrategy())
+		case "least_recent":
+			strategies = append(strategies, NewLeastRecentlyAssignedStrategy())
+		case "highest_rated":
+			strategies = append(strategies, NewHighestRatedStrategy())
+		default:
+			meters, ok := strings.CutPrefix(name, "max_distance:")
+			if !ok {
+				return nil, fmt.Errorf("unknown strategy %q", name)
+			}
+			n, err := strconv.ParseFloat(meters, 64)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("max_distance must be a positive number of meters")
+			}
+			strategies = append(strategies, NewMaxPickupDistanceStrategy(n))
+		}
+	}
+
+	return Chain(strategies...), nil
+}
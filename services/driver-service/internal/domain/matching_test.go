@@ -0,0 +1,279 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/driver"
+	pbt "ride-sharing/shared/proto/trip"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mmcloughlin/geohash"
+)
+
+// pickup is in San Francisco, the candidates are spread north of it
+var pickup = &pb.Location{Latitude: 37.7749, Longitude: -122.4194}
+
+func candidate(id string, latitudeOffset float64, rating float64, lastAssigned time.Duration) MatchCandidate {
+	c := MatchCandidate{
+		Driver: &pb.Driver{
+			Id:       id,
+			Location: &pb.Location{Latitude: pickup.Latitude + latitudeOffset, Longitude: pickup.Longitude},
+			Rating:   rating,
+		},
+	}
+	if lastAssigned > 0 {
+		c.LastAssignedAt = time.Unix(0, 0).Add(lastAssigned)
+	}
+	return c
+}
+
+func rankedIDs(candidates []MatchCandidate) []string {
+	ids := make([]string, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.Driver.Id
+	}
+	return ids
+}
+
+func TestMatchingStrategies(t *testing.T) {
+	// a is far and top rated, b near and rated 4, c nearest and rated 4, d has no location
+	candidates := func() []MatchCandidate {
+		d := candidate("d", 0, 3, 0)
+		d.Driver.Location = nil
+		return []MatchCandidate{
+			candidate("a", 0.05, 5, 3*time.Hour),
+			candidate("b", 0.01, 4, time.Hour),
+			candidate("c", 0.001, 4, 2*time.Hour),
+			d,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		strategy MatchingStrategy
+		want     []string
+	}{
+		{"nearest", NewNearestStrategy(), []string{"c", "b", "a", "d"}},
+		{"highest rated", NewHighestRatedStrategy(), []string{"a", "b", "c", "d"}},
+		{"least recent", NewLeastRecentlyAssignedStrategy(), []string{"d", "b", "c", "a"}},
+		{"max distance", NewMaxPickupDistanceStrategy(2000), []string{"b", "c"}},
+		{"rated then nearest", Chain(NewHighestRatedStrategy(), NewNearestStrategy()), []string{"a", "c", "b", "d"}},
+		{"nearest within distance", Chain(NewMaxPickupDistanceStrategy(2000), NewNearestStrategy()), []string{"c", "b"}},
+		{"filter anywhere in the chain", Chain(NewHighestRatedStrategy(), NewMaxPickupDistanceStrategy(2000)), []string{"b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rankedIDs(tt.strategy.Rank(MatchRequest{Pickup: pickup}, candidates()))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Rank = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchingStrategiesWithoutPickup(t *testing.T) {
+	candidates := []MatchCandidate{candidate("a", 0.05, 5, 0), candidate("b", 0.001, 4, 0)}
+
+	for _, strategy := range []MatchingStrategy{NewNearestStrategy(), NewMaxPickupDistanceStrategy(10)} {
+		got := rankedIDs(strategy.Rank(MatchRequest{}, slices.Clone(candidates)))
+		if !slices.Equal(got, []string{"a", "b"}) {
+			t.Errorf("%T without pickup ranked %v, want the candidates unchanged", strategy, got)
+		}
+	}
+}
+
+func TestTripPickup(t *testing.T) {
+	moved := &pb.Location{Latitude: 37.78, Longitude: -122.41}
+	withMovedPickup := tripAt("t1", pickup)
+	withMovedPickup.Trip.Pickup = &pbt.Coordinate{Latitude: moved.Latitude, Longitude: moved.Longitude}
+
+	tests := []struct {
+		name string
+		trip messaging.TripCreatedEvent
+		want *pb.Location
+	}{
+		{"start of the route", tripAt("t1", pickup), pickup},
+		{"moved by the rider", withMovedPickup, moved},
+		{"no route", tripAt("t1", nil), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tripPickup(tt.trip)
+			if got.GetLatitude() != tt.want.GetLatitude() || got.GetLongitude() != tt.want.GetLongitude() || (got == nil) != (tt.want == nil) {
+				t.Errorf("tripPickup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMatchingStrategy(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "random"},
+		{spec: "nearest"},
+		{spec: "least_recent > nearest"},
+		{spec: "highest_rated>max_distance:1500>nearest"},
+		{spec: "", wantErr: true},
+		{spec: "closest", wantErr: true},
+		{spec: "nearest>", wantErr: true},
+		{spec: "max_distance:0", wantErr: true},
+		{spec: "max_distance:far", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		strategy, err := ParseMatchingStrategy(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMatchingStrategy(%q) succeeded, want an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil || strategy == nil {
+			t.Errorf("ParseMatchingStrategy(%q) = %v, %v", tt.spec, strategy, err)
+		}
+	}
+}
+
+func TestParseMatchingPolicy(t *testing.T) {
+	pickupHash := geohash.Encode(pickup.Latitude, pickup.Longitude)
+
+	policy, err := ParseMatchingPolicy("sedan=highest_rated, region:" + pickupHash[:3] + "=least_recent,region:" +
+		pickupHash[:5] + "=nearest,*=max_distance:2000")
+	if err != nil {
+		t.Fatalf("ParseMatchingPolicy: %v", err)
+	}
+
+	elsewhere := &pb.Location{Latitude: -33.8688, Longitude: 151.2093}
+	tests := []struct {
+		name string
+		req  MatchRequest
+		want MatchingStrategy
+	}{
+		{"longest region prefix", MatchRequest{PackageSlug: "sedan", Pickup: pickup}, NewNearestStrategy()},
+		{"package outside the regions", MatchRequest{PackageSlug: "sedan", Pickup: elsewhere}, NewHighestRatedStrategy()},
+		{"package without pickup", MatchRequest{PackageSlug: "sedan"}, NewHighestRatedStrategy()},
+		{"default", MatchRequest{PackageSlug: "suv", Pickup: elsewhere}, NewMaxPickupDistanceStrategy(2000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.For(tt.req); got != tt.want {
+				t.Errorf("For = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if got := (MatchingPolicy{}).For(MatchRequest{PackageSlug: "sedan", Pickup: pickup}); got != nil {
+		t.Errorf("empty policy returned %#v, want nil", got)
+	}
+}
+
+func TestParseMatchingPolicyErrors(t *testing.T) {
+	for _, spec := range []string{"sedan", "=nearest", "region:=nearest", "sedan=closest"} {
+		if _, err := ParseMatchingPolicy(spec); !errors.Is(err, ErrInvalidMatchingPolicy) {
+			t.Errorf("ParseMatchingPolicy(%q) = %v, want ErrInvalidMatchingPolicy", spec, err)
+		}
+	}
+}
+
+func TestAssignTripsFollowsMatchingPolicy(t *testing.T) {
+	// near is close to the pickup and rated 4, far is 5km away and rated 5
+	drivers := func() []*pb.Driver {
+		near := driverAt("near", pickup.Latitude+0.001, pickup.Longitude)
+		near.Rating = 4
+		far := driverAt("far", pickup.Latitude+0.05, pickup.Longitude)
+		far.Rating = 5
+		return []*pb.Driver{near, far}
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		want   map[string]string
+	}{
+		{"lowest pickup ETA without a rule", "", map[string]string{"t1": "near"}},
+		{"package rule", testPackage + "=highest_rated", map[string]string{"t1": "far"}},
+		{"region rule", "region:" + geohash.Encode(pickup.Latitude, pickup.Longitude)[:5] + "=highest_rated", map[string]string{"t1": "far"}},
+		{"excluded drivers are not assigned", "*=max_distance:2000", map[string]string{"t1": "near"}},
+		{"no allowed driver", "*=max_distance:100", map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParseMatchingPolicy(tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := newTestService(policy, drivers()...)
+
+			got := s.AssignTrips(context.Background(), []messaging.TripCreatedEvent{tripAt("t1", pickup)})
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("AssignTrips() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A trip whose rule excludes every free driver is left without one, while the
+	// others are still matched by pickup ETA
+	policy, err := ParseMatchingPolicy("region:" + geohash.Encode(pickup.Latitude, pickup.Longitude)[:5] + "=max_distance:500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	elsewhere := &pb.Location{Latitude: pickup.Latitude + 0.3, Longitude: pickup.Longitude}
+	s := newTestService(policy, driverAt("far", pickup.Latitude+0.05, pickup.Longitude))
+	got := s.AssignTrips(context.Background(), []messaging.TripCreatedEvent{tripAt("t1", pickup), tripAt("t2", elsewhere)})
+	if want := map[string]string{"t2": "far"}; !maps.Equal(got, want) {
+		t.Errorf("AssignTrips() = %v, want %v", got, want)
+	}
+}
+
+// registrationProfiles resolves every driver to a profile and vehicle of their own
+type registrationProfiles struct {
+	ProfileService
+}
+
+func (registrationProfiles) ResolveForRegistration(ctx context.Context, driverID, vehicleID, packageSlug string) (*DriverProfile, *Vehicle, error) {
+	return &DriverProfile{ID: driverID}, &Vehicle{ID: "vehicle-" + driverID, DriverID: driverID}, nil
+}
+
+func TestLastAssignedAtSurvivesRegisteringAgain(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(MatchingPolicy{})
+	s.profiles = registrationProfiles{}
+
+	if _, err := s.RegisterDriver(ctx, "d1", testPackage, ""); err != nil {
+		t.Fatalf("RegisterDriver() error = %v", err)
+	}
+	for _, state := range []Availability{AvailabilityOffered, AvailabilityEnRouteToPickup} {
+		if err := s.UpdateAvailability("d1", state, "t1"); err != nil {
+			t.Fatalf("UpdateAvailability(%s) error = %v", state, err)
+		}
+	}
+	assignedAt := s.drivers[0].LastAssignedAt
+	if assignedAt.IsZero() {
+		t.Fatal("LastAssignedAt not set by the assignment")
+	}
+
+	if err := s.UnregisterDriver("d1"); err != nil {
+		t.Fatalf("UnregisterDriver() error = %v", err)
+	}
+	if _, err := s.RegisterDriver(ctx, "d1", testPackage, ""); err != nil {
+		t.Fatalf("RegisterDriver() again error = %v", err)
+	}
+
+	candidates := matchCandidates(s.drivers)
+	if len(candidates) != 1 || !candidates[0].LastAssignedAt.Equal(assignedAt) {
+		t.Errorf("LastAssignedAt after registering again = %v, want %v", candidates[0].LastAssignedAt, assignedAt)
+	}
+	if availability := availabilityOf(t, s, "d1"); availability.State != AvailabilityOnline {
+		t.Errorf("availability after registering again = %s, want %s", availability.State, AvailabilityOnline)
+	}
+}
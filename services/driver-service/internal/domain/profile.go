@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	pb "ride-sharing/shared/proto/driver"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrProfileNotFound is returned when a driver has no profile
+	ErrProfileNotFound = errors.New("driver profile not found")
+
+	// ErrProfileExists is returned when creating a profile for a driver that already has one
+	ErrProfileExists = errors.New("driver profile already exists")
+
+	// ErrVehicleNotFound is returned when a vehicle is not registered
+	ErrVehicleNotFound = errors.New("vehicle not found")
+
+	// ErrPlateTaken is returned when a plate is already registered to another vehicle
+	ErrPlateTaken = errors.New("plate already registered")
+
+	// ErrVehicleNotEligible is returned when a vehicle may not serve the requested package
+	ErrVehicleNotEligible = errors.New("vehicle not eligible for package")
+
+	// ErrInvalidProfile is returned when profile or vehicle fields are missing or malformed
+	ErrInvalidProfile = errors.New("invalid driver profile")
+)
+
+// DriverProfile is the identity of a driver, kept across their sessions
+type DriverProfile struct {
+	ID             string
+	Name           string
+	ProfilePicture string
+	Phone          string
+	LicenseNumber  string
+	// Rating is the average rider rating from 1 to 5
+	Rating    float64
+	CreatedAt time.Time
+}
+
+// Vehicle is a car registered to a driver
+type Vehicle struct {
+	ID           string
+	DriverID     string
+	Plate        string
+	Make         string
+	Model        string
+	Color        string
+	Seats        int32
+	PackageSlugs []string
+	CreatedAt    time.Time
+}
+
+// IsEligibleFor reports whether the vehicle may serve trips of the package
+func (v *Vehicle) IsEligibleFor(packageSlug string) bool {
+	return slices.Contains(v.PackageSlugs, packageSlug)
+}
+
+// ToProto converts the vehicle to its protobuf representation
+func (v *Vehicle) ToProto() *pb.Vehicle {
+	return &pb.Vehicle{
+		Id:           v.ID,
+		DriverID:     v.DriverID,
+		Plate:        v.Plate,
+		Make:         v.Make,
+		Model:        v.Model,
+		Color:        v.Color,
+		Seats:        v.Seats,
+		PackageSlugs: v.PackageSlugs,
+	}
+}
+
+// ToProto converts the profile and its vehicles to their protobuf representation
+func (p *DriverProfile) ToProto(vehicles []*Vehicle) *pb.DriverProfile {
+	protoVehicles := make([]*pb.Vehicle, len(vehicles))
+	for i, v := range vehicles {
+		protoVehicles[i] = v.ToProto()
+	}
+
+	return &pb.DriverProfile{
+		Id:             p.ID,
+		Name:           p.Name,
+		ProfilePicture: p.ProfilePicture,
+		Phone:          p.Phone,
+		LicenseNumber:  p.LicenseNumber,
+		Vehicles:       protoVehicles,
+		Rating:         p.Rating,
+	}
+}
+
+// NormalizePlate returns the canonical form of a plate used for uniqueness checks
+func NormalizePlate(plate string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(plate), ""))
+}
+
+// ProfileRepository stores driver profiles and their vehicles
+type ProfileRepository interface {
+	CreateProfile(ctx context.Context, profile *DriverProfile) error
+	GetProfile(ctx context.Context, driverID string) (*DriverProfile, error)
+	// CreateVehicle stores a vehicle and returns ErrPlateTaken if its plate is already registered
+	CreateVehicle(ctx context.Context, vehicle *Vehicle) error
+	GetVehicle(ctx context.Context, vehicleID string) (*Vehicle, error)
+	ListVehicles(ctx context.Context, driverID string) ([]*Vehicle, error)
+}
+
+// ProfileService defines the contract for managing driver profiles and vehicles
+type ProfileService interface {
+	// CreateProfile creates the profile of a new driver
+	CreateProfile(ctx context.Context, profile *DriverProfile) (*DriverProfile, error)
+
+	// GetProfile returns a driver's profile with their vehicles
+	GetProfile(ctx context.Context, driverID string) (*DriverProfile, []*Vehicle, error)
+
+	// AddVehicle registers a vehicle to an existing driver
+	AddVehicle(ctx context.Context, vehicle *Vehicle) (*Vehicle, error)
+
+	// ResolveForRegistration returns the profile and vehicle a driver goes online with.
+	// An empty vehicleID selects the driver's first vehicle eligible for the package.
+	ResolveForRegistration(ctx context.Context, driverID, vehicleID, packageSlug string) (*DriverProfile, *Vehicle, error)
+}
@@ -0,0 +1,197 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"ride-sharing/services/driver-service/internal/util"
+	sharedutil "ride-sharing/shared/util"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxPlateAttempts bounds how many random plates are tried when provisioning a vehicle
+const maxPlateAttempts = 10
+
+// avatarCount is the number of distinct avatars available from GetRandomAvatar
+const avatarCount = 10
+
+// defaultDriverRating is the rating of drivers who haven't been rated yet
+const defaultDriverRating = 5.0
+
+type profileService struct {
+	repo          ProfileRepository
+	autoProvision bool
+}
+
+// NewProfileService creates a new profile service. With autoProvision enabled, drivers
+// registering without a profile get a generated profile and vehicle, which keeps local
+// development working without any setup.
+func NewProfileService(repo ProfileRepository, autoProvision bool) ProfileService {
+	return &profileService{
+		repo:          repo,
+		autoProvision: autoProvision,
+	}
+}
+
+func (s *profileService) CreateProfile(ctx context.Context, profile *DriverProfile) (*DriverProfile, error) {
+	if strings.TrimSpace(profile.ID) == "" || strings.TrimSpace(profile.Name) == "" {
+		return nil, fmt.Errorf("%w: driver ID and name are required", ErrInvalidProfile)
+	}
+
+	profile.Rating = defaultDriverRating
+	profile.CreatedAt = time.Now()
+	if err := s.repo.CreateProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func (s *profileService) GetProfile(ctx context.Context, driverID string) (*DriverProfile, []*Vehicle, error) {
+	profile, err := s.repo.GetProfile(ctx, driverID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vehicles, err := s.repo.ListVehicles(ctx, driverID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return profile, vehicles, nil
+}
+
+func (s *profileService) AddVehicle(ctx context.Context, vehicle *Vehicle) (*Vehicle, error) {
+	if _, err := s.repo.GetProfile(ctx, vehicle.DriverID); err != nil {
+		return nil, err
+	}
+
+	vehicle.Plate = NormalizePlate(vehicle.Plate)
+	if vehicle.Plate == "" {
+		return nil, fmt.Errorf("%w: plate is required", ErrInvalidProfile)
+	}
+	if len(vehicle.PackageSlugs) == 0 {
+		return nil, fmt.Errorf("%w: at least one package is required", ErrInvalidProfile)
+	}
+	if vehicle.Seats <= 0 {
+		return nil, fmt.Errorf("%w: seats must be positive", ErrInvalidProfile)
+	}
+
+	vehicle.ID = uuid.NewString()
+	vehicle.CreatedAt = time.Now()
+	if err := s.repo.CreateVehicle(ctx, vehicle); err != nil {
+		return nil, err
+	}
+
+	return vehicle, nil
+}
+
+func (s *profileService) ResolveForRegistration(ctx context.Context, driverID, vehicleID, packageSlug string) (*DriverProfile, *Vehicle, error) {
+	profile, err := s.repo.GetProfile(ctx, driverID)
+	if errors.Is(err, ErrProfileNotFound) && s.autoProvision {
+		return s.provision(ctx, driverID, packageSlug)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vehicle, err := s.selectVehicle(ctx, driverID, vehicleID, packageSlug)
+	if errors.Is(err, ErrVehicleNotFound) && vehicleID == "" && s.autoProvision {
+		vehicle, err = s.provisionVehicle(ctx, driverID, packageSlug)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return profile, vehicle, nil
+}
+
+func (s *profileService) selectVehicle(ctx context.Context, driverID, vehicleID, packageSlug string) (*Vehicle, error) {
+	if vehicleID != "" {
+		vehicle, err := s.repo.GetVehicle(ctx, vehicleID)
+		if err != nil {
+			return nil, err
+		}
+		if vehicle.DriverID != driverID {
+			return nil, fmt.Errorf("%w: %s is not registered to driver %s", ErrVehicleNotFound, vehicleID, driverID)
+		}
+		if !vehicle.IsEligibleFor(packageSlug) {
+			return nil, fmt.Errorf("%w: %s for %s", ErrVehicleNotEligible, vehicleID, packageSlug)
+		}
+		return vehicle, nil
+	}
+
+	vehicles, err := s.repo.ListVehicles(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vehicle := range vehicles {
+		if vehicle.IsEligibleFor(packageSlug) {
+			return vehicle, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: driver %s has no vehicle for %s", ErrVehicleNotFound, driverID, packageSlug)
+}
+
+// provision creates a generated profile and vehicle for a driver registering for the first time
+func (s *profileService) provision(ctx context.Context, driverID, packageSlug string) (*DriverProfile, *Vehicle, error) {
+	profile := &DriverProfile{
+		ID:             driverID,
+		Name:           "Lando Norris",
+		ProfilePicture: sharedutil.GetRandomAvatar(rand.IntN(avatarCount)),
+		// Spread generated ratings so rating based matching has something to rank
+		Rating:    math.Round((4+rand.Float64())*10) / 10,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.repo.CreateProfile(ctx, profile); err != nil {
+		// Another connection of the same driver may have provisioned it concurrently
+		if !errors.Is(err, ErrProfileExists) {
+			return nil, nil, err
+		}
+		if profile, err = s.repo.GetProfile(ctx, driverID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	vehicle, err := s.selectVehicle(ctx, driverID, "", packageSlug)
+	if errors.Is(err, ErrVehicleNotFound) {
+		vehicle, err = s.provisionVehicle(ctx, driverID, packageSlug)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return profile, vehicle, nil
+}
+
+// provisionVehicle registers a generated vehicle, retrying until its random plate is unique
+func (s *profileService) provisionVehicle(ctx context.Context, driverID, packageSlug string) (*Vehicle, error) {
+	for range maxPlateAttempts {
+		vehicle := &Vehicle{
+			ID:           uuid.NewString(),
+			DriverID:     driverID,
+			Plate:        util.GenerateRandomPlate(),
+			Seats:        4,
+			PackageSlugs: []string{packageSlug},
+			CreatedAt:    time.Now(),
+		}
+
+		err := s.repo.CreateVehicle(ctx, vehicle)
+		if err == nil {
+			return vehicle, nil
+		}
+		if !errors.Is(err, ErrPlateTaken) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no free plate after %d attempts", ErrPlateTaken, maxPlateAttempts)
+}
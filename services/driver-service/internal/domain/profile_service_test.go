@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// stubProfileRepository holds a single profile and rejects the first takenPlates vehicles
+type stubProfileRepository struct {
+	ProfileRepository
+	profile     *DriverProfile
+	takenPlates int
+	attempts    int
+	vehicles    []*Vehicle
+}
+
+func (r *stubProfileRepository) CreateProfile(ctx context.Context, profile *DriverProfile) error {
+	r.profile = profile
+	return nil
+}
+
+func (r *stubProfileRepository) GetProfile(ctx context.Context, driverID string) (*DriverProfile, error) {
+	if r.profile == nil || r.profile.ID != driverID {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, driverID)
+	}
+	return r.profile, nil
+}
+
+func (r *stubProfileRepository) CreateVehicle(ctx context.Context, vehicle *Vehicle) error {
+	r.attempts++
+	if r.attempts <= r.takenPlates {
+		return fmt.Errorf("%w: %s", ErrPlateTaken, vehicle.Plate)
+	}
+	r.vehicles = append(r.vehicles, vehicle)
+	return nil
+}
+
+func (r *stubProfileRepository) ListVehicles(ctx context.Context, driverID string) ([]*Vehicle, error) {
+	return r.vehicles, nil
+}
+
+func TestNormalizePlate(t *testing.T) {
+	tests := []struct {
+		plate string
+		want  string
+	}{
+		{"ABC123", "ABC123"},
+		{"abc123", "ABC123"},
+		{" abc 123 ", "ABC123"},
+		{"ab\tc 1 23", "ABC123"},
+		{"   ", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizePlate(tt.plate); got != tt.want {
+			t.Errorf("NormalizePlate(%q) = %q, want %q", tt.plate, got, tt.want)
+		}
+	}
+}
+
+func TestAddVehicleNormalizesPlate(t *testing.T) {
+	repo := &stubProfileRepository{profile: &DriverProfile{ID: "driver-1"}}
+	s := NewProfileService(repo, false)
+
+	vehicle, err := s.AddVehicle(context.Background(), &Vehicle{DriverID: "driver-1", Plate: "abc 123", Seats: 4, PackageSlugs: []string{"sedan"}})
+	if err != nil {
+		t.Fatalf("AddVehicle() error = %v", err)
+	}
+	if vehicle.Plate != "ABC123" {
+		t.Errorf("plate = %q, want ABC123", vehicle.Plate)
+	}
+
+	if _, err := s.AddVehicle(context.Background(), &Vehicle{DriverID: "driver-1", Plate: " ", Seats: 4, PackageSlugs: []string{"sedan"}}); !errors.Is(err, ErrInvalidProfile) {
+		t.Errorf("AddVehicle() of a blank plate = %v, want ErrInvalidProfile", err)
+	}
+}
+
+func TestProvisionVehicleRetriesTakenPlates(t *testing.T) {
+	tests := []struct {
+		name         string
+		takenPlates  int
+		wantErr      error
+		wantAttempts int
+	}{
+		{"first plate free", 0, nil, 1},
+		{"free after retries", maxPlateAttempts - 1, nil, maxPlateAttempts},
+		{"no free plate", maxPlateAttempts, ErrPlateTaken, maxPlateAttempts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubProfileRepository{takenPlates: tt.takenPlates}
+			s := NewProfileService(repo, true)
+
+			_, vehicle, err := s.ResolveForRegistration(context.Background(), "driver-1", "", "sedan")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResolveForRegistration() error = %v, want %v", err, tt.wantErr)
+			}
+			if repo.attempts != tt.wantAttempts {
+				t.Errorf("%d plates tried, want %d", repo.attempts, tt.wantAttempts)
+			}
+			if err == nil && !vehicle.IsEligibleFor("sedan") {
+				t.Errorf("provisioned vehicle %+v isn't eligible for sedan", vehicle)
+			}
+		})
+	}
+}
@@ -4,44 +4,69 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math/rand"
 	math "math/rand/v2"
-	"ride-sharing/services/driver-service/internal/util"
 	"ride-sharing/shared/messaging"
 	pb "ride-sharing/shared/proto/driver"
 	sharedutil "ride-sharing/shared/util"
 	"slices"
 	"sync"
+	"time"
 
 	"github.com/mmcloughlin/geohash"
+	"google.golang.org/protobuf/proto"
 )
 
+// offerTimeout is how long an unanswered offer keeps a driver from being matched again
+const offerTimeout = 30 * time.Second
+
 type driverService struct {
-	drivers []*driverInMap
-	mu      sync.Mutex
+	profiles ProfileService
+	dispatch DispatchPolicy
+	matching MatchingPolicy
+	drivers  []*driverInMap
+	mu       sync.Mutex
+	watchers *watcherRegistry
+	// offline remembers when unregistered drivers went offline
+	offline map[string]time.Time
+	// lastAssigned remembers when unregistered drivers were last assigned a trip, so
+	// registering again doesn't put them first in line for least_recent matching
+	lastAssigned map[string]time.Time
 }
 
 type driverInMap struct {
-	Driver *pb.Driver
+	Driver       *pb.Driver
+	Availability DriverAvailability
+	// LastAssignedAt is when the driver was last assigned a trip
+	LastAssignedAt time.Time
+	// Disconnected is set while the driver's connection is down
+	Disconnected bool
 }
 
 // NewDriverService creates a new driver service instance
-func NewDriverService() DriverService {
+func NewDriverService(profiles ProfileService, dispatch DispatchPolicy, matching MatchingPolicy) DriverService {
 	return &driverService{
-		drivers: make([]*driverInMap, 0),
+		profiles:     profiles,
+		dispatch:     dispatch,
+		matching:     matching,
+		drivers:      make([]*driverInMap, 0),
+		watchers:     newWatcherRegistry(),
+		offline:      make(map[string]time.Time),
+		lastAssigned: make(map[string]time.Time),
 	}
 }
 
-func (s *driverService) RegisterDriver(driverID string, packageSlug string) (*pb.Driver, error) {
+func (s *driverService) RegisterDriver(ctx context.Context, driverID, packageSlug, vehicleID string) (*pb.Driver, error) {
+	profile, vehicle, err := s.profiles.ResolveForRegistration(ctx, driverID, vehicleID, packageSlug)
+	if err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	randomIndex := math.IntN(len(PredefinedRoutes))
 	randomRoute := PredefinedRoutes[randomIndex]
 
-	randomPlate := util.GenerateRandomPlate()
-	randomAvatar := sharedutil.GetRandomAvatar(randomIndex)
-
 	// we can ignore this property for now, but it must be sent to the frontend.
 	geohashVal := geohash.Encode(randomRoute[0][0], randomRoute[0][1])
 
@@ -49,17 +74,43 @@ func (s *driverService) RegisterDriver(driverID string, packageSlug string) (*pb
 		Id:             driverID,
 		Geohash:        geohashVal,
 		Location:       &pb.Location{Latitude: randomRoute[0][0], Longitude: randomRoute[0][1]},
-		Name:           "Lando Norris",
+		Name:           profile.Name,
 		PackageSlug:    packageSlug,
-		ProfilePicture: randomAvatar,
-		CarPlate:       randomPlate,
+		ProfilePicture: profile.ProfilePicture,
+		CarPlate:       vehicle.Plate,
+		VehicleID:      vehicle.ID,
+		Rating:         profile.Rating,
+	}
+
+	// A driver registering again replaces their previous entry but keeps its
+	// position and availability, so a reconnect doesn't reset an ongoing trip
+	for _, existing := range s.drivers {
+		if existing.Driver.Id == driverID {
+			driver.Location = existing.Driver.Location
+			driver.Geohash = existing.Driver.Geohash
+			driver.Heading = existing.Driver.Heading
+			previous := existing.Driver
+			existing.Driver = driver
+			existing.Disconnected = false
+			s.watchers.notify(previous, driver)
+			return proto.Clone(driver).(*pb.Driver), nil
+		}
 	}
 
 	s.drivers = append(s.drivers, &driverInMap{
 		Driver: driver,
+		Availability: DriverAvailability{
+			DriverID: driverID,
+			State:    AvailabilityOnline,
+			Since:    time.Now(),
+		},
+		LastAssignedAt: s.lastAssigned[driverID],
 	})
+	delete(s.offline, driverID)
+	delete(s.lastAssigned, driverID)
+	s.watchers.notify(nil, driver)
 
-	return driver, nil
+	return proto.Clone(driver).(*pb.Driver), nil
 }
 
 func (s *driverService) UnregisterDriver(driverID string) error {
@@ -69,6 +120,11 @@ func (s *driverService) UnregisterDriver(driverID string) error {
 	for i, driver := range s.drivers {
 		if driver.Driver.Id == driverID {
 			s.drivers = slices.Delete(s.drivers, i, i+1)
+			s.offline[driverID] = time.Now()
+			if !driver.LastAssignedAt.IsZero() {
+				s.lastAssigned[driverID] = driver.LastAssignedAt
+			}
+			s.watchers.notify(driver.Driver, nil)
 			return nil
 		}
 	}
@@ -76,39 +132,384 @@ func (s *driverService) UnregisterDriver(driverID string) error {
 	return fmt.Errorf("%w: %s", ErrDriverNotFound, driverID)
 }
 
+func (s *driverService) GetDriver(driverID string) (*pb.Driver, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, driver := range s.drivers {
+		if driver.Driver.Id == driverID {
+			return proto.Clone(driver.Driver).(*pb.Driver), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrDriverNotFound, driverID)
+}
+
+func (s *driverService) UpdateDriverLocation(driverID string, location *pb.Location) (*pb.Driver, error) {
+	if !isValidLocation(location) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLocation, location)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, driver := range s.drivers {
+		if driver.Driver.Id == driverID {
+			previous := proto.Clone(driver.Driver).(*pb.Driver)
+
+			// Keep the previous heading when the driver is standing still
+			if prev := driver.Driver.Location; prev != nil &&
+				(prev.Latitude != location.Latitude || prev.Longitude != location.Longitude) {
+				driver.Driver.Heading = sharedutil.Bearing(prev.Latitude, prev.Longitude, location.Latitude, location.Longitude)
+			}
+
+			driver.Driver.Location = &pb.Location{
+				Latitude:  location.Latitude,
+				Longitude: location.Longitude,
+			}
+			driver.Driver.Geohash = geohash.Encode(location.Latitude, location.Longitude)
+			s.watchers.notify(previous, driver.Driver)
+			return proto.Clone(driver.Driver).(*pb.Driver), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrDriverNotFound, driverID)
+}
+
+func (s *driverService) WatchDrivers(filter DriverFilter) ([]*pb.Driver, <-chan DriverUpdate, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Registering while holding the lock guarantees no update is missed
+	// between the snapshot and the first streamed update
+	w := s.watchers.add(filter)
+
+	var snapshot []*pb.Driver
+	for _, driver := range s.drivers {
+		if filter.Matches(driver.Driver) {
+			snapshot = append(snapshot, proto.Clone(driver.Driver).(*pb.Driver))
+		}
+	}
+
+	return snapshot, w.updates, func() { s.watchers.remove(w) }
+}
+
+func isValidLocation(location *pb.Location) bool {
+	if location == nil {
+		return false
+	}
+
+	return location.Latitude >= -90 && location.Latitude <= 90 &&
+		location.Longitude >= -180 && location.Longitude <= 180
+}
+
 func (s *driverService) ProcessTripCreatedEvent(ctx context.Context, tripID, userID string) error {
 	log.Printf("Processing trip %s for user %s", tripID, userID)
 	return nil
 }
 
-func (s *driverService) FindAndNotifyDrivers(ctx context.Context, tripEvent messaging.TripCreatedEvent) (string, error) {
-	suitableDrivers := s.findAvailableDrivers(tripEvent.Trip.SelectedFare.PackageSlug)
+func (s *driverService) FindAndNotifyDrivers(ctx context.Context, tripEvent messaging.TripCreatedEvent) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	packageSlug := tripEvent.Trip.GetSelectedFare().GetPackageSlug()
+	suitableDrivers := s.findAvailableDrivers(packageSlug)
 	log.Printf("found suitable drivers: %v", len(suitableDrivers))
 
 	if len(suitableDrivers) == 0 {
-		return "", fmt.Errorf("no suitable drivers found")
+		return nil, fmt.Errorf("no suitable drivers found")
+	}
+
+	config := s.dispatch.ForPackage(packageSlug)
+	req := MatchRequest{
+		PackageSlug: packageSlug,
+		Pickup:      tripPickup(tripEvent),
+	}
+
+	strategy := s.matching.For(req)
+	if strategy == nil {
+		strategy = defaultMatchingStrategy(config)
+	}
+
+	ranked := strategy.Rank(req, matchCandidates(suitableDrivers))
+	if len(ranked) == 0 {
+		return nil, fmt.Errorf("no suitable drivers found: all %d excluded by matching strategy", len(suitableDrivers))
 	}
+	ranked = ranked[:min(config.offerCount(), len(ranked))]
 
-	randomIndex := rand.Intn(len(suitableDrivers))
+	// Marking the drivers as offered under the same lock prevents a concurrent
+	// trip from being offered to the same drivers
+	driverIDs := make([]string, 0, len(ranked))
+	for _, candidate := range ranked {
+		candidate.entry.setAvailability(AvailabilityOffered, tripEvent.Trip.Id)
+		driverIDs = append(driverIDs, candidate.entry.Driver.Id)
+	}
 
-	return suitableDrivers[randomIndex], nil
+	return driverIDs, nil
 }
 
-func (s *driverService) findAvailableDrivers(packageType string) []string {
+// defaultMatchingStrategy is used when the matching policy has no rule for a trip.
+// Broadcasts go to the nearest drivers, single offers to a random one.
+func defaultMatchingStrategy(config DispatchConfig) MatchingStrategy {
+	if config.Mode == DispatchModeBroadcast {
+		return NewNearestStrategy()
+	}
+	return NewRandomStrategy()
+}
+
+// matchCandidates wraps drivers for a matching strategy
+func matchCandidates(drivers []*driverInMap) []MatchCandidate {
+	candidates := make([]MatchCandidate, len(drivers))
+	for i, driver := range drivers {
+		candidates[i] = MatchCandidate{
+			Driver:         driver.Driver,
+			LastAssignedAt: driver.LastAssignedAt,
+			entry:          driver,
+		}
+	}
+	return candidates
+}
+
+func (s *driverService) DispatchConfigFor(packageSlug string) DispatchConfig {
+	return s.dispatch.ForPackage(packageSlug)
+}
+
+const (
+	// rankPenalty is the batch cost of each place a driver ranks below the first in a
+	// trip's matching rule. It exceeds any pickup ETA, so the rule decides and the
+	// ETA only chooses between assignments that rank equally well.
+	rankPenalty = 2 * unknownPickupETA
+	// excludedCost is the batch cost of a driver the trip's matching rule drops. It
+	// exceeds the cost of any ranked driver, so excluded pairs are only left in the
+	// assignment when a trip has no other driver, and are then not assigned.
+	excludedCost = 1e12
+)
+
+// batchCosts returns the cost of each driver for a trip in batch mode: the pickup ETA,
+// plus a penalty by rank for trips with a matching rule, or excludedCost for drivers
+// the rule drops. The caller must hold s.mu.
+func (s *driverService) batchCosts(trip messaging.TripCreatedEvent, drivers []*driverInMap) []float64 {
+	req := MatchRequest{
+		PackageSlug: trip.Trip.GetSelectedFare().GetPackageSlug(),
+		Pickup:      tripPickup(trip),
+	}
+
+	costs := make([]float64, len(drivers))
+	for j, driver := range drivers {
+		costs[j] = PickupETASeconds(driver.Driver.Location, req.Pickup)
+	}
+
+	strategy := s.matching.For(req)
+	if strategy == nil {
+		return costs
+	}
+
+	rank := make(map[*driverInMap]int, len(drivers))
+	for position, candidate := range strategy.Rank(req, matchCandidates(drivers)) {
+		rank[candidate.entry] = position
+	}
+	for j, driver := range drivers {
+		position, ok := rank[driver]
+		if !ok {
+			costs[j] = excludedCost
+			continue
+		}
+		costs[j] += float64(position) * rankPenalty
+	}
+	return costs
+}
+
+func (s *driverService) AssignTrips(ctx context.Context, trips []messaging.TripCreatedEvent) map[string]string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var matchingDrivers []string
+	// Drivers only serve their own package, so each package is matched on its own
+	byPackage := make(map[string][]messaging.TripCreatedEvent)
+	for _, trip := range trips {
+		packageSlug := trip.Trip.GetSelectedFare().GetPackageSlug()
+		byPackage[packageSlug] = append(byPackage[packageSlug], trip)
+	}
 
-	for _, driver := range s.drivers {
-		if driver.Driver.PackageSlug == packageType {
-			matchingDrivers = append(matchingDrivers, driver.Driver.Id)
+	assigned := make(map[string]string)
+	for packageSlug, packageTrips := range byPackage {
+		drivers := s.findAvailableDrivers(packageSlug)
+		if len(drivers) == 0 {
+			continue
+		}
+
+		cost := make([][]float64, len(packageTrips))
+		for i, trip := range packageTrips {
+			cost[i] = s.batchCosts(trip, drivers)
+		}
+
+		matched := 0
+		for i, j := range MinCostAssignment(cost) {
+			// Trips left with a driver their matching rule excludes get none
+			if j < 0 || cost[i][j] >= excludedCost {
+				continue
+			}
+			tripID := packageTrips[i].Trip.Id
+			drivers[j].setAvailability(AvailabilityOffered, tripID)
+			assigned[tripID] = drivers[j].Driver.Id
+			matched++
+		}
+
+		log.Printf("batch matched %d of %d %s trips with %d drivers", matched, len(packageTrips), packageSlug, len(drivers))
+	}
+
+	return assigned
+}
+
+// tripPickup returns where the rider is picked up, the first point of the trip's
+// route for trips that don't carry their pickup
+func tripPickup(tripEvent messaging.TripCreatedEvent) *pb.Location {
+	if pickup := tripEvent.Trip.GetPickup(); pickup != nil {
+		return &pb.Location{
+			Latitude:  pickup.Latitude,
+			Longitude: pickup.Longitude,
 		}
 	}
 
-	if len(matchingDrivers) == 0 {
-		return []string{}
+	route := tripEvent.Trip.GetRoute()
+	if route == nil || len(route.Geometry) == 0 || len(route.Geometry[0].Coordinates) == 0 {
+		return nil
+	}
+
+	// Route coordinates keep OSRM's [longitude, latitude] order, so the
+	// latitude field holds the longitude
+	start := route.Geometry[0].Coordinates[0]
+	return &pb.Location{
+		Latitude:  start.Longitude,
+		Longitude: start.Latitude,
+	}
+}
+
+// findAvailableDrivers returns the online drivers of a package. The caller must hold s.mu.
+func (s *driverService) findAvailableDrivers(packageType string) []*driverInMap {
+	var matchingDrivers []*driverInMap
+
+	for _, driver := range s.drivers {
+		if driver.Driver.PackageSlug == packageType && driver.isAvailable(time.Now()) {
+			matchingDrivers = append(matchingDrivers, driver)
+		}
 	}
 
 	return matchingDrivers
 }
+
+func (s *driverService) GetAvailability(driverID string) (*DriverAvailability, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, driver := range s.drivers {
+		if driver.Driver.Id == driverID {
+			availability := driver.currentAvailability(time.Now())
+			availability.Connected = !driver.Disconnected
+			return &availability, nil
+		}
+	}
+
+	if since, ok := s.offline[driverID]; ok {
+		return &DriverAvailability{
+			DriverID: driverID,
+			State:    AvailabilityOffline,
+			Since:    since,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrDriverNotFound, driverID)
+}
+
+func (s *driverService) UpdateAvailability(driverID string, state Availability, tripID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, driver := range s.drivers {
+		if driver.Driver.Id != driverID {
+			continue
+		}
+
+		current := driver.Availability.State
+		if current == state && driver.Availability.TripID == tripID {
+			// Redelivered events are harmless
+			return nil
+		}
+
+		// Events about a trip the driver no longer works on are stale
+		if driver.Availability.TripID != "" && tripID != "" && driver.Availability.TripID != tripID {
+			return fmt.Errorf("%w: driver %s is %s for trip %s, not %s", ErrInvalidTransition, driverID, current, driver.Availability.TripID, tripID)
+		}
+
+		if !current.CanTransitionTo(state) {
+			return fmt.Errorf("%w: driver %s from %s to %s", ErrInvalidTransition, driverID, current, state)
+		}
+
+		driver.setAvailability(state, tripID)
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrDriverNotFound, driverID)
+}
+
+func (s *driverService) SetConnected(driverID string, connected bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, driver := range s.drivers {
+		if driver.Driver.Id == driverID {
+			driver.Disconnected = !connected
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrDriverNotFound, driverID)
+}
+
+// setAvailability moves the driver to a new state. The caller must hold s.mu.
+func (d *driverInMap) setAvailability(state Availability, tripID string) {
+	if state == AvailabilityOnline || state == AvailabilityOffline {
+		tripID = ""
+	}
+
+	d.Availability = DriverAvailability{
+		DriverID: d.Driver.Id,
+		State:    state,
+		Since:    time.Now(),
+		TripID:   tripID,
+	}
+
+	if state == AvailabilityEnRouteToPickup {
+		d.LastAssignedAt = d.Availability.Since
+	}
+}
+
+// currentAvailability returns the driver's availability, with an offer that was
+// never answered reported as online again once it expired
+func (d *driverInMap) currentAvailability(now time.Time) DriverAvailability {
+	if d.Availability.State == AvailabilityOffered && now.Sub(d.Availability.Since) > offerTimeout {
+		return DriverAvailability{
+			DriverID: d.Driver.Id,
+			State:    AvailabilityOnline,
+			Since:    d.Availability.Since.Add(offerTimeout),
+		}
+	}
+	return d.Availability
+}
+
+// isAvailable reports whether the driver can be offered a trip. Offers that
+// were never answered expire after offerTimeout, and disconnected drivers wait
+// until they are back.
+func (d *driverInMap) isAvailable(now time.Time) bool {
+	if d.Disconnected {
+		return false
+	}
+
+	switch d.Availability.State {
+	case AvailabilityOnline:
+		return true
+	case AvailabilityOffered:
+		return now.Sub(d.Availability.Since) > offerTimeout
+	default:
+		return false
+	}
+}
@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"log"
+	pb "ride-sharing/shared/proto/driver"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// watcherBufferSize bounds how many updates a slow watcher may fall behind before its stream is closed
+const watcherBufferSize = 64
+
+// BoundingBox is a rectangular area delimited by its south-west and north-east corners
+type BoundingBox struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// Contains reports whether the location lies within the box
+func (b *BoundingBox) Contains(location *pb.Location) bool {
+	if location == nil {
+		return false
+	}
+
+	return location.Latitude >= b.MinLatitude && location.Latitude <= b.MaxLatitude &&
+		location.Longitude >= b.MinLongitude && location.Longitude <= b.MaxLongitude
+}
+
+// DriverFilter selects which drivers a watcher is interested in. Empty fields match every driver.
+type DriverFilter struct {
+	PackageSlug   string
+	GeohashPrefix string
+	BoundingBox   *BoundingBox
+}
+
+// Matches reports whether the driver satisfies every criteria of the filter
+func (f DriverFilter) Matches(driver *pb.Driver) bool {
+	if f.PackageSlug != "" && driver.PackageSlug != f.PackageSlug {
+		return false
+	}
+
+	if f.GeohashPrefix != "" && !strings.HasPrefix(driver.Geohash, f.GeohashPrefix) {
+		return false
+	}
+
+	if f.BoundingBox != nil && !f.BoundingBox.Contains(driver.Location) {
+		return false
+	}
+
+	return true
+}
+
+// DriverUpdate is delivered to watchers when a driver moves or goes offline
+type DriverUpdate struct {
+	Driver  *pb.Driver
+	Removed bool
+}
+
+type watcher struct {
+	filter  DriverFilter
+	updates chan DriverUpdate
+}
+
+// watcherRegistry fans driver updates out to every interested watcher
+type watcherRegistry struct {
+	watchers map[*watcher]struct{}
+	mu       sync.RWMutex
+}
+
+func newWatcherRegistry() *watcherRegistry {
+	return &watcherRegistry{
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+func (r *watcherRegistry) add(filter DriverFilter) *watcher {
+	w := &watcher{
+		filter:  filter,
+		updates: make(chan DriverUpdate, watcherBufferSize),
+	}
+
+	r.mu.Lock()
+	r.watchers[w] = struct{}{}
+	r.mu.Unlock()
+
+	return w
+}
+
+func (r *watcherRegistry) remove(w *watcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.watchers[w]; ok {
+		delete(r.watchers, w)
+		close(w.updates)
+	}
+}
+
+// notify delivers the change of a driver from previous to current. previous is nil
+// for drivers that just registered, current for drivers that went offline. Watchers
+// get a Removed update when the driver leaves their filter, so they can drop it.
+//
+// Updates are sent without blocking. A watcher that fell behind is closed instead
+// of missing updates, its client has to watch again to get a fresh snapshot.
+func (r *watcherRegistry) notify(previous, current *pb.Driver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for w := range r.watchers {
+		update, ok := w.update(previous, current)
+		if !ok {
+			continue
+		}
+
+		select {
+		case w.updates <- update:
+		default:
+			log.Printf("Closing slow watcher, it missed the update of driver %s", update.Driver.Id)
+			delete(r.watchers, w)
+			close(w.updates)
+		}
+	}
+}
+
+// update returns the update the watcher gets for the change of a driver, if any
+func (w *watcher) update(previous, current *pb.Driver) (DriverUpdate, bool) {
+	if current != nil && w.filter.Matches(current) {
+		return DriverUpdate{Driver: proto.Clone(current).(*pb.Driver)}, true
+	}
+
+	if previous != nil && w.filter.Matches(previous) {
+		last := current
+		if last == nil {
+			last = previous
+		}
+		return DriverUpdate{Driver: proto.Clone(last).(*pb.Driver), Removed: true}, true
+	}
+
+	return DriverUpdate{}, false
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	pb "ride-sharing/shared/proto/driver"
+	"testing"
+)
+
+func driverAt(id string, latitude, longitude float64) *pb.Driver {
+	return &pb.Driver{Id: id, Location: &pb.Location{Latitude: latitude, Longitude: longitude}}
+}
+
+func TestWatcherRegistryNotify(t *testing.T) {
+	box := DriverFilter{BoundingBox: &BoundingBox{MinLatitude: 0, MinLongitude: 0, MaxLatitude: 1, MaxLongitude: 1}}
+	inside, outside := driverAt("d1", 0.5, 0.5), driverAt("d1", 2, 2)
+
+	tests := []struct {
+		name              string
+		previous, current *pb.Driver
+		wantUpdate        bool
+		wantRemoved       bool
+	}{
+		{"registers inside", nil, inside, true, false},
+		{"registers outside", nil, outside, false, false},
+		{"moves inside", inside, inside, true, false},
+		{"enters", outside, inside, true, false},
+		{"leaves", inside, outside, true, true},
+		{"moves outside", outside, outside, false, false},
+		{"goes offline inside", inside, nil, true, true},
+		{"goes offline outside", outside, nil, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newWatcherRegistry()
+			w := r.add(box)
+
+			r.notify(tt.previous, tt.current)
+
+			select {
+			case update := <-w.updates:
+				if !tt.wantUpdate {
+					t.Fatalf("got update %+v, want none", update)
+				}
+				if update.Removed != tt.wantRemoved {
+					t.Errorf("Removed = %v, want %v", update.Removed, tt.wantRemoved)
+				}
+				if update.Driver.Id != "d1" {
+					t.Errorf("Driver.Id = %q, want d1", update.Driver.Id)
+				}
+			default:
+				if tt.wantUpdate {
+					t.Fatal("got no update")
+				}
+			}
+		})
+	}
+}
+
+func TestWatcherRegistryClosesSlowWatchers(t *testing.T) {
+	r := newWatcherRegistry()
+	slow := r.add(DriverFilter{})
+
+	for range watcherBufferSize + 1 {
+		r.notify(nil, driverAt("d1", 0.5, 0.5))
+	}
+
+	received := 0
+	for range slow.updates {
+		received++
+	}
+	if received != watcherBufferSize {
+		t.Errorf("received %d updates before the close, want %d", received, watcherBufferSize)
+	}
+	if len(r.watchers) != 0 {
+		t.Errorf("slow watcher still registered")
+	}
+
+	// Stopping a closed watcher must not close its channel twice
+	r.remove(slow)
+}
@@ -14,21 +14,29 @@ import (
 
 type driverHandler struct {
 	pb.UnimplementedDriverServiceServer
-	service domain.DriverService
+	service   domain.DriverService
+	profiles  domain.ProfileService
+	publisher domain.DriverEventPublisher
 }
 
 // NewDriverHandler creates and registers a new driver gRPC handler
-func NewDriverHandler(s *grpc.Server, service domain.DriverService) {
+func NewDriverHandler(s *grpc.Server, service domain.DriverService, profiles domain.ProfileService, publisher domain.DriverEventPublisher) {
 	handler := &driverHandler{
-		service: service,
+		service:   service,
+		profiles:  profiles,
+		publisher: publisher,
 	}
 
 	pb.RegisterDriverServiceServer(s, handler)
 }
 
 func (h *driverHandler) RegisterDriver(ctx context.Context, req *pb.RegisterDriverRequest) (*pb.RegisterDriverResponse, error) {
-	driver, err := h.service.RegisterDriver(req.GetDriverID(), req.GetPackageSlug())
+	driver, err := h.service.RegisterDriver(ctx, req.GetDriverID(), req.GetPackageSlug(), req.GetVehicleID())
 	if err != nil {
+		if st, ok := profileErrorStatus(err); ok {
+			return nil, st.Err()
+		}
+
 		log.Printf("Failed to register driver: %v", err)
 		return nil, status.Errorf(codes.Internal, "failed to register driver: %v", err)
 	}
@@ -38,6 +46,58 @@ func (h *driverHandler) RegisterDriver(ctx context.Context, req *pb.RegisterDriv
 	}, nil
 }
 
+func (h *driverHandler) GetDriver(ctx context.Context, req *pb.GetDriverRequest) (*pb.GetDriverResponse, error) {
+	driver, err := h.service.GetDriver(req.GetDriverID())
+	if err != nil {
+		if errors.Is(err, domain.ErrDriverNotFound) {
+			return nil, status.Errorf(codes.NotFound, "driver not found: %s", req.GetDriverID())
+		}
+
+		log.Printf("Failed to get driver: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to get driver: %v", err)
+	}
+
+	return &pb.GetDriverResponse{
+		Driver: driver,
+	}, nil
+}
+
+func (h *driverHandler) GetDriverAvailability(ctx context.Context, req *pb.GetDriverRequest) (*pb.GetDriverAvailabilityResponse, error) {
+	availability, err := h.service.GetAvailability(req.GetDriverID())
+	if err != nil {
+		if errors.Is(err, domain.ErrDriverNotFound) {
+			return nil, status.Errorf(codes.NotFound, "driver not found: %s", req.GetDriverID())
+		}
+
+		log.Printf("Failed to get driver availability: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to get driver availability: %v", err)
+	}
+
+	return &pb.GetDriverAvailabilityResponse{
+		DriverID:        availability.DriverID,
+		Availability:    availability.State.ToProto(),
+		SinceUnixMillis: availability.Since.UnixMilli(),
+		TripID:          availability.TripID,
+		Connected:       availability.Connected,
+	}, nil
+}
+
+func (h *driverHandler) UpdateDriverConnection(ctx context.Context, req *pb.UpdateDriverConnectionRequest) (*pb.UpdateDriverConnectionResponse, error) {
+	if err := h.service.SetConnected(req.GetDriverID(), req.GetConnected()); err != nil {
+		if errors.Is(err, domain.ErrDriverNotFound) {
+			return nil, status.Errorf(codes.NotFound, "driver not found: %s", req.GetDriverID())
+		}
+
+		log.Printf("Failed to update driver connection: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to update driver connection: %v", err)
+	}
+
+	return &pb.UpdateDriverConnectionResponse{
+		DriverID:  req.GetDriverID(),
+		Connected: req.GetConnected(),
+	}, nil
+}
+
 func (h *driverHandler) UnregisterDriver(ctx context.Context, req *pb.RegisterDriverRequest) (*pb.RegisterDriverResponse, error) {
 	err := h.service.UnregisterDriver(req.GetDriverID())
 	if err != nil {
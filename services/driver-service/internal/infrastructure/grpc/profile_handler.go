@@ -0,0 +1,83 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"ride-sharing/services/driver-service/internal/domain"
+	pb "ride-sharing/shared/proto/driver"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (h *driverHandler) CreateDriverProfile(ctx context.Context, req *pb.CreateDriverProfileRequest) (*pb.DriverProfileResponse, error) {
+	profile, err := h.profiles.CreateProfile(ctx, &domain.DriverProfile{
+		ID:             req.GetDriverID(),
+		Name:           req.GetName(),
+		ProfilePicture: req.GetProfilePicture(),
+		Phone:          req.GetPhone(),
+		LicenseNumber:  req.GetLicenseNumber(),
+	})
+	if err != nil {
+		return nil, toProfileStatusError("failed to create driver profile", err)
+	}
+
+	return &pb.DriverProfileResponse{
+		Profile: profile.ToProto(nil),
+	}, nil
+}
+
+func (h *driverHandler) GetDriverProfile(ctx context.Context, req *pb.GetDriverRequest) (*pb.DriverProfileResponse, error) {
+	profile, vehicles, err := h.profiles.GetProfile(ctx, req.GetDriverID())
+	if err != nil {
+		return nil, toProfileStatusError("failed to get driver profile", err)
+	}
+
+	return &pb.DriverProfileResponse{
+		Profile: profile.ToProto(vehicles),
+	}, nil
+}
+
+func (h *driverHandler) AddVehicle(ctx context.Context, req *pb.AddVehicleRequest) (*pb.VehicleResponse, error) {
+	vehicle, err := h.profiles.AddVehicle(ctx, &domain.Vehicle{
+		DriverID:     req.GetDriverID(),
+		Plate:        req.GetPlate(),
+		Make:         req.GetMake(),
+		Model:        req.GetModel(),
+		Color:        req.GetColor(),
+		Seats:        req.GetSeats(),
+		PackageSlugs: req.GetPackageSlugs(),
+	})
+	if err != nil {
+		return nil, toProfileStatusError("failed to add vehicle", err)
+	}
+
+	return &pb.VehicleResponse{
+		Vehicle: vehicle.ToProto(),
+	}, nil
+}
+
+// profileErrorStatus maps profile domain errors to gRPC statuses
+func profileErrorStatus(err error) (*status.Status, bool) {
+	switch {
+	case errors.Is(err, domain.ErrProfileNotFound), errors.Is(err, domain.ErrVehicleNotFound):
+		return status.New(codes.NotFound, err.Error()), true
+	case errors.Is(err, domain.ErrProfileExists), errors.Is(err, domain.ErrPlateTaken):
+		return status.New(codes.AlreadyExists, err.Error()), true
+	case errors.Is(err, domain.ErrVehicleNotEligible):
+		return status.New(codes.FailedPrecondition, err.Error()), true
+	case errors.Is(err, domain.ErrInvalidProfile):
+		return status.New(codes.InvalidArgument, err.Error()), true
+	}
+	return nil, false
+}
+
+func toProfileStatusError(msg string, err error) error {
+	if st, ok := profileErrorStatus(err); ok {
+		return st.Err()
+	}
+
+	log.Printf("%s: %v", msg, err)
+	return status.Errorf(codes.Internal, "%s: %v", msg, err)
+}
@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"ride-sharing/services/driver-service/internal/domain"
+	"ride-sharing/shared/grpcserver"
+	pb "ride-sharing/shared/proto/driver"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReportLocation accepts a stream of driver positions and applies each one to the driver index.
+// A driver's stream is bound to the driver, internal callers and admins report for anyone.
+func (h *driverHandler) ReportLocation(stream pb.DriverService_ReportLocationServer) error {
+	boundDriverID, err := streamDriverID(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	var accepted, rejected int64
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&pb.ReportLocationResponse{
+				Accepted: accepted,
+				Rejected: rejected,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		if boundDriverID != "" && req.GetDriverID() != boundDriverID {
+			return status.Errorf(codes.PermissionDenied, "stream is bound to driver %s", boundDriverID)
+		}
+
+		driver, err := h.service.UpdateDriverLocation(req.GetDriverID(), req.GetLocation())
+		if err != nil {
+			// Unknown drivers and invalid positions are counted, not fatal to the stream
+			if errors.Is(err, domain.ErrDriverNotFound) || errors.Is(err, domain.ErrInvalidLocation) {
+				rejected++
+				continue
+			}
+
+			log.Printf("Failed to update driver location: %v", err)
+			return status.Errorf(codes.Internal, "failed to update driver location: %v", err)
+		}
+		accepted++
+
+		if err := h.publisher.PublishLocationUpdated(stream.Context(), driver); err != nil {
+			log.Printf("Failed to publish location of driver %s: %v", driver.Id, err)
+		}
+	}
+}
+
+// streamDriverID returns the driver a location stream is bound to, or "" when the
+// caller may report for every driver
+func streamDriverID(ctx context.Context) (string, error) {
+	principal, ok := grpcserver.PrincipalFrom(ctx)
+	if !ok || principal.Role == grpcserver.RoleAdmin {
+		return "", nil
+	}
+	if principal.Role != grpcserver.RoleDriver {
+		return "", status.Error(codes.PermissionDenied, "only drivers report locations")
+	}
+	return principal.UserID, nil
+}
+
+// WatchDrivers streams the drivers matching the request filter, starting with a snapshot
+// of the current matches followed by every subsequent move or removal. Watchers that
+// fall behind get ABORTED and should watch again.
+func (h *driverHandler) WatchDrivers(req *pb.WatchDriversRequest, stream pb.DriverService_WatchDriversServer) error {
+	filter, err := toDriverFilter(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	snapshot, updates, stop := h.service.WatchDrivers(filter)
+	defer stop()
+
+	for _, driver := range snapshot {
+		if err := stream.Send(&pb.WatchDriversResponse{Driver: driver}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case update, ok := <-updates:
+			if !ok {
+				// The watcher fell behind and was closed, the client has to watch again
+				return status.Error(codes.Aborted, "watcher fell behind, watch again for a fresh snapshot")
+			}
+
+			if err := stream.Send(&pb.WatchDriversResponse{
+				Driver:  update.Driver,
+				Removed: update.Removed,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toDriverFilter(req *pb.WatchDriversRequest) (domain.DriverFilter, error) {
+	filter := domain.DriverFilter{
+		PackageSlug:   req.GetPackageSlug(),
+		GeohashPrefix: req.GetGeohashPrefix(),
+	}
+
+	if box := req.GetBoundingBox(); box != nil {
+		sw, ne := box.GetSouthWest(), box.GetNorthEast()
+		if sw == nil || ne == nil {
+			return filter, errors.New("bounding box requires both southWest and northEast corners")
+		}
+		if sw.Latitude > ne.Latitude || sw.Longitude > ne.Longitude {
+			return filter, errors.New("bounding box southWest corner must be below and left of northEast corner")
+		}
+
+		filter.BoundingBox = &domain.BoundingBox{
+			MinLatitude:  sw.Latitude,
+			MinLongitude: sw.Longitude,
+			MaxLatitude:  ne.Latitude,
+			MaxLongitude: ne.Longitude,
+		}
+	}
+
+	return filter, nil
+}
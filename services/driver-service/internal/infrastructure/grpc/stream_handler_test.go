@@ -0,0 +1,113 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"ride-sharing/services/driver-service/internal/domain"
+	"ride-sharing/shared/grpcserver"
+	pb "ride-sharing/shared/proto/driver"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// locationRecorder keeps the drivers whose location was updated
+type locationRecorder struct {
+	domain.DriverService
+	updated []string
+}
+
+func (s *locationRecorder) UpdateDriverLocation(driverID string, location *pb.Location) (*pb.Driver, error) {
+	s.updated = append(s.updated, driverID)
+	return &pb.Driver{Id: driverID, Location: location}, nil
+}
+
+type discardPublisher struct{}
+
+func (discardPublisher) PublishLocationUpdated(ctx context.Context, driver *pb.Driver) error {
+	return nil
+}
+
+// testServiceToken authenticates the callers of dialDriverHandler
+const testServiceToken = "service-token"
+
+// dialDriverHandler serves a driverHandler with the shared interceptors over an in-memory listener
+func dialDriverHandler(t *testing.T, service domain.DriverService) pb.DriverServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpcserver.ServerOptions(slog.New(slog.NewTextHandler(io.Discard, nil)), testServiceToken)...)
+	NewDriverHandler(server, service, nil, discardPublisher{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewDriverServiceClient(conn)
+}
+
+func TestReportLocationIsBoundToTheDriver(t *testing.T) {
+	location := &pb.Location{Latitude: 37.77, Longitude: -122.42}
+
+	tests := []struct {
+		name         string
+		serviceToken string
+		principal    *grpcserver.Principal
+		driverIDs    []string
+		wantCode     codes.Code
+		wantUpdated  int
+	}{
+		{"internal caller", testServiceToken, nil, []string{"driver-1", "driver-2"}, codes.OK, 2},
+		{"admin", testServiceToken, &grpcserver.Principal{UserID: "admin-1", Role: grpcserver.RoleAdmin}, []string{"driver-1", "driver-2"}, codes.OK, 2},
+		{"own driver", testServiceToken, &grpcserver.Principal{UserID: "driver-1", Role: grpcserver.RoleDriver}, []string{"driver-1", "driver-1"}, codes.OK, 2},
+		{"other driver", testServiceToken, &grpcserver.Principal{UserID: "driver-1", Role: grpcserver.RoleDriver}, []string{"driver-1", "driver-2"}, codes.PermissionDenied, 1},
+		{"rider", testServiceToken, &grpcserver.Principal{UserID: "rider-1", Role: grpcserver.RoleRider}, []string{"driver-1"}, codes.PermissionDenied, 0},
+		{"caller without token", "", nil, []string{"driver-1"}, codes.Unauthenticated, 0},
+		{"admin without token", "", &grpcserver.Principal{UserID: "admin-1", Role: grpcserver.RoleAdmin}, []string{"driver-1"}, codes.Unauthenticated, 0},
+		{"wrong token", "guessed", nil, []string{"driver-1"}, codes.Unauthenticated, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &locationRecorder{}
+			client := dialDriverHandler(t, service)
+
+			ctx := grpcserver.OutgoingServiceContext(context.Background(), tt.serviceToken)
+			if tt.principal != nil {
+				ctx = grpcserver.OutgoingContext(ctx, *tt.principal)
+			}
+			stream, err := client.ReportLocation(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, driverID := range tt.driverIDs {
+				if err := stream.Send(&pb.ReportLocationRequest{DriverID: driverID, Location: location}); err != nil {
+					// The server ended the stream, CloseAndRecv returns its status
+					break
+				}
+			}
+
+			_, err = stream.CloseAndRecv()
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("ReportLocation() error = %v, want %s", err, tt.wantCode)
+			}
+			if len(service.updated) != tt.wantUpdated {
+				t.Errorf("updated %v, want %d updates", service.updated, tt.wantUpdated)
+			}
+		})
+	}
+}
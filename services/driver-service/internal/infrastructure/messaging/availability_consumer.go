@@ -0,0 +1,138 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"ride-sharing/services/driver-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// availabilityConsumer implements domain.DriverAvailabilityConsumer
+type availabilityConsumer struct {
+	messageBroker messaging.MessageBroker
+	service       domain.DriverService
+}
+
+// tripDriverRef holds the parts of trip payloads needed to find the trip's driver.
+// JSON field matching is case-insensitive, so it decodes both the protobuf trip
+// of TripCreatedEvent and the trip model published with trip.event.driver_assigned.
+type tripDriverRef struct {
+	ID     string `json:"id"`
+	Driver *struct {
+		ID string `json:"id"`
+	} `json:"driver"`
+}
+
+// NewAvailabilityConsumer creates a new driver availability consumer
+func NewAvailabilityConsumer(messageBroker messaging.MessageBroker, service domain.DriverService) domain.DriverAvailabilityConsumer {
+	return &availabilityConsumer{
+		messageBroker: messageBroker,
+		service:       service,
+	}
+}
+
+// ConsumeAvailabilityEvents starts consuming trip lifecycle events from the queue
+func (c *availabilityConsumer) ConsumeAvailabilityEvents(ctx context.Context, queue string, handler messaging.MessageHandler) error {
+	// If no handler provided, use the default handleAvailabilityEvent
+	if handler == nil {
+		handler = c.handleAvailabilityEvent
+	}
+	return c.messageBroker.Consume(ctx, queue, handler)
+}
+
+// handleAvailabilityEvent moves the driver of a trip to the state implied by the event
+func (c *availabilityConsumer) handleAvailabilityEvent(ctx context.Context, delivery amqp091.Delivery) error {
+	var msg contracts.AmqpMessage
+	if err := json.Unmarshal(delivery.Body, &msg); err != nil {
+		log.Printf("failed to unmarshal message: %v", err)
+		return nil
+	}
+
+	driverID, tripID, err := extractTripDriver(delivery.RoutingKey, msg.Data)
+	if err != nil {
+		log.Printf("failed to unmarshal %s payload: %v", delivery.RoutingKey, err)
+		return nil
+	}
+
+	if driverID == "" {
+		// Trips cancelled before a driver was assigned don't affect anyone
+		return nil
+	}
+
+	var state domain.Availability
+	switch delivery.RoutingKey {
+	case contracts.DriverCmdTripDecline, contracts.DriverCmdTripOfferRevoked, contracts.DriverCmdTripAlreadyTaken,
+		contracts.TripEventCompleted, contracts.TripEventCancelled:
+		state = domain.AvailabilityOnline
+	case contracts.TripEventDriverAssigned:
+		state = domain.AvailabilityEnRouteToPickup
+	case contracts.TripEventStarted:
+		state = domain.AvailabilityOnTrip
+	default:
+		return nil
+	}
+
+	if err := c.service.UpdateAvailability(driverID, state, tripID); err != nil {
+		if errors.Is(err, domain.ErrDriverNotFound) || errors.Is(err, domain.ErrInvalidTransition) {
+			log.Printf("Ignoring %s for driver %s: %v", delivery.RoutingKey, driverID, err)
+			return nil
+		}
+		return err
+	}
+
+	log.Printf("Driver %s is now %s (trip %s)", driverID, state, tripID)
+	return nil
+}
+
+// extractTripDriver returns the driver and trip IDs carried by each kind of event
+func extractTripDriver(routingKey string, data []byte) (driverID, tripID string, err error) {
+	switch routingKey {
+	case contracts.DriverCmdTripDecline:
+		var payload messaging.DriveTripResponseData
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return "", "", err
+		}
+		if payload.Driver == nil {
+			return "", payload.TripID, nil
+		}
+		return payload.Driver.Id, payload.TripID, nil
+
+	case contracts.DriverCmdTripOfferRevoked, contracts.DriverCmdTripAlreadyTaken:
+		var payload messaging.TripOfferClosedData
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return "", "", err
+		}
+		return payload.DriverID, payload.TripID, nil
+
+	case contracts.TripEventDriverAssigned:
+		var trip tripDriverRef
+		if err := json.Unmarshal(data, &trip); err != nil {
+			return "", "", err
+		}
+		return trip.driverID(), trip.ID, nil
+
+	default:
+		var event struct {
+			Trip *tripDriverRef `json:"trip"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return "", "", err
+		}
+		if event.Trip == nil {
+			return "", "", nil
+		}
+		return event.Trip.driverID(), event.Trip.ID, nil
+	}
+}
+
+func (t *tripDriverRef) driverID() string {
+	if t.Driver == nil {
+		return ""
+	}
+	return t.Driver.ID
+}
@@ -0,0 +1,40 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ride-sharing/services/driver-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/driver"
+)
+
+// driverEventPublisher implements domain.DriverEventPublisher
+type driverEventPublisher struct {
+	messageBroker messaging.MessageBroker
+}
+
+// NewDriverEventPublisher creates a new driver event publisher
+func NewDriverEventPublisher(messageBroker messaging.MessageBroker) domain.DriverEventPublisher {
+	return &driverEventPublisher{
+		messageBroker: messageBroker,
+	}
+}
+
+// PublishLocationUpdated notifies other services of a driver's new position
+func (p *driverEventPublisher) PublishLocationUpdated(ctx context.Context, driver *pb.Driver) error {
+	payload, err := json.Marshal(messaging.DriverLocationData{
+		DriverID: driver.Id,
+		Location: driver.Location,
+		Heading:  driver.Heading,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal driver location: %w", err)
+	}
+
+	return p.messageBroker.Publish(ctx, contracts.DriverEventLocationUpdated, contracts.AmqpMessage{
+		OwnerID: driver.Id,
+		Data:    payload,
+	})
+}
@@ -0,0 +1,72 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"ride-sharing/services/driver-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// locationConsumer implements domain.DriverLocationConsumer
+type locationConsumer struct {
+	messageBroker messaging.MessageBroker
+	service       domain.DriverService
+	publisher     domain.DriverEventPublisher
+}
+
+// NewLocationConsumer creates a new driver location consumer
+func NewLocationConsumer(messageBroker messaging.MessageBroker, service domain.DriverService, publisher domain.DriverEventPublisher) domain.DriverLocationConsumer {
+	return &locationConsumer{
+		messageBroker: messageBroker,
+		service:       service,
+		publisher:     publisher,
+	}
+}
+
+// ConsumeDriverLocation starts consuming driver location updates from the queue
+func (c *locationConsumer) ConsumeDriverLocation(ctx context.Context, queue string, handler messaging.MessageHandler) error {
+	// If no handler provided, use the default handleDriverLocation
+	if handler == nil {
+		handler = c.handleDriverLocation
+	}
+	return c.messageBroker.Consume(ctx, queue, handler)
+}
+
+// handleDriverLocation applies a driver location update to the driver index
+func (c *locationConsumer) handleDriverLocation(ctx context.Context, delivery amqp091.Delivery) error {
+	var msg contracts.AmqpMessage
+	if err := json.Unmarshal(delivery.Body, &msg); err != nil {
+		log.Printf("failed to unmarshal message: %v", err)
+		// Malformed messages will never succeed, don't requeue them
+		return nil
+	}
+
+	var payload messaging.DriverLocationData
+	if err := json.Unmarshal(msg.Data, &payload); err != nil {
+		log.Printf("failed to unmarshal driver location: %v", err)
+		return nil
+	}
+
+	driverID := payload.DriverID
+	if driverID == "" {
+		driverID = msg.OwnerID
+	}
+
+	driver, err := c.service.UpdateDriverLocation(driverID, payload.Location)
+	if err != nil {
+		// Drivers may unregister while updates are still in flight, and invalid
+		// locations will never become valid, so neither is worth a requeue
+		if errors.Is(err, domain.ErrDriverNotFound) || errors.Is(err, domain.ErrInvalidLocation) {
+			log.Printf("Dropping location update for driver %s: %v", driverID, err)
+			return nil
+		}
+		return err
+	}
+
+	return c.publisher.PublishLocationUpdated(ctx, driver)
+}
@@ -0,0 +1,100 @@
+package messaging
+
+import (
+	"context"
+	"log"
+	"ride-sharing/shared/messaging"
+	"sync"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// batchedTrip is a trip waiting for the next dispatch window, with the deliveries
+// that queued it. They stay unacknowledged until the trip is dispatched, so a
+// restart hands the trip to another instance instead of dropping it.
+type batchedTrip struct {
+	event      messaging.TripCreatedEvent
+	deliveries []amqp091.Delivery
+}
+
+// ack acknowledges the deliveries of a dispatched trip
+func (t *batchedTrip) ack() {
+	for _, delivery := range t.deliveries {
+		if err := delivery.Ack(false); err != nil {
+			log.Printf("Failed to ack trip %s: %v", t.event.Trip.GetId(), err)
+		}
+	}
+}
+
+// requeue returns the deliveries of a trip that couldn't be dispatched to the queue
+func (t *batchedTrip) requeue() {
+	for _, delivery := range t.deliveries {
+		if err := delivery.Nack(false, true); err != nil {
+			log.Printf("Failed to requeue trip %s: %v", t.event.Trip.GetId(), err)
+		}
+	}
+}
+
+// tripBatch collects trips until the next dispatch window closes
+type tripBatch struct {
+	window  time.Duration
+	mu      sync.Mutex
+	pending []*batchedTrip
+}
+
+func newTripBatch(window time.Duration) *tripBatch {
+	return &tripBatch{window: window}
+}
+
+// add queues a trip for the next batch. A trip queued twice, e.g. after a
+// decline, is only dispatched once, and both deliveries are acknowledged with it.
+func (b *tripBatch) add(tripEvent messaging.TripCreatedEvent, delivery amqp091.Delivery) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, pending := range b.pending {
+		if pending.event.Trip.GetId() == tripEvent.Trip.GetId() {
+			pending.event = tripEvent
+			pending.deliveries = append(pending.deliveries, delivery)
+			return
+		}
+	}
+	b.pending = append(b.pending, &batchedTrip{
+		event:      tripEvent,
+		deliveries: []amqp091.Delivery{delivery},
+	})
+}
+
+// take empties the batch and returns its trips
+func (b *tripBatch) take() []*batchedTrip {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	trips := b.pending
+	b.pending = nil
+	return trips
+}
+
+// run hands the collected trips to dispatch at the end of every window until ctx is done
+func (b *tripBatch) run(ctx context.Context, dispatch func(context.Context, []*batchedTrip)) {
+	ticker := time.NewTicker(b.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if trips := b.take(); len(trips) > 0 {
+				log.Printf("Requeueing %d batched trips on shutdown", len(trips))
+				for _, trip := range trips {
+					trip.requeue()
+				}
+			}
+			return
+		case <-ticker.C:
+			if trips := b.take(); len(trips) > 0 {
+				dispatch(ctx, trips)
+			}
+		}
+	}
+}
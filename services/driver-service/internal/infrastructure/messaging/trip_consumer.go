@@ -7,25 +7,38 @@ import (
 	"ride-sharing/services/driver-service/internal/domain"
 	"ride-sharing/shared/contracts"
 	"ride-sharing/shared/messaging"
+	"time"
 
 	"github.com/rabbitmq/amqp091-go"
 )
 
+// batchPrefetch is how many trips a dispatch window may hold unacknowledged. Further
+// trips wait in the queue until the window is dispatched.
+const batchPrefetch = 256
+
 // tripConsumer implements domain.TripEventConsumer
 type tripConsumer struct {
 	messageBroker messaging.MessageBroker
 	service       domain.DriverService
+	// batch collects trips of packages in batch dispatch mode, nil when batching is disabled
+	batch *tripBatch
 }
 
-// NewTripConsumer creates a new trip event consumer
+// NewTripConsumer creates a new trip event consumer. Trips of packages in batch
+// dispatch mode are matched together every batchWindow; a zero window dispatches
+// them one by one like single mode packages.
 // Following Dependency Inversion Principle (DIP):
 // - Depends on domain.DriverService interface, not concrete implementation
 // - Depends on messaging.MessageBroker interface
-func NewTripConsumer(messageBroker messaging.MessageBroker, service domain.DriverService) domain.TripEventConsumer {
-	return &tripConsumer{
+func NewTripConsumer(messageBroker messaging.MessageBroker, service domain.DriverService, batchWindow time.Duration) domain.TripEventConsumer {
+	c := &tripConsumer{
 		messageBroker: messageBroker,
 		service:       service,
 	}
+	if batchWindow > 0 {
+		c.batch = newTripBatch(batchWindow)
+	}
+	return c
 }
 
 // ConsumeTripCreated starts consuming trip created events from the queue
@@ -34,7 +47,14 @@ func (c *tripConsumer) ConsumeTripCreated(ctx context.Context, queue string, han
 	if handler == nil {
 		handler = c.handleTripCreated
 	}
-	return c.messageBroker.Consume(ctx, queue, handler)
+
+	if c.batch == nil {
+		return c.messageBroker.Consume(ctx, queue, handler)
+	}
+
+	// Batched trips are acknowledged once their window is dispatched
+	go c.batch.run(ctx, c.dispatchBatch)
+	return c.messageBroker.ConsumeDeferred(ctx, queue, batchPrefetch, handler)
 }
 
 // handleTripCreated processes incoming trip created messages
@@ -55,8 +75,19 @@ func (c *tripConsumer) handleTripCreated(ctx context.Context, delivery amqp091.D
 
 	log.Printf("Driver recieved message: %+v", tripEvent)
 
+	if tripEvent.Trip == nil {
+		// Redelivering won't add the trip, don't requeue
+		log.Printf("Ignoring %s without a trip", delivery.RoutingKey)
+		return nil
+	}
+
 	switch delivery.RoutingKey {
 	case contracts.TripEventCreated, contracts.TripEventDriverNotInterested:
+		if c.batch != nil && c.service.DispatchConfigFor(tripEvent.Trip.GetSelectedFare().GetPackageSlug()).Mode == domain.DispatchModeBatch {
+			// The delivery is held until the trip's window is dispatched
+			c.batch.add(tripEvent, delivery)
+			return messaging.ErrAckDeferred
+		}
 		return c.handleTripEventCreated(ctx, tripEvent)
 	}
 
@@ -65,16 +96,77 @@ func (c *tripConsumer) handleTripCreated(ctx context.Context, delivery amqp091.D
 
 // handleTripEventCreated processes trip created events and publishes appropriate responses
 func (c *tripConsumer) handleTripEventCreated(ctx context.Context, tripEvent messaging.TripCreatedEvent) error {
-	driverID, err := c.service.FindAndNotifyDrivers(ctx, tripEvent)
+	driverIDs, err := c.service.FindAndNotifyDrivers(ctx, tripEvent)
 	if err != nil {
 		log.Print(err)
 		return c.publishDriverNotFoundEvent(ctx, tripEvent)
 	}
 
-	log.Printf("found driver %s for trip %s", driverID, tripEvent.Trip.Id)
+	log.Printf("found drivers %v for trip %s", driverIDs, tripEvent.Trip.Id)
+	if err := c.publishTripOfferedEvent(ctx, driverIDs, tripEvent); err != nil {
+		return err
+	}
+
+	for _, driverID := range driverIDs {
+		if err := c.publishDriverFoundEvent(ctx, driverID, tripEvent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dispatchBatch offers the trips of a batch to the drivers picked by the global
+// assignment. Trips left without a driver are reported like single dispatch does.
+// Each trip is acknowledged once its messages are published, and requeued if they
+// can't be.
+func (c *tripConsumer) dispatchBatch(ctx context.Context, trips []*batchedTrip) {
+	events := make([]messaging.TripCreatedEvent, len(trips))
+	for i, trip := range trips {
+		events[i] = trip.event
+	}
+	assigned := c.service.AssignTrips(ctx, events)
+
+	for _, trip := range trips {
+		if err := c.publishBatchedTrip(ctx, trip.event, assigned); err != nil {
+			log.Printf("Failed to dispatch batched trip %s: %v", trip.event.Trip.Id, err)
+			trip.requeue()
+			continue
+		}
+		trip.ack()
+	}
+}
+
+// publishBatchedTrip offers a batched trip to its assigned driver, or reports that it has none
+func (c *tripConsumer) publishBatchedTrip(ctx context.Context, tripEvent messaging.TripCreatedEvent, assigned map[string]string) error {
+	driverID, ok := assigned[tripEvent.Trip.Id]
+	if !ok {
+		return c.publishDriverNotFoundEvent(ctx, tripEvent)
+	}
+
+	if err := c.publishTripOfferedEvent(ctx, []string{driverID}, tripEvent); err != nil {
+		return err
+	}
 	return c.publishDriverFoundEvent(ctx, driverID, tripEvent)
 }
 
+// publishTripOfferedEvent tells trip-service which drivers are about to be offered the trip
+func (c *tripConsumer) publishTripOfferedEvent(ctx context.Context, driverIDs []string, tripEvent messaging.TripCreatedEvent) error {
+	marshalledEvent, err := json.Marshal(messaging.TripOfferedData{
+		TripID:    tripEvent.Trip.Id,
+		RiderID:   tripEvent.Trip.UserID,
+		DriverIDs: driverIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.messageBroker.Publish(ctx, contracts.DriverEventTripOffered, contracts.AmqpMessage{
+		OwnerID: tripEvent.Trip.UserID,
+		Data:    marshalledEvent,
+	})
+}
+
 // publishDriverNotFoundEvent publishes an event when no drivers are available
 func (c *tripConsumer) publishDriverNotFoundEvent(ctx context.Context, tripEvent messaging.TripCreatedEvent) error {
 	log.Printf("publishing message with routing key: %v", contracts.TripEventNoDriversFound)
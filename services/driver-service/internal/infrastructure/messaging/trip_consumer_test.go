@@ -0,0 +1,133 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ride-sharing/services/driver-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pbt "ride-sharing/shared/proto/trip"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// batchService dispatches every package in batch mode and assigns driver-1 to the trips in assigned
+type batchService struct {
+	domain.DriverService
+	assigned map[string]string
+}
+
+func (s *batchService) DispatchConfigFor(packageSlug string) domain.DispatchConfig {
+	return domain.DispatchConfig{Mode: domain.DispatchModeBatch}
+}
+
+func (s *batchService) AssignTrips(ctx context.Context, trips []messaging.TripCreatedEvent) map[string]string {
+	return s.assigned
+}
+
+// failingBroker records published routing keys and fails those in fail
+type failingBroker struct {
+	messaging.MessageBroker
+	fail      string
+	published []string
+}
+
+func (b *failingBroker) Publish(ctx context.Context, routingKey string, msg contracts.AmqpMessage) error {
+	if routingKey == b.fail {
+		return errors.New("broker unavailable")
+	}
+	b.published = append(b.published, routingKey)
+	return nil
+}
+
+// ackRecorder records how each delivery tag was settled
+type ackRecorder map[uint64]string
+
+func (a ackRecorder) Ack(tag uint64, multiple bool) error {
+	a[tag] = "ack"
+	return nil
+}
+
+func (a ackRecorder) Nack(tag uint64, multiple, requeue bool) error {
+	a[tag] = "requeue"
+	return nil
+}
+
+func (a ackRecorder) Reject(tag uint64, requeue bool) error {
+	a[tag] = "reject"
+	return nil
+}
+
+func tripDelivery(t *testing.T, acks ackRecorder, tag uint64, trip *pbt.Trip) amqp091.Delivery {
+	t.Helper()
+
+	data, err := json.Marshal(messaging.TripCreatedEvent{Trip: trip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(contracts.AmqpMessage{OwnerID: "rider-1", Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amqp091.Delivery{Acknowledger: acks, DeliveryTag: tag, RoutingKey: contracts.TripEventCreated, Body: body}
+}
+
+func TestBatchedTripsAreAckedAfterDispatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     string
+		assigned map[string]string
+		want     string
+	}{
+		{"offered", "", map[string]string{"trip-1": "driver-1"}, "ack"},
+		{"no driver", "", nil, "ack"},
+		{"offer not published", contracts.DriverCmdTripRequest, map[string]string{"trip-1": "driver-1"}, "requeue"},
+		{"no driver not published", contracts.TripEventNoDriversFound, nil, "requeue"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := &failingBroker{fail: tt.fail}
+			c := NewTripConsumer(broker, &batchService{assigned: tt.assigned}, time.Minute).(*tripConsumer)
+			acks := ackRecorder{}
+
+			// The trip is queued twice, e.g. created and then declined within the window
+			for tag := range uint64(2) {
+				err := c.handleTripCreated(context.Background(), tripDelivery(t, acks, tag, &pbt.Trip{Id: "trip-1"}))
+				if !errors.Is(err, messaging.ErrAckDeferred) {
+					t.Fatalf("handleTripCreated() = %v, want ErrAckDeferred", err)
+				}
+			}
+			if len(acks) != 0 {
+				t.Fatalf("deliveries settled before the window closed: %v", acks)
+			}
+
+			c.dispatchBatch(context.Background(), c.batch.take())
+
+			for tag := range uint64(2) {
+				if acks[tag] != tt.want {
+					t.Errorf("delivery %d settled as %q, want %q", tag, acks[tag], tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTripCreatedToleratesMissingFields(t *testing.T) {
+	c := NewTripConsumer(&failingBroker{}, &batchService{}, time.Minute).(*tripConsumer)
+	acks := ackRecorder{}
+
+	// A trip without a selected fare doesn't panic
+	err := c.handleTripCreated(context.Background(), tripDelivery(t, acks, 1, &pbt.Trip{Id: "trip-1"}))
+	if !errors.Is(err, messaging.ErrAckDeferred) {
+		t.Fatalf("handleTripCreated() = %v, want ErrAckDeferred", err)
+	}
+
+	// An event without a trip is dropped
+	if err := c.handleTripCreated(context.Background(), tripDelivery(t, acks, 2, nil)); err != nil {
+		t.Errorf("handleTripCreated() without a trip = %v, want nil", err)
+	}
+}
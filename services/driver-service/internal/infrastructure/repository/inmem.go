@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"ride-sharing/services/driver-service/internal/domain"
+	"slices"
+	"sync"
+)
+
+// inmemProfileRepository keeps profiles and vehicles in memory, so they are lost
+// when the service restarts. It stores and returns copies, callers can't change
+// stored records behind the lock.
+type inmemProfileRepository struct {
+	profiles map[string]*domain.DriverProfile
+	vehicles map[string]*domain.Vehicle
+	// plates indexes vehicle IDs by normalized plate to keep plates unique
+	plates map[string]string
+	mu     sync.RWMutex
+}
+
+func NewInmemProfileRepository() *inmemProfileRepository {
+	return &inmemProfileRepository{
+		profiles: make(map[string]*domain.DriverProfile),
+		vehicles: make(map[string]*domain.Vehicle),
+		plates:   make(map[string]string),
+	}
+}
+
+func (r *inmemProfileRepository) CreateProfile(ctx context.Context, profile *domain.DriverProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.profiles[profile.ID]; exists {
+		return fmt.Errorf("%w: %s", domain.ErrProfileExists, profile.ID)
+	}
+
+	r.profiles[profile.ID] = copyProfile(profile)
+	return nil
+}
+
+func (r *inmemProfileRepository) GetProfile(ctx context.Context, driverID string) (*domain.DriverProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	profile, exists := r.profiles[driverID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", domain.ErrProfileNotFound, driverID)
+	}
+
+	return copyProfile(profile), nil
+}
+
+func (r *inmemProfileRepository) CreateVehicle(ctx context.Context, vehicle *domain.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	plate := domain.NormalizePlate(vehicle.Plate)
+	if _, taken := r.plates[plate]; taken {
+		return fmt.Errorf("%w: %s", domain.ErrPlateTaken, plate)
+	}
+
+	r.vehicles[vehicle.ID] = copyVehicle(vehicle)
+	r.plates[plate] = vehicle.ID
+	return nil
+}
+
+func (r *inmemProfileRepository) GetVehicle(ctx context.Context, vehicleID string) (*domain.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	vehicle, exists := r.vehicles[vehicleID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", domain.ErrVehicleNotFound, vehicleID)
+	}
+
+	return copyVehicle(vehicle), nil
+}
+
+func (r *inmemProfileRepository) ListVehicles(ctx context.Context, driverID string) ([]*domain.Vehicle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var vehicles []*domain.Vehicle
+	for _, vehicle := range r.vehicles {
+		if vehicle.DriverID == driverID {
+			vehicles = append(vehicles, copyVehicle(vehicle))
+		}
+	}
+
+	// Keep the default vehicle selection stable across calls
+	slices.SortFunc(vehicles, func(a, b *domain.Vehicle) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return vehicles, nil
+}
+
+func copyProfile(profile *domain.DriverProfile) *domain.DriverProfile {
+	copied := *profile
+	return &copied
+}
+
+func copyVehicle(vehicle *domain.Vehicle) *domain.Vehicle {
+	copied := *vehicle
+	copied.PackageSlugs = slices.Clone(vehicle.PackageSlugs)
+	return &copied
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"ride-sharing/services/driver-service/internal/domain"
+	"testing"
+)
+
+func TestInmemProfileRepositoryPlatesAreUnique(t *testing.T) {
+	ctx := context.Background()
+	r := NewInmemProfileRepository()
+	if err := r.CreateVehicle(ctx, &domain.Vehicle{ID: "vehicle-1", DriverID: "driver-1", Plate: "ABC123"}); err != nil {
+		t.Fatalf("CreateVehicle: %v", err)
+	}
+
+	tests := []struct {
+		plate   string
+		wantErr error
+	}{
+		{"ABC123", domain.ErrPlateTaken},
+		// Plates differing in case or spacing are the same plate
+		{"abc 123", domain.ErrPlateTaken},
+		{"ABC124", nil},
+	}
+
+	for i, tt := range tests {
+		vehicle := &domain.Vehicle{ID: "vehicle-" + tt.plate, DriverID: "driver-2", Plate: tt.plate}
+		if err := r.CreateVehicle(ctx, vehicle); !errors.Is(err, tt.wantErr) {
+			t.Errorf("vehicle %d: CreateVehicle(%q) = %v, want %v", i, tt.plate, err, tt.wantErr)
+		}
+	}
+
+	vehicles, err := r.ListVehicles(ctx, "driver-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vehicles) != 1 || vehicles[0].Plate != "ABC124" {
+		t.Errorf("driver-2 has vehicles %v, want only ABC124", vehicles)
+	}
+}
+
+func TestInmemProfileRepositoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	r := NewInmemProfileRepository()
+
+	profile := &domain.DriverProfile{ID: "driver-1", Name: "Lando Norris"}
+	vehicle := &domain.Vehicle{ID: "vehicle-1", DriverID: "driver-1", Plate: "ABC123", PackageSlugs: []string{"sedan"}}
+	if err := r.CreateProfile(ctx, profile); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CreateVehicle(ctx, vehicle); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing the stored or returned records doesn't change the store
+	profile.Name = "changed"
+	vehicle.PackageSlugs[0] = "changed"
+	read, _ := r.GetVehicle(ctx, "vehicle-1")
+	read.Plate = "changed"
+	listed, _ := r.ListVehicles(ctx, "driver-1")
+	listed[0].PackageSlugs[0] = "changed"
+
+	if got, _ := r.GetProfile(ctx, "driver-1"); got.Name != "Lando Norris" {
+		t.Errorf("profile name = %q, want Lando Norris", got.Name)
+	}
+	if got, _ := r.GetVehicle(ctx, "vehicle-1"); got.Plate != "ABC123" || !got.IsEligibleFor("sedan") {
+		t.Errorf("vehicle = %+v, want plate ABC123 eligible for sedan", got)
+	}
+}
@@ -2,13 +2,18 @@ package util
 
 import "math/rand"
 
-// GenerateRandomPlate generates a random 3-letter license plate
+// GenerateRandomPlate generates a random license plate of three letters followed by three digits.
+// Plates are not guaranteed to be unique; callers must check them against registered vehicles.
 func GenerateRandomPlate() string {
 	letters := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits := "0123456789"
 	plate := ""
 	for range 3 {
 		plate += string(letters[rand.Intn(len(letters))])
 	}
+	for range 3 {
+		plate += string(digits[rand.Intn(len(digits))])
+	}
 
 	return plate
 }
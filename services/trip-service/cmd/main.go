@@ -6,15 +6,15 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"ride-sharing/services/trip-service/internal/domain"
 	"ride-sharing/services/trip-service/internal/infrastructure/events"
 	"ride-sharing/services/trip-service/internal/infrastructure/grpc"
 	"ride-sharing/services/trip-service/internal/infrastructure/repository"
 	"ride-sharing/services/trip-service/internal/service"
 	"ride-sharing/shared/env"
+	"ride-sharing/shared/grpcserver"
 	"ride-sharing/shared/messaging"
 	"syscall"
-
-	grpcserver "google.golang.org/grpc"
 )
 
 var GrpcAddr = ":9093"
@@ -25,8 +25,14 @@ func main() {
 	if rabbitMqUri == "" {
 		log.Fatal("RABBITMQ_URI environment variable is required")
 	}
+	chatFilter, err := domain.ParseChatFilter(env.GetString("CHAT_FILTERS", "contact_details"))
+	if err != nil {
+		log.Fatalf("failed to parse CHAT_FILTERS: %v", err)
+	}
+
 	repo := repository.NewInmemRepository()
 	svc := service.NewService(repo)
+	chatSvc := service.NewChatService(repo, repository.NewInmemChatRepository(), chatFilter)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -47,6 +53,10 @@ func main() {
 	log.Println("Starting Rabbit MQ connection")
 	publisher := events.NewTripEventPublisher(rabbitMq)
 	consumer := events.NewDriverConsumer(rabbitMq, svc)
+	locationConsumer := events.NewDriverLocationConsumer(rabbitMq, svc)
+	offerExpiry := events.NewOfferExpiry(rabbitMq, svc)
+	riderConsumer := events.NewRiderConsumer(rabbitMq, svc)
+	chatConsumer := events.NewChatConsumer(rabbitMq, chatSvc)
 
 	// Start RabbitMQ consumer in background
 	go func() {
@@ -58,13 +68,47 @@ func main() {
 		}
 	}()
 
+	go func() {
+		log.Printf("Starting RabbitMQ consumer for queue: %s", messaging.TripDriverLocationQueue)
+		if err := locationConsumer.ConsumeDriverLocation(ctx, messaging.TripDriverLocationQueue, nil); err != nil {
+			log.Printf("Consumer error: %v", err)
+			cancel()
+		}
+	}()
+
+	go offerExpiry.Run(ctx)
+
+	go func() {
+		log.Printf("Starting RabbitMQ consumer for queue: %s", messaging.RiderCmdTripQueue)
+		if err := riderConsumer.ConsumeRiderCommands(ctx, messaging.RiderCmdTripQueue, nil); err != nil {
+			log.Printf("Consumer error: %v", err)
+			cancel()
+		}
+	}()
+
+	go func() {
+		log.Printf("Starting RabbitMQ consumer for queue: %s", messaging.ChatCmdQueue)
+		if err := chatConsumer.ConsumeChatCommands(ctx, messaging.ChatCmdQueue, nil); err != nil {
+			log.Printf("Consumer error: %v", err)
+			cancel()
+		}
+	}()
+
 	lis, err := net.Listen("tcp", GrpcAddr)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	grpcServer := grpcserver.NewServer()
-	grpc.NewGRPCHandler(grpcServer, svc, publisher)
+	serviceToken, err := grpcserver.ServiceTokenFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure the gRPC server: %v", err)
+	}
+	if serviceToken == "" {
+		log.Printf("GRPC_SERVICE_TOKEN is not set, every caller is trusted")
+	}
+
+	grpcServer := grpcserver.NewServer(serviceToken)
+	grpc.NewGRPCHandler(grpcServer, svc, chatSvc, publisher)
 
 	log.Printf("Starting gRPC server TripService on port: %v", lis.Addr().String())
 
@@ -0,0 +1,259 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"regexp"
+	"ride-sharing/shared/contracts"
+	pb "ride-sharing/shared/proto/trip"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ChatRoleRider  = "rider"
+	ChatRoleDriver = "driver"
+)
+
+// MaxChatMessagesPerTrip bounds the history a trip's chat can grow to
+const MaxChatMessagesPerTrip = 500
+
+var (
+	// ErrChatClosed is returned for messages sent once the trip is no longer active
+	ErrChatClosed = errors.New("chat is closed")
+	// ErrNotChatParticipant is returned when a user is neither the trip's rider nor its assigned driver
+	ErrNotChatParticipant = errors.New("not a participant of the trip's chat")
+	// ErrInvalidChatMessage is returned for empty or too long messages
+	ErrInvalidChatMessage = errors.New("invalid chat message")
+	// ErrChatMessageRejected is returned when the content filter refuses a message
+	ErrChatMessageRejected = errors.New("chat message rejected")
+	// ErrChatFull is returned once a trip's chat holds MaxChatMessagesPerTrip messages
+	ErrChatFull = errors.New("chat is full")
+	// ErrChatMessageNotFound is returned for receipts of unknown messages
+	ErrChatMessageNotFound = errors.New("chat message not found")
+)
+
+// ChatMessageModel is a message between the rider and the assigned driver of a trip
+type ChatMessageModel struct {
+	ID          primitive.ObjectID
+	TripID      string
+	SenderID    string
+	RecipientID string
+	SenderRole  string
+	// CommandID is the ID of the sender's command, a redelivered command finds its
+	// message stored already
+	CommandID string
+	Text      string
+	SentAt    time.Time
+	// DeliveredAt and ReadAt are zero until the recipient reported them
+	DeliveredAt time.Time
+	ReadAt      time.Time
+}
+
+func (m *ChatMessageModel) ToProto() *pb.ChatMessage {
+	return &pb.ChatMessage{
+		Id:                    m.ID.Hex(),
+		TripID:                m.TripID,
+		SenderID:              m.SenderID,
+		SenderRole:            m.SenderRole,
+		Text:                  m.Text,
+		SentAtUnixMillis:      m.SentAt.UnixMilli(),
+		DeliveredAtUnixMillis: unixMillis(m.DeliveredAt),
+		ReadAtUnixMillis:      unixMillis(m.ReadAt),
+	}
+}
+
+func ToChatMessagesProto(messages []*ChatMessageModel) []*pb.ChatMessage {
+	protoMessages := make([]*pb.ChatMessage, 0, len(messages))
+	for _, m := range messages {
+		protoMessages = append(protoMessages, m.ToProto())
+	}
+
+	return protoMessages
+}
+
+// unixMillis keeps zero times zero, instead of a large negative number
+func unixMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// ChatParticipant returns the role of a user in the trip's chat and the other
+// participant, empty until a driver is assigned. Only the rider and the assigned
+// driver take part.
+func (t *TripModel) ChatParticipant(userID string) (role, otherID string, err error) {
+	switch {
+	case userID == t.UserID:
+		if !t.HasDriver() {
+			return ChatRoleRider, "", nil
+		}
+		return ChatRoleRider, t.Driver.Id, nil
+	case t.HasDriver() && userID == t.Driver.Id:
+		return ChatRoleDriver, t.UserID, nil
+	default:
+		return "", "", ErrNotChatParticipant
+	}
+}
+
+// ValidateChatText checks the length of a message's text, before it is filtered. The
+// gateway checks it already, this guards other publishers.
+func ValidateChatText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("%w: text is empty", ErrInvalidChatMessage)
+	}
+	if !utf8.ValidString(text) {
+		return fmt.Errorf("%w: text is not valid UTF-8", ErrInvalidChatMessage)
+	}
+	if utf8.RuneCountInString(text) > contracts.MaxChatMessageLength {
+		return fmt.Errorf("%w: text is longer than %d characters", ErrInvalidChatMessage, contracts.MaxChatMessageLength)
+	}
+	return nil
+}
+
+type ChatRepository interface {
+	// AddChatMessage stores a message, unless its trip already holds limit messages
+	AddChatMessage(ctx context.Context, message *ChatMessageModel, limit int) error
+	// ChatMessageByCommand returns a copy of the message a sender sent with a command,
+	// nil if there is none
+	ChatMessageByCommand(ctx context.Context, tripID, senderID, commandID string) (*ChatMessageModel, error)
+	// ListChatMessages returns copies of a trip's messages, oldest first
+	ListChatMessages(ctx context.Context, tripID string) ([]*ChatMessageModel, error)
+	// MarkChatMessages records that the recipient received, or read, their messages of a
+	// trip up to and including messageID. It returns a copy of that message.
+	MarkChatMessages(ctx context.Context, tripID, recipientID, messageID, status string, at time.Time) (*ChatMessageModel, error)
+}
+
+type ChatService interface {
+	// SendChatMessage stores a message of the trip's rider or assigned driver while the
+	// trip is active. A command sent again returns the message stored the first time.
+	SendChatMessage(ctx context.Context, tripID, senderID, commandID, text string) (*ChatMessageModel, error)
+	// MarkChatMessages records a delivery or read receipt of a participant and returns
+	// the message it covers up to
+	MarkChatMessages(ctx context.Context, tripID, recipientID, messageID, status string) (*ChatMessageModel, error)
+	// ChatHistory returns the messages of a trip to one of its participants, and whether
+	// the chat is closed
+	ChatHistory(ctx context.Context, tripID, userID string) (messages []*ChatMessageModel, closed bool, err error)
+}
+
+// ChatFilter checks the text of chat messages before they are stored
+type ChatFilter interface {
+	// Filter returns the text to store, which may be masked, or an error wrapping
+	// ErrChatMessageRejected when the message must not be sent at all
+	Filter(text string) (string, error)
+}
+
+type noopChatFilter struct{}
+
+func (noopChatFilter) Filter(text string) (string, error) {
+	return text, nil
+}
+
+type blocklistChatFilter struct {
+	pattern *regexp.Regexp
+}
+
+// NewBlocklistChatFilter creates a filter that masks the given words, ignoring case
+func NewBlocklistChatFilter(words []string) ChatFilter {
+	if len(words) == 0 {
+		return noopChatFilter{}
+	}
+
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	return blocklistChatFilter{
+		pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`),
+	}
+}
+
+func (f blocklistChatFilter) Filter(text string) (string, error) {
+	return f.pattern.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(word))
+	}), nil
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`)
+	// phonePattern matches 8 or more digits, optionally separated like phone numbers are
+	phonePattern = regexp.MustCompile(`\+?\d(?:[\s().-]*\d){7,}`)
+)
+
+type contactDetailsChatFilter struct{}
+
+// NewContactDetailsChatFilter creates a filter that rejects messages with email
+// addresses or phone numbers, so riders and drivers keep in touch through the app
+func NewContactDetailsChatFilter() ChatFilter {
+	return contactDetailsChatFilter{}
+}
+
+func (contactDetailsChatFilter) Filter(text string) (string, error) {
+	if emailPattern.MatchString(text) || phonePattern.MatchString(text) {
+		return "", fmt.Errorf("%w: messages can't share contact details", ErrChatMessageRejected)
+	}
+	return text, nil
+}
+
+type chainChatFilter []ChatFilter
+
+// ChainChatFilters applies filters in order, each one to the text left by the previous
+func ChainChatFilters(filters ...ChatFilter) ChatFilter {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return chainChatFilter(filters)
+}
+
+func (c chainChatFilter) Filter(text string) (string, error) {
+	for _, filter := range c {
+		filtered, err := filter.Filter(text)
+		if err != nil {
+			return "", err
+		}
+		text = filtered
+	}
+	return text, nil
+}
+
+// ParseChatFilter builds the chat filter of a spec like
+// "contact_details,blocklist:word1|word2". An empty spec lets every message through.
+func ParseChatFilter(spec string) (ChatFilter, error) {
+	var filters []ChatFilter
+
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+
+		switch name {
+		case "":
+			continue
+		case "contact_details":
+			filters = append(filters, NewContactDetailsChatFilter())
+		default:
+			list, ok := strings.CutPrefix(name, "blocklist:")
+			if !ok {
+				return nil, fmt.Errorf("unknown chat filter %q", name)
+			}
+
+			var words []string
+			for _, word := range strings.Split(list, "|") {
+				if word = strings.TrimSpace(word); word != "" {
+					words = append(words, word)
+				}
+			}
+			if len(words) == 0 {
+				return nil, fmt.Errorf("blocklist needs at least one word")
+			}
+			filters = append(filters, NewBlocklistChatFilter(words))
+		}
+	}
+
+	if len(filters) == 0 {
+		return noopChatFilter{}, nil
+	}
+	return ChainChatFilters(filters...), nil
+}
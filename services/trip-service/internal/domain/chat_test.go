@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"errors"
+	"ride-sharing/shared/contracts"
+	pb "ride-sharing/shared/proto/trip"
+	"strings"
+	"testing"
+)
+
+func TestContactDetailsChatFilter(t *testing.T) {
+	tests := []struct {
+		text     string
+		rejected bool
+	}{
+		{"mail me at jane.doe+rides@example.co.uk", true},
+		{"JANE@EXAMPLE.COM", true},
+		{"call me on +1 (415) 555-0123", true},
+		{"my number is 0176 12345678", true},
+		{"4155550123", true},
+		{"text 415.555.0123", true},
+
+		// Everyday messages with numbers and @ stay allowed
+		{"I'm at gate 12, terminal 2", false},
+		{"arriving in 5 minutes", false},
+		{"meet me at 221B Baker Street", false},
+		{"flight LH 1234 just landed", false},
+		{"pickup between 10:15 and 10:30", false},
+		{"I'm @ the corner", false},
+		{"follow @jane_doe", false},
+		{"the fare was 25.50", false},
+		{"code 1234567", false},
+	}
+
+	filter := NewContactDetailsChatFilter()
+	for _, tt := range tests {
+		filtered, err := filter.Filter(tt.text)
+		if tt.rejected {
+			if !errors.Is(err, ErrChatMessageRejected) {
+				t.Errorf("Filter(%q) = %q, %v, want ErrChatMessageRejected", tt.text, filtered, err)
+			}
+			continue
+		}
+		if err != nil || filtered != tt.text {
+			t.Errorf("Filter(%q) = %q, %v, want the text unchanged", tt.text, filtered, err)
+		}
+	}
+}
+
+func TestBlocklistChatFilter(t *testing.T) {
+	filter := NewBlocklistChatFilter([]string{"darn", "heck"})
+
+	tests := []struct {
+		text, want string
+	}{
+		{"darn it", "**** it"},
+		{"DARN, what the Heck", "****, what the ****"},
+		// Only whole words are masked
+		{"darned heckler", "darned heckler"},
+		{"nothing to mask", "nothing to mask"},
+	}
+
+	for _, tt := range tests {
+		if got, err := filter.Filter(tt.text); err != nil || got != tt.want {
+			t.Errorf("Filter(%q) = %q, %v, want %q", tt.text, got, err, tt.want)
+		}
+	}
+
+	if got, _ := NewBlocklistChatFilter(nil).Filter("darn"); got != "darn" {
+		t.Errorf("empty blocklist masked %q", got)
+	}
+}
+
+func TestParseChatFilter(t *testing.T) {
+	tests := []struct {
+		spec, text string
+		want       string
+		rejected   bool
+	}{
+		{"", "call 4155550123, darn", "call 4155550123, darn", false},
+		{"blocklist:darn", "darn it", "**** it", false},
+		{"blocklist: darn | heck ", "heck", "****", false},
+		{"contact_details", "call 4155550123", "", true},
+		{"contact_details, blocklist:darn", "darn it", "**** it", false},
+		{"blocklist:darn,contact_details", "darn, call 4155550123", "", true},
+	}
+
+	for _, tt := range tests {
+		filter, err := ParseChatFilter(tt.spec)
+		if err != nil {
+			t.Errorf("ParseChatFilter(%q) error = %v", tt.spec, err)
+			continue
+		}
+
+		got, err := filter.Filter(tt.text)
+		if tt.rejected {
+			if !errors.Is(err, ErrChatMessageRejected) {
+				t.Errorf("%q: Filter(%q) = %q, %v, want ErrChatMessageRejected", tt.spec, tt.text, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: Filter(%q) = %q, %v, want %q", tt.spec, tt.text, got, err, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"profanity", "blocklist:", "blocklist: | ", "contact_details,links"} {
+		if _, err := ParseChatFilter(spec); err == nil {
+			t.Errorf("ParseChatFilter(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestValidateChatText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr bool
+	}{
+		{"text", "on my way", false},
+		{"longest", strings.Repeat("a", contracts.MaxChatMessageLength), false},
+		// The length counts characters, not bytes
+		{"longest multibyte", strings.Repeat("ü", contracts.MaxChatMessageLength), false},
+		{"empty", "", true},
+		{"whitespace", " \n\t", true},
+		{"too long", strings.Repeat("a", contracts.MaxChatMessageLength+1), true},
+		{"invalid UTF-8", "on my way \xff", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateChatText(tt.text)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s: ValidateChatText() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidChatMessage) {
+			t.Errorf("%s: ValidateChatText() error = %v, want ErrInvalidChatMessage", tt.name, err)
+		}
+	}
+}
+
+func TestChatParticipant(t *testing.T) {
+	trip := &TripModel{UserID: "rider-1", Driver: &pb.TripDriver{Id: "driver-1"}}
+
+	tests := []struct {
+		userID    string
+		wantRole  string
+		wantOther string
+		wantErr   error
+	}{
+		{"rider-1", ChatRoleRider, "driver-1", nil},
+		{"driver-1", ChatRoleDriver, "rider-1", nil},
+		{"driver-2", "", "", ErrNotChatParticipant},
+		{"", "", "", ErrNotChatParticipant},
+	}
+
+	for _, tt := range tests {
+		role, other, err := trip.ChatParticipant(tt.userID)
+		if role != tt.wantRole || other != tt.wantOther || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ChatParticipant(%q) = %q, %q, %v, want %q, %q, %v", tt.userID, role, other, err, tt.wantRole, tt.wantOther, tt.wantErr)
+		}
+	}
+
+	// Before a driver is assigned only the rider takes part, and an empty driver ID matches nobody
+	pending := &TripModel{UserID: "rider-1", Driver: &pb.TripDriver{}}
+	if role, other, err := pending.ChatParticipant("rider-1"); role != ChatRoleRider || other != "" || err != nil {
+		t.Errorf("ChatParticipant(rider) of a pending trip = %q, %q, %v", role, other, err)
+	}
+	if _, _, err := pending.ChatParticipant(""); !errors.Is(err, ErrNotChatParticipant) {
+		t.Errorf("ChatParticipant(\"\") of a pending trip error = %v, want ErrNotChatParticipant", err)
+	}
+}
@@ -1,9 +1,28 @@
 package domain
 
 import (
+	"errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	tripTypes "ride-sharing/services/trip-service/pkg/types"
 	pb "ride-sharing/shared/proto/trip"
+	"time"
+)
+
+// FareValidity is how long a previewed fare can be booked, so trips aren't started
+// at prices computed for old traffic conditions
+const FareValidity = 10 * time.Minute
+
+var (
+	// ErrFareNotFound is returned for unknown fare IDs
+	ErrFareNotFound = errors.New("fare not found")
+	// ErrFareNotOwned is returned when a user books a fare previewed by another user
+	ErrFareNotOwned = errors.New("fare doesn't belong to user")
+	// ErrFareExpired is returned when a user books a fare older than FareValidity
+	ErrFareExpired = errors.New("fare expired")
+	// ErrRouteUnavailable is returned when the routing service can't be reached
+	ErrRouteUnavailable = errors.New("routing service unavailable")
+	// ErrNoRoute is returned when there is no route between two locations
+	ErrNoRoute = errors.New("no route found")
 )
 
 type RideFareModel struct {
@@ -12,6 +31,7 @@ type RideFareModel struct {
 	PackageSlug       string
 	TotalPriceInCents float64
 	Route             *tripTypes.OsrmApiResponse
+	ExpiresAt         time.Time
 }
 
 func (r *RideFareModel) ToProto() *pb.RideFare {
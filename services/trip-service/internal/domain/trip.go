@@ -2,11 +2,52 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	tripTypes "ride-sharing/services/trip-service/pkg/types"
 	pbd "ride-sharing/shared/proto/driver"
 	pb "ride-sharing/shared/proto/trip"
 	"ride-sharing/shared/types"
+	"slices"
+	"time"
+)
+
+const (
+	TripStatusPending    = "pending"
+	TripStatusAccepted   = "accepted"
+	TripStatusInProgress = "in_progress"
+	TripStatusCompleted  = "completed"
+	TripStatusCancelled  = "cancelled"
+)
+
+// MaxTripStops is how many stops a rider may add to a trip
+const MaxTripStops = 3
+
+// TripOfferTimeout is how long the drivers of a dispatch round may accept the trip.
+// It is shorter than driver-service's offer timeout, so an accept is never taken
+// once driver-service may have offered the driver another trip.
+const TripOfferTimeout = 25 * time.Second
+
+var (
+	// ErrTripAlreadyTaken is returned when a driver accepts a trip that is no longer pending
+	ErrTripAlreadyTaken = errors.New("trip already taken")
+	// ErrTripNotOffered is returned when a driver answers an offer they don't have
+	ErrTripNotOffered = errors.New("trip not offered to the driver")
+	// ErrTripOfferExpired is returned when a driver accepts an offer after TripOfferTimeout
+	ErrTripOfferExpired = errors.New("trip offer expired")
+	// ErrNotTripDriver is returned when a driver moves a trip assigned to another driver
+	ErrNotTripDriver = errors.New("trip is assigned to another driver")
+	// ErrTripNotFound is returned for unknown trip IDs
+	ErrTripNotFound = errors.New("trip not found")
+	// ErrTripNotOwned is returned when a rider changes another rider's trip
+	ErrTripNotOwned = errors.New("trip belongs to another rider")
+	// ErrTripNotModifiable is returned for rider changes the trip's status no longer allows
+	ErrTripNotModifiable = errors.New("trip can no longer be changed")
+	// ErrTooManyStops is returned when a rider adds more than MaxTripStops stops
+	ErrTooManyStops = errors.New("too many stops")
+	// ErrTripAlreadyCancelled is returned when a rider cancels a trip they already cancelled
+	ErrTripAlreadyCancelled = errors.New("trip already cancelled")
 )
 
 type TripModel struct {
@@ -15,6 +56,15 @@ type TripModel struct {
 	Status   string
 	RideFare *RideFareModel
 	Driver   *pb.TripDriver
+	// OfferedDriverIDs are the drivers with an open offer for the trip.
+	// It is internal bookkeeping and never sent to riders.
+	OfferedDriverIDs []string `json:"-"`
+	// OffersExpireAt is when the open offers stop being accepted
+	OffersExpireAt time.Time `json:"-"`
+	// Pickup is where the rider moved the pickup point to, the route starts there otherwise
+	Pickup *types.Coordinate
+	// Stops are the stops the rider added, in the order they were added
+	Stops []*types.Coordinate
 }
 
 func (t *TripModel) ToProto() *pb.Trip {
@@ -25,6 +75,62 @@ func (t *TripModel) ToProto() *pb.Trip {
 		Status:       t.Status,
 		Driver:       t.Driver,
 		Route:        t.RideFare.Route.ToProto(),
+		Pickup:       coordinateToProto(t.PickupCoordinate()),
+	}
+}
+
+func coordinateToProto(coordinate *types.Coordinate) *pb.Coordinate {
+	if coordinate == nil {
+		return nil
+	}
+	return &pb.Coordinate{
+		Latitude:  coordinate.Latitude,
+		Longitude: coordinate.Longitude,
+	}
+}
+
+// IsActive reports whether the trip has an assigned driver and has not ended yet
+func (t *TripModel) IsActive() bool {
+	return t.Status == TripStatusAccepted || t.Status == TripStatusInProgress
+}
+
+// HasDriver reports whether a driver was assigned to the trip
+func (t *TripModel) HasDriver() bool {
+	return t.Driver != nil && t.Driver.Id != ""
+}
+
+// CheckRiderChange returns why the rider can't change the trip, if they can't. The
+// trip must belong to the rider and be in one of the allowed statuses.
+func (t *TripModel) CheckRiderChange(riderID string, allowed ...string) error {
+	if t.UserID != riderID {
+		return ErrTripNotOwned
+	}
+	if !slices.Contains(allowed, t.Status) {
+		return fmt.Errorf("%w: trip is %s", ErrTripNotModifiable, t.Status)
+	}
+	return nil
+}
+
+// PickupCoordinate returns where the rider is picked up, the start of the trip's
+// route unless the rider moved it
+func (t *TripModel) PickupCoordinate() *types.Coordinate {
+	if t.Pickup != nil {
+		return t.Pickup
+	}
+
+	if t.RideFare == nil || t.RideFare.Route == nil || len(t.RideFare.Route.Routes) == 0 {
+		return nil
+	}
+
+	coordinates := t.RideFare.Route.Routes[0].Geometry.Coordinates
+	if len(coordinates) == 0 {
+		return nil
+	}
+
+	// OSRM returns GeoJSON coordinates in [longitude, latitude] order
+	return &types.Coordinate{
+		Latitude:  coordinates[0][1],
+		Longitude: coordinates[0][0],
 	}
 }
 
@@ -34,6 +140,32 @@ type TripRepository interface {
 	GetFareByID(ctx context.Context, fareID string) (*RideFareModel, error)
 	GetTripByID(ctx context.Context, id string) (*TripModel, error)
 	UpdateTrip(ctx context.Context, tripID string, status string, driver *pbd.Driver) error
+	GetActiveTripByDriverID(ctx context.Context, driverID string) (*TripModel, error)
+	// SetTripOffers replaces the open offers of a trip, which expire at expiresAt
+	SetTripOffers(ctx context.Context, tripID string, driverIDs []string, expiresAt time.Time) error
+	// RemoveTripOffer closes the offer of a driver. It returns ErrTripNotOffered when
+	// the driver has no open offer.
+	RemoveTripOffer(ctx context.Context, tripID, driverID string) (remaining int, err error)
+	// ExpireTripOffers clears the offers of the pending trips that expired before at.
+	// The trips are returned with their expired offers.
+	ExpireTripOffers(ctx context.Context, at time.Time) ([]*TripModel, error)
+	// AssignDriver atomically assigns a pending trip to the driver and clears its
+	// offers. It returns ErrTripAlreadyTaken when the trip is no longer pending,
+	// ErrTripNotOffered when the driver has no open offer and ErrTripOfferExpired
+	// when the offers expired before at.
+	AssignDriver(ctx context.Context, tripID string, driver *pbd.Driver, at time.Time) (*TripModel, error)
+	// TransitionTrip atomically moves the trip of the driver from one status to the
+	// next. It returns ErrNotTripDriver for other drivers and ErrTripNotModifiable
+	// when the trip isn't in the from status anymore.
+	TransitionTrip(ctx context.Context, tripID, driverID, from, to string) (*TripModel, error)
+	// CancelTrip cancels the rider's trip if it hasn't started yet. The trip is
+	// returned as it was before, with its open offers and driver. It returns
+	// ErrTripAlreadyCancelled when the trip was cancelled before.
+	CancelTrip(ctx context.Context, tripID, riderID string) (*TripModel, error)
+	// SetTripPickup moves the pickup point of the rider's trip until it starts
+	SetTripPickup(ctx context.Context, tripID, riderID string, pickup *types.Coordinate) (*TripModel, error)
+	// AddTripStop adds a stop to the rider's trip until it ends
+	AddTripStop(ctx context.Context, tripID, riderID string, stop *types.Coordinate) (*TripModel, error)
 }
 
 type TripService interface {
@@ -44,6 +176,27 @@ type TripService interface {
 	GetAndValidateFare(ctx context.Context, fareID, userID string) (*RideFareModel, error)
 	GetTripByID(ctx context.Context, tripID string) (*TripModel, error)
 	UpdateTrip(ctx context.Context, tripID string, status string, driver *pbd.Driver) error
+	GetActiveTripByDriverID(ctx context.Context, driverID string) (*TripModel, error)
+	EstimatePickup(trip *TripModel, driverLocation *types.Coordinate) (distanceMeters, etaSeconds float64, err error)
+	// RecordTripOffers remembers which drivers were offered the trip in the latest dispatch round
+	RecordTripOffers(ctx context.Context, tripID string, driverIDs []string) error
+	// DeclineTripOffer closes a driver's offer and returns how many offers are still open
+	DeclineTripOffer(ctx context.Context, tripID, driverID string) (remaining int, err error)
+	// ExpireTripOffers closes the offers nobody accepted in time. The trips are
+	// returned with their expired offers, so they can be revoked and dispatched again.
+	ExpireTripOffers(ctx context.Context) ([]*TripModel, error)
+	// AcceptTripOffer assigns the trip to the first driver to accept it. The other drivers
+	// that were offered the trip are returned so they can be told the offer is gone.
+	AcceptTripOffer(ctx context.Context, tripID string, driver *pbd.Driver) (trip *TripModel, revoked []string, err error)
+	// TransitionTrip moves a trip from one status to the next on behalf of its assigned driver
+	TransitionTrip(ctx context.Context, tripID, driverID, from, to string) (*TripModel, error)
+	// CancelTrip cancels a trip on behalf of its rider. The drivers with an open offer
+	// are returned so they can be told the offer is gone.
+	CancelTrip(ctx context.Context, tripID, riderID string) (trip *TripModel, revoked []string, err error)
+	// UpdatePickup moves the pickup point of a trip on behalf of its rider, before the driver picked them up
+	UpdatePickup(ctx context.Context, tripID, riderID string, pickup *types.Coordinate) (*TripModel, error)
+	// AddStop adds a stop to a trip on behalf of its rider, up to MaxTripStops
+	AddStop(ctx context.Context, tripID, riderID string, stop *types.Coordinate) (*TripModel, error)
 }
 
 type TripEventPublisher interface {
@@ -0,0 +1,222 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// chatConsumer stores the chat messages of trips and relays them to the other participant
+type chatConsumer struct {
+	messageBroker messaging.MessageBroker
+	service       domain.ChatService
+}
+
+// NewChatConsumer creates a new chat command consumer
+func NewChatConsumer(messageBroker messaging.MessageBroker, service domain.ChatService) *chatConsumer {
+	return &chatConsumer{
+		messageBroker: messageBroker,
+		service:       service,
+	}
+}
+
+// ConsumeChatCommands starts consuming chat commands from the queue
+func (c *chatConsumer) ConsumeChatCommands(ctx context.Context, queue string, handler messaging.MessageHandler) error {
+	if handler == nil {
+		handler = c.handleChatCommand
+	}
+	return c.messageBroker.Consume(ctx, queue, handler)
+}
+
+// handleChatCommand applies a chat command of a participant, who is told with a chat
+// error when the trip doesn't allow it
+func (c *chatConsumer) handleChatCommand(ctx context.Context, delivery amqp091.Delivery) error {
+	var msg contracts.AmqpMessage
+	if err := json.Unmarshal(delivery.Body, &msg); err != nil {
+		log.Printf("failed to unmarshal message: %v", err)
+		return nil
+	}
+
+	var command messaging.ChatCommandData
+	if err := json.Unmarshal(msg.Data, &command); err != nil {
+		log.Printf("failed to unmarshal chat command: %v", err)
+		return nil
+	}
+
+	userID := msg.OwnerID
+	var err error
+	switch delivery.RoutingKey {
+	case contracts.ChatCmdSend:
+		err = c.handleSend(ctx, userID, command)
+	case contracts.ChatCmdReceipt:
+		err = c.handleReceipt(ctx, userID, command)
+	default:
+		return nil
+	}
+
+	if code, refused := chatRefusalCode(err); refused {
+		log.Printf("Refused %s of user %s for trip %s: %v", delivery.RoutingKey, userID, command.TripID, err)
+		return c.publishChatError(ctx, userID, delivery.RoutingKey, command, code, err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to handle %s for trip %s: %v", delivery.RoutingKey, command.TripID, err)
+		return err
+	}
+
+	return nil
+}
+
+// handleSend stores a message and sends it to the recipient, and back to the sender
+// correlated to their command. A command redelivered after a failed publish finds its
+// message stored, which is sent again with the same ID, so clients drop duplicates.
+func (c *chatConsumer) handleSend(ctx context.Context, senderID string, command messaging.ChatCommandData) error {
+	message, err := c.service.SendChatMessage(ctx, command.TripID, senderID, command.CommandID, command.Text)
+	if err != nil {
+		return err
+	}
+
+	marshalledMessage, err := json.Marshal(chatMessageData(message))
+	if err != nil {
+		return err
+	}
+
+	if err := c.messageBroker.Publish(ctx, contracts.ChatEventMessage, contracts.AmqpMessage{
+		OwnerID: message.RecipientID,
+		Data:    marshalledMessage,
+	}); err != nil {
+		return err
+	}
+
+	return c.messageBroker.Publish(ctx, contracts.ChatEventMessage, contracts.AmqpMessage{
+		OwnerID:       senderID,
+		Data:          marshalledMessage,
+		CorrelationID: command.CommandID,
+	})
+}
+
+// handleReceipt records that the recipient received or read messages, and tells their
+// sender. Receipts only fill in missing times, a redelivered receipt tells the sender
+// the times recorded the first time again.
+func (c *chatConsumer) handleReceipt(ctx context.Context, recipientID string, command messaging.ChatCommandData) error {
+	message, err := c.service.MarkChatMessages(ctx, command.TripID, recipientID, command.MessageID, command.Status)
+	if err != nil {
+		return err
+	}
+
+	at := message.DeliveredAt
+	if command.Status == contracts.ChatReceiptRead {
+		at = message.ReadAt
+	}
+
+	marshalledReceipt, err := json.Marshal(messaging.ChatReceiptData{
+		TripID:    command.TripID,
+		MessageID: command.MessageID,
+		Status:    command.Status,
+		At:        at,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.messageBroker.Publish(ctx, contracts.ChatEventReceipt, contracts.AmqpMessage{
+		OwnerID: message.SenderID,
+		Data:    marshalledReceipt,
+	})
+}
+
+// publishChatError answers a participant's chat command with the reason it was refused
+func (c *chatConsumer) publishChatError(ctx context.Context, userID, commandType string, command messaging.ChatCommandData, code, message string) error {
+	marshalledResult, err := json.Marshal(contracts.WSCommandResultData{
+		Command: commandType,
+		TripID:  command.TripID,
+		Code:    code,
+		Message: message,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.messageBroker.Publish(ctx, contracts.ChatEventError, contracts.AmqpMessage{
+		OwnerID:       userID,
+		Data:          marshalledResult,
+		CorrelationID: command.CommandID,
+	})
+}
+
+// publishChatClosed tells the rider and the driver of a trip that ended with the given
+// status that its chat is closed
+func publishChatClosed(ctx context.Context, messageBroker messaging.MessageBroker, trip *domain.TripModel, status string) error {
+	if !trip.HasDriver() {
+		// Nobody could chat about the trip
+		return nil
+	}
+
+	marshalledEvent, err := json.Marshal(messaging.ChatClosedData{
+		TripID:     trip.ID.Hex(),
+		TripStatus: status,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, participant := range []string{trip.UserID, trip.Driver.Id} {
+		if err := messageBroker.Publish(ctx, contracts.ChatEventClosed, contracts.AmqpMessage{
+			OwnerID: participant,
+			Data:    marshalledEvent,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func chatMessageData(m *domain.ChatMessageModel) messaging.ChatMessageData {
+	return messaging.ChatMessageData{
+		ID:          m.ID.Hex(),
+		TripID:      m.TripID,
+		SenderID:    m.SenderID,
+		SenderRole:  m.SenderRole,
+		Text:        m.Text,
+		SentAt:      m.SentAt,
+		DeliveredAt: optionalTime(m.DeliveredAt),
+		ReadAt:      optionalTime(m.ReadAt),
+	}
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// chatRefusalCode returns the API error code of the errors which refuse a chat
+// command, rather than failing to handle it
+func chatRefusalCode(err error) (string, bool) {
+	switch {
+	case err == nil:
+		return "", false
+	case errors.Is(err, domain.ErrTripNotFound), errors.Is(err, domain.ErrChatMessageNotFound):
+		return contracts.ErrCodeNotFound, true
+	case errors.Is(err, domain.ErrNotChatParticipant):
+		return contracts.ErrCodeForbidden, true
+	case errors.Is(err, domain.ErrChatClosed):
+		return contracts.ErrCodeChatClosed, true
+	case errors.Is(err, domain.ErrChatMessageRejected):
+		return contracts.ErrCodeMessageRejected, true
+	case errors.Is(err, domain.ErrChatFull):
+		return contracts.ErrCodeConflict, true
+	case errors.Is(err, domain.ErrInvalidChatMessage):
+		return contracts.ErrCodeBadRequest, true
+	default:
+		return "", false
+	}
+}
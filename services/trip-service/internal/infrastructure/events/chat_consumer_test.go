@@ -0,0 +1,300 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/services/trip-service/internal/infrastructure/repository"
+	"ride-sharing/services/trip-service/internal/service"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pbd "ride-sharing/shared/proto/driver"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestChatConsumer returns a chat consumer filtering contact details, and the
+// repositories behind it
+func newTestChatConsumer(broker messaging.MessageBroker) (*chatConsumer, domain.TripRepository, domain.ChatRepository) {
+	trips := repository.NewInmemRepository()
+	chats := repository.NewInmemChatRepository()
+	return NewChatConsumer(broker, service.NewChatService(trips, chats, domain.NewContactDetailsChatFilter())), trips, chats
+}
+
+func chatCommand(t *testing.T, commandType, userID string, command messaging.ChatCommandData) amqp091.Delivery {
+	t.Helper()
+	data, err := json.Marshal(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(contracts.AmqpMessage{OwnerID: userID, Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amqp091.Delivery{RoutingKey: commandType, Body: body}
+}
+
+// publishedData decodes the data of the i-th published message
+func publishedData[T any](t *testing.T, broker *recordingBroker, i int) T {
+	t.Helper()
+	var data T
+	if err := json.Unmarshal(broker.messages[i].Data, &data); err != nil {
+		t.Fatalf("invalid message %s: %v", broker.messages[i].Data, err)
+	}
+	return data
+}
+
+func TestChatConsumerSend(t *testing.T) {
+	broker := &recordingBroker{}
+	consumer, trips, _ := newTestChatConsumer(broker)
+	tripID := newRiderTrip(t, trips, domain.TripStatusAccepted)
+
+	command := messaging.ChatCommandData{CommandID: "cmd-1", TripID: tripID, Text: "on my way"}
+	if err := consumer.handleChatCommand(context.Background(), chatCommand(t, contracts.ChatCmdSend, "driver-1", command)); err != nil {
+		t.Fatalf("handleChatCommand() error = %v", err)
+	}
+
+	// The recipient gets the message, the sender its copy correlated to the command
+	want := []string{
+		contracts.ChatEventMessage + " -> rider-1",
+		contracts.ChatEventMessage + " -> driver-1",
+	}
+	if !slices.Equal(broker.published, want) {
+		t.Fatalf("published %v, want %v", broker.published, want)
+	}
+	if broker.messages[0].CorrelationID != "" || broker.messages[1].CorrelationID != "cmd-1" {
+		t.Errorf("correlation IDs = %q and %q, want none for the recipient and cmd-1 for the sender",
+			broker.messages[0].CorrelationID, broker.messages[1].CorrelationID)
+	}
+
+	received := publishedData[messaging.ChatMessageData](t, broker, 0)
+	echoed := publishedData[messaging.ChatMessageData](t, broker, 1)
+	if received != echoed {
+		t.Errorf("recipient got %+v, sender %+v, want the same message", received, echoed)
+	}
+	if received.ID == "" || received.TripID != tripID || received.SenderID != "driver-1" || received.SenderRole != domain.ChatRoleDriver || received.Text != "on my way" {
+		t.Errorf("message = %+v", received)
+	}
+}
+
+func TestChatConsumerReceipt(t *testing.T) {
+	broker := &recordingBroker{}
+	consumer, trips, _ := newTestChatConsumer(broker)
+	tripID := newRiderTrip(t, trips, domain.TripStatusAccepted)
+
+	send := messaging.ChatCommandData{CommandID: "cmd-1", TripID: tripID, Text: "on my way"}
+	if err := consumer.handleChatCommand(context.Background(), chatCommand(t, contracts.ChatCmdSend, "driver-1", send)); err != nil {
+		t.Fatalf("handleChatCommand(send) error = %v", err)
+	}
+	messageID := publishedData[messaging.ChatMessageData](t, broker, 0).ID
+
+	receipt := messaging.ChatCommandData{CommandID: "cmd-2", TripID: tripID, MessageID: messageID, Status: contracts.ChatReceiptRead}
+	delivery := chatCommand(t, contracts.ChatCmdReceipt, "rider-1", receipt)
+	if err := consumer.handleChatCommand(context.Background(), delivery); err != nil {
+		t.Fatalf("handleChatCommand(receipt) error = %v", err)
+	}
+
+	// The receipt reaches the sender of the message
+	if got := broker.published[2:]; !slices.Equal(got, []string{contracts.ChatEventReceipt + " -> driver-1"}) {
+		t.Fatalf("published %v, want the receipt to driver-1", got)
+	}
+	data := publishedData[messaging.ChatReceiptData](t, broker, 2)
+	if data.TripID != tripID || data.MessageID != messageID || data.Status != contracts.ChatReceiptRead || data.At.IsZero() {
+		t.Errorf("receipt = %+v", data)
+	}
+
+	// A redelivered receipt tells the same time again
+	delivery.Redelivered = true
+	if err := consumer.handleChatCommand(context.Background(), delivery); err != nil {
+		t.Fatalf("handleChatCommand(redelivered receipt) error = %v", err)
+	}
+	if again := publishedData[messaging.ChatReceiptData](t, broker, 3); !again.At.Equal(data.At) {
+		t.Errorf("redelivered receipt at %v, want %v", again.At, data.At)
+	}
+}
+
+func TestChatConsumerRefusals(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		unknownTrip bool
+		fullChat    bool
+		commandType string
+		userID      string
+		command     messaging.ChatCommandData
+		wantCode    string
+	}{
+		{"unknown trip", domain.TripStatusAccepted, true, false, contracts.ChatCmdSend, "rider-1", messaging.ChatCommandData{Text: "hello"}, contracts.ErrCodeNotFound},
+		{"not a participant", domain.TripStatusAccepted, false, false, contracts.ChatCmdSend, "driver-2", messaging.ChatCommandData{Text: "hello"}, contracts.ErrCodeForbidden},
+		{"completed trip", domain.TripStatusCompleted, false, false, contracts.ChatCmdSend, "rider-1", messaging.ChatCommandData{Text: "hello"}, contracts.ErrCodeChatClosed},
+		{"contact details", domain.TripStatusAccepted, false, false, contracts.ChatCmdSend, "rider-1", messaging.ChatCommandData{Text: "call me on 4155550123"}, contracts.ErrCodeMessageRejected},
+		{"empty text", domain.TripStatusAccepted, false, false, contracts.ChatCmdSend, "rider-1", messaging.ChatCommandData{Text: " "}, contracts.ErrCodeBadRequest},
+		{"full chat", domain.TripStatusAccepted, false, true, contracts.ChatCmdSend, "rider-1", messaging.ChatCommandData{Text: "hello"}, contracts.ErrCodeConflict},
+		{"receipt of unknown message", domain.TripStatusAccepted, false, false, contracts.ChatCmdReceipt, "rider-1", messaging.ChatCommandData{MessageID: primitive.NewObjectID().Hex(), Status: contracts.ChatReceiptRead}, contracts.ErrCodeNotFound},
+		{"unknown receipt status", domain.TripStatusAccepted, false, false, contracts.ChatCmdReceipt, "rider-1", messaging.ChatCommandData{MessageID: primitive.NewObjectID().Hex(), Status: "seen"}, contracts.ErrCodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			broker := &recordingBroker{}
+			consumer, trips, chats := newTestChatConsumer(broker)
+
+			tripID := newRiderTrip(t, trips, tt.status)
+			if tt.unknownTrip {
+				tripID = primitive.NewObjectID().Hex()
+			}
+			if tt.fullChat {
+				for range domain.MaxChatMessagesPerTrip {
+					message := &domain.ChatMessageModel{ID: primitive.NewObjectID(), TripID: tripID, SenderID: "driver-1", RecipientID: "rider-1"}
+					if err := chats.AddChatMessage(ctx, message, domain.MaxChatMessagesPerTrip); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			command := tt.command
+			command.CommandID = "cmd-1"
+			command.TripID = tripID
+			if err := consumer.handleChatCommand(ctx, chatCommand(t, tt.commandType, tt.userID, command)); err != nil {
+				t.Fatalf("handleChatCommand() error = %v", err)
+			}
+
+			if want := []string{contracts.ChatEventError + " -> " + tt.userID}; !slices.Equal(broker.published, want) {
+				t.Fatalf("published %v, want %v", broker.published, want)
+			}
+			result := publishedData[contracts.WSCommandResultData](t, broker, 0)
+			if broker.messages[0].CorrelationID != "cmd-1" || result.Command != tt.commandType || result.TripID != tripID || result.Code != tt.wantCode {
+				t.Errorf("chat error = %+v correlated to %q, want %s of cmd-1", result, broker.messages[0].CorrelationID, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestChatConsumerRedeliveredSend(t *testing.T) {
+	ctx := context.Background()
+	broker := &recordingBroker{failOnce: contracts.ChatEventMessage + " -> rider-1"}
+	consumer, trips, chats := newTestChatConsumer(broker)
+	tripID := newRiderTrip(t, trips, domain.TripStatusAccepted)
+
+	command := messaging.ChatCommandData{CommandID: "cmd-1", TripID: tripID, Text: "on my way"}
+	delivery := chatCommand(t, contracts.ChatCmdSend, "driver-1", command)
+	if err := consumer.handleChatCommand(ctx, delivery); !errors.Is(err, errPublishFailed) {
+		t.Fatalf("handleChatCommand() error = %v, want the publish error to requeue the command", err)
+	}
+
+	delivery.Redelivered = true
+	if err := consumer.handleChatCommand(ctx, delivery); err != nil {
+		t.Fatalf("handleChatCommand(redelivered) error = %v", err)
+	}
+
+	// The message is stored once and published with the same ID
+	messages, err := chats.ListChatMessages(ctx, tripID)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("ListChatMessages() = %v, %v, want one message", messages, err)
+	}
+	want := []string{
+		contracts.ChatEventMessage + " -> rider-1",
+		contracts.ChatEventMessage + " -> driver-1",
+	}
+	if !slices.Equal(broker.published, want) {
+		t.Fatalf("published %v, want %v", broker.published, want)
+	}
+	if id := publishedData[messaging.ChatMessageData](t, broker, 0).ID; id != messages[0].ID.Hex() {
+		t.Errorf("published message %s, want the stored %s", id, messages[0].ID.Hex())
+	}
+}
+
+// driverCommand is a trip command of driver-1
+func driverCommand(t *testing.T, commandType, tripID string) amqp091.Delivery {
+	t.Helper()
+	data, err := json.Marshal(messaging.DriveTripResponseData{TripID: tripID, Driver: &pbd.Driver{Id: "driver-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(contracts.AmqpMessage{OwnerID: "driver-1", Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amqp091.Delivery{RoutingKey: commandType, Body: body}
+}
+
+func TestChatClosedOnCompletion(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInmemRepository()
+	// Closing the chat for the driver fails once, the command is redelivered
+	broker := &recordingBroker{failOnce: contracts.ChatEventClosed + " -> driver-1"}
+	consumer := NewDriverConsumer(broker, service.NewService(repo))
+	tripID := newRiderTrip(t, repo, domain.TripStatusInProgress)
+
+	delivery := driverCommand(t, contracts.DriverCmdTripComplete, tripID)
+	if err := consumer.handleDriverResponse(ctx, delivery); !errors.Is(err, errPublishFailed) {
+		t.Fatalf("handleDriverResponse() error = %v, want the publish error to requeue the command", err)
+	}
+
+	delivery.Redelivered = true
+	if err := consumer.handleDriverResponse(ctx, delivery); err != nil {
+		t.Fatalf("handleDriverResponse(redelivered) error = %v", err)
+	}
+
+	// The completed trip is published again, now with the chat closed for both
+	want := []string{
+		contracts.TripEventCompleted + " -> rider-1",
+		contracts.ChatEventClosed + " -> rider-1",
+		contracts.TripEventCompleted + " -> rider-1",
+		contracts.ChatEventClosed + " -> rider-1",
+		contracts.ChatEventClosed + " -> driver-1",
+	}
+	if !slices.Equal(broker.published, want) {
+		t.Fatalf("published %v, want %v", broker.published, want)
+	}
+	closed := publishedData[messaging.ChatClosedData](t, broker, 4)
+	if closed.TripID != tripID || closed.TripStatus != domain.TripStatusCompleted {
+		t.Errorf("chat closed = %+v, want trip %s completed", closed, tripID)
+	}
+
+	// A command sent again, rather than redelivered, publishes nothing
+	delivery.Redelivered = false
+	if err := consumer.handleDriverResponse(ctx, delivery); err != nil {
+		t.Fatalf("handleDriverResponse(repeated) error = %v", err)
+	}
+	if len(broker.published) != len(want) {
+		t.Errorf("repeated command published %v", broker.published[len(want):])
+	}
+}
+
+func TestRedeliveredCancelClosesChat(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInmemRepository()
+	broker := &recordingBroker{failOnce: contracts.ChatEventClosed + " -> driver-1"}
+	consumer := NewRiderConsumer(broker, service.NewService(repo))
+	tripID := newRiderTrip(t, repo, domain.TripStatusAccepted)
+
+	delivery := riderCommand(t, contracts.RiderCmdCancelTrip, "rider-1", messaging.RiderCommandData{CommandID: "cmd-1", TripID: tripID})
+	if err := consumer.handleRiderCommand(ctx, delivery); !errors.Is(err, errPublishFailed) {
+		t.Fatalf("handleRiderCommand() error = %v, want the publish error to requeue the command", err)
+	}
+
+	delivery.Redelivered = true
+	if err := consumer.handleRiderCommand(ctx, delivery); err != nil {
+		t.Fatalf("handleRiderCommand(redelivered) error = %v", err)
+	}
+
+	var closedFor []string
+	for _, published := range broker.published {
+		if owner, ok := strings.CutPrefix(published, contracts.ChatEventClosed+" -> "); ok {
+			closedFor = append(closedFor, owner)
+		}
+	}
+	if !slices.Contains(closedFor, "driver-1") {
+		t.Errorf("chat closed for %v, want driver-1 told after the redelivery", closedFor)
+	}
+	if results := commandResults(t, broker); len(results) != 1 || results[0].Code != "" {
+		t.Errorf("command results = %+v, want one ack", results)
+	}
+}
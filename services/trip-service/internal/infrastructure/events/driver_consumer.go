@@ -3,7 +3,7 @@ package events
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"ride-sharing/services/trip-service/internal/domain"
 	"ride-sharing/shared/contracts"
@@ -49,6 +49,10 @@ func (c *driverConsumer) handleDriverResponse(ctx context.Context, delivery amqp
 		return err
 	}
 
+	if delivery.RoutingKey == contracts.DriverEventTripOffered {
+		return c.handleTripOffered(ctx, msg.Data)
+	}
+
 	var payload messaging.DriveTripResponseData
 	if err := json.Unmarshal(msg.Data, &payload); err != nil {
 		log.Printf("failed to unmarshal message: %v", err)
@@ -64,73 +68,217 @@ func (c *driverConsumer) handleDriverResponse(ctx context.Context, delivery amqp
 			return err
 		}
 	case contracts.DriverCmdTripDecline:
-		if err := c.handleTripDeclined(ctx, payload.TripID, payload.RiderID); err != nil {
+		if err := c.handleTripDeclined(ctx, payload.TripID, payload.Driver); err != nil {
 			log.Printf("Failed to handle trip declined: %v", err)
 			return err
 		}
 		return nil
+	case contracts.DriverCmdTripStart:
+		if err := c.handleTripProgress(ctx, payload.TripID, payload.Driver, domain.TripStatusAccepted, domain.TripStatusInProgress, contracts.TripEventStarted, delivery.Redelivered); err != nil {
+			log.Printf("Failed to handle trip start: %v", err)
+			return err
+		}
+	case contracts.DriverCmdTripComplete:
+		if err := c.handleTripProgress(ctx, payload.TripID, payload.Driver, domain.TripStatusInProgress, domain.TripStatusCompleted, contracts.TripEventCompleted, delivery.Redelivered); err != nil {
+			log.Printf("Failed to handle trip complete: %v", err)
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (c *driverConsumer) handleTripDeclined(ctx context.Context, tripID, riderID string) error {
+// handleTripOffered records which drivers were offered a trip, so the first
+// accept can revoke the others
+func (c *driverConsumer) handleTripOffered(ctx context.Context, data []byte) error {
+	var payload messaging.TripOfferedData
+	if err := json.Unmarshal(data, &payload); err != nil {
+		log.Printf("failed to unmarshal trip offers: %v", err)
+		return nil
+	}
+
+	if err := c.service.RecordTripOffers(ctx, payload.TripID, payload.DriverIDs); err != nil {
+		// Unknown trips will never appear, don't requeue
+		log.Printf("Failed to record offers for trip %s: %v", payload.TripID, err)
+	}
+
+	return nil
+}
+
+func (c *driverConsumer) handleTripDeclined(ctx context.Context, tripID string, driver *pb.Driver) error {
 	trip, err := c.service.GetTripByID(ctx, tripID)
 	if err != nil {
 		return err
 	}
 
-	newPayload := messaging.TripCreatedEvent{
+	if trip == nil || trip.Status != domain.TripStatusPending {
+		// Late declines of trips another driver already took need no new driver
+		log.Printf("Ignoring decline for trip %s: no longer pending", tripID)
+		return nil
+	}
+
+	if driver != nil {
+		remaining, err := c.service.DeclineTripOffer(ctx, tripID, driver.Id)
+		if errors.Is(err, domain.ErrTripNotOffered) {
+			// The offer expired or was declined before, the trip was dispatched again already
+			log.Printf("Ignoring decline of driver %s for trip %s: no open offer", driver.Id, tripID)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if remaining > 0 {
+			// Other drivers are still considering the trip
+			log.Printf("Driver %s declined trip %s, %d offers still open", driver.Id, tripID, remaining)
+			return nil
+		}
+	}
+
+	return publishRedispatch(ctx, c.messageBroker, trip)
+}
+
+// publishRedispatch asks driver-service to offer a pending trip to other drivers.
+// It answers the rider with no_drivers_found when there are none.
+func publishRedispatch(ctx context.Context, broker messaging.MessageBroker, trip *domain.TripModel) error {
+	marshalledPayload, err := json.Marshal(messaging.TripCreatedEvent{
 		Trip: trip.ToProto(),
+	})
+	if err != nil {
+		return err
 	}
 
-	mashalledPayload, err := json.Marshal(newPayload)
+	return broker.Publish(ctx, contracts.TripEventDriverNotInterested, contracts.AmqpMessage{
+		OwnerID: trip.UserID,
+		Data:    marshalledPayload,
+	})
+}
+
+// handleTripAccepted assigns the trip to the first driver to accept it. The other
+// offered drivers get their offer revoked and late accepts are told the trip is taken.
+func (c *driverConsumer) handleTripAccepted(ctx context.Context, tripID string, driver *pb.Driver) error {
+	if driver == nil {
+		log.Printf("Ignoring accept for trip %s without a driver", tripID)
+		return nil
+	}
+
+	trip, revoked, err := c.service.AcceptTripOffer(ctx, tripID, driver)
+	if errors.Is(err, domain.ErrTripAlreadyTaken) {
+		if current, err := c.service.GetTripByID(ctx, tripID); err == nil && current != nil &&
+			current.Driver != nil && current.Driver.Id == driver.Id {
+			// A redelivered accept of the winning driver
+			return nil
+		}
+
+		log.Printf("Driver %s accepted trip %s too late", driver.Id, tripID)
+		return publishOfferClosed(ctx, c.messageBroker, contracts.DriverCmdTripAlreadyTaken, tripID, driver.Id, messaging.OfferClosedAlreadyTaken)
+	}
+	if errors.Is(err, domain.ErrTripNotOffered) {
+		log.Printf("Driver %s accepted trip %s without an open offer", driver.Id, tripID)
+		return publishOfferClosed(ctx, c.messageBroker, contracts.DriverCmdTripAlreadyTaken, tripID, driver.Id, messaging.OfferClosedNotOffered)
+	}
+	if errors.Is(err, domain.ErrTripOfferExpired) {
+		log.Printf("Driver %s accepted trip %s after the offer expired", driver.Id, tripID)
+		return publishOfferClosed(ctx, c.messageBroker, contracts.DriverCmdTripOfferRevoked, tripID, driver.Id, messaging.OfferClosedExpired)
+	}
 	if err != nil {
+		log.Printf("Failed to update trip: %v", err)
 		return err
 	}
 
-	if err := c.messageBroker.Publish(ctx, contracts.TripEventDriverNotInterested, contracts.AmqpMessage{
-		OwnerID: riderID,
-		Data:    mashalledPayload,
+	marshalledTrip, err := json.Marshal(trip.ToProto())
+	if err != nil {
+		return nil
+	}
+
+	//Notify the rider that a driver has been assigned
+	if err := c.messageBroker.Publish(ctx, contracts.TripEventDriverAssigned, contracts.AmqpMessage{
+		OwnerID: trip.UserID,
+		Data:    marshalledTrip,
 	}); err != nil {
 		return err
 	}
 
+	for _, driverID := range revoked {
+		if err := publishOfferClosed(ctx, c.messageBroker, contracts.DriverCmdTripOfferRevoked, tripID, driverID, messaging.OfferClosedAcceptedByAnother); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (c *driverConsumer) handleTripAccepted(ctx context.Context, tripID string, driver *pb.Driver) error {
-	trip, err := c.service.GetTripByID(ctx, tripID)
+// publishOfferClosed tells a driver their offer for a trip is no longer open
+func publishOfferClosed(ctx context.Context, broker messaging.MessageBroker, routingKey, tripID, driverID, reason string) error {
+	marshalledPayload, err := json.Marshal(messaging.TripOfferClosedData{
+		TripID:   tripID,
+		DriverID: driverID,
+		Reason:   reason,
+	})
 	if err != nil {
 		return err
 	}
 
-	if trip == nil {
-		return fmt.Errorf("trip not found %s", tripID)
-	}
+	return broker.Publish(ctx, routingKey, contracts.AmqpMessage{
+		OwnerID: driverID,
+		Data:    marshalledPayload,
+	})
+}
 
-	if err := c.service.UpdateTrip(ctx, tripID, "accepted", driver); err != nil {
-		log.Printf("Failed to update trip: %v", err)
-		return err
+// handleTripProgress moves a trip from one status to the next on behalf of its
+// assigned driver and tells the rider and driver-service about it. Completing the
+// trip closes its chat. A command redelivered after a failed publish finds the trip
+// moved already, and publishes again.
+func (c *driverConsumer) handleTripProgress(ctx context.Context, tripID string, driver *pb.Driver, from, to, routingKey string, redelivered bool) error {
+	if driver == nil {
+		log.Printf("Ignoring %s for trip %s without a driver", routingKey, tripID)
+		return nil
 	}
 
-	trip, err = c.service.GetTripByID(ctx, tripID)
+	// The transition is checked and applied at once, so redelivered or concurrent
+	// commands can't both move the trip
+	trip, err := c.service.TransitionTrip(ctx, tripID, driver.Id, from, to)
+	if errors.Is(err, domain.ErrTripNotModifiable) && redelivered {
+		trip, err = c.movedTrip(ctx, tripID, driver.Id, to, err)
+	}
+	if errors.Is(err, domain.ErrTripNotFound) || errors.Is(err, domain.ErrNotTripDriver) || errors.Is(err, domain.ErrTripNotModifiable) {
+		// Retrying won't change the answer, don't requeue
+		log.Printf("Ignoring %s for trip %s: %v", routingKey, tripID, err)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	marshalledTrip, err := json.Marshal(trip)
+	marshalledEvent, err := json.Marshal(messaging.TripCreatedEvent{
+		Trip: trip.ToProto(),
+	})
 	if err != nil {
-		return nil
+		return err
 	}
 
-	//Notify the rider that a driver has been assigned
-	if err := c.messageBroker.Publish(ctx, contracts.TripEventDriverAssigned, contracts.AmqpMessage{
+	if err := c.messageBroker.Publish(ctx, routingKey, contracts.AmqpMessage{
 		OwnerID: trip.UserID,
-		Data:    marshalledTrip,
+		Data:    marshalledEvent,
 	}); err != nil {
 		return err
 	}
 
+	if to == domain.TripStatusCompleted {
+		return publishChatClosed(ctx, c.messageBroker, trip, to)
+	}
 	return nil
 }
+
+// movedTrip returns the trip a redelivered command moved to the status to already,
+// or notModifiable if it is in another status
+func (c *driverConsumer) movedTrip(ctx context.Context, tripID, driverID, to string, notModifiable error) (*domain.TripModel, error) {
+	trip, err := c.service.GetTripByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	if trip == nil || trip.Status != to || !trip.HasDriver() || trip.Driver.Id != driverID {
+		return nil, notModifiable
+	}
+	return trip, nil
+}
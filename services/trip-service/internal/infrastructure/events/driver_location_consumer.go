@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/types"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// driverLocationConsumer relays driver positions to the rider of the driver's active trip
+type driverLocationConsumer struct {
+	messageBroker messaging.MessageBroker
+	service       domain.TripService
+}
+
+// NewDriverLocationConsumer creates a new driver location consumer
+func NewDriverLocationConsumer(messageBroker messaging.MessageBroker, service domain.TripService) *driverLocationConsumer {
+	return &driverLocationConsumer{
+		messageBroker: messageBroker,
+		service:       service,
+	}
+}
+
+// ConsumeDriverLocation starts consuming driver location updates from the queue
+func (c *driverLocationConsumer) ConsumeDriverLocation(ctx context.Context, queue string, handler messaging.MessageHandler) error {
+	// If no handler provided, use the default handleDriverLocation
+	if handler == nil {
+		handler = c.handleDriverLocation
+	}
+	return c.messageBroker.Consume(ctx, queue, handler)
+}
+
+// handleDriverLocation forwards a driver position to the rider while the trip is active
+func (c *driverLocationConsumer) handleDriverLocation(ctx context.Context, delivery amqp091.Delivery) error {
+	var msg contracts.AmqpMessage
+	if err := json.Unmarshal(delivery.Body, &msg); err != nil {
+		log.Printf("failed to unmarshal message: %v", err)
+		return nil
+	}
+
+	var payload messaging.DriverLocationData
+	if err := json.Unmarshal(msg.Data, &payload); err != nil {
+		log.Printf("failed to unmarshal driver location: %v", err)
+		return nil
+	}
+
+	if payload.Location == nil {
+		return nil
+	}
+
+	trip, err := c.service.GetActiveTripByDriverID(ctx, payload.DriverID)
+	if err != nil {
+		return err
+	}
+
+	// Only drivers with an assigned rider are tracked; completed and
+	// cancelled trips are no longer active, so their updates stop here
+	if trip == nil {
+		return nil
+	}
+
+	locationEvent := messaging.TripDriverLocationEvent{
+		TripID:   trip.ID.Hex(),
+		DriverID: payload.DriverID,
+		Location: payload.Location,
+		Heading:  payload.Heading,
+	}
+
+	// The pickup ETA only matters until the rider is picked up. Without an
+	// estimate the rider still gets the position, just no ETA.
+	if trip.Status == domain.TripStatusAccepted {
+		distance, eta, err := c.service.EstimatePickup(trip, &types.Coordinate{
+			Latitude:  payload.Location.Latitude,
+			Longitude: payload.Location.Longitude,
+		})
+		if err != nil {
+			log.Printf("failed to estimate pickup for trip %s: %v", trip.ID.Hex(), err)
+		} else {
+			locationEvent.DistanceToPickupMeters = &distance
+			locationEvent.PickupETASeconds = &eta
+		}
+	}
+
+	event, err := json.Marshal(locationEvent)
+	if err != nil {
+		return err
+	}
+
+	// The rider is the owner, so only the trip's own rider receives the update
+	return c.messageBroker.Publish(ctx, contracts.TripEventDriverLocation, contracts.AmqpMessage{
+		OwnerID: trip.UserID,
+		Data:    event,
+	})
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/services/trip-service/internal/infrastructure/repository"
+	"ride-sharing/services/trip-service/internal/service"
+	tripTypes "ride-sharing/services/trip-service/pkg/types"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pbd "ride-sharing/shared/proto/driver"
+	pb "ride-sharing/shared/proto/trip"
+	"testing"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recordingBroker keeps what was published, as "routing key -> owner" and in full
+type recordingBroker struct {
+	messaging.MessageBroker
+	published []string
+	messages  []contracts.AmqpMessage
+	// failOnce fails the next publish matching it, as "routing key -> owner"
+	failOnce string
+}
+
+var errPublishFailed = errors.New("publish failed")
+
+func (b *recordingBroker) Publish(ctx context.Context, routingKey string, msg contracts.AmqpMessage) error {
+	published := fmt.Sprintf("%s -> %s", routingKey, msg.OwnerID)
+	if published == b.failOnce {
+		b.failOnce = ""
+		return errPublishFailed
+	}
+	b.published = append(b.published, published)
+	b.messages = append(b.messages, msg)
+	return nil
+}
+
+func driverLocation(t *testing.T, driverID string, location *pbd.Location) amqp091.Delivery {
+	t.Helper()
+	data, err := json.Marshal(messaging.DriverLocationData{DriverID: driverID, Location: location})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(contracts.AmqpMessage{OwnerID: driverID, Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amqp091.Delivery{RoutingKey: contracts.DriverCmdLocation, Body: body}
+}
+
+func TestDriverLocationConsumerRelaysToRider(t *testing.T) {
+	withRoute := &tripTypes.OsrmApiResponse{}
+	if err := json.Unmarshal([]byte(`{"routes":[{"geometry":{"coordinates":[[-122.42,37.77],[-122.4,37.8]]}}]}`), withRoute); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		route   *tripTypes.OsrmApiResponse
+		wantETA bool
+	}{
+		{"with pickup", withRoute, true},
+		// Without a pickup there is no estimate, the position is still relayed
+		{"without pickup", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewInmemRepository()
+			broker := &recordingBroker{}
+			consumer := NewDriverLocationConsumer(broker, service.NewService(repo))
+
+			trip := &domain.TripModel{
+				ID:       primitive.NewObjectID(),
+				UserID:   "rider-1",
+				Status:   domain.TripStatusAccepted,
+				RideFare: &domain.RideFareModel{Route: tt.route},
+				Driver:   &pb.TripDriver{Id: "driver-1"},
+			}
+			if _, err := repo.CreateTrip(context.Background(), trip); err != nil {
+				t.Fatalf("CreateTrip: %v", err)
+			}
+
+			location := &pbd.Location{Latitude: 37.78, Longitude: -122.41}
+			if err := consumer.handleDriverLocation(context.Background(), driverLocation(t, "driver-1", location)); err != nil {
+				t.Fatalf("handleDriverLocation() error = %v", err)
+			}
+
+			if len(broker.messages) != 1 || broker.published[0] != contracts.TripEventDriverLocation+" -> rider-1" {
+				t.Fatalf("published %v, want a single location event to rider-1", broker.published)
+			}
+			var fields map[string]any
+			if err := json.Unmarshal(broker.messages[0].Data, &fields); err != nil {
+				t.Fatal(err)
+			}
+			for _, field := range []string{"distanceToPickupMeters", "pickupEtaSeconds"} {
+				value, ok := fields[field]
+				if ok != tt.wantETA {
+					t.Errorf("%s = %v, want it set: %v", field, value, tt.wantETA)
+				}
+				if ok && value.(float64) <= 0 {
+					t.Errorf("%s = %v, want a positive estimate", field, value)
+				}
+			}
+		})
+	}
+}
+
+func TestDriverLocationConsumerIgnoresDriversWithoutTrip(t *testing.T) {
+	broker := &recordingBroker{}
+	consumer := NewDriverLocationConsumer(broker, service.NewService(repository.NewInmemRepository()))
+
+	location := &pbd.Location{Latitude: 37.78, Longitude: -122.41}
+	if err := consumer.handleDriverLocation(context.Background(), driverLocation(t, "driver-1", location)); err != nil {
+		t.Fatalf("handleDriverLocation() error = %v", err)
+	}
+	if len(broker.published) != 0 {
+		t.Errorf("published %v, want nothing", broker.published)
+	}
+}
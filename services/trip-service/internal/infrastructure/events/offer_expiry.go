@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"log"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"time"
+)
+
+// offerExpiryInterval is how often expired offers are looked for
+const offerExpiryInterval = time.Second
+
+// offerExpiry closes the offers nobody accepted within domain.TripOfferTimeout, so
+// a driver who never answers can't keep a trip pending forever
+type offerExpiry struct {
+	messageBroker messaging.MessageBroker
+	service       domain.TripService
+}
+
+// NewOfferExpiry creates the expiry of unanswered trip offers
+func NewOfferExpiry(messageBroker messaging.MessageBroker, service domain.TripService) *offerExpiry {
+	return &offerExpiry{
+		messageBroker: messageBroker,
+		service:       service,
+	}
+}
+
+// Run expires offers until ctx is done
+func (e *offerExpiry) Run(ctx context.Context) {
+	ticker := time.NewTicker(offerExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.expireOffers(ctx)
+		}
+	}
+}
+
+// expireOffers revokes the expired offers and dispatches their trips again.
+// driver-service answers the rider with no_drivers_found when nobody else is left.
+func (e *offerExpiry) expireOffers(ctx context.Context) {
+	trips, err := e.service.ExpireTripOffers(ctx)
+	if err != nil {
+		log.Printf("Failed to expire trip offers: %v", err)
+		return
+	}
+
+	for _, trip := range trips {
+		tripID := trip.ID.Hex()
+		log.Printf("Offers of trip %s to %v expired", tripID, trip.OfferedDriverIDs)
+
+		for _, driverID := range trip.OfferedDriverIDs {
+			if err := publishOfferClosed(ctx, e.messageBroker, contracts.DriverCmdTripOfferRevoked, tripID, driverID, messaging.OfferClosedExpired); err != nil {
+				log.Printf("Failed to revoke the expired offer of trip %s to driver %s: %v", tripID, driverID, err)
+			}
+		}
+
+		if err := publishRedispatch(ctx, e.messageBroker, trip); err != nil {
+			log.Printf("Failed to dispatch trip %s again: %v", tripID, err)
+		}
+	}
+}
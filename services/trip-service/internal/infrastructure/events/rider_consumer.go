@@ -0,0 +1,247 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	"ride-sharing/shared/types"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// riderConsumer applies the commands riders send about their trips
+type riderConsumer struct {
+	messageBroker messaging.MessageBroker
+	service       domain.TripService
+}
+
+// NewRiderConsumer creates a new rider command consumer
+func NewRiderConsumer(messageBroker messaging.MessageBroker, service domain.TripService) *riderConsumer {
+	return &riderConsumer{
+		messageBroker: messageBroker,
+		service:       service,
+	}
+}
+
+// ConsumeRiderCommands starts consuming rider commands from the queue
+func (c *riderConsumer) ConsumeRiderCommands(ctx context.Context, queue string, handler messaging.MessageHandler) error {
+	if handler == nil {
+		handler = c.handleRiderCommand
+	}
+	return c.messageBroker.Consume(ctx, queue, handler)
+}
+
+// handleRiderCommand applies a command to the rider's trip and answers the rider
+// with an ack, or an error when the trip doesn't allow it
+func (c *riderConsumer) handleRiderCommand(ctx context.Context, delivery amqp091.Delivery) error {
+	var msg contracts.AmqpMessage
+	if err := json.Unmarshal(delivery.Body, &msg); err != nil {
+		log.Printf("failed to unmarshal message: %v", err)
+		return nil
+	}
+
+	var command messaging.RiderCommandData
+	if err := json.Unmarshal(msg.Data, &command); err != nil {
+		log.Printf("failed to unmarshal rider command: %v", err)
+		return nil
+	}
+
+	riderID := msg.OwnerID
+	var err error
+	switch delivery.RoutingKey {
+	case contracts.RiderCmdCancelTrip:
+		err = c.handleCancelTrip(ctx, riderID, command, delivery.Redelivered)
+	case contracts.RiderCmdUpdatePickup:
+		err = c.handleRouteChange(ctx, riderID, command, c.service.UpdatePickup)
+	case contracts.RiderCmdAddStop:
+		err = c.handleRouteChange(ctx, riderID, command, c.service.AddStop)
+	default:
+		return nil
+	}
+
+	if code, refused := refusalCode(err); refused {
+		log.Printf("Refused %s of rider %s for trip %s: %v", delivery.RoutingKey, riderID, command.TripID, err)
+		return c.publishCommandResult(ctx, contracts.RiderEventCommandError, riderID, delivery.RoutingKey, command, code, err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to handle %s for trip %s: %v", delivery.RoutingKey, command.TripID, err)
+		return err
+	}
+
+	return c.publishCommandResult(ctx, contracts.RiderEventCommandAck, riderID, delivery.RoutingKey, command, "", "")
+}
+
+// handleCancelTrip cancels the trip and tells the rider, driver-service and every
+// driver who was offered or assigned the trip. The chat with the assigned driver closes.
+func (c *riderConsumer) handleCancelTrip(ctx context.Context, riderID string, command messaging.RiderCommandData, redelivered bool) error {
+	trip, revoked, err := c.service.CancelTrip(ctx, command.TripID, riderID)
+	if errors.Is(err, domain.ErrTripAlreadyCancelled) {
+		if redelivered {
+			// Publishing failed the first time, tell the rider and the driver again. The
+			// revoked offers are gone with the cancel, they expire on their own.
+			return c.republishCancelled(ctx, command.TripID)
+		}
+		// A retried cancel is acked again, everyone was told the first time
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Rider %s cancelled trip %s (%s)", riderID, command.TripID, command.Reason)
+
+	return c.publishCancelled(ctx, trip, revoked)
+}
+
+// republishCancelled publishes the cancel of a trip again
+func (c *riderConsumer) republishCancelled(ctx context.Context, tripID string) error {
+	trip, err := c.service.GetTripByID(ctx, tripID)
+	if err != nil {
+		return err
+	}
+	if trip == nil {
+		return nil
+	}
+	return c.publishCancelled(ctx, trip, nil)
+}
+
+// publishCancelled tells the rider, the assigned driver and the drivers whose offers
+// were revoked that a trip is cancelled
+func (c *riderConsumer) publishCancelled(ctx context.Context, trip *domain.TripModel, revoked []string) error {
+	tripID := trip.ID.Hex()
+
+	marshalledEvent, err := json.Marshal(messaging.TripCreatedEvent{
+		Trip: trip.ToProto(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.messageBroker.Publish(ctx, contracts.TripEventCancelled, contracts.AmqpMessage{
+		OwnerID: trip.UserID,
+		Data:    marshalledEvent,
+	}); err != nil {
+		return err
+	}
+
+	if trip.HasDriver() {
+		if err := c.publishOfferClosed(ctx, contracts.DriverCmdTripCancelled, tripID, trip.Driver.Id); err != nil {
+			return err
+		}
+	}
+
+	if err := publishChatClosed(ctx, c.messageBroker, trip, trip.Status); err != nil {
+		return err
+	}
+
+	for _, driverID := range revoked {
+		if err := c.publishOfferClosed(ctx, contracts.DriverCmdTripOfferRevoked, tripID, driverID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleRouteChange moves the pickup point or adds a stop, and tells the rider and
+// the assigned driver where the trip now goes
+func (c *riderConsumer) handleRouteChange(
+	ctx context.Context,
+	riderID string,
+	command messaging.RiderCommandData,
+	change func(ctx context.Context, tripID, riderID string, location *types.Coordinate) (*domain.TripModel, error)) error {
+	trip, err := change(ctx, command.TripID, riderID, command.Location)
+	if err != nil {
+		return err
+	}
+
+	event := messaging.TripRouteUpdatedEvent{
+		TripID: command.TripID,
+		Pickup: trip.Pickup,
+		Stops:  trip.Stops,
+	}
+	if event.Stops == nil {
+		// Clients read stops as a list
+		event.Stops = []*types.Coordinate{}
+	}
+
+	marshalledEvent, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	recipients := []string{trip.UserID}
+	if trip.HasDriver() {
+		recipients = append(recipients, trip.Driver.Id)
+	}
+
+	for _, recipient := range recipients {
+		if err := c.messageBroker.Publish(ctx, contracts.TripEventRouteUpdated, contracts.AmqpMessage{
+			OwnerID: recipient,
+			Data:    marshalledEvent,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publishOfferClosed tells a driver the rider cancelled the trip they were offered or assigned
+func (c *riderConsumer) publishOfferClosed(ctx context.Context, routingKey, tripID, driverID string) error {
+	marshalledPayload, err := json.Marshal(messaging.TripOfferClosedData{
+		TripID:   tripID,
+		DriverID: driverID,
+		Reason:   messaging.OfferClosedCancelledByRider,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.messageBroker.Publish(ctx, routingKey, contracts.AmqpMessage{
+		OwnerID: driverID,
+		Data:    marshalledPayload,
+	})
+}
+
+// publishCommandResult answers a rider's command with an ack or an error
+func (c *riderConsumer) publishCommandResult(ctx context.Context, routingKey, riderID, commandType string, command messaging.RiderCommandData, code, message string) error {
+	marshalledResult, err := json.Marshal(contracts.WSCommandResultData{
+		Command: commandType,
+		TripID:  command.TripID,
+		Code:    code,
+		Message: message,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.messageBroker.Publish(ctx, routingKey, contracts.AmqpMessage{
+		OwnerID:       riderID,
+		Data:          marshalledResult,
+		CorrelationID: command.CommandID,
+	})
+}
+
+// refusalCode returns the API error code of the errors which refuse a command
+// because of the state of the trip, rather than failing to handle it
+func refusalCode(err error) (string, bool) {
+	switch {
+	case err == nil:
+		return "", false
+	case errors.Is(err, domain.ErrTripNotFound):
+		return contracts.ErrCodeNotFound, true
+	case errors.Is(err, domain.ErrTripNotOwned):
+		return contracts.ErrCodeForbidden, true
+	case errors.Is(err, domain.ErrTripNotModifiable):
+		return contracts.ErrCodeConflict, true
+	case errors.Is(err, domain.ErrTooManyStops):
+		return contracts.ErrCodeBadRequest, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,267 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/services/trip-service/internal/infrastructure/repository"
+	"ride-sharing/services/trip-service/internal/service"
+	tripTypes "ride-sharing/services/trip-service/pkg/types"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/types"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// commandResult is an ack or error frame, with the ID of the command it answers
+type commandResult struct {
+	contracts.WSCommandResultData
+	CorrelationID string
+}
+
+// commandResults returns the ack and error frames published to riders
+func commandResults(t *testing.T, broker *recordingBroker) []commandResult {
+	t.Helper()
+
+	var results []commandResult
+	for i, msg := range broker.messages {
+		routingKey, _, _ := strings.Cut(broker.published[i], " -> ")
+		if routingKey != contracts.RiderEventCommandAck && routingKey != contracts.RiderEventCommandError {
+			continue
+		}
+		var result contracts.WSCommandResultData
+		if err := json.Unmarshal(msg.Data, &result); err != nil {
+			t.Fatalf("invalid command result %s: %v", msg.Data, err)
+		}
+		results = append(results, commandResult{result, msg.CorrelationID})
+	}
+	return results
+}
+
+// newRiderTrip stores a trip of rider-1 in the given status, with a driver once
+// accepted and offers while pending
+func newRiderTrip(t *testing.T, repo domain.TripRepository, status string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	route := &tripTypes.OsrmApiResponse{}
+	if err := json.Unmarshal([]byte(`{"routes":[{"geometry":{"coordinates":[[-122.42,37.77],[-122.4,37.8]]}}]}`), route); err != nil {
+		t.Fatal(err)
+	}
+
+	trip := &domain.TripModel{
+		ID:       primitive.NewObjectID(),
+		UserID:   "rider-1",
+		Status:   status,
+		RideFare: &domain.RideFareModel{Route: route},
+	}
+	if status != domain.TripStatusPending {
+		trip.Driver = &pb.TripDriver{Id: "driver-1"}
+	}
+	if _, err := repo.CreateTrip(ctx, trip); err != nil {
+		t.Fatalf("CreateTrip: %v", err)
+	}
+	if status == domain.TripStatusPending {
+		if err := repo.SetTripOffers(ctx, trip.ID.Hex(), []string{"driver-1", "driver-2"}, time.Now().Add(time.Minute)); err != nil {
+			t.Fatalf("SetTripOffers: %v", err)
+		}
+	}
+	return trip.ID.Hex()
+}
+
+func riderCommand(t *testing.T, commandType, riderID string, command messaging.RiderCommandData) amqp091.Delivery {
+	t.Helper()
+	data, err := json.Marshal(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(contracts.AmqpMessage{OwnerID: riderID, Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return amqp091.Delivery{RoutingKey: commandType, Body: body}
+}
+
+func TestRiderConsumerCommands(t *testing.T) {
+	location := &types.Coordinate{Latitude: 37.78, Longitude: -122.41}
+
+	tests := []struct {
+		name          string
+		status        string
+		commandType   string
+		riderID       string
+		unknownTrip   bool
+		wantPublished []string
+		wantCode      string
+	}{
+		{
+			name:        "cancel pending trip",
+			status:      domain.TripStatusPending,
+			commandType: contracts.RiderCmdCancelTrip,
+			wantPublished: []string{
+				contracts.TripEventCancelled + " -> rider-1",
+				contracts.DriverCmdTripOfferRevoked + " -> driver-1",
+				contracts.DriverCmdTripOfferRevoked + " -> driver-2",
+				contracts.RiderEventCommandAck + " -> rider-1",
+			},
+		},
+		{
+			name:        "cancel accepted trip",
+			status:      domain.TripStatusAccepted,
+			commandType: contracts.RiderCmdCancelTrip,
+			wantPublished: []string{
+				contracts.TripEventCancelled + " -> rider-1",
+				contracts.DriverCmdTripCancelled + " -> driver-1",
+				contracts.ChatEventClosed + " -> rider-1",
+				contracts.ChatEventClosed + " -> driver-1",
+				contracts.RiderEventCommandAck + " -> rider-1",
+			},
+		},
+		{
+			name:        "update pickup",
+			status:      domain.TripStatusAccepted,
+			commandType: contracts.RiderCmdUpdatePickup,
+			wantPublished: []string{
+				contracts.TripEventRouteUpdated + " -> rider-1",
+				contracts.TripEventRouteUpdated + " -> driver-1",
+				contracts.RiderEventCommandAck + " -> rider-1",
+			},
+		},
+		{
+			name:        "add stop during the trip",
+			status:      domain.TripStatusInProgress,
+			commandType: contracts.RiderCmdAddStop,
+			wantPublished: []string{
+				contracts.TripEventRouteUpdated + " -> rider-1",
+				contracts.TripEventRouteUpdated + " -> driver-1",
+				contracts.RiderEventCommandAck + " -> rider-1",
+			},
+		},
+		{
+			name:          "unknown trip",
+			status:        domain.TripStatusPending,
+			commandType:   contracts.RiderCmdCancelTrip,
+			unknownTrip:   true,
+			wantPublished: []string{contracts.RiderEventCommandError + " -> rider-1"},
+			wantCode:      contracts.ErrCodeNotFound,
+		},
+		{
+			name:          "trip of another rider",
+			status:        domain.TripStatusPending,
+			commandType:   contracts.RiderCmdCancelTrip,
+			riderID:       "rider-2",
+			wantPublished: []string{contracts.RiderEventCommandError + " -> rider-2"},
+			wantCode:      contracts.ErrCodeForbidden,
+		},
+		{
+			name:          "cancel started trip",
+			status:        domain.TripStatusInProgress,
+			commandType:   contracts.RiderCmdCancelTrip,
+			wantPublished: []string{contracts.RiderEventCommandError + " -> rider-1"},
+			wantCode:      contracts.ErrCodeConflict,
+		},
+		{
+			name:          "move pickup of started trip",
+			status:        domain.TripStatusInProgress,
+			commandType:   contracts.RiderCmdUpdatePickup,
+			wantPublished: []string{contracts.RiderEventCommandError + " -> rider-1"},
+			wantCode:      contracts.ErrCodeConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewInmemRepository()
+			broker := &recordingBroker{}
+			consumer := NewRiderConsumer(broker, service.NewService(repo))
+
+			tripID := newRiderTrip(t, repo, tt.status)
+			if tt.unknownTrip {
+				tripID = primitive.NewObjectID().Hex()
+			}
+			riderID := tt.riderID
+			if riderID == "" {
+				riderID = "rider-1"
+			}
+
+			command := messaging.RiderCommandData{CommandID: "cmd-1", TripID: tripID, Location: location}
+			if err := consumer.handleRiderCommand(context.Background(), riderCommand(t, tt.commandType, riderID, command)); err != nil {
+				t.Fatalf("handleRiderCommand() error = %v", err)
+			}
+
+			if !slices.Equal(broker.published, tt.wantPublished) {
+				t.Errorf("published %v, want %v", broker.published, tt.wantPublished)
+			}
+			results := commandResults(t, broker)
+			if len(results) != 1 {
+				t.Fatalf("got %d command results, want 1", len(results))
+			}
+			result := results[0]
+			if result.CorrelationID != "cmd-1" || result.Command != tt.commandType || result.TripID != tripID || result.Code != tt.wantCode {
+				t.Errorf("command result = %+v, want command cmd-1 of %s answered with code %q", result, tt.commandType, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRiderConsumerStopLimit(t *testing.T) {
+	repo := repository.NewInmemRepository()
+	broker := &recordingBroker{}
+	consumer := NewRiderConsumer(broker, service.NewService(repo))
+	tripID := newRiderTrip(t, repo, domain.TripStatusAccepted)
+
+	for i := range domain.MaxTripStops + 1 {
+		command := messaging.RiderCommandData{
+			CommandID: fmt.Sprintf("cmd-%d", i),
+			TripID:    tripID,
+			Location:  &types.Coordinate{Latitude: float64(i), Longitude: float64(i)},
+		}
+		if err := consumer.handleRiderCommand(context.Background(), riderCommand(t, contracts.RiderCmdAddStop, "rider-1", command)); err != nil {
+			t.Fatalf("handleRiderCommand() error = %v", err)
+		}
+	}
+
+	results := commandResults(t, broker)
+	last := results[len(results)-1]
+	if last.CorrelationID != fmt.Sprintf("cmd-%d", domain.MaxTripStops) || last.Code != contracts.ErrCodeBadRequest {
+		t.Errorf("stop past the limit answered with %+v, want %s", last, contracts.ErrCodeBadRequest)
+	}
+}
+
+func TestRiderConsumerRepeatedCancel(t *testing.T) {
+	repo := repository.NewInmemRepository()
+	broker := &recordingBroker{}
+	consumer := NewRiderConsumer(broker, service.NewService(repo))
+	tripID := newRiderTrip(t, repo, domain.TripStatusAccepted)
+
+	for _, commandID := range []string{"cmd-1", "cmd-2"} {
+		command := messaging.RiderCommandData{CommandID: commandID, TripID: tripID}
+		if err := consumer.handleRiderCommand(context.Background(), riderCommand(t, contracts.RiderCmdCancelTrip, "rider-1", command)); err != nil {
+			t.Fatalf("handleRiderCommand(%s) error = %v", commandID, err)
+		}
+	}
+
+	// The retry is acked without telling the driver again
+	want := []string{
+		contracts.TripEventCancelled + " -> rider-1",
+		contracts.DriverCmdTripCancelled + " -> driver-1",
+		contracts.ChatEventClosed + " -> rider-1",
+		contracts.ChatEventClosed + " -> driver-1",
+		contracts.RiderEventCommandAck + " -> rider-1",
+		contracts.RiderEventCommandAck + " -> rider-1",
+	}
+	if !slices.Equal(broker.published, want) {
+		t.Errorf("published %v, want %v", broker.published, want)
+	}
+	if results := commandResults(t, broker); len(results) != 2 || results[1].CorrelationID != "cmd-2" || results[1].Code != "" {
+		t.Errorf("command results = %+v, want the retry acked", results)
+	}
+}
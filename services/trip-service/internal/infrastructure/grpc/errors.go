@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies trip-service as the origin of the reasons in error details
+const errorDomain = "trip-service"
+
+// domainErrors maps the domain errors clients can act on to their status code and
+// the API error code sent as the reason
+var domainErrors = []struct {
+	err    error
+	code   codes.Code
+	reason string
+}{
+	{domain.ErrFareNotFound, codes.NotFound, contracts.ErrCodeNotFound},
+	{domain.ErrFareNotOwned, codes.PermissionDenied, contracts.ErrCodeFareNotOwned},
+	{domain.ErrFareExpired, codes.FailedPrecondition, contracts.ErrCodeFareExpired},
+	{domain.ErrNoRoute, codes.InvalidArgument, contracts.ErrCodeBadRequest},
+	{domain.ErrRouteUnavailable, codes.Unavailable, contracts.ErrCodeServiceUnavailable},
+	{domain.ErrTripNotFound, codes.NotFound, contracts.ErrCodeNotFound},
+	{domain.ErrNotChatParticipant, codes.PermissionDenied, contracts.ErrCodeForbidden},
+}
+
+// toStatus converts an error into a gRPC status error. Known domain errors keep their
+// message and carry an ErrorInfo with their reason, others become INTERNAL with the
+// given description.
+func toStatus(err error, description string) error {
+	for _, known := range domainErrors {
+		if !errors.Is(err, known.err) {
+			continue
+		}
+
+		st, detailErr := status.New(known.code, err.Error()).WithDetails(&errdetails.ErrorInfo{
+			Reason: known.reason,
+			Domain: errorDomain,
+		})
+		if detailErr != nil {
+			return status.Error(known.code, err.Error())
+		}
+		return st.Err()
+	}
+
+	return status.Error(codes.Internal, fmt.Sprintf("%s: %v", description, err))
+}
@@ -8,19 +8,19 @@ import (
 	"ride-sharing/shared/types"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type gRPCHandler struct {
 	pb.UnimplementedTripServiceServer
 	service   domain.TripService
+	chat      domain.ChatService
 	publisher domain.TripEventPublisher
 }
 
-func NewGRPCHandler(server *grpc.Server, service domain.TripService, publisher domain.TripEventPublisher) *gRPCHandler {
+func NewGRPCHandler(server *grpc.Server, service domain.TripService, chat domain.ChatService, publisher domain.TripEventPublisher) *gRPCHandler {
 	handler := &gRPCHandler{
 		service:   service,
+		chat:      chat,
 		publisher: publisher,
 	}
 
@@ -44,7 +44,7 @@ func (h *gRPCHandler) PreviewTrip(ctx context.Context, req *pb.PreviewTripReques
 	route, err := h.service.GetRoute(ctx, pickupCoordinate, destinationCoordinate)
 	if err != nil {
 		log.Println(err)
-		return nil, status.Errorf(codes.Internal, "Failed to get route: %v", err)
+		return nil, toStatus(err, "failed to get route")
 	}
 
 	userID := req.UserID
@@ -52,7 +52,7 @@ func (h *gRPCHandler) PreviewTrip(ctx context.Context, req *pb.PreviewTripReques
 	estimatedFares := h.service.EstimatePackagesPriceWithRoute(route)
 	fares, err := h.service.GenerateTripFares(ctx, estimatedFares, userID, route)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to generate ride fares: %v", err)
+		return nil, toStatus(err, "failed to generate ride fares")
 	}
 
 	return &pb.PreviewTripResponse{
@@ -86,12 +86,12 @@ func (h *gRPCHandler) CreateTrip(ctx context.Context, req *pb.CreateTripRequest)
 	userID := req.GetUserID()
 	rideFare, err := h.service.GetAndValidateFare(ctx, fareID, userID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to validate the fare: %v", err)
+		return nil, toStatus(err, "failed to validate the fare")
 	}
 
 	trip, err := h.service.CreateTrip(ctx, rideFare)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create the trip: %v", err)
+		return nil, toStatus(err, "failed to create the trip")
 	}
 
 	// TODO: REFACTOR - Replace synchronous publish with async outbox pattern
@@ -102,10 +102,23 @@ func (h *gRPCHandler) CreateTrip(ctx context.Context, req *pb.CreateTripRequest)
 	//   - Violates eventual consistency patterns for microservices
 	// ACTION: Store event in outbox, background worker publishes asynchronously
 	if err := h.publisher.PublishTripCreated(ctx, trip); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to publish the tripEvent: %v", err)
+		return nil, toStatus(err, "failed to publish the tripEvent")
 	}
 
 	return &pb.CreateTripResponse{
 		TripID: trip.ID.Hex(),
 	}, nil
 }
+
+// ListChatMessages returns the chat history of a trip to its rider or assigned driver
+func (h *gRPCHandler) ListChatMessages(ctx context.Context, req *pb.ListChatMessagesRequest) (*pb.ListChatMessagesResponse, error) {
+	messages, closed, err := h.chat.ChatHistory(ctx, req.GetTripID(), req.GetUserID())
+	if err != nil {
+		return nil, toStatus(err, "failed to list the chat messages")
+	}
+
+	return &pb.ListChatMessagesResponse{
+		Messages: domain.ToChatMessagesProto(messages),
+		Closed:   closed,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"sync"
+	"time"
+)
+
+type inmemChatRepository struct {
+	mu sync.RWMutex
+	// messages holds the messages of each trip, oldest first
+	messages map[string][]*domain.ChatMessageModel
+}
+
+func NewInmemChatRepository() *inmemChatRepository {
+	return &inmemChatRepository{
+		messages: make(map[string][]*domain.ChatMessageModel),
+	}
+}
+
+func (r *inmemChatRepository) AddChatMessage(ctx context.Context, message *domain.ChatMessageModel, limit int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.messages[message.TripID]) >= limit {
+		return fmt.Errorf("%w: a trip holds at most %d messages", domain.ErrChatFull, limit)
+	}
+
+	stored := *message
+	r.messages[message.TripID] = append(r.messages[message.TripID], &stored)
+	return nil
+}
+
+func (r *inmemChatRepository) ChatMessageByCommand(ctx context.Context, tripID, senderID, commandID string) (*domain.ChatMessageModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, message := range r.messages[tripID] {
+		if message.SenderID == senderID && message.CommandID == commandID {
+			copied := *message
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *inmemChatRepository) ListChatMessages(ctx context.Context, tripID string) ([]*domain.ChatMessageModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	messages := make([]*domain.ChatMessageModel, len(r.messages[tripID]))
+	for i, message := range r.messages[tripID] {
+		copied := *message
+		messages[i] = &copied
+	}
+	return messages, nil
+}
+
+func (r *inmemChatRepository) MarkChatMessages(ctx context.Context, tripID, recipientID, messageID, status string, at time.Time) (*domain.ChatMessageModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := r.messages[tripID]
+	last := -1
+	for i, message := range messages {
+		if message.ID.Hex() == messageID && message.RecipientID == recipientID {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("%w: %s", domain.ErrChatMessageNotFound, messageID)
+	}
+
+	for _, message := range messages[:last+1] {
+		if message.RecipientID != recipientID {
+			continue
+		}
+		if message.DeliveredAt.IsZero() {
+			message.DeliveredAt = at
+		}
+		if status == contracts.ChatReceiptRead && message.ReadAt.IsZero() {
+			message.ReadAt = at
+		}
+	}
+
+	marked := *messages[last]
+	return &marked, nil
+}
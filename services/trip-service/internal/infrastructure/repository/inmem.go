@@ -6,9 +6,15 @@ import (
 	"ride-sharing/services/trip-service/internal/domain"
 	pbd "ride-sharing/shared/proto/driver"
 	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/types"
+	"slices"
+	"sync"
+	"time"
 )
 
 type inmemRepository struct {
+	// mu guards the maps, since several consumers update trips concurrently
+	mu        sync.RWMutex
 	trips     map[string]*domain.TripModel
 	rideFares map[string]*domain.RideFareModel
 }
@@ -21,33 +27,48 @@ func NewInmemRepository() *inmemRepository {
 }
 
 func (r *inmemRepository) CreateTrip(ctx context.Context, trip *domain.TripModel) (*domain.TripModel, error) {
-	r.trips[trip.ID.Hex()] = trip
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.trips[trip.ID.Hex()] = copyTrip(trip)
 	return trip, nil
 }
 
 func (r *inmemRepository) SaveRideFare(ctx context.Context, f *domain.RideFareModel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.rideFares[f.ID.Hex()] = f
 	return nil
 }
 
 func (r *inmemRepository) GetFareByID(ctx context.Context, fareID string) (*domain.RideFareModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	fare, exist := r.rideFares[fareID]
 	if !exist {
-		return nil, fmt.Errorf("fare doesnot exist with ID: %s", fareID)
+		return nil, fmt.Errorf("%w: %s", domain.ErrFareNotFound, fareID)
 	}
 
 	return fare, nil
 }
 
 func (r *inmemRepository) GetTripByID(ctx context.Context, id string) (*domain.TripModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	trip, ok := r.trips[id]
 	if !ok {
 		return nil, nil
 	}
-	return trip, nil
+	return copyTrip(trip), nil
 }
 
 func (r *inmemRepository) UpdateTrip(ctx context.Context, tripID string, status string, driver *pbd.Driver) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	trip, ok := r.trips[tripID]
 	if !ok {
 		return fmt.Errorf("trip not found with ID: %s", tripID)
@@ -56,12 +77,205 @@ func (r *inmemRepository) UpdateTrip(ctx context.Context, tripID string, status
 	trip.Status = status
 
 	if driver != nil {
-		trip.Driver = &pb.TripDriver{
-			Id:             driver.Id,
-			Name:           driver.Name,
-			CarPlate:       driver.CarPlate,
-			ProfilePicture: driver.ProfilePicture,
+		trip.Driver = toTripDriver(driver)
+	}
+	return nil
+}
+
+func (r *inmemRepository) GetActiveTripByDriverID(ctx context.Context, driverID string) (*domain.TripModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, trip := range r.trips {
+		if trip.Driver != nil && trip.Driver.Id == driverID && trip.IsActive() {
+			return copyTrip(trip), nil
 		}
 	}
+	return nil, nil
+}
+
+func (r *inmemRepository) SetTripOffers(ctx context.Context, tripID string, driverIDs []string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, ok := r.trips[tripID]
+	if !ok {
+		return fmt.Errorf("trip not found with ID: %s", tripID)
+	}
+
+	trip.OfferedDriverIDs = slices.Clone(driverIDs)
+	trip.OffersExpireAt = expiresAt
 	return nil
 }
+
+func (r *inmemRepository) RemoveTripOffer(ctx context.Context, tripID, driverID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, ok := r.trips[tripID]
+	if !ok {
+		return 0, fmt.Errorf("trip not found with ID: %s", tripID)
+	}
+
+	if !slices.Contains(trip.OfferedDriverIDs, driverID) {
+		return 0, fmt.Errorf("%w: %s", domain.ErrTripNotOffered, tripID)
+	}
+
+	trip.OfferedDriverIDs = slices.DeleteFunc(trip.OfferedDriverIDs, func(id string) bool {
+		return id == driverID
+	})
+	return len(trip.OfferedDriverIDs), nil
+}
+
+func (r *inmemRepository) ExpireTripOffers(ctx context.Context, at time.Time) ([]*domain.TripModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []*domain.TripModel
+	for _, trip := range r.trips {
+		if trip.Status != domain.TripStatusPending || len(trip.OfferedDriverIDs) == 0 ||
+			trip.OffersExpireAt.IsZero() || !at.After(trip.OffersExpireAt) {
+			continue
+		}
+
+		expired = append(expired, copyTrip(trip))
+		trip.OfferedDriverIDs = nil
+		trip.OffersExpireAt = time.Time{}
+	}
+	return expired, nil
+}
+
+func (r *inmemRepository) AssignDriver(ctx context.Context, tripID string, driver *pbd.Driver, at time.Time) (*domain.TripModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, ok := r.trips[tripID]
+	if !ok {
+		return nil, fmt.Errorf("trip not found with ID: %s", tripID)
+	}
+
+	if trip.Status != domain.TripStatusPending {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripAlreadyTaken, tripID)
+	}
+
+	if !slices.Contains(trip.OfferedDriverIDs, driver.Id) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripNotOffered, tripID)
+	}
+	if !trip.OffersExpireAt.IsZero() && at.After(trip.OffersExpireAt) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripOfferExpired, tripID)
+	}
+
+	// Hand back the offers as they were, so the caller can revoke them
+	assigned := copyTrip(trip)
+	trip.Status = domain.TripStatusAccepted
+	trip.Driver = toTripDriver(driver)
+	trip.OfferedDriverIDs = nil
+	trip.OffersExpireAt = time.Time{}
+
+	assigned.Status = trip.Status
+	assigned.Driver = trip.Driver
+	return assigned, nil
+}
+
+func (r *inmemRepository) TransitionTrip(ctx context.Context, tripID, driverID, from, to string) (*domain.TripModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, ok := r.trips[tripID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+
+	if trip.Driver == nil || trip.Driver.Id != driverID {
+		return nil, fmt.Errorf("%w: %s", domain.ErrNotTripDriver, tripID)
+	}
+	if trip.Status != from {
+		return nil, fmt.Errorf("%w: trip is %s", domain.ErrTripNotModifiable, trip.Status)
+	}
+
+	trip.Status = to
+	return copyTrip(trip), nil
+}
+
+func (r *inmemRepository) CancelTrip(ctx context.Context, tripID, riderID string) (*domain.TripModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, err := r.riderTrip(tripID, riderID, domain.TripStatusPending, domain.TripStatusAccepted, domain.TripStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	if trip.Status == domain.TripStatusCancelled {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripAlreadyCancelled, tripID)
+	}
+
+	// Hand back the offers and driver as they were, so the caller can notify them
+	cancelled := copyTrip(trip)
+	trip.Status = domain.TripStatusCancelled
+	trip.OfferedDriverIDs = nil
+	trip.OffersExpireAt = time.Time{}
+
+	cancelled.Status = trip.Status
+	return cancelled, nil
+}
+
+func (r *inmemRepository) SetTripPickup(ctx context.Context, tripID, riderID string, pickup *types.Coordinate) (*domain.TripModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, err := r.riderTrip(tripID, riderID, domain.TripStatusPending, domain.TripStatusAccepted)
+	if err != nil {
+		return nil, err
+	}
+
+	trip.Pickup = pickup
+	return copyTrip(trip), nil
+}
+
+func (r *inmemRepository) AddTripStop(ctx context.Context, tripID, riderID string, stop *types.Coordinate) (*domain.TripModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trip, err := r.riderTrip(tripID, riderID, domain.TripStatusPending, domain.TripStatusAccepted, domain.TripStatusInProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(trip.Stops) >= domain.MaxTripStops {
+		return nil, fmt.Errorf("%w: a trip has at most %d stops", domain.ErrTooManyStops, domain.MaxTripStops)
+	}
+
+	trip.Stops = append(trip.Stops, stop)
+	return copyTrip(trip), nil
+}
+
+// riderTrip returns a trip the rider may change in its current status. The caller must hold the lock.
+func (r *inmemRepository) riderTrip(tripID, riderID string, allowed ...string) (*domain.TripModel, error) {
+	trip, ok := r.trips[tripID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+
+	if err := trip.CheckRiderChange(riderID, allowed...); err != nil {
+		return nil, err
+	}
+	return trip, nil
+}
+
+// copyTrip returns a copy of a stored trip that callers can read without the lock.
+// The slices are cloned, since the repository changes them in place.
+func copyTrip(trip *domain.TripModel) *domain.TripModel {
+	copied := *trip
+	copied.OfferedDriverIDs = slices.Clone(trip.OfferedDriverIDs)
+	copied.Stops = slices.Clone(trip.Stops)
+	return &copied
+}
+
+func toTripDriver(driver *pbd.Driver) *pb.TripDriver {
+	return &pb.TripDriver{
+		Id:             driver.Id,
+		Name:           driver.Name,
+		CarPlate:       driver.CarPlate,
+		ProfilePicture: driver.ProfilePicture,
+	}
+}
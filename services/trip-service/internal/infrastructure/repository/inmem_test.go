@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"ride-sharing/services/trip-service/internal/domain"
+	pbd "ride-sharing/shared/proto/driver"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newPendingTrip(t *testing.T, r *inmemRepository, expiresAt time.Time, offered ...string) string {
+	t.Helper()
+
+	trip := &domain.TripModel{
+		ID:       primitive.NewObjectID(),
+		UserID:   "rider-1",
+		Status:   domain.TripStatusPending,
+		RideFare: &domain.RideFareModel{},
+	}
+	if _, err := r.CreateTrip(context.Background(), trip); err != nil {
+		t.Fatalf("CreateTrip: %v", err)
+	}
+	if err := r.SetTripOffers(context.Background(), trip.ID.Hex(), offered, expiresAt); err != nil {
+		t.Fatalf("SetTripOffers: %v", err)
+	}
+	return trip.ID.Hex()
+}
+
+func TestInmemRepositoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	r := NewInmemRepository()
+	tripID := newPendingTrip(t, r, time.Now().Add(time.Minute), "driver-1", "driver-2")
+
+	read, err := r.GetTripByID(ctx, tripID)
+	if err != nil || read == nil {
+		t.Fatalf("GetTripByID = %v, %v", read, err)
+	}
+
+	if _, err := r.RemoveTripOffer(ctx, tripID, "driver-1"); err != nil {
+		t.Fatalf("RemoveTripOffer: %v", err)
+	}
+	if _, err := r.AssignDriver(ctx, tripID, &pbd.Driver{Id: "driver-2"}, time.Now()); err != nil {
+		t.Fatalf("AssignDriver: %v", err)
+	}
+
+	if read.Status != domain.TripStatusPending || read.Driver != nil {
+		t.Errorf("earlier read changed to status %q, driver %v", read.Status, read.Driver)
+	}
+	if len(read.OfferedDriverIDs) != 2 || read.OfferedDriverIDs[0] != "driver-1" {
+		t.Errorf("earlier read offers changed to %v", read.OfferedDriverIDs)
+	}
+
+	read.Status = domain.TripStatusCancelled
+	stored, _ := r.GetTripByID(ctx, tripID)
+	if stored.Status != domain.TripStatusAccepted {
+		t.Errorf("changing a read trip changed the stored status to %q", stored.Status)
+	}
+}
+
+// Run with -race: consumers update trips while the location relay looks them up
+func TestInmemRepositoryConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	r := NewInmemRepository()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			tripID := newPendingTrip(t, r, time.Now().Add(time.Minute))
+			if err := r.UpdateTrip(ctx, tripID, domain.TripStatusAccepted, &pbd.Driver{Id: "driver-1"}); err != nil {
+				t.Errorf("UpdateTrip: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := r.GetActiveTripByDriverID(ctx, "driver-1"); err != nil {
+				t.Errorf("GetActiveTripByDriverID: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if trip, _ := r.GetActiveTripByDriverID(ctx, "driver-1"); trip == nil {
+		t.Error("GetActiveTripByDriverID() = nil after the trips were accepted")
+	}
+}
+
+func TestInmemRepositoryAssignDriverExpiredOffers(t *testing.T) {
+	ctx := context.Background()
+	r := NewInmemRepository()
+	expiresAt := time.Now()
+	tripID := newPendingTrip(t, r, expiresAt, "driver-1")
+
+	_, err := r.AssignDriver(ctx, tripID, &pbd.Driver{Id: "driver-1"}, expiresAt.Add(time.Second))
+	if !errors.Is(err, domain.ErrTripOfferExpired) {
+		t.Fatalf("AssignDriver after expiry = %v, want ErrTripOfferExpired", err)
+	}
+
+	if _, err := r.AssignDriver(ctx, tripID, &pbd.Driver{Id: "driver-1"}, expiresAt); err != nil {
+		t.Fatalf("AssignDriver before expiry: %v", err)
+	}
+}
+
+func TestInmemRepositoryTransitionTrip(t *testing.T) {
+	ctx := context.Background()
+	r := NewInmemRepository()
+	tripID := newPendingTrip(t, r, time.Now().Add(time.Minute), "driver-1")
+	if _, err := r.AssignDriver(ctx, tripID, &pbd.Driver{Id: "driver-1"}, time.Now()); err != nil {
+		t.Fatalf("AssignDriver: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		driverID string
+		from, to string
+		wantErr  error
+	}{
+		{"another driver", "driver-2", domain.TripStatusAccepted, domain.TripStatusInProgress, domain.ErrNotTripDriver},
+		{"start", "driver-1", domain.TripStatusAccepted, domain.TripStatusInProgress, nil},
+		{"start again", "driver-1", domain.TripStatusAccepted, domain.TripStatusInProgress, domain.ErrTripNotModifiable},
+		{"complete", "driver-1", domain.TripStatusInProgress, domain.TripStatusCompleted, nil},
+		{"complete again", "driver-1", domain.TripStatusInProgress, domain.TripStatusCompleted, domain.ErrTripNotModifiable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trip, err := r.TransitionTrip(ctx, tripID, tt.driverID, tt.from, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TransitionTrip = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && trip.Status != tt.to {
+				t.Errorf("status = %q, want %q", trip.Status, tt.to)
+			}
+		})
+	}
+
+	if _, err := r.TransitionTrip(ctx, "unknown", "driver-1", domain.TripStatusAccepted, domain.TripStatusInProgress); !errors.Is(err, domain.ErrTripNotFound) {
+		t.Errorf("TransitionTrip of an unknown trip = %v, want ErrTripNotFound", err)
+	}
+}
+
+func TestInmemRepositoryTransitionTripConcurrent(t *testing.T) {
+	ctx := context.Background()
+	r := NewInmemRepository()
+	tripID := newPendingTrip(t, r, time.Now().Add(time.Minute), "driver-1")
+	if _, err := r.AssignDriver(ctx, tripID, &pbd.Driver{Id: "driver-1"}, time.Now()); err != nil {
+		t.Fatalf("AssignDriver: %v", err)
+	}
+
+	const attempts = 10
+	results := make(chan error, attempts)
+	for range attempts {
+		go func() {
+			_, err := r.TransitionTrip(ctx, tripID, "driver-1", domain.TripStatusAccepted, domain.TripStatusInProgress)
+			results <- err
+		}()
+	}
+
+	succeeded := 0
+	for range attempts {
+		if err := <-results; err == nil {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d concurrent starts succeeded, want 1", succeeded)
+	}
+}
+
+func TestInmemRepositoryAssignDriverNotOffered(t *testing.T) {
+	ctx := context.Background()
+	r := NewInmemRepository()
+	tripID := newPendingTrip(t, r, time.Now().Add(time.Minute), "driver-1")
+
+	if _, err := r.AssignDriver(ctx, tripID, &pbd.Driver{Id: "driver-2"}, time.Now()); !errors.Is(err, domain.ErrTripNotOffered) {
+		t.Fatalf("AssignDriver of a driver without offer = %v, want ErrTripNotOffered", err)
+	}
+	if _, err := r.RemoveTripOffer(ctx, tripID, "driver-2"); !errors.Is(err, domain.ErrTripNotOffered) {
+		t.Fatalf("RemoveTripOffer of a driver without offer = %v, want ErrTripNotOffered", err)
+	}
+
+	trip, _ := r.GetTripByID(ctx, tripID)
+	if trip.Status != domain.TripStatusPending || len(trip.OfferedDriverIDs) != 1 {
+		t.Errorf("trip changed to status %q, offers %v", trip.Status, trip.OfferedDriverIDs)
+	}
+}
+
+func TestInmemRepositoryExpireTripOffers(t *testing.T) {
+	ctx := context.Background()
+	r := NewInmemRepository()
+	now := time.Now()
+	expired := newPendingTrip(t, r, now.Add(-time.Second), "driver-1", "driver-2")
+	open := newPendingTrip(t, r, now.Add(time.Minute), "driver-3")
+
+	trips, err := r.ExpireTripOffers(ctx, now)
+	if err != nil {
+		t.Fatalf("ExpireTripOffers: %v", err)
+	}
+	if len(trips) != 1 || trips[0].ID.Hex() != expired || len(trips[0].OfferedDriverIDs) != 2 {
+		t.Fatalf("ExpireTripOffers = %v, want trip %s with its 2 offers", trips, expired)
+	}
+
+	if trips, _ := r.ExpireTripOffers(ctx, now); len(trips) != 0 {
+		t.Errorf("offers expired twice: %v", trips)
+	}
+	if _, err := r.AssignDriver(ctx, expired, &pbd.Driver{Id: "driver-1"}, now); !errors.Is(err, domain.ErrTripNotOffered) {
+		t.Errorf("AssignDriver after expiry = %v, want ErrTripNotOffered", err)
+	}
+	if stored, _ := r.GetTripByID(ctx, open); len(stored.OfferedDriverIDs) != 1 {
+		t.Errorf("open offers were expired: %v", stored.OfferedDriverIDs)
+	}
+}
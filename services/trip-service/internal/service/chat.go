@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/shared/contracts"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type chatService struct {
+	trips  domain.TripRepository
+	chats  domain.ChatRepository
+	filter domain.ChatFilter
+}
+
+func NewChatService(trips domain.TripRepository, chats domain.ChatRepository, filter domain.ChatFilter) *chatService {
+	return &chatService{
+		trips:  trips,
+		chats:  chats,
+		filter: filter,
+	}
+}
+
+// SendChatMessage implements domain.ChatService.
+func (s *chatService) SendChatMessage(ctx context.Context, tripID, senderID, commandID, text string) (*domain.ChatMessageModel, error) {
+	trip, err := s.trip(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, recipientID, err := trip.ChatParticipant(senderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if commandID != "" {
+		// The command was redelivered after its message was stored, maybe after the trip
+		// ended, it must neither be stored again nor refused
+		sent, err := s.chats.ChatMessageByCommand(ctx, tripID, senderID, commandID)
+		if err != nil {
+			return nil, err
+		}
+		if sent != nil {
+			return sent, nil
+		}
+	}
+
+	if !trip.IsActive() {
+		return nil, fmt.Errorf("%w: trip is %s", domain.ErrChatClosed, trip.Status)
+	}
+
+	if err := domain.ValidateChatText(text); err != nil {
+		return nil, err
+	}
+
+	filtered, err := s.filter.Filter(text)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &domain.ChatMessageModel{
+		ID:          primitive.NewObjectID(),
+		TripID:      tripID,
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		SenderRole:  role,
+		CommandID:   commandID,
+		Text:        filtered,
+		SentAt:      time.Now(),
+	}
+	if err := s.chats.AddChatMessage(ctx, message, domain.MaxChatMessagesPerTrip); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// MarkChatMessages implements domain.ChatService.
+// Receipts are accepted after the trip ended, the recipient may read the last messages late.
+func (s *chatService) MarkChatMessages(ctx context.Context, tripID, recipientID, messageID, status string) (*domain.ChatMessageModel, error) {
+	trip, err := s.trip(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := trip.ChatParticipant(recipientID); err != nil {
+		return nil, err
+	}
+
+	if status != contracts.ChatReceiptDelivered && status != contracts.ChatReceiptRead {
+		return nil, fmt.Errorf("%w: unknown receipt status %q", domain.ErrInvalidChatMessage, status)
+	}
+
+	return s.chats.MarkChatMessages(ctx, tripID, recipientID, messageID, status, time.Now())
+}
+
+// ChatHistory implements domain.ChatService.
+func (s *chatService) ChatHistory(ctx context.Context, tripID, userID string) ([]*domain.ChatMessageModel, bool, error) {
+	trip, err := s.trip(ctx, tripID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if _, _, err := trip.ChatParticipant(userID); err != nil {
+		return nil, false, err
+	}
+
+	messages, err := s.chats.ListChatMessages(ctx, tripID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return messages, !trip.IsActive(), nil
+}
+
+func (s *chatService) trip(ctx context.Context, tripID string) (*domain.TripModel, error) {
+	trip, err := s.trips.GetTripByID(ctx, tripID)
+	if err != nil {
+		return nil, err
+	}
+	if trip == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrTripNotFound, tripID)
+	}
+	return trip, nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/services/trip-service/internal/infrastructure/repository"
+	"ride-sharing/shared/contracts"
+	pb "ride-sharing/shared/proto/trip"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newChatTrip stores a trip of rider-1 with status and, unless pending, driver-1
+func newChatTrip(t *testing.T, trips domain.TripRepository, status string) string {
+	t.Helper()
+
+	trip := &domain.TripModel{
+		ID:       primitive.NewObjectID(),
+		UserID:   "rider-1",
+		Status:   status,
+		RideFare: &domain.RideFareModel{},
+		Driver:   &pb.TripDriver{},
+	}
+	if status != domain.TripStatusPending {
+		trip.Driver.Id = "driver-1"
+	}
+	if _, err := trips.CreateTrip(context.Background(), trip); err != nil {
+		t.Fatalf("CreateTrip: %v", err)
+	}
+	return trip.ID.Hex()
+}
+
+func newTestChatService(t *testing.T, filterSpec string) (*chatService, domain.TripRepository) {
+	t.Helper()
+	filter, err := domain.ParseChatFilter(filterSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trips := repository.NewInmemRepository()
+	return NewChatService(trips, repository.NewInmemChatRepository(), filter), trips
+}
+
+func TestChatRefusesNonParticipants(t *testing.T) {
+	ctx := context.Background()
+	s, trips := newTestChatService(t, "")
+	tripID := newChatTrip(t, trips, domain.TripStatusAccepted)
+
+	sent, err := s.SendChatMessage(ctx, tripID, "driver-1", "cmd-1", "on my way")
+	if err != nil {
+		t.Fatalf("SendChatMessage of the driver error = %v", err)
+	}
+
+	if _, err := s.SendChatMessage(ctx, tripID, "driver-2", "cmd-2", "I'm closer"); !errors.Is(err, domain.ErrNotChatParticipant) {
+		t.Errorf("SendChatMessage of another driver error = %v, want ErrNotChatParticipant", err)
+	}
+	if _, _, err := s.ChatHistory(ctx, tripID, "rider-2"); !errors.Is(err, domain.ErrNotChatParticipant) {
+		t.Errorf("ChatHistory of another rider error = %v, want ErrNotChatParticipant", err)
+	}
+	if _, err := s.MarkChatMessages(ctx, tripID, "rider-2", sent.ID.Hex(), contracts.ChatReceiptRead); !errors.Is(err, domain.ErrNotChatParticipant) {
+		t.Errorf("MarkChatMessages of another rider error = %v, want ErrNotChatParticipant", err)
+	}
+
+	if _, err := s.SendChatMessage(ctx, primitive.NewObjectID().Hex(), "rider-1", "cmd-3", "hello"); !errors.Is(err, domain.ErrTripNotFound) {
+		t.Errorf("SendChatMessage to an unknown trip error = %v, want ErrTripNotFound", err)
+	}
+}
+
+func TestChatIsOpenWhileTheTripIsActive(t *testing.T) {
+	ctx := context.Background()
+	s, trips := newTestChatService(t, "")
+
+	tests := []struct {
+		status  string
+		wantErr error
+	}{
+		{domain.TripStatusAccepted, nil},
+		{domain.TripStatusInProgress, nil},
+		{domain.TripStatusCompleted, domain.ErrChatClosed},
+		{domain.TripStatusCancelled, domain.ErrChatClosed},
+	}
+
+	for _, tt := range tests {
+		tripID := newChatTrip(t, trips, tt.status)
+		if _, err := s.SendChatMessage(ctx, tripID, "rider-1", "cmd-4", "hello"); !errors.Is(err, tt.wantErr) {
+			t.Errorf("SendChatMessage on a %s trip error = %v, want %v", tt.status, err, tt.wantErr)
+		}
+
+		_, closed, err := s.ChatHistory(ctx, tripID, "rider-1")
+		if err != nil || closed != (tt.wantErr != nil) {
+			t.Errorf("ChatHistory of a %s trip = closed %v, %v", tt.status, closed, err)
+		}
+	}
+
+	// The rider of a pending trip has nobody to chat with yet
+	tripID := newChatTrip(t, trips, domain.TripStatusPending)
+	if _, err := s.SendChatMessage(ctx, tripID, "rider-1", "cmd-5", "hello"); !errors.Is(err, domain.ErrChatClosed) {
+		t.Errorf("SendChatMessage on a pending trip error = %v, want ErrChatClosed", err)
+	}
+}
+
+func TestSendChatMessageFiltersText(t *testing.T) {
+	ctx := context.Background()
+	s, trips := newTestChatService(t, "contact_details,blocklist:darn")
+	tripID := newChatTrip(t, trips, domain.TripStatusAccepted)
+
+	sent, err := s.SendChatMessage(ctx, tripID, "rider-1", "cmd-6", "darn traffic")
+	if err != nil {
+		t.Fatalf("SendChatMessage error = %v", err)
+	}
+	if sent.Text != "**** traffic" || sent.SenderRole != domain.ChatRoleRider || sent.RecipientID != "driver-1" {
+		t.Errorf("sent %+v", sent)
+	}
+
+	tests := []struct {
+		text    string
+		wantErr error
+	}{
+		{"call me on 4155550123", domain.ErrChatMessageRejected},
+		{"   ", domain.ErrInvalidChatMessage},
+	}
+	for _, tt := range tests {
+		if _, err := s.SendChatMessage(ctx, tripID, "rider-1", "cmd-7", tt.text); !errors.Is(err, tt.wantErr) {
+			t.Errorf("SendChatMessage(%q) error = %v, want %v", tt.text, err, tt.wantErr)
+		}
+	}
+
+	// Refused messages are not stored
+	messages, _, err := s.ChatHistory(ctx, tripID, "driver-1")
+	if err != nil || len(messages) != 1 || messages[0].Text != "**** traffic" {
+		t.Errorf("ChatHistory() = %v, %v, want the masked message only", messages, err)
+	}
+}
+
+func TestSendChatMessageOncePerCommand(t *testing.T) {
+	ctx := context.Background()
+	s, trips := newTestChatService(t, "")
+	tripID := newChatTrip(t, trips, domain.TripStatusAccepted)
+
+	sent, err := s.SendChatMessage(ctx, tripID, "rider-1", "cmd-1", "hello")
+	if err != nil {
+		t.Fatalf("SendChatMessage error = %v", err)
+	}
+
+	// A redelivered command returns the stored message, even once the trip ended
+	if err := trips.UpdateTrip(ctx, tripID, domain.TripStatusCompleted, nil); err != nil {
+		t.Fatal(err)
+	}
+	again, err := s.SendChatMessage(ctx, tripID, "rider-1", "cmd-1", "hello")
+	if err != nil || again.ID != sent.ID {
+		t.Errorf("SendChatMessage of the same command = %+v, %v, want message %s", again, err, sent.ID.Hex())
+	}
+
+	// The IDs are the sender's own, the other participant may use the same
+	if _, err := s.SendChatMessage(ctx, tripID, "driver-1", "cmd-1", "hi"); !errors.Is(err, domain.ErrChatClosed) {
+		t.Errorf("SendChatMessage of the driver error = %v, want ErrChatClosed", err)
+	}
+
+	messages, _, err := s.ChatHistory(ctx, tripID, "rider-1")
+	if err != nil || len(messages) != 1 {
+		t.Errorf("ChatHistory() = %v, %v, want one message", messages, err)
+	}
+}
+
+func TestMarkChatMessages(t *testing.T) {
+	ctx := context.Background()
+	s, trips := newTestChatService(t, "")
+	tripID := newChatTrip(t, trips, domain.TripStatusAccepted)
+
+	first, _ := s.SendChatMessage(ctx, tripID, "driver-1", "cmd-8", "on my way")
+	reply, _ := s.SendChatMessage(ctx, tripID, "rider-1", "cmd-9", "thanks")
+	second, _ := s.SendChatMessage(ctx, tripID, "driver-1", "cmd-10", "here")
+
+	// The sender can't mark their own messages
+	if _, err := s.MarkChatMessages(ctx, tripID, "driver-1", second.ID.Hex(), contracts.ChatReceiptRead); !errors.Is(err, domain.ErrChatMessageNotFound) {
+		t.Errorf("MarkChatMessages of the sender error = %v, want ErrChatMessageNotFound", err)
+	}
+	if _, err := s.MarkChatMessages(ctx, tripID, "rider-1", second.ID.Hex(), "seen"); !errors.Is(err, domain.ErrInvalidChatMessage) {
+		t.Errorf("MarkChatMessages with an unknown status error = %v, want ErrInvalidChatMessage", err)
+	}
+
+	// A receipt covers the recipient's earlier messages as well
+	delivered, err := s.MarkChatMessages(ctx, tripID, "rider-1", first.ID.Hex(), contracts.ChatReceiptDelivered)
+	if err != nil || delivered.DeliveredAt.IsZero() || !delivered.ReadAt.IsZero() {
+		t.Fatalf("MarkChatMessages() = %+v, %v, want the delivered first message", delivered, err)
+	}
+	marked, err := s.MarkChatMessages(ctx, tripID, "rider-1", second.ID.Hex(), contracts.ChatReceiptRead)
+	if err != nil || marked.ID != second.ID || marked.ReadAt.IsZero() {
+		t.Fatalf("MarkChatMessages() = %+v, %v, want the read second message", marked, err)
+	}
+
+	messages, _, err := s.ChatHistory(ctx, tripID, "rider-1")
+	if err != nil || len(messages) != 3 {
+		t.Fatalf("ChatHistory() = %v, %v", messages, err)
+	}
+	for _, message := range messages {
+		received := message.ID != reply.ID
+		if received != !message.ReadAt.IsZero() || received != !message.DeliveredAt.IsZero() {
+			t.Errorf("message %q delivered at %v, read at %v", message.Text, message.DeliveredAt, message.ReadAt)
+		}
+	}
+	// Reading keeps the earlier delivery time
+	if !messages[0].DeliveredAt.Equal(delivered.DeliveredAt) {
+		t.Errorf("first message delivered at %v, want %v", messages[0].DeliveredAt, delivered.DeliveredAt)
+	}
+
+	// Receipts are still accepted once the trip ended
+	if err := trips.UpdateTrip(ctx, tripID, domain.TripStatusCompleted, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.MarkChatMessages(ctx, tripID, "driver-1", reply.ID.Hex(), contracts.ChatReceiptRead); err != nil {
+		t.Errorf("MarkChatMessages after the trip ended error = %v", err)
+	}
+}
@@ -10,19 +10,35 @@ import (
 	pbd "ride-sharing/shared/proto/driver"
 	pb "ride-sharing/shared/proto/trip"
 	"ride-sharing/shared/types"
+	"ride-sharing/shared/util"
+	"slices"
+	"time"
 
 	tripTypes "ride-sharing/services/trip-service/pkg/types"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// averagePickupSpeedMps is the assumed city driving speed (30 km/h) used for pickup ETAs
+const averagePickupSpeedMps = 30 * 1000.0 / 3600
+
+// osrmURL is the public OSRM API the routes are fetched from
+const osrmURL = "http://router.project-osrm.org"
+
 type service struct {
 	repo domain.TripRepository
+	// routeURL is the base URL of the OSRM API
+	routeURL   string
+	httpClient *http.Client
+	now        func() time.Time
 }
 
 func NewService(repo domain.TripRepository) *service {
 	return &service{
-		repo: repo,
+		repo:       repo,
+		routeURL:   osrmURL,
+		httpClient: http.DefaultClient,
+		now:        time.Now,
 	}
 }
 
@@ -30,7 +46,7 @@ func (s *service) CreateTrip(ctx context.Context, fare *domain.RideFareModel) (*
 	trip := &domain.TripModel{
 		ID:       primitive.NewObjectID(),
 		UserID:   fare.UserID,
-		Status:   "pending",
+		Status:   domain.TripStatusPending,
 		RideFare: fare,
 		Driver:   &pb.TripDriver{},
 	}
@@ -39,24 +55,38 @@ func (s *service) CreateTrip(ctx context.Context, fare *domain.RideFareModel) (*
 
 func (s *service) GetRoute(ctx context.Context, pickup, destination *types.Coordinate) (*tripTypes.OsrmApiResponse, error) {
 	url := fmt.Sprintf(
-		"http://router.project-osrm.org/route/v1/driving/%f,%f;%f,%f?overview=full&geometries=geojson",
-		pickup.Longitude, pickup.Latitude, destination.Longitude, destination.Latitude)
+		"%s/route/v1/driving/%f,%f;%f,%f?overview=full&geometries=geojson",
+		s.routeURL, pickup.Longitude, pickup.Latitude, destination.Longitude, destination.Latitude)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the route request: %v", err)
+	}
 
-	resp, err := http.Get(url)
+	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch route from OSRM api: %v", err)
+		return nil, fmt.Errorf("%w: failed to fetch route from OSRM api: %v", domain.ErrRouteUnavailable, err)
 	}
 
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: OSRM api answered %s", domain.ErrRouteUnavailable, resp.Status)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
+		return nil, fmt.Errorf("%w: failed to read response: %v", domain.ErrRouteUnavailable, err)
 	}
 
 	var routeResp tripTypes.OsrmApiResponse
 	if err := json.Unmarshal(body, &routeResp); err != nil {
-		return nil, fmt.Errorf("failed to parse route response: %v", err)
+		return nil, fmt.Errorf("%w: failed to parse route response: %v", domain.ErrRouteUnavailable, err)
+	}
+
+	// OSRM answers unroutable locations with an error code and no routes
+	if len(routeResp.Routes) == 0 {
+		return nil, domain.ErrNoRoute
 	}
 
 	return &routeResp, nil
@@ -91,6 +121,7 @@ func (s *service) estimateFareRoute(f *domain.RideFareModel, route *tripTypes.Os
 
 func (s *service) GenerateTripFares(ctx context.Context, rideFares []*domain.RideFareModel, userID string, route *tripTypes.OsrmApiResponse) ([]*domain.RideFareModel, error) {
 	fares := make([]*domain.RideFareModel, len(rideFares))
+	expiresAt := s.now().Add(domain.FareValidity)
 
 	for i, f := range rideFares {
 		id := primitive.NewObjectID()
@@ -100,6 +131,7 @@ func (s *service) GenerateTripFares(ctx context.Context, rideFares []*domain.Rid
 			TotalPriceInCents: f.TotalPriceInCents,
 			PackageSlug:       f.PackageSlug,
 			Route:             route,
+			ExpiresAt:         expiresAt,
 		}
 
 		if err := s.repo.SaveRideFare(ctx, fare); err != nil {
@@ -139,11 +171,15 @@ func (s *service) GetAndValidateFare(ctx context.Context, fareID, userId string)
 	}
 
 	if fare == nil {
-		return nil, fmt.Errorf("fare does not exist")
+		return nil, fmt.Errorf("%w: %s", domain.ErrFareNotFound, fareID)
 	}
 
 	if userId != fare.UserID {
-		return nil, fmt.Errorf("fare doesn't belong to user")
+		return nil, domain.ErrFareNotOwned
+	}
+
+	if s.now().After(fare.ExpiresAt) {
+		return nil, domain.ErrFareExpired
 	}
 
 	return fare, nil
@@ -158,3 +194,80 @@ func (s *service) GetTripByID(ctx context.Context, tripID string) (*domain.TripM
 func (s *service) UpdateTrip(ctx context.Context, tripID string, status string, driver *pbd.Driver) error {
 	return s.repo.UpdateTrip(ctx, tripID, status, driver)
 }
+
+// GetActiveTripByDriverID implements domain.TripService.
+func (s *service) GetActiveTripByDriverID(ctx context.Context, driverID string) (*domain.TripModel, error) {
+	return s.repo.GetActiveTripByDriverID(ctx, driverID)
+}
+
+// TransitionTrip implements domain.TripService.
+func (s *service) TransitionTrip(ctx context.Context, tripID, driverID, from, to string) (*domain.TripModel, error) {
+	return s.repo.TransitionTrip(ctx, tripID, driverID, from, to)
+}
+
+// EstimatePickup implements domain.TripService.
+// The ETA assumes the driver covers the straight-line distance at the average pickup speed.
+func (s *service) EstimatePickup(trip *domain.TripModel, driverLocation *types.Coordinate) (float64, float64, error) {
+	pickup := trip.PickupCoordinate()
+	if pickup == nil {
+		return 0, 0, fmt.Errorf("trip %s has no pickup location", trip.ID.Hex())
+	}
+
+	distance := util.HaversineDistance(driverLocation.Latitude, driverLocation.Longitude, pickup.Latitude, pickup.Longitude)
+	eta := distance / averagePickupSpeedMps
+
+	return distance, eta, nil
+}
+
+// RecordTripOffers implements domain.TripService.
+func (s *service) RecordTripOffers(ctx context.Context, tripID string, driverIDs []string) error {
+	return s.repo.SetTripOffers(ctx, tripID, driverIDs, s.now().Add(domain.TripOfferTimeout))
+}
+
+// DeclineTripOffer implements domain.TripService.
+func (s *service) DeclineTripOffer(ctx context.Context, tripID, driverID string) (int, error) {
+	return s.repo.RemoveTripOffer(ctx, tripID, driverID)
+}
+
+// ExpireTripOffers implements domain.TripService.
+func (s *service) ExpireTripOffers(ctx context.Context) ([]*domain.TripModel, error) {
+	return s.repo.ExpireTripOffers(ctx, s.now())
+}
+
+// AcceptTripOffer implements domain.TripService.
+func (s *service) AcceptTripOffer(ctx context.Context, tripID string, driver *pbd.Driver) (*domain.TripModel, []string, error) {
+	trip, err := s.repo.AssignDriver(ctx, tripID, driver, s.now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	revoked := slices.DeleteFunc(trip.OfferedDriverIDs, func(id string) bool {
+		return id == driver.Id
+	})
+	trip.OfferedDriverIDs = nil
+
+	return trip, revoked, nil
+}
+
+// CancelTrip implements domain.TripService.
+func (s *service) CancelTrip(ctx context.Context, tripID, riderID string) (*domain.TripModel, []string, error) {
+	trip, err := s.repo.CancelTrip(ctx, tripID, riderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	revoked := trip.OfferedDriverIDs
+	trip.OfferedDriverIDs = nil
+
+	return trip, revoked, nil
+}
+
+// UpdatePickup implements domain.TripService.
+func (s *service) UpdatePickup(ctx context.Context, tripID, riderID string, pickup *types.Coordinate) (*domain.TripModel, error) {
+	return s.repo.SetTripPickup(ctx, tripID, riderID, pickup)
+}
+
+// AddStop implements domain.TripService.
+func (s *service) AddStop(ctx context.Context, tripID, riderID string, stop *types.Coordinate) (*domain.TripModel, error) {
+	return s.repo.AddTripStop(ctx, tripID, riderID, stop)
+}
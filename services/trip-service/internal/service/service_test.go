@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"ride-sharing/services/trip-service/internal/domain"
+	"ride-sharing/services/trip-service/internal/infrastructure/repository"
+	"ride-sharing/shared/types"
+	"testing"
+	"time"
+)
+
+// newTestService returns a service on an in-memory repository whose clock is
+// read from *now and whose routes are fetched from routeURL
+func newTestService(now *time.Time, routeURL string) *service {
+	s := NewService(repository.NewInmemRepository())
+	s.now = func() time.Time { return *now }
+	s.routeURL = routeURL
+	return s
+}
+
+func TestGetAndValidateFareExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestService(&now, "")
+
+	fares, err := s.GenerateTripFares(ctx, []*domain.RideFareModel{{PackageSlug: "sedan", TotalPriceInCents: 1000}}, "rider-1", nil)
+	if err != nil {
+		t.Fatalf("GenerateTripFares error = %v", err)
+	}
+	fareID := fares[0].ID.Hex()
+
+	now = now.Add(domain.FareValidity)
+	if _, err := s.GetAndValidateFare(ctx, fareID, "rider-1"); err != nil {
+		t.Errorf("GetAndValidateFare at the end of the validity error = %v", err)
+	}
+	if _, err := s.GetAndValidateFare(ctx, fareID, "rider-2"); !errors.Is(err, domain.ErrFareNotOwned) {
+		t.Errorf("GetAndValidateFare of another rider error = %v, want ErrFareNotOwned", err)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := s.GetAndValidateFare(ctx, fareID, "rider-1"); !errors.Is(err, domain.ErrFareExpired) {
+		t.Errorf("GetAndValidateFare after the validity error = %v, want ErrFareExpired", err)
+	}
+}
+
+func TestGetRoute(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{
+			name:   "route",
+			status: http.StatusOK,
+			body:   `{"code":"Ok","routes":[{"distance":1200,"duration":180,"geometry":{"coordinates":[[13.4,52.5],[13.41,52.51]]}}]}`,
+		},
+		{
+			name:    "no route",
+			status:  http.StatusBadRequest,
+			body:    `{"code":"NoRoute","message":"Impossible route between points"}`,
+			wantErr: domain.ErrNoRoute,
+		},
+		{
+			name:    "unavailable",
+			status:  http.StatusServiceUnavailable,
+			body:    `{"code":"Ok","routes":[]}`,
+			wantErr: domain.ErrRouteUnavailable,
+		},
+		{
+			name:    "rate limited",
+			status:  http.StatusTooManyRequests,
+			body:    `Too Many Requests`,
+			wantErr: domain.ErrRouteUnavailable,
+		},
+		{
+			name:    "not json",
+			status:  http.StatusNotFound,
+			body:    `<html>Not Found</html>`,
+			wantErr: domain.ErrRouteUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			now := time.Now()
+			s := newTestService(&now, server.URL)
+			route, err := s.GetRoute(context.Background(),
+				&types.Coordinate{Latitude: 52.5, Longitude: 13.4},
+				&types.Coordinate{Latitude: 52.51, Longitude: 13.41})
+
+			if wantPath := "/route/v1/driving/13.400000,52.500000;13.410000,52.510000"; path != wantPath {
+				t.Errorf("requested path = %q, want %q", path, wantPath)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetRoute error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetRoute error = %v", err)
+			}
+			if got := route.Routes[0].Distance; got != 1200 {
+				t.Errorf("route distance = %v, want 1200", got)
+			}
+		})
+	}
+}
+
+func TestGetRouteTransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	now := time.Now()
+	s := newTestService(&now, server.URL)
+	_, err := s.GetRoute(context.Background(),
+		&types.Coordinate{Latitude: 52.5, Longitude: 13.4},
+		&types.Coordinate{Latitude: 52.51, Longitude: 13.41})
+	if !errors.Is(err, domain.ErrRouteUnavailable) {
+		t.Errorf("GetRoute error = %v, want ErrRouteUnavailable", err)
+	}
+}
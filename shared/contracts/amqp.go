@@ -4,6 +4,8 @@ package contracts
 type AmqpMessage struct {
 	OwnerID string `json:"ownerId"`
 	Data    []byte `json:"data"`
+	// CorrelationID is the ID of the client message this one answers, passed on to the client
+	CorrelationID string `json:"correlationId,omitempty"`
 }
 
 // Routing keys - using consistent event/command patterns
@@ -13,13 +15,61 @@ const (
 	TripEventDriverAssigned      = "trip.event.driver_assigned"
 	TripEventNoDriversFound      = "trip.event.no_drivers_found"
 	TripEventDriverNotInterested = "trip.event.driver_not_interested"
+	TripEventDriverLocation      = "trip.event.driver_location"
+	TripEventStarted             = "trip.event.started"
+	TripEventCompleted           = "trip.event.completed"
+	TripEventCancelled           = "trip.event.cancelled"
+	// TripEventRouteUpdated follows a rider moving their pickup point or adding a stop
+	TripEventRouteUpdated = "trip.event.route_updated"
 
 	// Driver commands (driver.cmd.*)
-	DriverCmdTripRequest = "driver.cmd.trip_request"
-	DriverCmdTripAccept  = "driver.cmd.trip_accept"
-	DriverCmdTripDecline = "driver.cmd.trip_decline"
-	DriverCmdLocation    = "driver.cmd.location"
-	DriverCmdRegister    = "driver.cmd.register"
+	DriverCmdTripRequest      = "driver.cmd.trip_request"
+	DriverCmdTripAccept       = "driver.cmd.trip_accept"
+	DriverCmdTripDecline      = "driver.cmd.trip_decline"
+	DriverCmdTripStart        = "driver.cmd.trip_start"
+	DriverCmdTripComplete     = "driver.cmd.trip_complete"
+	DriverCmdTripOfferRevoked = "driver.cmd.trip_offer_revoked"
+	DriverCmdTripAlreadyTaken = "driver.cmd.trip_already_taken"
+	DriverCmdTripCancelled    = "driver.cmd.trip_cancelled"
+	DriverCmdLocation         = "driver.cmd.location"
+	DriverCmdRegister         = "driver.cmd.register"
+
+	// Driver events (driver.event.*)
+	DriverEventLocationUpdated = "driver.event.location_updated"
+	DriverEventTripOffered     = "driver.event.trip_offered"
+
+	// Rider commands (rider.cmd.*), answered with a rider.event.command_ack or command_error
+	RiderCmdCancelTrip   = "rider.cmd.cancel_trip"
+	RiderCmdUpdatePickup = "rider.cmd.update_pickup"
+	RiderCmdAddStop      = "rider.cmd.add_stop"
+
+	// Rider events (rider.event.*)
+	RiderEventCommandAck   = "rider.event.command_ack"
+	RiderEventCommandError = "rider.event.command_error"
+
+	// Chat commands (chat.cmd.*), sent by the rider and the assigned driver of a trip
+	ChatCmdSend = "chat.cmd.send"
+	// ChatCmdReceipt reports that messages reached their recipient or were read
+	ChatCmdReceipt = "chat.cmd.receipt"
+
+	// Chat events (chat.event.*)
+	ChatEventMessage = "chat.event.message"
+	ChatEventReceipt = "chat.event.receipt"
+	ChatEventError   = "chat.event.error"
+	// ChatEventClosed is sent to both participants when the trip ends
+	ChatEventClosed = "chat.event.closed"
+
+	// Client commands (client.cmd.*), sent by riders and drivers alike
+	ClientCmdAck = "client.cmd.ack"
+	// ClientCmdHeartbeat is echoed back by the gateway, for clients that can't see pings
+	ClientCmdHeartbeat = "client.cmd.heartbeat"
+
+	// Client events (client.event.*), sent to riders and drivers alike
+	ClientEventError = "client.event.error"
+
+	// Gateway messages (gateway.*), exchanged between API Gateway instances
+	GatewayEventPresence = "gateway.event.presence"
+	GatewayCmdDeliver    = "gateway.cmd.deliver"
 
 	// Payment events (payment.event.*)
 	PaymentEventSessionCreated = "payment.event.session_created"
@@ -10,4 +10,42 @@ type APIResponse struct {
 type APIError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	// RequestID identifies the failed request in the gateway logs
+	RequestID string `json:"requestID,omitempty"`
 }
+
+// CommandAccepted answers a rider or chat command sent over HTTP. The command's result
+// arrives on the user's stream, correlated to CommandID.
+type CommandAccepted struct {
+	CommandID string `json:"commandID"`
+}
+
+// API error codes. They are stable, clients branch on them instead of on messages.
+// Services report the domain specific ones as the reason of their gRPC errors.
+const (
+	ErrCodeBadRequest         = "BAD_REQUEST"
+	ErrCodeUnauthorized       = "UNAUTHORIZED"
+	ErrCodeForbidden          = "FORBIDDEN"
+	ErrCodeNotFound           = "NOT_FOUND"
+	ErrCodeConflict           = "CONFLICT"
+	ErrCodeRateLimited        = "RATE_LIMITED"
+	ErrCodeInternal           = "INTERNAL"
+	ErrCodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	ErrCodeTimeout            = "TIMEOUT"
+
+	// ErrCodeFareNotOwned means the fare was previewed by another user
+	ErrCodeFareNotOwned = "FARE_NOT_OWNED"
+	// ErrCodeFareExpired means the fare was previewed too long ago, the trip has to be previewed again
+	ErrCodeFareExpired = "FARE_EXPIRED"
+	// ErrCodeChatClosed means the trip of a chat ended, no more messages can be sent
+	ErrCodeChatClosed = "CHAT_CLOSED"
+	// ErrCodeMessageRejected means the content filter refused a chat message
+	ErrCodeMessageRejected = "MESSAGE_REJECTED"
+
+	// ErrCodeUnknownMessageType means a WebSocket message has a type clients may not send
+	ErrCodeUnknownMessageType = "UNKNOWN_MESSAGE_TYPE"
+	// ErrCodeUnsupportedVersion means a WebSocket message has a version the server doesn't speak
+	ErrCodeUnsupportedVersion = "UNSUPPORTED_VERSION"
+	// ErrCodeInvalidPayload means the data of a WebSocket message doesn't match its type
+	ErrCodeInvalidPayload = "INVALID_PAYLOAD"
+)
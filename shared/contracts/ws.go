@@ -1,14 +1,209 @@
 package contracts
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	pbd "ride-sharing/shared/proto/driver"
+	"ride-sharing/shared/types"
+	"strings"
+	"time"
+	"unicode/utf8"
 
-// WSMessage is the message structure for the WebSocket.
+	"github.com/google/uuid"
+)
+
+// WSProtocolVersion is the version of the WebSocket envelope and payloads. Clients
+// may omit it, messages of other versions are rejected.
+const WSProtocolVersion = 1
+
+// maxCancelReasonLength bounds the reason riders may give for cancelling a trip
+const maxCancelReasonLength = 200
+
+// MaxChatMessageLength is the maximum number of characters of a chat message
+const MaxChatMessageLength = 500
+
+// Statuses of chat receipts. A read message was delivered as well.
+const (
+	ChatReceiptDelivered = "delivered"
+	ChatReceiptRead      = "read"
+)
+
+// WSMessage is the envelope of the messages sent to WebSocket clients.
+// ID identifies the message, and stays the same when it is delivered again.
+// CorrelationID is the ID of the client message it answers, if any.
+// Seq numbers the messages a client must acknowledge, it is omitted otherwise.
 type WSMessage struct {
-	Type string `json:"type"`
-	Data any    `json:"data"`
+	Type          string    `json:"type"`
+	Version       int       `json:"version"`
+	ID            string    `json:"id"`
+	CorrelationID string    `json:"correlationID,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	Data          any       `json:"data,omitempty"`
+	Seq           uint64    `json:"seq,omitempty"`
+}
+
+// NewWSMessage creates a message of the current version with a new ID
+func NewWSMessage(messageType string, data any) WSMessage {
+	return WSMessage{
+		Type:      messageType,
+		Version:   WSProtocolVersion,
+		ID:        uuid.NewString(),
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// WSRawMessage is the envelope of a WebSocket message with its payload still encoded
+type WSRawMessage struct {
+	Type          string          `json:"type"`
+	Version       int             `json:"version,omitempty"`
+	ID            string          `json:"id,omitempty"`
+	CorrelationID string          `json:"correlationID,omitempty"`
+	Timestamp     *time.Time      `json:"timestamp,omitempty"`
+	Data          json.RawMessage `json:"data,omitempty"`
+	Seq           uint64          `json:"seq,omitempty"`
+}
+
+// WSAckData acknowledges all messages up to and including Seq
+type WSAckData struct {
+	Seq uint64 `json:"seq"`
+}
+
+func (d *WSAckData) Validate() error {
+	if d.Seq == 0 {
+		return errors.New("seq is required")
+	}
+	return nil
+}
+
+// WSDriverLocationData is the payload of a driver.cmd.location message
+type WSDriverLocationData struct {
+	Location *pbd.Location `json:"location"`
+}
+
+func (d *WSDriverLocationData) Validate() error {
+	if d.Location == nil {
+		return errors.New("location is required")
+	}
+	return validateCoordinate(d.Location.Latitude, d.Location.Longitude)
+}
+
+// WSCancelTripData is the payload of a rider.cmd.cancel_trip command
+type WSCancelTripData struct {
+	TripID string `json:"tripID"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (d *WSCancelTripData) Validate() error {
+	if d.TripID == "" {
+		return errors.New("tripID is required")
+	}
+	if len(d.Reason) > maxCancelReasonLength {
+		return fmt.Errorf("reason must be at most %d bytes", maxCancelReasonLength)
+	}
+	return nil
+}
+
+// WSUpdatePickupData is the payload of a rider.cmd.update_pickup command
+type WSUpdatePickupData struct {
+	TripID string            `json:"tripID"`
+	Pickup *types.Coordinate `json:"pickup"`
+}
+
+func (d *WSUpdatePickupData) Validate() error {
+	if d.TripID == "" {
+		return errors.New("tripID is required")
+	}
+	if d.Pickup == nil {
+		return errors.New("pickup is required")
+	}
+	return validateCoordinate(d.Pickup.Latitude, d.Pickup.Longitude)
+}
+
+// WSAddStopData is the payload of a rider.cmd.add_stop command
+type WSAddStopData struct {
+	TripID string            `json:"tripID"`
+	Stop   *types.Coordinate `json:"stop"`
+}
+
+func (d *WSAddStopData) Validate() error {
+	if d.TripID == "" {
+		return errors.New("tripID is required")
+	}
+	if d.Stop == nil {
+		return errors.New("stop is required")
+	}
+	return validateCoordinate(d.Stop.Latitude, d.Stop.Longitude)
+}
+
+// WSChatSendData is the payload of a chat.cmd.send command
+type WSChatSendData struct {
+	TripID string `json:"tripID"`
+	Text   string `json:"text"`
+}
+
+func (d *WSChatSendData) Validate() error {
+	if d.TripID == "" {
+		return errors.New("tripID is required")
+	}
+	if strings.TrimSpace(d.Text) == "" {
+		return errors.New("text is required")
+	}
+	if !utf8.ValidString(d.Text) {
+		return errors.New("text must be valid UTF-8")
+	}
+	if utf8.RuneCountInString(d.Text) > MaxChatMessageLength {
+		return fmt.Errorf("text must be at most %d characters", MaxChatMessageLength)
+	}
+	return nil
+}
+
+// WSChatReceiptData is the payload of a chat.cmd.receipt command. It covers the
+// recipient's messages up to and including MessageID.
+type WSChatReceiptData struct {
+	TripID    string `json:"tripID"`
+	MessageID string `json:"messageID"`
+	Status    string `json:"status"`
+}
+
+func (d *WSChatReceiptData) Validate() error {
+	if d.TripID == "" {
+		return errors.New("tripID is required")
+	}
+	if d.MessageID == "" {
+		return errors.New("messageID is required")
+	}
+	if d.Status != ChatReceiptDelivered && d.Status != ChatReceiptRead {
+		return fmt.Errorf("status must be %s or %s", ChatReceiptDelivered, ChatReceiptRead)
+	}
+	return nil
+}
+
+// WSCommandResultData answers a rider command, the envelope's correlationID is the
+// command's ID. Code and Message are only set on errors.
+type WSCommandResultData struct {
+	Command string `json:"command"`
+	TripID  string `json:"tripID,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// WSErrorData is the payload of a client.event.error frame, sent for client messages
+// which fail validation. The envelope's correlationID is the ID of the message, if it had one.
+type WSErrorData struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Type is the type of the rejected message, if it could be read
+	Type string `json:"type,omitempty"`
 }
 
-type WSDriverMessage struct {
-	Type string          `json:"type"`
-	Data json.RawMessage `json:"data"`
+func validateCoordinate(latitude, longitude float64) error {
+	if latitude < -90 || latitude > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if longitude < -180 || longitude > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	return nil
 }
@@ -0,0 +1,229 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCircuitOpen is returned for calls the circuit breaker rejected without trying
+// the backend. It carries the UNAVAILABLE status, like a backend that is down.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets every call through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects every call until its timeout ran out
+	BreakerOpen
+	// BreakerHalfOpen lets a few probes through, which decide whether it closes or opens again
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig holds the tunables of a circuit breaker
+type BreakerConfig struct {
+	// FailureThreshold is how many failures in a row open the breaker, 0 disables it
+	FailureThreshold int
+	// OpenTimeout is how long an open breaker rejects calls before probing the backend
+	OpenTimeout time.Duration
+	// HalfOpenProbes is how many probes may run at once, and must succeed to close the breaker
+	HalfOpenProbes int
+}
+
+// CircuitBreaker stops calling a backend that keeps failing, so callers fail fast
+// instead of piling up on timeouts. Only failures of the backend itself count, see
+// isBackendFailure; errors about the request don't.
+type CircuitBreaker struct {
+	config BreakerConfig
+	now    func() time.Time
+	// metrics counts the times the breaker opened, if set
+	metrics *expvar.Map
+
+	mutex     sync.Mutex
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+	// generation changes with every state change, results of calls allowed in an
+	// earlier generation are stale
+	generation uint64
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+	return &CircuitBreaker{config: config, now: time.Now}
+}
+
+// State returns the current state, an open breaker whose timeout ran out is half-open
+func (b *CircuitBreaker) State() BreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.refreshLocked()
+	return b.state
+}
+
+// Allow reports whether a call may go through, and returns the generation it was
+// allowed in. Every allowed call must be followed by Done with the generation and
+// its error.
+func (b *CircuitBreaker) Allow() (uint64, error) {
+	if b.config.FailureThreshold <= 0 {
+		return 0, nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.refreshLocked()
+	switch b.state {
+	case BreakerOpen:
+		return 0, circuitOpenError{retryIn: b.config.OpenTimeout - b.now().Sub(b.openedAt)}
+	case BreakerHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			return 0, circuitOpenError{}
+		}
+		b.probes++
+	}
+	return b.generation, nil
+}
+
+// Done records the result of a call allowed in generation. Results of calls allowed
+// before the last state change are ignored: a slow call started while the breaker
+// was closed says nothing about the backend after it opened, and isn't a probe.
+func (b *CircuitBreaker) Done(generation uint64, err error) {
+	if b.config.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	// A canceled call says nothing about the backend, the caller went away. It only
+	// frees its probe slot.
+	if isCanceled(err) {
+		if b.state == BreakerHalfOpen {
+			b.probes--
+		}
+		return
+	}
+
+	failed := isBackendFailure(err)
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.probes--
+		if failed {
+			b.openLocked()
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.state = BreakerClosed
+			b.failures = 0
+			b.generation++
+		}
+	case BreakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.openLocked()
+		}
+	}
+}
+
+// refreshLocked moves an open breaker whose timeout ran out to half-open. The caller must hold the lock.
+func (b *CircuitBreaker) refreshLocked() {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.state = BreakerHalfOpen
+		b.probes = 0
+		b.successes = 0
+		b.generation++
+	}
+}
+
+// openLocked opens the breaker. The caller must hold the lock.
+func (b *CircuitBreaker) openLocked() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.failures = 0
+	b.generation++
+	if b.metrics != nil {
+		b.metrics.Add("breaker_opened", 1)
+	}
+}
+
+// circuitOpenError is a rejection of the breaker, with when it probes again if known
+type circuitOpenError struct {
+	retryIn time.Duration
+}
+
+func (e circuitOpenError) Error() string {
+	if e.retryIn > 0 {
+		return fmt.Sprintf("%v, probing again in %v", ErrCircuitOpen, e.retryIn.Round(time.Millisecond))
+	}
+	return ErrCircuitOpen.Error()
+}
+
+func (e circuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+func (e circuitOpenError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
+
+// isCanceled reports whether a call ended because its caller canceled it
+func isCanceled(err error) bool {
+	return errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled
+}
+
+// isBackendFailure reports whether an error means the backend is unhealthy, rather
+// than that the request was wrong or refused. Errors carrying an ErrorInfo were
+// answered on purpose by a healthy backend, for example when one of its own
+// dependencies is down, and don't count.
+func isBackendFailure(err error) bool {
+	st := status.Convert(err)
+	for _, detail := range st.Details() {
+		if _, ok := detail.(*errdetails.ErrorInfo); ok {
+			return false
+		}
+	}
+
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,248 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errUnavailable = status.Error(codes.Unavailable, "backend down")
+	errNotFound    = status.Error(codes.NotFound, "no such trip")
+)
+
+// fakeClock is the breaker's now, moved by hand
+type fakeClock struct {
+	current time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.current
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.current = c.current.Add(d)
+}
+
+func newTestBreaker(config BreakerConfig) (*CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{current: time.Unix(1_700_000_000, 0)}
+	breaker := NewCircuitBreaker(config)
+	breaker.now = clock.now
+	return breaker, clock
+}
+
+// call runs one call through the breaker, reporting whether it was allowed
+func call(b *CircuitBreaker, err error) bool {
+	generation, allowErr := b.Allow()
+	if allowErr != nil {
+		return false
+	}
+	b.Done(generation, err)
+	return true
+}
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker(BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Second})
+
+	// A success resets the count, and so does an error about the request, which
+	// the backend answered
+	for _, answer := range []error{nil, errNotFound} {
+		call(b, errUnavailable)
+		call(b, errUnavailable)
+		call(b, answer)
+		if state := b.State(); state != BreakerClosed {
+			t.Fatalf("State() = %v after two failures and %v, want closed", state, answer)
+		}
+	}
+
+	call(b, errUnavailable)
+	call(b, errUnavailable)
+	call(b, errUnavailable)
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("State() = %v after three failures in a row, want open", state)
+	}
+
+	_, err := b.Allow()
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Allow() of an open breaker = %v, want ErrCircuitOpen", err)
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("status code = %v, want Unavailable", status.Code(err))
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	b, clock := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second, HalfOpenProbes: 2})
+	call(b, errUnavailable)
+
+	clock.advance(999 * time.Millisecond)
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("State() = %v before the timeout, want open", state)
+	}
+	clock.advance(time.Millisecond)
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("State() = %v after the timeout, want half-open", state)
+	}
+
+	// Only HalfOpenProbes calls may run at once
+	first, err := b.Allow()
+	if err != nil {
+		t.Fatalf("first probe rejected: %v", err)
+	}
+	second, err := b.Allow()
+	if err != nil {
+		t.Fatalf("second probe rejected: %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("third concurrent probe = %v, want ErrCircuitOpen", err)
+	}
+
+	b.Done(first, nil)
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("State() = %v after one of two probes, want half-open", state)
+	}
+	b.Done(second, nil)
+	if state := b.State(); state != BreakerClosed {
+		t.Fatalf("State() = %v after the probes succeeded, want closed", state)
+	}
+}
+
+func TestCircuitBreakerReopensOnFailedProbe(t *testing.T) {
+	b, clock := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second})
+	call(b, errUnavailable)
+	clock.advance(time.Second)
+
+	call(b, errUnavailable)
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("State() = %v after a failed probe, want open", state)
+	}
+
+	// The timeout starts over
+	clock.advance(999 * time.Millisecond)
+	if state := b.State(); state != BreakerOpen {
+		t.Errorf("State() = %v before the new timeout, want open", state)
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleResults(t *testing.T) {
+	b, clock := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second, HalfOpenProbes: 1})
+
+	// A slow call starts while the breaker is closed, then another one opens it
+	slow, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	call(b, errUnavailable)
+	clock.advance(time.Second)
+
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+
+	// The slow call's success is no probe, neither closes the breaker nor frees the probe slot
+	b.Done(slow, nil)
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("State() = %v after a stale success, want half-open", state)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow() while the probe runs = %v, want ErrCircuitOpen", err)
+	}
+
+	b.Done(probe, nil)
+	if state := b.State(); state != BreakerClosed {
+		t.Fatalf("State() = %v after the probe succeeded, want closed", state)
+	}
+
+	// A probe slot freed by a stale result would let a second probe through; check
+	// the counts stayed consistent by opening and probing once more
+	call(b, errUnavailable)
+	clock.advance(time.Second)
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("probe after reopening rejected: %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second concurrent probe = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleFailures(t *testing.T) {
+	b, clock := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second})
+
+	slow, _ := b.Allow()
+	call(b, errUnavailable)
+	clock.advance(time.Second)
+	call(b, nil)
+	if state := b.State(); state != BreakerClosed {
+		t.Fatalf("State() = %v after the probe succeeded, want closed", state)
+	}
+
+	// The failure of a call from before the breaker opened doesn't open it again
+	b.Done(slow, errUnavailable)
+	if state := b.State(); state != BreakerClosed {
+		t.Errorf("State() = %v after a stale failure, want closed", state)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b, _ := newTestBreaker(BreakerConfig{})
+	for range 10 {
+		if !call(b, errUnavailable) {
+			t.Fatal("disabled breaker rejected a call")
+		}
+	}
+	if state := b.State(); state != BreakerClosed {
+		t.Errorf("State() = %v, want closed", state)
+	}
+}
+
+func TestCircuitBreakerIgnoresErrorsWithReason(t *testing.T) {
+	b, _ := newTestBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second})
+
+	// The backend is healthy and reports that one of its own dependencies is down
+	st, err := status.New(codes.Unavailable, "routing service unavailable").WithDetails(&errdetails.ErrorInfo{
+		Reason: "SERVICE_UNAVAILABLE",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		call(b, st.Err())
+	}
+	if state := b.State(); state != BreakerClosed {
+		t.Errorf("State() = %v after errors with a reason, want closed", state)
+	}
+}
+
+func TestCircuitBreakerCanceledProbeIsNeutral(t *testing.T) {
+	b, clock := newTestBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Second})
+
+	// A canceled call neither resets nor adds to the failure count
+	call(b, errUnavailable)
+	call(b, status.Error(codes.Canceled, "context canceled"))
+	call(b, errUnavailable)
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("State() = %v after failures around a canceled call, want open", state)
+	}
+	clock.advance(time.Second)
+
+	for _, canceled := range []error{status.Error(codes.Canceled, "context canceled"), context.Canceled} {
+		call(b, canceled)
+		if state := b.State(); state != BreakerHalfOpen {
+			t.Fatalf("State() = %v after a probe canceled with %v, want half-open", state, canceled)
+		}
+	}
+
+	// The canceled probes freed their slot
+	if !call(b, nil) {
+		t.Fatal("probe after canceled probes rejected")
+	}
+	if state := b.State(); state != BreakerClosed {
+		t.Errorf("State() = %v after the probe succeeded, want closed", state)
+	}
+}
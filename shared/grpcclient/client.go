@@ -0,0 +1,263 @@
+/*
+Package grpcclient dials gRPC backends with a shared stack of client interceptors:
+per-method deadlines, retries with jittered backoff for idempotent methods, a circuit
+breaker per backend and call metrics on /debug/vars. The request ID of the caller's
+context is sent along as metadata.
+*/
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"ride-sharing/shared/requestid"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// clientMetrics exposes the calls of every backend on /debug/vars, per backend name:
+// calls by method and status code, retries, calls rejected by the circuit breaker,
+// the times it opened and its current state
+var clientMetrics = expvar.NewMap("grpc_client")
+
+// MethodPolicy is how the calls of a method are made
+type MethodPolicy struct {
+	// Timeout is the deadline of a call including its retries, unless the caller's
+	// context ends sooner. 0 leaves the caller's deadline alone.
+	Timeout time.Duration
+	// Idempotent methods can be called again safely and are retried
+	Idempotent bool
+}
+
+// RetryPolicy is how idempotent calls are retried
+type RetryPolicy struct {
+	// MaxAttempts is the most a call is tried, including the first attempt
+	MaxAttempts int
+	// InitialBackoff and MaxBackoff bound the exponential backoff between attempts.
+	// The wait is drawn at random up to the backoff, so retrying clients spread out.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Codes are the status codes worth retrying
+	Codes []codes.Code
+}
+
+// Config holds the policies of a backend
+type Config struct {
+	// Name identifies the backend in metrics and logs
+	Name string
+	// Methods are the policies of methods by full method name, like
+	// "/trip.TripService/CreateTrip". Other methods use DefaultPolicy.
+	Methods       map[string]MethodPolicy
+	DefaultPolicy MethodPolicy
+	Retry         RetryPolicy
+	Breaker       BreakerConfig
+}
+
+// DefaultConfig returns a Config with sensible default values. No method is
+// retried until it is declared idempotent.
+func DefaultConfig(name string) Config {
+	return Config{
+		Name:          name,
+		Methods:       map[string]MethodPolicy{},
+		DefaultPolicy: MethodPolicy{Timeout: 5 * time.Second},
+		Retry: RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: 100 * time.Millisecond,
+			MaxBackoff:     1 * time.Second,
+			Codes:          []codes.Code{codes.Unavailable},
+		},
+		Breaker: BreakerConfig{
+			FailureThreshold: 5,
+			OpenTimeout:      10 * time.Second,
+			HalfOpenProbes:   1,
+		},
+	}
+}
+
+// Dial creates a client connection to target with the interceptors of the config
+func Dial(target string, config Config, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	interceptors := NewInterceptors(config)
+
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(interceptors.Unary),
+		grpc.WithChainStreamInterceptor(interceptors.Stream),
+	}, opts...)
+
+	return grpc.NewClient(target, opts...)
+}
+
+// Interceptors are the client interceptors of a backend. They share its circuit breaker.
+type Interceptors struct {
+	config  Config
+	breaker *CircuitBreaker
+	metrics *expvar.Map
+}
+
+// NewInterceptors creates the interceptors of a backend and registers its metrics
+func NewInterceptors(config Config) *Interceptors {
+	metrics := new(expvar.Map).Init()
+	clientMetrics.Set(config.Name, metrics)
+
+	breaker := NewCircuitBreaker(config.Breaker)
+	breaker.metrics = metrics
+	metrics.Set("breaker_state", expvar.Func(func() any {
+		return breaker.State().String()
+	}))
+
+	return &Interceptors{
+		config:  config,
+		breaker: breaker,
+		metrics: metrics,
+	}
+}
+
+// policy returns the policy of a method
+func (i *Interceptors) policy(method string) MethodPolicy {
+	if policy, ok := i.config.Methods[method]; ok {
+		return policy
+	}
+	return i.config.DefaultPolicy
+}
+
+// Unary applies the method's deadline and retries the attempts the retry policy allows
+func (i *Interceptors) Unary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx = withRequestID(ctx)
+
+	policy := i.policy(method)
+	if policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		defer cancel()
+	}
+
+	attempts := 1
+	if policy.Idempotent && i.config.Retry.MaxAttempts > 1 {
+		attempts = i.config.Retry.MaxAttempts
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if !i.wait(ctx, attempt) {
+				break
+			}
+			i.metrics.Add("retries", 1)
+		}
+
+		err = i.attempt(ctx, method, func() error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+		if !i.retryable(err) {
+			break
+		}
+	}
+
+	return err
+}
+
+// Stream guards the opening of streams with the circuit breaker. Streams are not
+// retried and get no deadline, they usually outlive any sensible one.
+func (i *Interceptors) Stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx = withRequestID(ctx)
+
+	var stream grpc.ClientStream
+	err := i.attempt(ctx, method, func() error {
+		var err error
+		stream, err = streamer(ctx, desc, cc, method, opts...)
+		return err
+	})
+	return stream, err
+}
+
+// attempt makes one call through the circuit breaker and counts its outcome
+func (i *Interceptors) attempt(ctx context.Context, method string, call func() error) error {
+	generation, err := i.breaker.Allow()
+	if err != nil {
+		i.metrics.Add("rejected", 1)
+		return err
+	}
+
+	err = call()
+	i.breaker.Done(generation, err)
+	i.metrics.Add(fmt.Sprintf("%s %s", method, status.Code(err)), 1)
+
+	if err != nil && isBackendFailure(err) {
+		log.Printf("gRPC call %s to %s failed: %v", method, i.config.Name, err)
+	}
+	return err
+}
+
+// retryable reports whether an attempt failed in a way another attempt may not.
+// Rejections of the circuit breaker would only be rejected again.
+func (i *Interceptors) retryable(err error) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	code := status.Code(err)
+	for _, retryable := range i.config.Retry.Codes {
+		if code == retryable {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the exponential backoff before a retry, attempt counting from 1.
+// It doubles until it reaches MaxBackoff, so late attempts can't overflow.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for range attempt - 1 {
+		if backoff >= p.MaxBackoff {
+			break
+		}
+		backoff *= 2
+	}
+	if backoff <= 0 || backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// wait sleeps before a retry, for a random time up to the exponential backoff of the
+// attempt. It reports false if the context ends first or too soon for another attempt.
+func (i *Interceptors) wait(ctx context.Context, attempt int) bool {
+	wait := rand.N(i.config.Retry.backoff(attempt) + 1)
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+		return false
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// withRequestID adds the request ID of a context to its outgoing metadata, unless
+// the caller set one already
+func withRequestID(ctx context.Context) context.Context {
+	requestID := requestid.FromContext(ctx)
+	if requestID == "" {
+		return ctx
+	}
+
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(requestid.MetadataKey)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, requestid.MetadataKey, requestID)
+}
@@ -0,0 +1,147 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testMethod = "/trip.TripService/ListChatMessages"
+
+func testInterceptors(t *testing.T, idempotent bool) *Interceptors {
+	t.Helper()
+	config := DefaultConfig(t.Name())
+	config.Methods[testMethod] = MethodPolicy{Timeout: time.Second, Idempotent: idempotent}
+	config.Retry.InitialBackoff = time.Millisecond
+	config.Retry.MaxBackoff = 2 * time.Millisecond
+	config.Breaker.FailureThreshold = 10
+	return NewInterceptors(config)
+}
+
+// failingInvoker fails the first failures calls with err and counts the calls
+func failingInvoker(failures int, err error, calls *int) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= failures {
+			return err
+		}
+		return nil
+	}
+}
+
+func TestUnaryRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		idempotent bool
+		failures   int
+		err        error
+		wantCalls  int
+		wantCode   codes.Code
+	}{
+		{"success", true, 0, nil, 1, codes.OK},
+		{"retried until success", true, 2, errUnavailable, 3, codes.OK},
+		{"gives up after max attempts", true, 5, errUnavailable, 3, codes.Unavailable},
+		{"not idempotent", false, 1, errUnavailable, 1, codes.Unavailable},
+		{"code not retried", true, 1, errNotFound, 1, codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := testInterceptors(t, tt.idempotent)
+
+			calls := 0
+			err := i.Unary(context.Background(), testMethod, nil, nil, nil, failingInvoker(tt.failures, tt.err, &calls))
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("Unary() error = %v, want code %v", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUnaryStopsRetryingWhenTheBreakerOpens(t *testing.T) {
+	i := testInterceptors(t, true)
+	i.breaker.config.FailureThreshold = 1
+
+	calls := 0
+	err := i.Unary(context.Background(), testMethod, nil, nil, nil, failingInvoker(5, errUnavailable, &calls))
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Unary() error = %v, want ErrCircuitOpen", err)
+	}
+
+	calls = 0
+	err = i.Unary(context.Background(), testMethod, nil, nil, nil, failingInvoker(0, nil, &calls))
+	if calls != 0 || !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("call on an open breaker made %d calls, error = %v", calls, err)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		// Attempts far past the cap don't overflow
+		{40, time.Second},
+		{64, time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := policy.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestWaitGivesUpBeforeTheDeadline(t *testing.T) {
+	i := testInterceptors(t, true)
+	i.config.Retry.InitialBackoff = time.Hour
+	i.config.Retry.MaxBackoff = time.Hour
+
+	// A wait of up to an hour practically never fits before the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if i.wait(ctx, 1) {
+		t.Error("wait() = true although the deadline was too close")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if i.wait(cancelled, 1) {
+		t.Error("wait() = true for a cancelled context")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("wait() took %v to notice the cancelled context", elapsed)
+	}
+}
+
+func TestWaitSleepsWithinTheBackoff(t *testing.T) {
+	i := testInterceptors(t, true)
+	i.config.Retry.InitialBackoff = 5 * time.Millisecond
+	i.config.Retry.MaxBackoff = 5 * time.Millisecond
+
+	start := time.Now()
+	if !i.wait(context.Background(), 3) {
+		t.Fatal("wait() = false without a deadline")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("wait() slept %v, want at most the 5ms backoff", elapsed)
+	}
+}
@@ -0,0 +1,224 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"ride-sharing/shared/requestid"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// maxIDLength bounds the IDs taken from metadata, longer request IDs are replaced
+const maxIDLength = 128
+
+// UnaryRequestID puts the request ID of the caller's metadata into the context, or a
+// new one if it sent none, and returns it in the response header
+func UnaryRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// StreamRequestID is UnaryRequestID for streams
+func StreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, withContext(stream, withRequestID(stream.Context())))
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	requestID := firstMetadata(ctx, requestid.MetadataKey)
+	if !loggable(requestID) {
+		requestID = uuid.NewString()
+	}
+
+	// Only fails once headers were sent, which no handler did yet
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestid.MetadataKey, requestID))
+	return requestid.NewContext(ctx, requestID)
+}
+
+// loggable only accepts short, printable ASCII IDs from metadata, so they can't inject into logs
+func loggable(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// UnaryAccessLog logs every call with its status code and duration
+func UnaryAccessLog(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamAccessLog logs every stream once it ended
+func StreamAccessLog(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		logCall(stream.Context(), logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("request_id", requestid.FromContext(ctx)),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+	// Read from the metadata, the principal is only validated further down the chain
+	if userID := firstMetadata(ctx, UserIDMetadataKey); loggable(userID) {
+		attrs = append(attrs, slog.String("user_id", userID))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+
+	logger.LogAttrs(ctx, level, "grpc call", attrs...)
+}
+
+// UnaryRecovery answers calls whose handler panics with INTERNAL, logging the panic
+// and its stack, so one bad request can't crash the service
+func UnaryRecovery(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = recoverPanic(ctx, logger, info.FullMethod, recovered)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery is UnaryRecovery for streams
+func StreamRecovery(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = recoverPanic(stream.Context(), logger, info.FullMethod, recovered)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+func recoverPanic(ctx context.Context, logger *slog.Logger, method string, recovered any) error {
+	logger.ErrorContext(ctx, "grpc handler panicked",
+		slog.String("method", method),
+		slog.String("request_id", requestid.FromContext(ctx)),
+		slog.Any("panic", recovered),
+		slog.String("stack", string(debug.Stack())),
+	)
+	// The panic may reveal internals, clients only get the request ID to report
+	return status.Errorf(codes.Internal, "internal error, request %s", requestid.FromContext(ctx))
+}
+
+// UnaryPrincipal puts the principal of the caller's metadata into the context. Calls
+// with an incomplete principal, or without the service token if one is set, are refused
+// with UNAUTHENTICATED.
+func UnaryPrincipal(serviceToken string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := withPrincipal(ctx, serviceToken)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamPrincipal is UnaryPrincipal for streams
+func StreamPrincipal(serviceToken string) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := withPrincipal(stream.Context(), serviceToken)
+		if err != nil {
+			return err
+		}
+		return handler(srv, withContext(stream, ctx))
+	}
+}
+
+// UnaryValidation refuses requests that fail their Validate method, or that act on
+// behalf of another user than the principal, with INVALID_ARGUMENT or PERMISSION_DENIED
+func UnaryValidation() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := validateRequest(ctx, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamValidation is UnaryValidation for every message a client streams
+func StreamValidation() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validatingStream{ServerStream: stream})
+	}
+}
+
+// validatingStream validates the messages it receives
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return validateRequest(s.Context(), m)
+}
+
+// validator is implemented by the request messages that check their fields
+type validator interface {
+	Validate() error
+}
+
+func validateRequest(ctx context.Context, req any) error {
+	if v, ok := req.(validator); ok {
+		if err := v.Validate(); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	return checkPrincipal(ctx, req)
+}
+
+// firstMetadata returns the first value of an incoming metadata key
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
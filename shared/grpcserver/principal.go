@@ -0,0 +1,117 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"ride-sharing/shared/env"
+	"ride-sharing/shared/jwt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys of the principal the API Gateway authenticated. The services trust it,
+// and calls without one, only from callers presenting the service token.
+const (
+	UserIDMetadataKey = "x-user-id"
+	RoleMetadataKey   = "x-user-role"
+	// ServiceTokenMetadataKey carries the secret shared by the services and their callers
+	ServiceTokenMetadataKey = "x-service-token"
+)
+
+// ErrServiceTokenRequired is returned in production without GRPC_SERVICE_TOKEN, every
+// caller could otherwise act as any user, or as an internal caller
+var ErrServiceTokenRequired = errors.New("GRPC_SERVICE_TOKEN is required in production")
+
+// Roles a principal may have, the roles of the gateway's tokens
+const (
+	RoleRider  = jwt.RoleRider
+	RoleDriver = jwt.RoleDriver
+	RoleAdmin  = jwt.RoleAdmin
+)
+
+// Principal is the user a call is made on behalf of
+type Principal struct {
+	UserID string
+	Role   string
+}
+
+type principalKey struct{}
+
+// PrincipalFrom returns the principal of a call. Calls without one come from
+// authenticated internal callers, or from a gateway with authentication disabled.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// OutgoingContext returns a copy of ctx that sends the principal along with the calls made with it
+func OutgoingContext(ctx context.Context, principal Principal) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, UserIDMetadataKey, principal.UserID, RoleMetadataKey, principal.Role)
+}
+
+// ServiceTokenFromEnv returns the service token of GRPC_SERVICE_TOKEN. Without one the
+// callers aren't authenticated, which only outside production is allowed.
+func ServiceTokenFromEnv() (string, error) {
+	token := strings.TrimSpace(env.GetString("GRPC_SERVICE_TOKEN", ""))
+	if token == "" && env.GetString("ENVIRONMENT", "") == "production" {
+		return "", ErrServiceTokenRequired
+	}
+	return token, nil
+}
+
+// OutgoingServiceContext returns a copy of ctx that sends the service token along with
+// the calls made with it. An empty token sends nothing.
+func OutgoingServiceContext(ctx context.Context, serviceToken string) context.Context {
+	if serviceToken == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, ServiceTokenMetadataKey, serviceToken)
+}
+
+// withPrincipal authenticates the caller with the service token, if one is set, and
+// reads the principal of its metadata into the context
+func withPrincipal(ctx context.Context, serviceToken string) (context.Context, error) {
+	if serviceToken != "" &&
+		subtle.ConstantTimeCompare([]byte(firstMetadata(ctx, ServiceTokenMetadataKey)), []byte(serviceToken)) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "caller has no valid service token")
+	}
+
+	userID := firstMetadata(ctx, UserIDMetadataKey)
+	role := firstMetadata(ctx, RoleMetadataKey)
+	if userID == "" && role == "" {
+		return ctx, nil
+	}
+
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "principal has no user ID")
+	}
+	switch role {
+	case RoleRider, RoleDriver, RoleAdmin:
+	default:
+		return nil, status.Errorf(codes.Unauthenticated, "principal has unknown role %q", role)
+	}
+
+	return context.WithValue(ctx, principalKey{}, Principal{UserID: userID, Role: role}), nil
+}
+
+// checkPrincipal refuses requests a rider or driver makes on behalf of another user.
+// Requests name their user as userID, drivers as driverID. Admins act for anyone.
+func checkPrincipal(ctx context.Context, req any) error {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok || principal.Role == RoleAdmin {
+		return nil
+	}
+
+	if r, ok := req.(interface{ GetUserID() string }); ok && r.GetUserID() != "" && r.GetUserID() != principal.UserID {
+		return status.Error(codes.PermissionDenied, "userID doesn't match the authenticated user")
+	}
+	if r, ok := req.(interface{ GetDriverID() string }); ok && principal.Role == RoleDriver &&
+		r.GetDriverID() != "" && r.GetDriverID() != principal.UserID {
+		return status.Error(codes.PermissionDenied, "driverID doesn't match the authenticated driver")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Package grpcserver creates the gRPC servers of the services with a shared chain of
+interceptors. In order, every call:
+
+  - gets the request ID of the caller's metadata, or a new one
+  - is written to the access log with its status code and duration
+  - is answered with INTERNAL if its handler panics, instead of crashing the service
+  - is refused with UNAUTHENTICATED without the service token, if one is set
+  - carries the principal the API Gateway authenticated, if any
+  - is refused with INVALID_ARGUMENT if its request fails validation
+*/
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/grpc"
+)
+
+// NewServer creates a gRPC server with the interceptors of the package, followed by
+// the given options. An empty serviceToken accepts every caller, see ServiceTokenFromEnv.
+func NewServer(serviceToken string, opts ...grpc.ServerOption) *grpc.Server {
+	return grpc.NewServer(append(ServerOptions(slog.Default(), serviceToken), opts...)...)
+}
+
+// ServerOptions returns the options installing the interceptors, logging to logger
+// and authenticating callers with serviceToken
+func ServerOptions(logger *slog.Logger, serviceToken string) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			UnaryRequestID(),
+			UnaryAccessLog(logger),
+			UnaryRecovery(logger),
+			UnaryPrincipal(serviceToken),
+			UnaryValidation(),
+		),
+		grpc.ChainStreamInterceptor(
+			StreamRequestID(),
+			StreamAccessLog(logger),
+			StreamRecovery(logger),
+			StreamPrincipal(serviceToken),
+			StreamValidation(),
+		),
+	}
+}
+
+// serverStream replaces the context of a stream, so interceptors can pass values on to the handler
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// withContext returns the stream with ctx as its context
+func withContext(stream grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	if wrapped, ok := stream.(*serverStream); ok {
+		return &serverStream{ServerStream: wrapped.ServerStream, ctx: ctx}
+	}
+	return &serverStream{ServerStream: stream, ctx: ctx}
+}
@@ -0,0 +1,325 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	pb "ride-sharing/shared/proto/driver"
+	tripPb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/requestid"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testServiceToken authenticates the callers of dialTestServer
+const testServiceToken = "service-token"
+
+// serviceContext returns a context sending the test service token
+func serviceContext() context.Context {
+	return OutgoingServiceContext(context.Background(), testServiceToken)
+}
+
+// echoServer answers GetDriver with the request ID and principal the handler saw,
+// and panics for the driver "panic". ReportLocation does the same for streams, sending
+// the principal as header and counting the locations.
+type echoServer struct {
+	pb.UnimplementedDriverServiceServer
+}
+
+func (echoServer) ReportLocation(stream pb.DriverService_ReportLocationServer) error {
+	principal, _ := PrincipalFrom(stream.Context())
+	if err := stream.SendHeader(metadata.Pairs("principal", principal.UserID)); err != nil {
+		return err
+	}
+
+	var accepted int64
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&pb.ReportLocationResponse{Accepted: accepted})
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetDriverID() == "panic" {
+			panic("handler bug")
+		}
+		accepted++
+	}
+}
+
+func (echoServer) GetDriver(ctx context.Context, req *pb.GetDriverRequest) (*pb.GetDriverResponse, error) {
+	if req.GetDriverID() == "panic" {
+		panic("handler bug")
+	}
+
+	principal, _ := PrincipalFrom(ctx)
+	return &pb.GetDriverResponse{Driver: &pb.Driver{
+		Id:   requestid.FromContext(ctx),
+		Name: principal.UserID,
+	}}, nil
+}
+
+// dialTestServer serves echoServer with the package's interceptors over an in-memory listener
+func dialTestServer(t *testing.T) pb.DriverServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(ServerOptions(slog.New(slog.NewTextHandler(io.Discard, nil)), testServiceToken)...)
+	pb.RegisterDriverServiceServer(server, echoServer{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewDriverServiceClient(conn)
+}
+
+func TestServerRecoversPanics(t *testing.T) {
+	client := dialTestServer(t)
+	ctx := metadata.AppendToOutgoingContext(serviceContext(), requestid.MetadataKey, "req-1")
+
+	_, err := client.GetDriver(ctx, &pb.GetDriverRequest{DriverID: "panic"})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("GetDriver() error = %v, want INTERNAL", err)
+	}
+	if msg := status.Convert(err).Message(); !strings.Contains(msg, "req-1") || strings.Contains(msg, "handler bug") {
+		t.Errorf("message = %q, want the request ID without the panic", msg)
+	}
+
+	// The server survived the panic
+	if _, err := client.GetDriver(ctx, &pb.GetDriverRequest{DriverID: "driver-1"}); err != nil {
+		t.Errorf("GetDriver() after a panic = %v", err)
+	}
+}
+
+func TestServerRequestID(t *testing.T) {
+	client := dialTestServer(t)
+
+	tests := []struct {
+		name      string
+		requestID string
+		wantKept  bool
+	}{
+		{"kept", "req-1", true},
+		{"missing", "", false},
+		{"with spaces", "req 1", false},
+		{"too long", strings.Repeat("a", maxIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := serviceContext()
+			if tt.requestID != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, requestid.MetadataKey, tt.requestID)
+			}
+
+			var header metadata.MD
+			resp, err := client.GetDriver(ctx, &pb.GetDriverRequest{DriverID: "driver-1"}, grpc.Header(&header))
+			if err != nil {
+				t.Fatalf("GetDriver() error = %v", err)
+			}
+
+			seen := resp.GetDriver().GetId()
+			if values := header.Get(requestid.MetadataKey); len(values) != 1 || values[0] != seen {
+				t.Errorf("response header = %v, want the handler's request ID %q", values, seen)
+			}
+			if tt.wantKept {
+				if seen != tt.requestID {
+					t.Errorf("request ID = %q, want %q", seen, tt.requestID)
+				}
+			} else if _, err := uuid.Parse(seen); err != nil {
+				t.Errorf("request ID = %q, want a generated one", seen)
+			}
+		})
+	}
+}
+
+func TestServerPrincipal(t *testing.T) {
+	client := dialTestServer(t)
+
+	tests := []struct {
+		name     string
+		metadata []string
+		driverID string
+		wantCode codes.Code
+		wantUser string
+	}{
+		{"internal caller", []string{}, "driver-1", codes.OK, ""},
+		{"caller without token", nil, "driver-1", codes.Unauthenticated, ""},
+		{"driver without token", []string{ServiceTokenMetadataKey, "", UserIDMetadataKey, "driver-1", RoleMetadataKey, RoleDriver}, "driver-1", codes.Unauthenticated, ""},
+		{"wrong token", []string{ServiceTokenMetadataKey, "guessed"}, "driver-1", codes.Unauthenticated, ""},
+		{"own driver", []string{UserIDMetadataKey, "driver-1", RoleMetadataKey, RoleDriver}, "driver-1", codes.OK, "driver-1"},
+		{"admin for another driver", []string{UserIDMetadataKey, "admin-1", RoleMetadataKey, RoleAdmin}, "driver-1", codes.OK, "admin-1"},
+		{"another driver", []string{UserIDMetadataKey, "driver-2", RoleMetadataKey, RoleDriver}, "driver-1", codes.PermissionDenied, ""},
+		{"missing role", []string{UserIDMetadataKey, "driver-1"}, "driver-1", codes.Unauthenticated, ""},
+		{"missing user ID", []string{RoleMetadataKey, RoleDriver}, "driver-1", codes.Unauthenticated, ""},
+		{"unknown role", []string{UserIDMetadataKey, "driver-1", RoleMetadataKey, "root"}, "driver-1", codes.Unauthenticated, ""},
+		{"invalid request", []string{UserIDMetadataKey, "driver-1", RoleMetadataKey, RoleDriver}, "", codes.InvalidArgument, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Cases without metadata send no token, cases with metadata the test token
+			// unless they send their own
+			ctx := context.Background()
+			if tt.metadata != nil {
+				if !slices.Contains(tt.metadata, ServiceTokenMetadataKey) {
+					ctx = OutgoingServiceContext(ctx, testServiceToken)
+				}
+				ctx = metadata.AppendToOutgoingContext(ctx, tt.metadata...)
+			}
+
+			resp, err := client.GetDriver(ctx, &pb.GetDriverRequest{DriverID: tt.driverID})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("GetDriver() error = %v, want %s", err, tt.wantCode)
+			}
+			if err == nil && resp.GetDriver().GetName() != tt.wantUser {
+				t.Errorf("principal = %q, want %q", resp.GetDriver().GetName(), tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestServerStreams(t *testing.T) {
+	client := dialTestServer(t)
+
+	tests := []struct {
+		name      string
+		token     string
+		principal *Principal
+		driverIDs []string
+		wantCode  codes.Code
+		wantUser  string
+	}{
+		{"internal caller", testServiceToken, nil, []string{"driver-1", "driver-2"}, codes.OK, ""},
+		{"own driver", testServiceToken, &Principal{UserID: "driver-1", Role: RoleDriver}, []string{"driver-1"}, codes.OK, "driver-1"},
+		{"another driver", testServiceToken, &Principal{UserID: "driver-1", Role: RoleDriver}, []string{"driver-1", "driver-2"}, codes.PermissionDenied, ""},
+		{"unknown role", testServiceToken, &Principal{UserID: "driver-1", Role: "root"}, []string{"driver-1"}, codes.Unauthenticated, ""},
+		{"caller without token", "", nil, []string{"driver-1"}, codes.Unauthenticated, ""},
+		{"panic", testServiceToken, nil, []string{"driver-1", "panic"}, codes.Internal, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := OutgoingServiceContext(context.Background(), tt.token)
+			if tt.principal != nil {
+				ctx = OutgoingContext(ctx, *tt.principal)
+			}
+
+			stream, err := client.ReportLocation(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, driverID := range tt.driverIDs {
+				if err := stream.Send(&pb.ReportLocationRequest{DriverID: driverID}); err != nil {
+					// The server ended the stream, CloseAndRecv returns its status
+					break
+				}
+			}
+
+			resp, err := stream.CloseAndRecv()
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("ReportLocation() error = %v, want %s", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if got := resp.GetAccepted(); got != int64(len(tt.driverIDs)) {
+				t.Errorf("accepted = %d, want %d", got, len(tt.driverIDs))
+			}
+			header, err := stream.Header()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := header.Get("principal"); len(got) != 1 || got[0] != tt.wantUser {
+				t.Errorf("principal = %v, want %q", got, tt.wantUser)
+			}
+		})
+	}
+
+	// The server survived the panic
+	stream, err := client.ReportLocation(serviceContext())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.CloseAndRecv(); err != nil {
+		t.Errorf("ReportLocation() after a panic = %v", err)
+	}
+}
+
+func TestCheckPrincipalUserID(t *testing.T) {
+	req := &tripPb.PreviewTripRequest{UserID: "rider-1"}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		wantCode  codes.Code
+	}{
+		{"internal caller", nil, codes.OK},
+		{"own rider", &Principal{UserID: "rider-1", Role: RoleRider}, codes.OK},
+		{"admin", &Principal{UserID: "admin-1", Role: RoleAdmin}, codes.OK},
+		{"another rider", &Principal{UserID: "rider-2", Role: RoleRider}, codes.PermissionDenied},
+		{"driver", &Principal{UserID: "driver-1", Role: RoleDriver}, codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = context.WithValue(ctx, principalKey{}, *tt.principal)
+			}
+			if err := checkPrincipal(ctx, req); status.Code(err) != tt.wantCode {
+				t.Errorf("checkPrincipal() error = %v, want %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServiceTokenFromEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		token       string
+		want        string
+		wantErr     error
+	}{
+		{"development without token", "development", "", "", nil},
+		{"production", "production", " secret ", "secret", nil},
+		{"production without token", "production", " ", "", ErrServiceTokenRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT", tt.environment)
+			t.Setenv("GRPC_SERVICE_TOKEN", tt.token)
+
+			got, err := ServiceTokenFromEnv()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ServiceTokenFromEnv() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ServiceTokenFromEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
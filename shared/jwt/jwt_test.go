@@ -0,0 +1,49 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestAudienceUnmarshal(t *testing.T) {
+	for _, raw := range []string{`"api-gateway"`, `["web","api-gateway"]`} {
+		var a Audience
+		if err := a.UnmarshalJSON([]byte(raw)); err != nil {
+			t.Fatalf("UnmarshalJSON(%s) error = %v", raw, err)
+		}
+		if a[len(a)-1] != "api-gateway" {
+			t.Errorf("UnmarshalJSON(%s) = %v", raw, a)
+		}
+	}
+}
+
+func TestSignHS256(t *testing.T) {
+	claims := Claims{Subject: "driver-1", Role: RoleDriver, ExpiresAt: 1_700_003_600}
+	token, err := SignHS256(claims, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token %q has %d segments, want 3", token, len(parts))
+	}
+
+	var header Header
+	if err := DecodeSegment(parts[0], &header); err != nil || header.Alg != AlgHS256 {
+		t.Errorf("header = %+v, %v, want alg %s", header, err, AlgHS256)
+	}
+	var decoded Claims
+	if err := DecodeSegment(parts[1], &decoded); err != nil || decoded.Subject != claims.Subject || decoded.Role != claims.Role || decoded.ExpiresAt != claims.ExpiresAt {
+		t.Errorf("claims = %+v, %v, want %+v", decoded, err, claims)
+	}
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if want := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)); parts[2] != want {
+		t.Errorf("signature = %s, want %s", parts[2], want)
+	}
+}
@@ -22,6 +22,7 @@ type connWrapper struct {
 type ConnectionManager struct {
 	connections map[string]*connWrapper
 	mutex       sync.RWMutex
+	upgrader    websocket.Upgrader
 }
 
 // TODO: REFACTOR - Extract WebSocketUpgrader into separate component (SRP violation)
@@ -33,25 +34,17 @@ type ConnectionManager struct {
 // - Makes it impossible to customize upgrade behavior (CORS, compression, buffer sizes)
 // - Mixing infrastructure concerns (HTTP) with business logic (connection tracking)
 // ACTION: Extract WebSocketUpgrader into separate component, inject it into handlers
-var upgrader = websocket.Upgrader{
-	// TODO: REFACTOR - Remove hardcoded CheckOrigin security bypass (OCP + Security violation)
-	// WHY: Hardcoding return true:
-	//   - SECURITY RISK: Allows any origin to establish WebSocket connections (CORS bypass)
-	//   - Not extensible - cannot change validation per environment (dev vs production)
-	//   - Cannot implement whitelist, JWT, or other validation strategies
-	//   - Violates Open/Closed Principle (closed for extension)
-	// ACTION: Extract into OriginValidator interface, support multiple strategies:
-	//   - WhitelistOriginValidator (list of allowed origins)
-	//   - AllowAllOriginValidator (dev/test only)
-	//   - JWTOriginValidator (validate with JWT token)
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+func newUpgrader(originValidator OriginValidator) websocket.Upgrader {
+	return websocket.Upgrader{
+		CheckOrigin: CheckOrigin(originValidator),
+	}
 }
 
-func NewConnectionManager() *ConnectionManager {
+// NewConnectionManager creates a connection manager whose upgrades only accept the origins allowed by originValidator
+func NewConnectionManager(originValidator OriginValidator) *ConnectionManager {
 	return &ConnectionManager{
 		connections: make(map[string]*connWrapper),
+		upgrader:    newUpgrader(originValidator),
 	}
 }
 
@@ -63,7 +56,7 @@ func NewConnectionManager() *ConnectionManager {
 //   - Cannot reuse ConnectionManager without HTTP dependencies
 // ACTION: Extract into WebSocketUpgrader struct with its own constructor and methods
 func (cm *ConnectionManager) Upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := cm.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return nil, err
 	}
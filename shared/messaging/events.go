@@ -1,8 +1,12 @@
 package messaging
 
 import (
+	"errors"
+	"ride-sharing/shared/contracts"
 	pbd "ride-sharing/shared/proto/driver"
 	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/types"
+	"time"
 )
 
 const (
@@ -11,14 +15,170 @@ const (
 	DriverCmdTripResponseQueue      = "driver_cmd_trip_response"
 	NotifyDriverNoDriversFoundQueue = "notify_driver_no_drivers_found"
 	NotifyDriverAssignedQueue       = "notify_driver_assigned_queue"
+	DriverLocationQueue             = "driver_location_updates"
+	TripDriverLocationQueue         = "trip_driver_location"
+	NotifyDriverLocationQueue       = "notify_driver_location"
+	NotifyTripStatusQueue           = "notify_trip_status"
+	DriverAvailabilityQueue         = "driver_availability"
+	RiderCmdTripQueue               = "rider_cmd_trip"
+	NotifyRiderCommandResultQueue   = "notify_rider_command_result"
+	ChatCmdQueue                    = "chat_cmd"
+	NotifyChatQueue                 = "notify_chat"
 )
 
+// GatewayQueue returns the exclusive queue of an API Gateway instance
+func GatewayQueue(gatewayID string) string {
+	return "gateway_" + gatewayID
+}
+
+// GatewayDeliverRoutingKey returns the routing key of messages handed to one API Gateway instance
+func GatewayDeliverRoutingKey(gatewayID string) string {
+	return contracts.GatewayCmdDeliver + "." + gatewayID
+}
+
+// GatewayPresenceEvent tells the other API Gateway instances which users are connected
+// to a gateway. A snapshot replaces everything previously known about the gateway.
+type GatewayPresenceEvent struct {
+	GatewayID string   `json:"gatewayID"`
+	Online    []string `json:"online,omitempty"`
+	Offline   []string `json:"offline,omitempty"`
+	Snapshot  bool     `json:"snapshot,omitempty"`
+}
+
+// GatewayDelivery is a message forwarded to the API Gateway instance its owner is connected to
+type GatewayDelivery struct {
+	RoutingKey string                `json:"routingKey"`
+	Message    contracts.AmqpMessage `json:"message"`
+	// ID and Timestamp are those of the message's WebSocket envelope, every instance
+	// sends the message with the same ones so clients can drop duplicates
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	// Seq is set on stored messages handed over to another instance, which keeps their number
+	Seq uint64 `json:"seq,omitempty"`
+}
+
 type TripCreatedEvent struct {
 	Trip *pb.Trip `json:"trip"`
 }
 
+type DriverLocationData struct {
+	DriverID string        `json:"driverID"`
+	Location *pbd.Location `json:"location"`
+	Heading  float64       `json:"heading"`
+}
+
+// TripDriverLocationEvent is sent to a rider while their trip is active. Pickup
+// distance and ETA are only set until the rider is picked up, and left out when
+// they can't be estimated.
+type TripDriverLocationEvent struct {
+	TripID                 string        `json:"tripID"`
+	DriverID               string        `json:"driverID"`
+	Location               *pbd.Location `json:"location"`
+	Heading                float64       `json:"heading"`
+	DistanceToPickupMeters *float64      `json:"distanceToPickupMeters,omitempty"`
+	PickupETASeconds       *float64      `json:"pickupEtaSeconds,omitempty"`
+}
+
+// TripOfferedData lists every driver a trip was offered to in one dispatch round
+type TripOfferedData struct {
+	TripID    string   `json:"tripID"`
+	RiderID   string   `json:"riderID"`
+	DriverIDs []string `json:"driverIDs"`
+}
+
+// TripOfferClosedData tells a driver their offer is no longer open, either
+// because another driver accepted first or because their accept came too late
+type TripOfferClosedData struct {
+	TripID   string `json:"tripID"`
+	DriverID string `json:"driverID"`
+	Reason   string `json:"reason"`
+}
+
+const (
+	OfferClosedAcceptedByAnother = "accepted_by_another_driver"
+	OfferClosedAlreadyTaken      = "already_taken"
+	OfferClosedExpired           = "offer_expired"
+	OfferClosedNotOffered        = "not_offered"
+	OfferClosedCancelledByRider  = "cancelled_by_rider"
+)
+
+// RiderCommandData is a rider's command about their trip, relayed by the API Gateway.
+// The rider is the OwnerID of the message. Location is the new pickup point or the
+// added stop.
+type RiderCommandData struct {
+	CommandID string            `json:"commandID"`
+	TripID    string            `json:"tripID"`
+	Reason    string            `json:"reason,omitempty"`
+	Location  *types.Coordinate `json:"location,omitempty"`
+}
+
+// TripRouteUpdatedEvent tells the rider and the assigned driver where the trip now
+// picks up and stops. Pickup is only set once the rider moved it.
+type TripRouteUpdatedEvent struct {
+	TripID string              `json:"tripID"`
+	Pickup *types.Coordinate   `json:"pickup,omitempty"`
+	Stops  []*types.Coordinate `json:"stops"`
+}
+
+// ChatCommandData is a chat command of a trip's rider or assigned driver, relayed by
+// the API Gateway. The sender is the OwnerID of the message. Text is set on messages,
+// MessageID and Status on receipts.
+type ChatCommandData struct {
+	CommandID string `json:"commandID"`
+	TripID    string `json:"tripID"`
+	Text      string `json:"text,omitempty"`
+	MessageID string `json:"messageID,omitempty"`
+	Status    string `json:"status,omitempty"`
+}
+
+// ChatMessageData is a chat message, sent to its recipient and its sender. DeliveredAt
+// and ReadAt are only set once the recipient reported them.
+type ChatMessageData struct {
+	ID          string     `json:"id"`
+	TripID      string     `json:"tripID"`
+	SenderID    string     `json:"senderID"`
+	SenderRole  string     `json:"senderRole"`
+	Text        string     `json:"text"`
+	SentAt      time.Time  `json:"sentAt"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+	ReadAt      *time.Time `json:"readAt,omitempty"`
+}
+
+// ChatReceiptData tells a sender that their messages up to and including MessageID
+// were delivered to or read by the recipient
+type ChatReceiptData struct {
+	TripID    string    `json:"tripID"`
+	MessageID string    `json:"messageID"`
+	Status    string    `json:"status"`
+	At        time.Time `json:"at"`
+}
+
+// ChatClosedData tells the participants of a chat that the trip ended, no more
+// messages can be sent. TripStatus is the trip's final status.
+type ChatClosedData struct {
+	TripID     string `json:"tripID"`
+	TripStatus string `json:"tripStatus"`
+}
+
+// DriveTripResponseData is a driver's command for a trip. The gateway replaces Driver
+// by the authenticated driver. RiderID is informational, the rider is taken from the trip.
 type DriveTripResponseData struct {
 	Driver  *pbd.Driver `json:"driver"`
 	TripID  string      `json:"tripID"`
-	RiderID string      `json:"riderID"`
+	RiderID string      `json:"riderID,omitempty"`
+}
+
+func (d *DriveTripResponseData) Validate() error {
+	if d.TripID == "" {
+		return errors.New("tripID is required")
+	}
+	return nil
+}
+
+// PaymentEventSessionCreatedData tells a rider to pay for their trip in the checkout session
+type PaymentEventSessionCreatedData struct {
+	TripID    string  `json:"tripID"`
+	SessionID string  `json:"sessionID"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
 }
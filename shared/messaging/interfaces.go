@@ -2,18 +2,30 @@ package messaging
 
 import (
 	"context"
+	"errors"
 	"ride-sharing/shared/contracts"
 
 	"github.com/rabbitmq/amqp091-go"
 )
 
-// MessageHandler processes incoming messages
+// MessageHandler processes incoming messages. Deliveries are acknowledged when the
+// handler returns nil and requeued when it returns an error.
 type MessageHandler func(ctx context.Context, msg amqp091.Delivery) error
 
+// ErrAckDeferred is returned by handlers that keep a delivery and acknowledge it
+// themselves, with Ack or Nack, once they are done with it
+var ErrAckDeferred = errors.New("acknowledgement deferred")
+
 // MessageBroker defines the contract for message publishing and subscription
 type MessageBroker interface {
 	Publish(ctx context.Context, routingKey string, msg contracts.AmqpMessage) error
 	Consume(ctx context.Context, queue string, handler MessageHandler) error
+	// ConsumeDeferred is Consume for handlers that defer acknowledgements. Up to
+	// prefetch deliveries may be held unacknowledged before the queue stops delivering.
+	ConsumeDeferred(ctx context.Context, queue string, prefetch int, handler MessageHandler) error
+	// DeclareExclusiveQueue declares a queue owned by this connection and bound to the
+	// routing keys. It is deleted when the connection closes.
+	DeclareExclusiveQueue(queue string, routingKeys []string) error
 	HealthCheck(ctx context.Context) error
 	Close() error
 }
@@ -0,0 +1,197 @@
+package messaging
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"ride-sharing/shared/env"
+	"strings"
+)
+
+var (
+	// ErrInvalidOrigin is returned for malformed allowlist entries
+	ErrInvalidOrigin = errors.New("invalid origin")
+	// ErrOriginsRequired is returned in production without WS_ALLOWED_ORIGINS, the web
+	// app is served from another origin and couldn't connect at all
+	ErrOriginsRequired = errors.New("WS_ALLOWED_ORIGINS is required in production")
+)
+
+// originMetrics counts the WebSocket upgrades by the outcome of their origin check on /debug/vars
+var originMetrics = expvar.NewMap("ws_origin")
+
+// OriginValidator decides whether a browser on an origin may open a WebSocket connection.
+// Its CheckOrigin method fits the CheckOrigin field of websocket.Upgrader.
+type OriginValidator interface {
+	CheckOrigin(r *http.Request) bool
+}
+
+// OriginValidators is a composite validator allowing an origin if any of the validators does
+type OriginValidators []OriginValidator
+
+func (v OriginValidators) CheckOrigin(r *http.Request) bool {
+	for _, validator := range v {
+		if validator.CheckOrigin(r) {
+			return true
+		}
+	}
+	return false
+}
+
+type allowAllOrigins struct{}
+
+// AllowAllOrigins returns a validator accepting every origin, for development only
+func AllowAllOrigins() OriginValidator {
+	return allowAllOrigins{}
+}
+
+func (allowAllOrigins) CheckOrigin(r *http.Request) bool {
+	return true
+}
+
+type sameOrigin struct{}
+
+// SameOrigin returns a validator accepting pages served by the host they connect to
+func SameOrigin() OriginValidator {
+	return sameOrigin{}
+}
+
+func (sameOrigin) CheckOrigin(r *http.Request) bool {
+	origin, err := url.Parse(r.Header.Get("Origin"))
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(origin.Host, r.Host)
+}
+
+// allowedOrigin is an allowlist entry. A host starting with "*." matches its subdomains.
+type allowedOrigin struct {
+	scheme string
+	host   string
+	port   string
+}
+
+type allowlistOrigins struct {
+	origins []allowedOrigin
+}
+
+// NewAllowlistOriginValidator returns a validator accepting the listed origins, e.g.
+// "https://app.example.com" or "https://*.example.com" for all subdomains of example.com.
+// Default ports may be omitted.
+func NewAllowlistOriginValidator(origins ...string) (OriginValidator, error) {
+	validator := &allowlistOrigins{}
+	for _, origin := range origins {
+		allowed, err := parseOrigin(origin)
+		if err != nil {
+			return nil, err
+		}
+		validator.origins = append(validator.origins, allowed)
+	}
+	return validator, nil
+}
+
+func (v *allowlistOrigins) CheckOrigin(r *http.Request) bool {
+	origin, err := parseOrigin(r.Header.Get("Origin"))
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range v.origins {
+		if allowed.scheme != origin.scheme || allowed.port != origin.port {
+			continue
+		}
+
+		if suffix, ok := strings.CutPrefix(allowed.host, "*"); ok {
+			if strings.HasSuffix(origin.host, suffix) {
+				return true
+			}
+		} else if allowed.host == origin.host {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOrigin normalizes an origin, filling in the default port of its scheme
+func parseOrigin(origin string) (allowedOrigin, error) {
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Hostname() == "" || (parsed.Path != "" && parsed.Path != "/") {
+		return allowedOrigin{}, fmt.Errorf("%w: %q", ErrInvalidOrigin, origin)
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	port := parsed.Port()
+	if port == "" {
+		switch scheme {
+		case "http", "ws":
+			port = "80"
+		case "https", "wss":
+			port = "443"
+		default:
+			return allowedOrigin{}, fmt.Errorf("%w: unsupported scheme in %q", ErrInvalidOrigin, origin)
+		}
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+		return allowedOrigin{}, fmt.Errorf("%w: wildcards are only allowed as the first label in %q", ErrInvalidOrigin, origin)
+	}
+
+	return allowedOrigin{scheme: scheme, host: host, port: port}, nil
+}
+
+// ParseOriginValidator builds a validator from a comma-separated list of origins. A "*"
+// entry allows all origins; otherwise the page's own origin and the listed ones are allowed.
+func ParseOriginValidator(origins string) (OriginValidator, error) {
+	var allowlist []string
+	for _, origin := range strings.Split(origins, ",") {
+		origin = strings.TrimSpace(origin)
+		switch origin {
+		case "":
+		case "*":
+			return AllowAllOrigins(), nil
+		default:
+			allowlist = append(allowlist, origin)
+		}
+	}
+
+	allowed, err := NewAllowlistOriginValidator(allowlist...)
+	if err != nil {
+		return nil, err
+	}
+	return OriginValidators{SameOrigin(), allowed}, nil
+}
+
+// OriginValidatorFromEnv builds the validator of WS_ALLOWED_ORIGINS. Without it, all
+// origins are allowed in development, it fails in production and only same-origin
+// pages are allowed in other environments.
+func OriginValidatorFromEnv() (OriginValidator, error) {
+	fallback := ""
+	switch env.GetString("ENVIRONMENT", "") {
+	case "development":
+		fallback = "*"
+	case "production":
+		if strings.TrimSpace(env.GetString("WS_ALLOWED_ORIGINS", "")) == "" {
+			return nil, ErrOriginsRequired
+		}
+	}
+	return ParseOriginValidator(env.GetString("WS_ALLOWED_ORIGINS", fallback))
+}
+
+// CheckOrigin wraps a validator into a websocket.Upgrader CheckOrigin function which logs
+// and counts rejected upgrades. Requests without an Origin header are allowed: only browsers
+// send it, and only browsers can be tricked by a foreign page into connecting.
+func CheckOrigin(validator OriginValidator) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		if r.Header.Get("Origin") == "" || validator.CheckOrigin(r) {
+			originMetrics.Add("allowed", 1)
+			return true
+		}
+
+		originMetrics.Add("rejected", 1)
+		log.Printf("Rejected WebSocket upgrade to %s from origin %q (%s)", r.URL.Path, r.Header.Get("Origin"), r.RemoteAddr)
+		return false
+	}
+}
@@ -0,0 +1,161 @@
+package messaging
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestAllowlistOriginValidator(t *testing.T) {
+	validator, err := NewAllowlistOriginValidator(
+		"https://app.example.com",
+		"https://*.example.org",
+		"http://localhost:3000",
+	)
+	if err != nil {
+		t.Fatalf("NewAllowlistOriginValidator() error = %v", err)
+	}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"https://app.example.com:443", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://other.example.com", false},
+		{"https://app.example.com.evil.com", false},
+		// Wildcards match subdomains at any depth, not the domain itself
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evilexample.org", false},
+		{"http://a.example.org", false},
+		{"http://localhost:3000", true},
+		{"http://localhost", false},
+		{"null", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://gateway.example.com/ws/riders", nil)
+		r.Header.Set("Origin", tt.origin)
+		if got := validator.CheckOrigin(r); got != tt.want {
+			t.Errorf("CheckOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestNewAllowlistOriginValidatorRejectsInvalidOrigins(t *testing.T) {
+	for _, origin := range []string{
+		"app.example.com",
+		"ftp://app.example.com",
+		"https://app.example.com/path",
+		"https://app.*.example.com",
+		"https://*.*.example.com",
+		"https://",
+	} {
+		if _, err := NewAllowlistOriginValidator(origin); !errors.Is(err, ErrInvalidOrigin) {
+			t.Errorf("NewAllowlistOriginValidator(%q) error = %v, want ErrInvalidOrigin", origin, err)
+		}
+	}
+}
+
+func TestParseOriginValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins string
+		host    string
+		origin  string
+		want    bool
+	}{
+		{"wildcard allows all", "*", "gateway.example.com", "https://anything.test", true},
+		{"wildcard among others", "https://app.example.com, *", "gateway.example.com", "https://anything.test", true},
+		{"listed origin", "https://app.example.com, https://admin.example.com", "gateway.example.com", "https://admin.example.com", true},
+		{"unlisted origin", "https://app.example.com", "gateway.example.com", "https://anything.test", false},
+		{"same origin without a list", "", "gateway.example.com", "https://gateway.example.com", true},
+		{"foreign origin without a list", "", "gateway.example.com", "https://anything.test", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator, err := ParseOriginValidator(tt.origins)
+			if err != nil {
+				t.Fatalf("ParseOriginValidator(%q) error = %v", tt.origins, err)
+			}
+
+			r := httptest.NewRequest("GET", "http://"+tt.host+"/ws/riders", nil)
+			r.Header.Set("Origin", tt.origin)
+			if got := validator.CheckOrigin(r); got != tt.want {
+				t.Errorf("CheckOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseOriginValidator("https://app.example.com, not-an-origin"); !errors.Is(err, ErrInvalidOrigin) {
+		t.Errorf("ParseOriginValidator() with an invalid entry error = %v, want ErrInvalidOrigin", err)
+	}
+}
+
+func TestOriginValidatorFromEnv(t *testing.T) {
+	tests := []struct {
+		environment string
+		origins     *string
+		origin      string
+		want        bool
+		wantErr     error
+	}{
+		{"development", nil, "https://anything.test", true, nil},
+		{"staging", nil, "https://anything.test", false, nil},
+		{"production", nil, "", false, ErrOriginsRequired},
+		{"production", ptr(" "), "", false, ErrOriginsRequired},
+		{"production", ptr("https://app.example.com"), "https://app.example.com", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("ENVIRONMENT", tt.environment)
+		// Setenv restores the variable after the test, even when it is unset
+		t.Setenv("WS_ALLOWED_ORIGINS", "")
+		if tt.origins != nil {
+			os.Setenv("WS_ALLOWED_ORIGINS", *tt.origins)
+		} else {
+			os.Unsetenv("WS_ALLOWED_ORIGINS")
+		}
+
+		validator, err := OriginValidatorFromEnv()
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("OriginValidatorFromEnv() in %s error = %v, want %v", tt.environment, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		r := httptest.NewRequest("GET", "http://gateway.example.com/ws/riders", nil)
+		r.Header.Set("Origin", tt.origin)
+		if got := validator.CheckOrigin(r); got != tt.want {
+			t.Errorf("CheckOrigin(%q) in %s = %v, want %v", tt.origin, tt.environment, got, tt.want)
+		}
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestCheckOriginAllowsRequestsWithoutOrigin(t *testing.T) {
+	check := CheckOrigin(OriginValidators{})
+
+	r := httptest.NewRequest("GET", "http://gateway.example.com/ws/riders", nil)
+	if !check(r) {
+		t.Error("request without an Origin header was rejected")
+	}
+
+	r.Header.Set("Origin", "https://anything.test")
+	if check(r) {
+		t.Error("request from a foreign origin was allowed by an empty validator")
+	}
+}
@@ -3,9 +3,11 @@ package messaging
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"ride-sharing/shared/contracts"
+	"sync"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
@@ -17,6 +19,9 @@ const (
 type rabbitmqBroker struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
+	// consumeMu keeps the prefetch of a consumer from applying to another one
+	// registered concurrently on the channel
+	consumeMu sync.Mutex
 }
 
 func NewRabbitMQ(uri string) (*rabbitmqBroker, error) {
@@ -71,7 +76,10 @@ func (r *rabbitmqBroker) setupExchangeAndQueues() error {
 	if err := r.declareAndBindQueue(
 		DriverCmdTripRequestQueue,
 		[]string{
-			contracts.DriverCmdTripRequest, // Driver found and assigned to trip
+			contracts.DriverCmdTripRequest,      // Driver found and assigned to trip
+			contracts.DriverCmdTripOfferRevoked, // Another driver accepted the trip first
+			contracts.DriverCmdTripAlreadyTaken, // Driver accepted a trip that was already taken
+			contracts.DriverCmdTripCancelled,    // Rider cancelled the driver's trip
 		},
 		TripExchange); err != nil {
 		return err
@@ -81,8 +89,13 @@ func (r *rabbitmqBroker) setupExchangeAndQueues() error {
 	if err := r.declareAndBindQueue(
 		DriverCmdTripResponseQueue,
 		[]string{
-			contracts.DriverCmdTripAccept,  // Driver accepted trip
-			contracts.DriverCmdTripDecline, // Driver declined trip
+			contracts.DriverCmdTripAccept,   // Driver accepted trip
+			contracts.DriverCmdTripDecline,  // Driver declined trip
+			contracts.DriverCmdTripStart,    // Driver picked up the rider
+			contracts.DriverCmdTripComplete, // Driver dropped off the rider
+			// Published before the trip requests so trip-service knows who was
+			// offered a trip before any of them can answer
+			contracts.DriverEventTripOffered,
 		},
 		TripExchange); err != nil {
 		return err
@@ -98,6 +111,112 @@ func (r *rabbitmqBroker) setupExchangeAndQueues() error {
 		return err
 	}
 
+	// Queue for driver-service to keep driver positions up to date
+	if err := r.declareAndBindQueue(
+		DriverLocationQueue,
+		[]string{
+			contracts.DriverCmdLocation,
+		},
+		TripExchange); err != nil {
+		return err
+	}
+
+	// Queue for driver-service to move drivers between availability states
+	if err := r.declareAndBindQueue(
+		DriverAvailabilityQueue,
+		[]string{
+			contracts.DriverCmdTripDecline,
+			contracts.DriverCmdTripOfferRevoked,
+			contracts.DriverCmdTripAlreadyTaken,
+			contracts.TripEventDriverAssigned,
+			contracts.TripEventStarted,
+			contracts.TripEventCompleted,
+			contracts.TripEventCancelled,
+		},
+		TripExchange); err != nil {
+		return err
+	}
+
+	// Queue for API Gateway to notify riders of trip progress
+	if err := r.declareAndBindQueue(
+		NotifyTripStatusQueue,
+		[]string{
+			contracts.TripEventStarted,
+			contracts.TripEventCompleted,
+			contracts.TripEventCancelled,
+			contracts.TripEventRouteUpdated,
+		},
+		TripExchange); err != nil {
+		return err
+	}
+
+	// Queue for trip-service to apply the commands riders send about their trips
+	if err := r.declareAndBindQueue(
+		RiderCmdTripQueue,
+		[]string{
+			contracts.RiderCmdCancelTrip,
+			contracts.RiderCmdUpdatePickup,
+			contracts.RiderCmdAddStop,
+		},
+		TripExchange); err != nil {
+		return err
+	}
+
+	// Queue for API Gateway to answer rider commands
+	if err := r.declareAndBindQueue(
+		NotifyRiderCommandResultQueue,
+		[]string{
+			contracts.RiderEventCommandAck,
+			contracts.RiderEventCommandError,
+		},
+		TripExchange); err != nil {
+		return err
+	}
+
+	// Queue for trip-service to store and relay the chat messages of trips
+	if err := r.declareAndBindQueue(
+		ChatCmdQueue,
+		[]string{
+			contracts.ChatCmdSend,
+			contracts.ChatCmdReceipt,
+		},
+		TripExchange); err != nil {
+		return err
+	}
+
+	// Queue for API Gateway to deliver chat messages, receipts and refusals
+	if err := r.declareAndBindQueue(
+		NotifyChatQueue,
+		[]string{
+			contracts.ChatEventMessage,
+			contracts.ChatEventReceipt,
+			contracts.ChatEventError,
+			contracts.ChatEventClosed,
+		},
+		TripExchange); err != nil {
+		return err
+	}
+
+	// Queue for trip-service to relate driver positions to their active trips
+	if err := r.declareAndBindQueue(
+		TripDriverLocationQueue,
+		[]string{
+			contracts.DriverEventLocationUpdated,
+		},
+		TripExchange); err != nil {
+		return err
+	}
+
+	// Queue for API Gateway to stream the assigned driver's position to riders
+	if err := r.declareAndBindQueue(
+		NotifyDriverLocationQueue,
+		[]string{
+			contracts.TripEventDriverLocation,
+		},
+		TripExchange); err != nil {
+		return err
+	}
+
 	if err := r.declareAndBindQueue(
 		NotifyDriverAssignedQueue,
 		[]string{
@@ -137,6 +256,28 @@ func (r *rabbitmqBroker) declareAndBindQueue(queueName string, messageTypes []st
 	return nil
 }
 
+func (r *rabbitmqBroker) DeclareExclusiveQueue(queue string, routingKeys []string) error {
+	q, err := r.channel.QueueDeclare(
+		queue, // name
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare exclusive queue %s: %w", queue, err)
+	}
+
+	for _, routingKey := range routingKeys {
+		if err := r.channel.QueueBind(q.Name, routingKey, TripExchange, false, nil); err != nil {
+			return fmt.Errorf("failed to bind queue: %s: %v", queue, err)
+		}
+	}
+
+	return nil
+}
+
 func (r *rabbitmqBroker) Publish(ctx context.Context, routingKey string, message contracts.AmqpMessage) error {
 
 	jsonMsg, err := json.Marshal(message)
@@ -187,22 +328,13 @@ func (r *rabbitmqBroker) Close() error {
 }
 
 func (r *rabbitmqBroker) Consume(ctx context.Context, queue string, handler MessageHandler) error {
-	err := r.channel.Qos(1, 0, false)
-	if err != nil {
-		return fmt.Errorf("failed to set Qos: %w", err)
-	}
-
-	deliveries, err := r.channel.Consume(
-		queue,
-		"",    // consumer tag
-		false, // auto-ack disabled for manual acknowledgment
-		false, // exclusive
-		false, // no-local
-		false, // no-wait
-		nil)
+	return r.ConsumeDeferred(ctx, queue, 1, handler)
+}
 
+func (r *rabbitmqBroker) ConsumeDeferred(ctx context.Context, queue string, prefetch int, handler MessageHandler) error {
+	deliveries, err := r.registerConsumer(queue, prefetch)
 	if err != nil {
-		return fmt.Errorf("failed to register consumer on queue %s: %w", queue, err)
+		return err
 	}
 
 	go func() {
@@ -218,7 +350,12 @@ func (r *rabbitmqBroker) Consume(ctx context.Context, queue string, handler Mess
 				}
 
 				// Call handler
-				if err := handler(ctx, delivery); err != nil {
+				err := handler(ctx, delivery)
+				if errors.Is(err, ErrAckDeferred) {
+					// The handler acknowledges the delivery itself
+					continue
+				}
+				if err != nil {
 					log.Printf("failed to handle message %v", err)
 					delivery.Nack(false, true) // Requeue on handler error
 					continue
@@ -232,3 +369,29 @@ func (r *rabbitmqBroker) Consume(ctx context.Context, queue string, handler Mess
 
 	return nil
 }
+
+// registerConsumer starts consuming a queue with at most prefetch unacknowledged deliveries
+func (r *rabbitmqBroker) registerConsumer(queue string, prefetch int) (<-chan amqp.Delivery, error) {
+	r.consumeMu.Lock()
+	defer r.consumeMu.Unlock()
+
+	// The prefetch applies to the consumers registered after it on the channel
+	err := r.channel.Qos(prefetch, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set Qos: %w", err)
+	}
+
+	deliveries, err := r.channel.Consume(
+		queue,
+		"",    // consumer tag
+		false, // auto-ack disabled for manual acknowledgment
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to register consumer on queue %s: %w", queue, err)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,226 @@
+package messaging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"ride-sharing/shared/contracts"
+	pbd "ride-sharing/shared/proto/driver"
+	pb "ride-sharing/shared/proto/trip"
+)
+
+// WSDirection tells who sends a WebSocket message type
+type WSDirection int
+
+const (
+	WSServerToClient WSDirection = 1 << iota
+	WSClientToServer
+)
+
+// WSMessageSpec describes a WebSocket message type
+type WSMessageSpec struct {
+	Type      string
+	Direction WSDirection
+	// Payload is a zero value of the type of the message's data, nil for messages without data
+	Payload any
+	// Doc is a short description, copied into the generated TypeScript contracts
+	Doc string
+}
+
+// wsMessages is the registry of all WebSocket messages. Inbound messages are validated
+// against it and the TypeScript contracts of the web client are generated from it, run
+// `make generate-ws-contracts` after changing it.
+var wsMessages = []WSMessageSpec{
+	// Riders and drivers
+	{Type: contracts.ClientCmdAck, Direction: WSClientToServer, Payload: contracts.WSAckData{},
+		Doc: "Acknowledges all messages up to and including seq"},
+	{Type: contracts.ClientCmdHeartbeat, Direction: WSClientToServer | WSServerToClient,
+		Doc: "Echoed back by the server, lets clients check the connection is alive"},
+	{Type: contracts.ClientEventError, Direction: WSServerToClient, Payload: contracts.WSErrorData{},
+		Doc: "A client message failed validation"},
+
+	// Drivers
+	{Type: contracts.DriverCmdLocation, Direction: WSClientToServer, Payload: contracts.WSDriverLocationData{}},
+	{Type: contracts.DriverCmdTripAccept, Direction: WSClientToServer, Payload: DriveTripResponseData{}},
+	{Type: contracts.DriverCmdTripDecline, Direction: WSClientToServer, Payload: DriveTripResponseData{}},
+	{Type: contracts.DriverCmdTripStart, Direction: WSClientToServer, Payload: DriveTripResponseData{},
+		Doc: "The driver picked up the rider"},
+	{Type: contracts.DriverCmdTripComplete, Direction: WSClientToServer, Payload: DriveTripResponseData{},
+		Doc: "The driver dropped off the rider"},
+	{Type: contracts.DriverCmdRegister, Direction: WSServerToClient, Payload: pbd.Driver{},
+		Doc: "The driver is registered, first message of every connection"},
+	{Type: contracts.DriverCmdTripRequest, Direction: WSServerToClient, Payload: TripCreatedEvent{}},
+	{Type: contracts.DriverCmdTripOfferRevoked, Direction: WSServerToClient, Payload: TripOfferClosedData{},
+		Doc: "Another driver accepted the trip first, or the rider cancelled it"},
+	{Type: contracts.DriverCmdTripAlreadyTaken, Direction: WSServerToClient, Payload: TripOfferClosedData{},
+		Doc: "The driver's accept arrived after another driver's"},
+	{Type: contracts.DriverCmdTripCancelled, Direction: WSServerToClient, Payload: TripOfferClosedData{},
+		Doc: "The rider cancelled the driver's trip"},
+
+	// Riders
+	{Type: contracts.RiderCmdCancelTrip, Direction: WSClientToServer, Payload: contracts.WSCancelTripData{}},
+	{Type: contracts.RiderCmdUpdatePickup, Direction: WSClientToServer, Payload: contracts.WSUpdatePickupData{}},
+	{Type: contracts.RiderCmdAddStop, Direction: WSClientToServer, Payload: contracts.WSAddStopData{}},
+	{Type: contracts.RiderEventCommandAck, Direction: WSServerToClient, Payload: contracts.WSCommandResultData{}},
+	{Type: contracts.RiderEventCommandError, Direction: WSServerToClient, Payload: contracts.WSCommandResultData{},
+		Doc: "trip-service refused a rider command"},
+	{Type: contracts.TripEventNoDriversFound, Direction: WSServerToClient},
+	{Type: contracts.TripEventDriverAssigned, Direction: WSServerToClient, Payload: pb.Trip{}},
+	{Type: contracts.TripEventDriverLocation, Direction: WSServerToClient, Payload: TripDriverLocationEvent{}},
+	{Type: contracts.TripEventStarted, Direction: WSServerToClient, Payload: TripCreatedEvent{}},
+	{Type: contracts.TripEventCompleted, Direction: WSServerToClient, Payload: TripCreatedEvent{}},
+	{Type: contracts.TripEventCancelled, Direction: WSServerToClient, Payload: TripCreatedEvent{}},
+	{Type: contracts.TripEventRouteUpdated, Direction: WSServerToClient, Payload: TripRouteUpdatedEvent{},
+		Doc: "Sent to the rider and the assigned driver"},
+
+	// Chat between a trip's rider and its assigned driver
+	{Type: contracts.ChatCmdSend, Direction: WSClientToServer, Payload: contracts.WSChatSendData{},
+		Doc: "Allowed while the trip is accepted or in progress"},
+	{Type: contracts.ChatCmdReceipt, Direction: WSClientToServer, Payload: contracts.WSChatReceiptData{},
+		Doc: "The recipient received or read the messages up to messageID"},
+	{Type: contracts.ChatEventMessage, Direction: WSServerToClient, Payload: ChatMessageData{},
+		Doc: "Sent to the recipient and the sender, whose correlationID is the id of their chat.cmd.send"},
+	{Type: contracts.ChatEventReceipt, Direction: WSServerToClient, Payload: ChatReceiptData{}},
+	{Type: contracts.ChatEventError, Direction: WSServerToClient, Payload: contracts.WSCommandResultData{},
+		Doc: "trip-service refused a chat command"},
+	{Type: contracts.ChatEventClosed, Direction: WSServerToClient, Payload: ChatClosedData{}},
+
+	{Type: contracts.PaymentEventSessionCreated, Direction: WSServerToClient, Payload: PaymentEventSessionCreatedData{}},
+}
+
+// WSMessageSpecs returns the registered WebSocket messages
+func WSMessageSpecs() []WSMessageSpec {
+	return wsMessages
+}
+
+// LookupWSMessage returns the spec of a message type
+func LookupWSMessage(messageType string) (WSMessageSpec, bool) {
+	for _, spec := range wsMessages {
+		if spec.Type == messageType {
+			return spec, true
+		}
+	}
+	return WSMessageSpec{}, false
+}
+
+// WSMessageError is returned for client messages failing validation. It carries what
+// the client is told in its client.event.error frame.
+type WSMessageError struct {
+	Code    string
+	Message string
+	// Type and ID are the type and ID of the rejected message, if they could be read
+	Type string
+	ID   string
+}
+
+func (e *WSMessageError) Error() string {
+	if e.Type == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+// ErrorMessage returns the client.event.error frame answering the rejected message
+func (e *WSMessageError) ErrorMessage() contracts.WSMessage {
+	message := contracts.NewWSMessage(contracts.ClientEventError, contracts.WSErrorData{
+		Code:    e.Code,
+		Message: e.Message,
+		Type:    e.Type,
+	})
+	message.CorrelationID = e.ID
+	return message
+}
+
+// DecodeWSMessage strictly parses a message sent by a client. The envelope and the
+// payload may not have unknown fields, the type must be one clients send and the
+// payload must pass its Validate method, if it has one. The payload is returned as a
+// pointer to the registered type, nil for messages without data. Errors are *WSMessageError.
+func DecodeWSMessage(frame []byte) (contracts.WSRawMessage, any, error) {
+	var msg contracts.WSRawMessage
+	if err := decodeStrict(frame, &msg); err != nil {
+		return msg, nil, &WSMessageError{Code: contracts.ErrCodeBadRequest, Message: fmt.Sprintf("malformed message: %v", err)}
+	}
+
+	if msg.Type == "" {
+		return msg, nil, &WSMessageError{Code: contracts.ErrCodeBadRequest, Message: "type is required", ID: msg.ID}
+	}
+
+	if msg.Version != 0 && msg.Version != contracts.WSProtocolVersion {
+		return msg, nil, &WSMessageError{
+			Code:    contracts.ErrCodeUnsupportedVersion,
+			Message: fmt.Sprintf("version %d is not supported, use %d", msg.Version, contracts.WSProtocolVersion),
+			Type:    msg.Type,
+			ID:      msg.ID,
+		}
+	}
+
+	spec, ok := LookupWSMessage(msg.Type)
+	if !ok || spec.Direction&WSClientToServer == 0 {
+		return msg, nil, &WSMessageError{Code: contracts.ErrCodeUnknownMessageType, Message: "unknown message type", Type: msg.Type, ID: msg.ID}
+	}
+
+	payload, err := decodePayload(spec, msg.Data)
+	if err != nil {
+		return msg, nil, &WSMessageError{Code: contracts.ErrCodeInvalidPayload, Message: err.Error(), Type: msg.Type, ID: msg.ID}
+	}
+
+	return msg, payload, nil
+}
+
+// DecodeWSPayload strictly parses the payload of a message type clients send, for
+// commands which arrive without an envelope, e.g. over HTTP. Errors are *WSMessageError.
+func DecodeWSPayload(messageType string, data []byte) (any, error) {
+	spec, ok := LookupWSMessage(messageType)
+	if !ok || spec.Direction&WSClientToServer == 0 {
+		return nil, &WSMessageError{Code: contracts.ErrCodeUnknownMessageType, Message: "unknown message type", Type: messageType}
+	}
+
+	payload, err := decodePayload(spec, data)
+	if err != nil {
+		return nil, &WSMessageError{Code: contracts.ErrCodeInvalidPayload, Message: err.Error(), Type: messageType}
+	}
+	return payload, nil
+}
+
+func decodePayload(spec WSMessageSpec, data json.RawMessage) (any, error) {
+	hasData := len(data) > 0 && !bytes.Equal(data, []byte("null"))
+
+	if spec.Payload == nil {
+		if hasData {
+			return nil, errors.New("the message has no data")
+		}
+		return nil, nil
+	}
+
+	if !hasData {
+		return nil, errors.New("data is required")
+	}
+
+	payload := reflect.New(reflect.TypeOf(spec.Payload)).Interface()
+	if err := decodeStrict(data, payload); err != nil {
+		return nil, err
+	}
+
+	if validator, ok := payload.(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return payload, nil
+}
+
+// decodeStrict unmarshals a single JSON value, rejecting unknown fields
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after the JSON value")
+	}
+	return nil
+}
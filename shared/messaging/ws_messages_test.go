@@ -0,0 +1,153 @@
+package messaging
+
+import (
+	"errors"
+	"ride-sharing/shared/contracts"
+	"testing"
+)
+
+func TestWSMessageRegistry(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, spec := range WSMessageSpecs() {
+		if seen[spec.Type] {
+			t.Errorf("%s is registered twice", spec.Type)
+		}
+		seen[spec.Type] = true
+
+		if spec.Direction&(WSClientToServer|WSServerToClient) == 0 {
+			t.Errorf("%s has no direction", spec.Type)
+		}
+		if found, ok := LookupWSMessage(spec.Type); !ok || found.Type != spec.Type {
+			t.Errorf("LookupWSMessage(%q) = %+v, %v", spec.Type, found, ok)
+		}
+	}
+
+	if _, ok := LookupWSMessage("rider.cmd.unknown"); ok {
+		t.Error("LookupWSMessage found an unregistered type")
+	}
+}
+
+func TestDecodeWSMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    string
+		wantCode string
+	}{
+		{"ack", `{"type":"client.cmd.ack","data":{"seq":5}}`, ""},
+		{"current version", `{"type":"client.cmd.ack","version":1,"id":"m1","data":{"seq":5}}`, ""},
+		{"heartbeat without data", `{"type":"client.cmd.heartbeat","id":"h1"}`, ""},
+		{"heartbeat with null data", `{"type":"client.cmd.heartbeat","data":null}`, ""},
+		{"driver location", `{"type":"driver.cmd.location","data":{"location":{"latitude":52.5,"longitude":13.4}}}`, ""},
+		{"trip accept", `{"type":"driver.cmd.trip_accept","data":{"tripID":"t1","driver":{"id":"d1"}}}`, ""},
+
+		{"malformed", `{"type":`, contracts.ErrCodeBadRequest},
+		{"trailing data", `{"type":"client.cmd.heartbeat"} {}`, contracts.ErrCodeBadRequest},
+		{"unknown envelope field", `{"type":"client.cmd.heartbeat","extra":1}`, contracts.ErrCodeBadRequest},
+		{"missing type", `{"data":{"seq":5}}`, contracts.ErrCodeBadRequest},
+		{"other version", `{"type":"client.cmd.ack","version":2,"data":{"seq":5}}`, contracts.ErrCodeUnsupportedVersion},
+		{"unknown type", `{"type":"rider.cmd.teleport"}`, contracts.ErrCodeUnknownMessageType},
+		{"server to client type", `{"type":"trip.event.started","data":{}}`, contracts.ErrCodeUnknownMessageType},
+		{"missing data", `{"type":"client.cmd.ack"}`, contracts.ErrCodeInvalidPayload},
+		{"data for a message without", `{"type":"client.cmd.heartbeat","data":{"seq":5}}`, contracts.ErrCodeInvalidPayload},
+		{"unknown payload field", `{"type":"client.cmd.ack","data":{"seq":5,"all":true}}`, contracts.ErrCodeInvalidPayload},
+		{"wrong payload type", `{"type":"client.cmd.ack","data":{"seq":"5"}}`, contracts.ErrCodeInvalidPayload},
+		{"failed validation", `{"type":"client.cmd.ack","data":{"seq":0}}`, contracts.ErrCodeInvalidPayload},
+		{"coordinate out of range", `{"type":"driver.cmd.location","data":{"location":{"latitude":91,"longitude":0}}}`, contracts.ErrCodeInvalidPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := DecodeWSMessage([]byte(tt.frame))
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("DecodeWSMessage() error = %v", err)
+				}
+				return
+			}
+
+			var msgErr *WSMessageError
+			if !errors.As(err, &msgErr) {
+				t.Fatalf("DecodeWSMessage() error = %v, want a *WSMessageError", err)
+			}
+			if msgErr.Code != tt.wantCode {
+				t.Errorf("Code = %s, want %s (%v)", msgErr.Code, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestDecodeWSMessageReturnsTypedPayload(t *testing.T) {
+	msg, payload, err := DecodeWSMessage([]byte(`{"type":"rider.cmd.cancel_trip","id":"c1","data":{"tripID":"t1","reason":"too slow"}}`))
+	if err != nil {
+		t.Fatalf("DecodeWSMessage() error = %v", err)
+	}
+	if msg.Type != contracts.RiderCmdCancelTrip || msg.ID != "c1" {
+		t.Errorf("envelope = %+v", msg)
+	}
+
+	cancel, ok := payload.(*contracts.WSCancelTripData)
+	if !ok {
+		t.Fatalf("payload is %T, want *contracts.WSCancelTripData", payload)
+	}
+	if cancel.TripID != "t1" || cancel.Reason != "too slow" {
+		t.Errorf("payload = %+v", cancel)
+	}
+
+	_, payload, err = DecodeWSMessage([]byte(`{"type":"client.cmd.heartbeat"}`))
+	if err != nil || payload != nil {
+		t.Errorf("DecodeWSMessage() of a message without data = %v, %v, want nil payload", payload, err)
+	}
+}
+
+func TestWSMessageErrorAnswersTheRejectedMessage(t *testing.T) {
+	_, _, err := DecodeWSMessage([]byte(`{"type":"client.cmd.ack","id":"m1","data":{"seq":0}}`))
+
+	var msgErr *WSMessageError
+	if !errors.As(err, &msgErr) {
+		t.Fatalf("DecodeWSMessage() error = %v, want a *WSMessageError", err)
+	}
+
+	message := msgErr.ErrorMessage()
+	if message.Type != contracts.ClientEventError {
+		t.Errorf("Type = %s, want %s", message.Type, contracts.ClientEventError)
+	}
+	if message.CorrelationID != "m1" {
+		t.Errorf("CorrelationID = %q, want m1", message.CorrelationID)
+	}
+	data, ok := message.Data.(contracts.WSErrorData)
+	if !ok || data.Code != contracts.ErrCodeInvalidPayload || data.Type != contracts.ClientCmdAck {
+		t.Errorf("Data = %+v", message.Data)
+	}
+}
+
+func TestDecodeWSPayload(t *testing.T) {
+	payload, err := DecodeWSPayload(contracts.RiderCmdAddStop, []byte(`{"tripID":"t1","stop":{"latitude":52.5,"longitude":13.4}}`))
+	if err != nil {
+		t.Fatalf("DecodeWSPayload() error = %v", err)
+	}
+	if stop, ok := payload.(*contracts.WSAddStopData); !ok || stop.TripID != "t1" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	tests := []struct {
+		name        string
+		messageType string
+		data        string
+		wantCode    string
+	}{
+		{"server to client type", contracts.TripEventStarted, `{}`, contracts.ErrCodeUnknownMessageType},
+		{"unknown type", "rider.cmd.teleport", `{}`, contracts.ErrCodeUnknownMessageType},
+		{"missing field", contracts.RiderCmdAddStop, `{"tripID":"t1"}`, contracts.ErrCodeInvalidPayload},
+		{"unknown field", contracts.RiderCmdCancelTrip, `{"tripID":"t1","refund":true}`, contracts.ErrCodeInvalidPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeWSPayload(tt.messageType, []byte(tt.data))
+			var msgErr *WSMessageError
+			if !errors.As(err, &msgErr) || msgErr.Code != tt.wantCode {
+				t.Errorf("DecodeWSPayload() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
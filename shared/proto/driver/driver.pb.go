@@ -21,29 +21,1049 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type DriverAvailability int32
+
+const (
+	DriverAvailability_DRIVER_AVAILABILITY_UNSPECIFIED        DriverAvailability = 0
+	DriverAvailability_DRIVER_AVAILABILITY_ONLINE             DriverAvailability = 1
+	DriverAvailability_DRIVER_AVAILABILITY_OFFERED            DriverAvailability = 2
+	DriverAvailability_DRIVER_AVAILABILITY_EN_ROUTE_TO_PICKUP DriverAvailability = 3
+	DriverAvailability_DRIVER_AVAILABILITY_ON_TRIP            DriverAvailability = 4
+	DriverAvailability_DRIVER_AVAILABILITY_OFFLINE            DriverAvailability = 5
+)
+
+// Enum value maps for DriverAvailability.
+var (
+	DriverAvailability_name = map[int32]string{
+		0: "DRIVER_AVAILABILITY_UNSPECIFIED",
+		1: "DRIVER_AVAILABILITY_ONLINE",
+		2: "DRIVER_AVAILABILITY_OFFERED",
+		3: "DRIVER_AVAILABILITY_EN_ROUTE_TO_PICKUP",
+		4: "DRIVER_AVAILABILITY_ON_TRIP",
+		5: "DRIVER_AVAILABILITY_OFFLINE",
+	}
+	DriverAvailability_value = map[string]int32{
+		"DRIVER_AVAILABILITY_UNSPECIFIED":        0,
+		"DRIVER_AVAILABILITY_ONLINE":             1,
+		"DRIVER_AVAILABILITY_OFFERED":            2,
+		"DRIVER_AVAILABILITY_EN_ROUTE_TO_PICKUP": 3,
+		"DRIVER_AVAILABILITY_ON_TRIP":            4,
+		"DRIVER_AVAILABILITY_OFFLINE":            5,
+	}
+)
+
+func (x DriverAvailability) Enum() *DriverAvailability {
+	p := new(DriverAvailability)
+	*p = x
+	return p
+}
+
+func (x DriverAvailability) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DriverAvailability) Descriptor() protoreflect.EnumDescriptor {
+	return file_driver_proto_enumTypes[0].Descriptor()
+}
+
+func (DriverAvailability) Type() protoreflect.EnumType {
+	return &file_driver_proto_enumTypes[0]
+}
+
+func (x DriverAvailability) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DriverAvailability.Descriptor instead.
+func (DriverAvailability) EnumDescriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{0}
+}
+
 type RegisterDriverRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	DriverID    string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	PackageSlug string                 `protobuf:"bytes,2,opt,name=packageSlug,proto3" json:"packageSlug,omitempty"`
+	// Optional, defaults to the driver's first vehicle eligible for the package
+	VehicleID     string `protobuf:"bytes,3,opt,name=vehicleID,proto3" json:"vehicleID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterDriverRequest) Reset() {
+	*x = RegisterDriverRequest{}
+	mi := &file_driver_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterDriverRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterDriverRequest) ProtoMessage() {}
+
+func (x *RegisterDriverRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterDriverRequest.ProtoReflect.Descriptor instead.
+func (*RegisterDriverRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RegisterDriverRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *RegisterDriverRequest) GetPackageSlug() string {
+	if x != nil {
+		return x.PackageSlug
+	}
+	return ""
+}
+
+func (x *RegisterDriverRequest) GetVehicleID() string {
+	if x != nil {
+		return x.VehicleID
+	}
+	return ""
+}
+
+type RegisterDriverResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Driver        *Driver                `protobuf:"bytes,1,opt,name=driver,proto3" json:"driver,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterDriverResponse) Reset() {
+	*x = RegisterDriverResponse{}
+	mi := &file_driver_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterDriverResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterDriverResponse) ProtoMessage() {}
+
+func (x *RegisterDriverResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterDriverResponse.ProtoReflect.Descriptor instead.
+func (*RegisterDriverResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RegisterDriverResponse) GetDriver() *Driver {
+	if x != nil {
+		return x.Driver
+	}
+	return nil
+}
+
+type GetDriverRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDriverRequest) Reset() {
+	*x = GetDriverRequest{}
+	mi := &file_driver_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDriverRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDriverRequest) ProtoMessage() {}
+
+func (x *GetDriverRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDriverRequest.ProtoReflect.Descriptor instead.
+func (*GetDriverRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetDriverRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+type GetDriverResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Driver        *Driver                `protobuf:"bytes,1,opt,name=driver,proto3" json:"driver,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDriverResponse) Reset() {
+	*x = GetDriverResponse{}
+	mi := &file_driver_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDriverResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDriverResponse) ProtoMessage() {}
+
+func (x *GetDriverResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDriverResponse.ProtoReflect.Descriptor instead.
+func (*GetDriverResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetDriverResponse) GetDriver() *Driver {
+	if x != nil {
+		return x.Driver
+	}
+	return nil
+}
+
+type GetDriverAvailabilityResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	DriverID        string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	Availability    DriverAvailability     `protobuf:"varint,2,opt,name=availability,proto3,enum=driver.DriverAvailability" json:"availability,omitempty"`
+	SinceUnixMillis int64                  `protobuf:"varint,3,opt,name=sinceUnixMillis,proto3" json:"sinceUnixMillis,omitempty"`
+	TripID          string                 `protobuf:"bytes,4,opt,name=tripID,proto3" json:"tripID,omitempty"`
+	Connected       bool                   `protobuf:"varint,5,opt,name=connected,proto3" json:"connected,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GetDriverAvailabilityResponse) Reset() {
+	*x = GetDriverAvailabilityResponse{}
+	mi := &file_driver_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDriverAvailabilityResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDriverAvailabilityResponse) ProtoMessage() {}
+
+func (x *GetDriverAvailabilityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDriverAvailabilityResponse.ProtoReflect.Descriptor instead.
+func (*GetDriverAvailabilityResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetDriverAvailabilityResponse) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *GetDriverAvailabilityResponse) GetAvailability() DriverAvailability {
+	if x != nil {
+		return x.Availability
+	}
+	return DriverAvailability_DRIVER_AVAILABILITY_UNSPECIFIED
+}
+
+func (x *GetDriverAvailabilityResponse) GetSinceUnixMillis() int64 {
+	if x != nil {
+		return x.SinceUnixMillis
+	}
+	return 0
+}
+
+func (x *GetDriverAvailabilityResponse) GetTripID() string {
+	if x != nil {
+		return x.TripID
+	}
+	return ""
+}
+
+func (x *GetDriverAvailabilityResponse) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+// UpdateDriverConnectionRequest marks a driver whose connection dropped as disconnected,
+// or as connected again once they reconnect. Disconnected drivers keep their state
+// but are not offered new trips.
+type UpdateDriverConnectionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	Connected     bool                   `protobuf:"varint,2,opt,name=connected,proto3" json:"connected,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateDriverConnectionRequest) Reset() {
+	*x = UpdateDriverConnectionRequest{}
+	mi := &file_driver_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateDriverConnectionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateDriverConnectionRequest) ProtoMessage() {}
+
+func (x *UpdateDriverConnectionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateDriverConnectionRequest.ProtoReflect.Descriptor instead.
+func (*UpdateDriverConnectionRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateDriverConnectionRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *UpdateDriverConnectionRequest) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+type UpdateDriverConnectionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	Connected     bool                   `protobuf:"varint,2,opt,name=connected,proto3" json:"connected,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateDriverConnectionResponse) Reset() {
+	*x = UpdateDriverConnectionResponse{}
+	mi := &file_driver_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateDriverConnectionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateDriverConnectionResponse) ProtoMessage() {}
+
+func (x *UpdateDriverConnectionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateDriverConnectionResponse.ProtoReflect.Descriptor instead.
+func (*UpdateDriverConnectionResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateDriverConnectionResponse) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *UpdateDriverConnectionResponse) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+type ReportLocationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	Location      *Location              `protobuf:"bytes,2,opt,name=location,proto3" json:"location,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportLocationRequest) Reset() {
+	*x = ReportLocationRequest{}
+	mi := &file_driver_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportLocationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportLocationRequest) ProtoMessage() {}
+
+func (x *ReportLocationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportLocationRequest.ProtoReflect.Descriptor instead.
+func (*ReportLocationRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ReportLocationRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *ReportLocationRequest) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+type ReportLocationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accepted      int64                  `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Rejected      int64                  `protobuf:"varint,2,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportLocationResponse) Reset() {
+	*x = ReportLocationResponse{}
+	mi := &file_driver_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportLocationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportLocationResponse) ProtoMessage() {}
+
+func (x *ReportLocationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportLocationResponse.ProtoReflect.Descriptor instead.
+func (*ReportLocationResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReportLocationResponse) GetAccepted() int64 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *ReportLocationResponse) GetRejected() int64 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+type BoundingBox struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SouthWest     *Location              `protobuf:"bytes,1,opt,name=southWest,proto3" json:"southWest,omitempty"`
+	NorthEast     *Location              `protobuf:"bytes,2,opt,name=northEast,proto3" json:"northEast,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BoundingBox) Reset() {
+	*x = BoundingBox{}
+	mi := &file_driver_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BoundingBox) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BoundingBox) ProtoMessage() {}
+
+func (x *BoundingBox) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BoundingBox.ProtoReflect.Descriptor instead.
+func (*BoundingBox) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BoundingBox) GetSouthWest() *Location {
+	if x != nil {
+		return x.SouthWest
+	}
+	return nil
+}
+
+func (x *BoundingBox) GetNorthEast() *Location {
+	if x != nil {
+		return x.NorthEast
+	}
+	return nil
+}
+
+// Empty filters match every driver
+type WatchDriversRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PackageSlug   string                 `protobuf:"bytes,1,opt,name=packageSlug,proto3" json:"packageSlug,omitempty"`
+	GeohashPrefix string                 `protobuf:"bytes,2,opt,name=geohashPrefix,proto3" json:"geohashPrefix,omitempty"`
+	BoundingBox   *BoundingBox           `protobuf:"bytes,3,opt,name=boundingBox,proto3" json:"boundingBox,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchDriversRequest) Reset() {
+	*x = WatchDriversRequest{}
+	mi := &file_driver_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchDriversRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchDriversRequest) ProtoMessage() {}
+
+func (x *WatchDriversRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchDriversRequest.ProtoReflect.Descriptor instead.
+func (*WatchDriversRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WatchDriversRequest) GetPackageSlug() string {
+	if x != nil {
+		return x.PackageSlug
+	}
+	return ""
+}
+
+func (x *WatchDriversRequest) GetGeohashPrefix() string {
+	if x != nil {
+		return x.GeohashPrefix
+	}
+	return ""
+}
+
+func (x *WatchDriversRequest) GetBoundingBox() *BoundingBox {
+	if x != nil {
+		return x.BoundingBox
+	}
+	return nil
+}
+
+type WatchDriversResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Driver        *Driver                `protobuf:"bytes,1,opt,name=driver,proto3" json:"driver,omitempty"`
+	Removed       bool                   `protobuf:"varint,2,opt,name=removed,proto3" json:"removed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchDriversResponse) Reset() {
+	*x = WatchDriversResponse{}
+	mi := &file_driver_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchDriversResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchDriversResponse) ProtoMessage() {}
+
+func (x *WatchDriversResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchDriversResponse.ProtoReflect.Descriptor instead.
+func (*WatchDriversResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchDriversResponse) GetDriver() *Driver {
+	if x != nil {
+		return x.Driver
+	}
+	return nil
+}
+
+func (x *WatchDriversResponse) GetRemoved() bool {
+	if x != nil {
+		return x.Removed
+	}
+	return false
+}
+
+type DriverProfile struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	ProfilePicture string                 `protobuf:"bytes,3,opt,name=profilePicture,proto3" json:"profilePicture,omitempty"`
+	Phone          string                 `protobuf:"bytes,4,opt,name=phone,proto3" json:"phone,omitempty"`
+	LicenseNumber  string                 `protobuf:"bytes,5,opt,name=licenseNumber,proto3" json:"licenseNumber,omitempty"`
+	Vehicles       []*Vehicle             `protobuf:"bytes,6,rep,name=vehicles,proto3" json:"vehicles,omitempty"`
+	Rating         float64                `protobuf:"fixed64,7,opt,name=rating,proto3" json:"rating,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DriverProfile) Reset() {
+	*x = DriverProfile{}
+	mi := &file_driver_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverProfile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverProfile) ProtoMessage() {}
+
+func (x *DriverProfile) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverProfile.ProtoReflect.Descriptor instead.
+func (*DriverProfile) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DriverProfile) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DriverProfile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DriverProfile) GetProfilePicture() string {
+	if x != nil {
+		return x.ProfilePicture
+	}
+	return ""
+}
+
+func (x *DriverProfile) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *DriverProfile) GetLicenseNumber() string {
+	if x != nil {
+		return x.LicenseNumber
+	}
+	return ""
+}
+
+func (x *DriverProfile) GetVehicles() []*Vehicle {
+	if x != nil {
+		return x.Vehicles
+	}
+	return nil
+}
+
+func (x *DriverProfile) GetRating() float64 {
+	if x != nil {
+		return x.Rating
+	}
+	return 0
+}
+
+type Vehicle struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	DriverID      string                 `protobuf:"bytes,2,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	Plate         string                 `protobuf:"bytes,3,opt,name=plate,proto3" json:"plate,omitempty"`
+	Make          string                 `protobuf:"bytes,4,opt,name=make,proto3" json:"make,omitempty"`
+	Model         string                 `protobuf:"bytes,5,opt,name=model,proto3" json:"model,omitempty"`
+	Color         string                 `protobuf:"bytes,6,opt,name=color,proto3" json:"color,omitempty"`
+	Seats         int32                  `protobuf:"varint,7,opt,name=seats,proto3" json:"seats,omitempty"`
+	PackageSlugs  []string               `protobuf:"bytes,8,rep,name=packageSlugs,proto3" json:"packageSlugs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Vehicle) Reset() {
+	*x = Vehicle{}
+	mi := &file_driver_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Vehicle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Vehicle) ProtoMessage() {}
+
+func (x *Vehicle) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Vehicle.ProtoReflect.Descriptor instead.
+func (*Vehicle) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Vehicle) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Vehicle) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *Vehicle) GetPlate() string {
+	if x != nil {
+		return x.Plate
+	}
+	return ""
+}
+
+func (x *Vehicle) GetMake() string {
+	if x != nil {
+		return x.Make
+	}
+	return ""
+}
+
+func (x *Vehicle) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *Vehicle) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *Vehicle) GetSeats() int32 {
+	if x != nil {
+		return x.Seats
+	}
+	return 0
+}
+
+func (x *Vehicle) GetPackageSlugs() []string {
+	if x != nil {
+		return x.PackageSlugs
+	}
+	return nil
+}
+
+type CreateDriverProfileRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	DriverID       string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
+	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	ProfilePicture string                 `protobuf:"bytes,3,opt,name=profilePicture,proto3" json:"profilePicture,omitempty"`
+	Phone          string                 `protobuf:"bytes,4,opt,name=phone,proto3" json:"phone,omitempty"`
+	LicenseNumber  string                 `protobuf:"bytes,5,opt,name=licenseNumber,proto3" json:"licenseNumber,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CreateDriverProfileRequest) Reset() {
+	*x = CreateDriverProfileRequest{}
+	mi := &file_driver_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDriverProfileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDriverProfileRequest) ProtoMessage() {}
+
+func (x *CreateDriverProfileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDriverProfileRequest.ProtoReflect.Descriptor instead.
+func (*CreateDriverProfileRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CreateDriverProfileRequest) GetDriverID() string {
+	if x != nil {
+		return x.DriverID
+	}
+	return ""
+}
+
+func (x *CreateDriverProfileRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateDriverProfileRequest) GetProfilePicture() string {
+	if x != nil {
+		return x.ProfilePicture
+	}
+	return ""
+}
+
+func (x *CreateDriverProfileRequest) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *CreateDriverProfileRequest) GetLicenseNumber() string {
+	if x != nil {
+		return x.LicenseNumber
+	}
+	return ""
+}
+
+type DriverProfileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Profile       *DriverProfile         `protobuf:"bytes,1,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DriverProfileResponse) Reset() {
+	*x = DriverProfileResponse{}
+	mi := &file_driver_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DriverProfileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DriverProfileResponse) ProtoMessage() {}
+
+func (x *DriverProfileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DriverProfileResponse.ProtoReflect.Descriptor instead.
+func (*DriverProfileResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DriverProfileResponse) GetProfile() *DriverProfile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+type AddVehicleRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	DriverID      string                 `protobuf:"bytes,1,opt,name=driverID,proto3" json:"driverID,omitempty"`
-	PackageSlug   string                 `protobuf:"bytes,2,opt,name=packageSlug,proto3" json:"packageSlug,omitempty"`
+	Plate         string                 `protobuf:"bytes,2,opt,name=plate,proto3" json:"plate,omitempty"`
+	Make          string                 `protobuf:"bytes,3,opt,name=make,proto3" json:"make,omitempty"`
+	Model         string                 `protobuf:"bytes,4,opt,name=model,proto3" json:"model,omitempty"`
+	Color         string                 `protobuf:"bytes,5,opt,name=color,proto3" json:"color,omitempty"`
+	Seats         int32                  `protobuf:"varint,6,opt,name=seats,proto3" json:"seats,omitempty"`
+	PackageSlugs  []string               `protobuf:"bytes,7,rep,name=packageSlugs,proto3" json:"packageSlugs,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterDriverRequest) Reset() {
-	*x = RegisterDriverRequest{}
-	mi := &file_driver_proto_msgTypes[0]
+func (x *AddVehicleRequest) Reset() {
+	*x = AddVehicleRequest{}
+	mi := &file_driver_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterDriverRequest) String() string {
+func (x *AddVehicleRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterDriverRequest) ProtoMessage() {}
+func (*AddVehicleRequest) ProtoMessage() {}
 
-func (x *RegisterDriverRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_driver_proto_msgTypes[0]
+func (x *AddVehicleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -54,47 +1074,82 @@ func (x *RegisterDriverRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterDriverRequest.ProtoReflect.Descriptor instead.
-func (*RegisterDriverRequest) Descriptor() ([]byte, []int) {
-	return file_driver_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use AddVehicleRequest.ProtoReflect.Descriptor instead.
+func (*AddVehicleRequest) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *RegisterDriverRequest) GetDriverID() string {
+func (x *AddVehicleRequest) GetDriverID() string {
 	if x != nil {
 		return x.DriverID
 	}
 	return ""
 }
 
-func (x *RegisterDriverRequest) GetPackageSlug() string {
+func (x *AddVehicleRequest) GetPlate() string {
 	if x != nil {
-		return x.PackageSlug
+		return x.Plate
 	}
 	return ""
 }
 
-type RegisterDriverResponse struct {
+func (x *AddVehicleRequest) GetMake() string {
+	if x != nil {
+		return x.Make
+	}
+	return ""
+}
+
+func (x *AddVehicleRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *AddVehicleRequest) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *AddVehicleRequest) GetSeats() int32 {
+	if x != nil {
+		return x.Seats
+	}
+	return 0
+}
+
+func (x *AddVehicleRequest) GetPackageSlugs() []string {
+	if x != nil {
+		return x.PackageSlugs
+	}
+	return nil
+}
+
+type VehicleResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Driver        *Driver                `protobuf:"bytes,1,opt,name=driver,proto3" json:"driver,omitempty"`
+	Vehicle       *Vehicle               `protobuf:"bytes,1,opt,name=vehicle,proto3" json:"vehicle,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterDriverResponse) Reset() {
-	*x = RegisterDriverResponse{}
-	mi := &file_driver_proto_msgTypes[1]
+func (x *VehicleResponse) Reset() {
+	*x = VehicleResponse{}
+	mi := &file_driver_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterDriverResponse) String() string {
+func (x *VehicleResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterDriverResponse) ProtoMessage() {}
+func (*VehicleResponse) ProtoMessage() {}
 
-func (x *RegisterDriverResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_driver_proto_msgTypes[1]
+func (x *VehicleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_driver_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -105,14 +1160,14 @@ func (x *RegisterDriverResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterDriverResponse.ProtoReflect.Descriptor instead.
-func (*RegisterDriverResponse) Descriptor() ([]byte, []int) {
-	return file_driver_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use VehicleResponse.ProtoReflect.Descriptor instead.
+func (*VehicleResponse) Descriptor() ([]byte, []int) {
+	return file_driver_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *RegisterDriverResponse) GetDriver() *Driver {
+func (x *VehicleResponse) GetVehicle() *Vehicle {
 	if x != nil {
-		return x.Driver
+		return x.Vehicle
 	}
 	return nil
 }
@@ -126,13 +1181,16 @@ type Driver struct {
 	Geohash        string                 `protobuf:"bytes,5,opt,name=geohash,proto3" json:"geohash,omitempty"`
 	PackageSlug    string                 `protobuf:"bytes,6,opt,name=packageSlug,proto3" json:"packageSlug,omitempty"`
 	Location       *Location              `protobuf:"bytes,7,opt,name=location,proto3" json:"location,omitempty"`
+	Heading        float64                `protobuf:"fixed64,8,opt,name=heading,proto3" json:"heading,omitempty"`
+	VehicleID      string                 `protobuf:"bytes,9,opt,name=vehicleID,proto3" json:"vehicleID,omitempty"`
+	Rating         float64                `protobuf:"fixed64,10,opt,name=rating,proto3" json:"rating,omitempty"`
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
 
 func (x *Driver) Reset() {
 	*x = Driver{}
-	mi := &file_driver_proto_msgTypes[2]
+	mi := &file_driver_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -144,7 +1202,7 @@ func (x *Driver) String() string {
 func (*Driver) ProtoMessage() {}
 
 func (x *Driver) ProtoReflect() protoreflect.Message {
-	mi := &file_driver_proto_msgTypes[2]
+	mi := &file_driver_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -157,7 +1215,7 @@ func (x *Driver) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Driver.ProtoReflect.Descriptor instead.
 func (*Driver) Descriptor() ([]byte, []int) {
-	return file_driver_proto_rawDescGZIP(), []int{2}
+	return file_driver_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *Driver) GetId() string {
@@ -209,6 +1267,27 @@ func (x *Driver) GetLocation() *Location {
 	return nil
 }
 
+func (x *Driver) GetHeading() float64 {
+	if x != nil {
+		return x.Heading
+	}
+	return 0
+}
+
+func (x *Driver) GetVehicleID() string {
+	if x != nil {
+		return x.VehicleID
+	}
+	return ""
+}
+
+func (x *Driver) GetRating() float64 {
+	if x != nil {
+		return x.Rating
+	}
+	return 0
+}
+
 type Location struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Latitude      float64                `protobuf:"fixed64,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
@@ -219,7 +1298,7 @@ type Location struct {
 
 func (x *Location) Reset() {
 	*x = Location{}
-	mi := &file_driver_proto_msgTypes[3]
+	mi := &file_driver_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -231,7 +1310,7 @@ func (x *Location) String() string {
 func (*Location) ProtoMessage() {}
 
 func (x *Location) ProtoReflect() protoreflect.Message {
-	mi := &file_driver_proto_msgTypes[3]
+	mi := &file_driver_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -244,7 +1323,7 @@ func (x *Location) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Location.ProtoReflect.Descriptor instead.
 func (*Location) Descriptor() ([]byte, []int) {
-	return file_driver_proto_rawDescGZIP(), []int{3}
+	return file_driver_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *Location) GetLatitude() float64 {
@@ -265,12 +1344,80 @@ var File_driver_proto protoreflect.FileDescriptor
 
 const file_driver_proto_rawDesc = "" +
 	"\n" +
-	"\fdriver.proto\x12\x06driver\"U\n" +
+	"\fdriver.proto\x12\x06driver\"s\n" +
 	"\x15RegisterDriverRequest\x12\x1a\n" +
 	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12 \n" +
-	"\vpackageSlug\x18\x02 \x01(\tR\vpackageSlug\"@\n" +
+	"\vpackageSlug\x18\x02 \x01(\tR\vpackageSlug\x12\x1c\n" +
+	"\tvehicleID\x18\x03 \x01(\tR\tvehicleID\"@\n" +
 	"\x16RegisterDriverResponse\x12&\n" +
-	"\x06driver\x18\x01 \x01(\v2\x0e.driver.DriverR\x06driver\"\xda\x01\n" +
+	"\x06driver\x18\x01 \x01(\v2\x0e.driver.DriverR\x06driver\".\n" +
+	"\x10GetDriverRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\";\n" +
+	"\x11GetDriverResponse\x12&\n" +
+	"\x06driver\x18\x01 \x01(\v2\x0e.driver.DriverR\x06driver\"\xdb\x01\n" +
+	"\x1dGetDriverAvailabilityResponse\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12>\n" +
+	"\favailability\x18\x02 \x01(\x0e2\x1a.driver.DriverAvailabilityR\favailability\x12(\n" +
+	"\x0fsinceUnixMillis\x18\x03 \x01(\x03R\x0fsinceUnixMillis\x12\x16\n" +
+	"\x06tripID\x18\x04 \x01(\tR\x06tripID\x12\x1c\n" +
+	"\tconnected\x18\x05 \x01(\bR\tconnected\"Y\n" +
+	"\x1dUpdateDriverConnectionRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12\x1c\n" +
+	"\tconnected\x18\x02 \x01(\bR\tconnected\"Z\n" +
+	"\x1eUpdateDriverConnectionResponse\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12\x1c\n" +
+	"\tconnected\x18\x02 \x01(\bR\tconnected\"a\n" +
+	"\x15ReportLocationRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12,\n" +
+	"\blocation\x18\x02 \x01(\v2\x10.driver.LocationR\blocation\"P\n" +
+	"\x16ReportLocationResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\x03R\baccepted\x12\x1a\n" +
+	"\brejected\x18\x02 \x01(\x03R\brejected\"m\n" +
+	"\vBoundingBox\x12.\n" +
+	"\tsouthWest\x18\x01 \x01(\v2\x10.driver.LocationR\tsouthWest\x12.\n" +
+	"\tnorthEast\x18\x02 \x01(\v2\x10.driver.LocationR\tnorthEast\"\x94\x01\n" +
+	"\x13WatchDriversRequest\x12 \n" +
+	"\vpackageSlug\x18\x01 \x01(\tR\vpackageSlug\x12$\n" +
+	"\rgeohashPrefix\x18\x02 \x01(\tR\rgeohashPrefix\x125\n" +
+	"\vboundingBox\x18\x03 \x01(\v2\x13.driver.BoundingBoxR\vboundingBox\"X\n" +
+	"\x14WatchDriversResponse\x12&\n" +
+	"\x06driver\x18\x01 \x01(\v2\x0e.driver.DriverR\x06driver\x12\x18\n" +
+	"\aremoved\x18\x02 \x01(\bR\aremoved\"\xdc\x01\n" +
+	"\rDriverProfile\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12&\n" +
+	"\x0eprofilePicture\x18\x03 \x01(\tR\x0eprofilePicture\x12\x14\n" +
+	"\x05phone\x18\x04 \x01(\tR\x05phone\x12$\n" +
+	"\rlicenseNumber\x18\x05 \x01(\tR\rlicenseNumber\x12+\n" +
+	"\bvehicles\x18\x06 \x03(\v2\x0f.driver.VehicleR\bvehicles\x12\x16\n" +
+	"\x06rating\x18\a \x01(\x01R\x06rating\"\xc5\x01\n" +
+	"\aVehicle\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bdriverID\x18\x02 \x01(\tR\bdriverID\x12\x14\n" +
+	"\x05plate\x18\x03 \x01(\tR\x05plate\x12\x12\n" +
+	"\x04make\x18\x04 \x01(\tR\x04make\x12\x14\n" +
+	"\x05model\x18\x05 \x01(\tR\x05model\x12\x14\n" +
+	"\x05color\x18\x06 \x01(\tR\x05color\x12\x14\n" +
+	"\x05seats\x18\a \x01(\x05R\x05seats\x12\"\n" +
+	"\fpackageSlugs\x18\b \x03(\tR\fpackageSlugs\"\xb0\x01\n" +
+	"\x1aCreateDriverProfileRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12&\n" +
+	"\x0eprofilePicture\x18\x03 \x01(\tR\x0eprofilePicture\x12\x14\n" +
+	"\x05phone\x18\x04 \x01(\tR\x05phone\x12$\n" +
+	"\rlicenseNumber\x18\x05 \x01(\tR\rlicenseNumber\"H\n" +
+	"\x15DriverProfileResponse\x12/\n" +
+	"\aprofile\x18\x01 \x01(\v2\x15.driver.DriverProfileR\aprofile\"\xbf\x01\n" +
+	"\x11AddVehicleRequest\x12\x1a\n" +
+	"\bdriverID\x18\x01 \x01(\tR\bdriverID\x12\x14\n" +
+	"\x05plate\x18\x02 \x01(\tR\x05plate\x12\x12\n" +
+	"\x04make\x18\x03 \x01(\tR\x04make\x12\x14\n" +
+	"\x05model\x18\x04 \x01(\tR\x05model\x12\x14\n" +
+	"\x05color\x18\x05 \x01(\tR\x05color\x12\x14\n" +
+	"\x05seats\x18\x06 \x01(\x05R\x05seats\x12\"\n" +
+	"\fpackageSlugs\x18\a \x03(\tR\fpackageSlugs\"<\n" +
+	"\x0fVehicleResponse\x12)\n" +
+	"\avehicle\x18\x01 \x01(\v2\x0f.driver.VehicleR\avehicle\"\xaa\x02\n" +
 	"\x06Driver\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12&\n" +
@@ -278,13 +1425,33 @@ const file_driver_proto_rawDesc = "" +
 	"\bcarPlate\x18\x04 \x01(\tR\bcarPlate\x12\x18\n" +
 	"\ageohash\x18\x05 \x01(\tR\ageohash\x12 \n" +
 	"\vpackageSlug\x18\x06 \x01(\tR\vpackageSlug\x12,\n" +
-	"\blocation\x18\a \x01(\v2\x10.driver.LocationR\blocation\"D\n" +
+	"\blocation\x18\a \x01(\v2\x10.driver.LocationR\blocation\x12\x18\n" +
+	"\aheading\x18\b \x01(\x01R\aheading\x12\x1c\n" +
+	"\tvehicleID\x18\t \x01(\tR\tvehicleID\x12\x16\n" +
+	"\x06rating\x18\n" +
+	" \x01(\x01R\x06rating\"D\n" +
 	"\bLocation\x12\x1a\n" +
 	"\blatitude\x18\x01 \x01(\x01R\blatitude\x12\x1c\n" +
-	"\tlongitude\x18\x02 \x01(\x01R\tlongitude2\xb3\x01\n" +
+	"\tlongitude\x18\x02 \x01(\x01R\tlongitude*\xe8\x01\n" +
+	"\x12DriverAvailability\x12#\n" +
+	"\x1fDRIVER_AVAILABILITY_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aDRIVER_AVAILABILITY_ONLINE\x10\x01\x12\x1f\n" +
+	"\x1bDRIVER_AVAILABILITY_OFFERED\x10\x02\x12*\n" +
+	"&DRIVER_AVAILABILITY_EN_ROUTE_TO_PICKUP\x10\x03\x12\x1f\n" +
+	"\x1bDRIVER_AVAILABILITY_ON_TRIP\x10\x04\x12\x1f\n" +
+	"\x1bDRIVER_AVAILABILITY_OFFLINE\x10\x052\xc1\x06\n" +
 	"\rDriverService\x12O\n" +
 	"\x0eRegisterDriver\x12\x1d.driver.RegisterDriverRequest\x1a\x1e.driver.RegisterDriverResponse\x12Q\n" +
-	"\x10UnregisterDriver\x12\x1d.driver.RegisterDriverRequest\x1a\x1e.driver.RegisterDriverResponseB\x1cZ\x1ashared/proto/driver;driverb\x06proto3"
+	"\x10UnregisterDriver\x12\x1d.driver.RegisterDriverRequest\x1a\x1e.driver.RegisterDriverResponse\x12@\n" +
+	"\tGetDriver\x12\x18.driver.GetDriverRequest\x1a\x19.driver.GetDriverResponse\x12Q\n" +
+	"\x0eReportLocation\x12\x1d.driver.ReportLocationRequest\x1a\x1e.driver.ReportLocationResponse(\x01\x12K\n" +
+	"\fWatchDrivers\x12\x1b.driver.WatchDriversRequest\x1a\x1c.driver.WatchDriversResponse0\x01\x12X\n" +
+	"\x15GetDriverAvailability\x12\x18.driver.GetDriverRequest\x1a%.driver.GetDriverAvailabilityResponse\x12X\n" +
+	"\x13CreateDriverProfile\x12\".driver.CreateDriverProfileRequest\x1a\x1d.driver.DriverProfileResponse\x12K\n" +
+	"\x10GetDriverProfile\x12\x18.driver.GetDriverRequest\x1a\x1d.driver.DriverProfileResponse\x12@\n" +
+	"\n" +
+	"AddVehicle\x12\x19.driver.AddVehicleRequest\x1a\x17.driver.VehicleResponse\x12g\n" +
+	"\x16UpdateDriverConnection\x12%.driver.UpdateDriverConnectionRequest\x1a&.driver.UpdateDriverConnectionResponseB\x1cZ\x1ashared/proto/driver;driverb\x06proto3"
 
 var (
 	file_driver_proto_rawDescOnce sync.Once
@@ -298,25 +1465,69 @@ func file_driver_proto_rawDescGZIP() []byte {
 	return file_driver_proto_rawDescData
 }
 
-var file_driver_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_driver_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_driver_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
 var file_driver_proto_goTypes = []any{
-	(*RegisterDriverRequest)(nil),  // 0: driver.RegisterDriverRequest
-	(*RegisterDriverResponse)(nil), // 1: driver.RegisterDriverResponse
-	(*Driver)(nil),                 // 2: driver.Driver
-	(*Location)(nil),               // 3: driver.Location
+	(DriverAvailability)(0),                // 0: driver.DriverAvailability
+	(*RegisterDriverRequest)(nil),          // 1: driver.RegisterDriverRequest
+	(*RegisterDriverResponse)(nil),         // 2: driver.RegisterDriverResponse
+	(*GetDriverRequest)(nil),               // 3: driver.GetDriverRequest
+	(*GetDriverResponse)(nil),              // 4: driver.GetDriverResponse
+	(*GetDriverAvailabilityResponse)(nil),  // 5: driver.GetDriverAvailabilityResponse
+	(*UpdateDriverConnectionRequest)(nil),  // 6: driver.UpdateDriverConnectionRequest
+	(*UpdateDriverConnectionResponse)(nil), // 7: driver.UpdateDriverConnectionResponse
+	(*ReportLocationRequest)(nil),          // 8: driver.ReportLocationRequest
+	(*ReportLocationResponse)(nil),         // 9: driver.ReportLocationResponse
+	(*BoundingBox)(nil),                    // 10: driver.BoundingBox
+	(*WatchDriversRequest)(nil),            // 11: driver.WatchDriversRequest
+	(*WatchDriversResponse)(nil),           // 12: driver.WatchDriversResponse
+	(*DriverProfile)(nil),                  // 13: driver.DriverProfile
+	(*Vehicle)(nil),                        // 14: driver.Vehicle
+	(*CreateDriverProfileRequest)(nil),     // 15: driver.CreateDriverProfileRequest
+	(*DriverProfileResponse)(nil),          // 16: driver.DriverProfileResponse
+	(*AddVehicleRequest)(nil),              // 17: driver.AddVehicleRequest
+	(*VehicleResponse)(nil),                // 18: driver.VehicleResponse
+	(*Driver)(nil),                         // 19: driver.Driver
+	(*Location)(nil),                       // 20: driver.Location
 }
 var file_driver_proto_depIdxs = []int32{
-	2, // 0: driver.RegisterDriverResponse.driver:type_name -> driver.Driver
-	3, // 1: driver.Driver.location:type_name -> driver.Location
-	0, // 2: driver.DriverService.RegisterDriver:input_type -> driver.RegisterDriverRequest
-	0, // 3: driver.DriverService.UnregisterDriver:input_type -> driver.RegisterDriverRequest
-	1, // 4: driver.DriverService.RegisterDriver:output_type -> driver.RegisterDriverResponse
-	1, // 5: driver.DriverService.UnregisterDriver:output_type -> driver.RegisterDriverResponse
-	4, // [4:6] is the sub-list for method output_type
-	2, // [2:4] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	19, // 0: driver.RegisterDriverResponse.driver:type_name -> driver.Driver
+	19, // 1: driver.GetDriverResponse.driver:type_name -> driver.Driver
+	0,  // 2: driver.GetDriverAvailabilityResponse.availability:type_name -> driver.DriverAvailability
+	20, // 3: driver.ReportLocationRequest.location:type_name -> driver.Location
+	20, // 4: driver.BoundingBox.southWest:type_name -> driver.Location
+	20, // 5: driver.BoundingBox.northEast:type_name -> driver.Location
+	10, // 6: driver.WatchDriversRequest.boundingBox:type_name -> driver.BoundingBox
+	19, // 7: driver.WatchDriversResponse.driver:type_name -> driver.Driver
+	14, // 8: driver.DriverProfile.vehicles:type_name -> driver.Vehicle
+	13, // 9: driver.DriverProfileResponse.profile:type_name -> driver.DriverProfile
+	14, // 10: driver.VehicleResponse.vehicle:type_name -> driver.Vehicle
+	20, // 11: driver.Driver.location:type_name -> driver.Location
+	1,  // 12: driver.DriverService.RegisterDriver:input_type -> driver.RegisterDriverRequest
+	1,  // 13: driver.DriverService.UnregisterDriver:input_type -> driver.RegisterDriverRequest
+	3,  // 14: driver.DriverService.GetDriver:input_type -> driver.GetDriverRequest
+	8,  // 15: driver.DriverService.ReportLocation:input_type -> driver.ReportLocationRequest
+	11, // 16: driver.DriverService.WatchDrivers:input_type -> driver.WatchDriversRequest
+	3,  // 17: driver.DriverService.GetDriverAvailability:input_type -> driver.GetDriverRequest
+	15, // 18: driver.DriverService.CreateDriverProfile:input_type -> driver.CreateDriverProfileRequest
+	3,  // 19: driver.DriverService.GetDriverProfile:input_type -> driver.GetDriverRequest
+	17, // 20: driver.DriverService.AddVehicle:input_type -> driver.AddVehicleRequest
+	6,  // 21: driver.DriverService.UpdateDriverConnection:input_type -> driver.UpdateDriverConnectionRequest
+	2,  // 22: driver.DriverService.RegisterDriver:output_type -> driver.RegisterDriverResponse
+	2,  // 23: driver.DriverService.UnregisterDriver:output_type -> driver.RegisterDriverResponse
+	4,  // 24: driver.DriverService.GetDriver:output_type -> driver.GetDriverResponse
+	9,  // 25: driver.DriverService.ReportLocation:output_type -> driver.ReportLocationResponse
+	12, // 26: driver.DriverService.WatchDrivers:output_type -> driver.WatchDriversResponse
+	5,  // 27: driver.DriverService.GetDriverAvailability:output_type -> driver.GetDriverAvailabilityResponse
+	16, // 28: driver.DriverService.CreateDriverProfile:output_type -> driver.DriverProfileResponse
+	16, // 29: driver.DriverService.GetDriverProfile:output_type -> driver.DriverProfileResponse
+	18, // 30: driver.DriverService.AddVehicle:output_type -> driver.VehicleResponse
+	7,  // 31: driver.DriverService.UpdateDriverConnection:output_type -> driver.UpdateDriverConnectionResponse
+	22, // [22:32] is the sub-list for method output_type
+	12, // [12:22] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
 }
 
 func init() { file_driver_proto_init() }
@@ -329,13 +1540,14 @@ func file_driver_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_driver_proto_rawDesc), len(file_driver_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      1,
+			NumMessages:   20,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_driver_proto_goTypes,
 		DependencyIndexes: file_driver_proto_depIdxs,
+		EnumInfos:         file_driver_proto_enumTypes,
 		MessageInfos:      file_driver_proto_msgTypes,
 	}.Build()
 	File_driver_proto = out.File
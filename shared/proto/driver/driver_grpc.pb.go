@@ -19,8 +19,16 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	DriverService_RegisterDriver_FullMethodName   = "/driver.DriverService/RegisterDriver"
-	DriverService_UnregisterDriver_FullMethodName = "/driver.DriverService/UnregisterDriver"
+	DriverService_RegisterDriver_FullMethodName         = "/driver.DriverService/RegisterDriver"
+	DriverService_UnregisterDriver_FullMethodName       = "/driver.DriverService/UnregisterDriver"
+	DriverService_GetDriver_FullMethodName              = "/driver.DriverService/GetDriver"
+	DriverService_ReportLocation_FullMethodName         = "/driver.DriverService/ReportLocation"
+	DriverService_WatchDrivers_FullMethodName           = "/driver.DriverService/WatchDrivers"
+	DriverService_GetDriverAvailability_FullMethodName  = "/driver.DriverService/GetDriverAvailability"
+	DriverService_CreateDriverProfile_FullMethodName    = "/driver.DriverService/CreateDriverProfile"
+	DriverService_GetDriverProfile_FullMethodName       = "/driver.DriverService/GetDriverProfile"
+	DriverService_AddVehicle_FullMethodName             = "/driver.DriverService/AddVehicle"
+	DriverService_UpdateDriverConnection_FullMethodName = "/driver.DriverService/UpdateDriverConnection"
 )
 
 // DriverServiceClient is the client API for DriverService service.
@@ -29,6 +37,14 @@ const (
 type DriverServiceClient interface {
 	RegisterDriver(ctx context.Context, in *RegisterDriverRequest, opts ...grpc.CallOption) (*RegisterDriverResponse, error)
 	UnregisterDriver(ctx context.Context, in *RegisterDriverRequest, opts ...grpc.CallOption) (*RegisterDriverResponse, error)
+	GetDriver(ctx context.Context, in *GetDriverRequest, opts ...grpc.CallOption) (*GetDriverResponse, error)
+	ReportLocation(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ReportLocationRequest, ReportLocationResponse], error)
+	WatchDrivers(ctx context.Context, in *WatchDriversRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchDriversResponse], error)
+	GetDriverAvailability(ctx context.Context, in *GetDriverRequest, opts ...grpc.CallOption) (*GetDriverAvailabilityResponse, error)
+	CreateDriverProfile(ctx context.Context, in *CreateDriverProfileRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error)
+	GetDriverProfile(ctx context.Context, in *GetDriverRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error)
+	AddVehicle(ctx context.Context, in *AddVehicleRequest, opts ...grpc.CallOption) (*VehicleResponse, error)
+	UpdateDriverConnection(ctx context.Context, in *UpdateDriverConnectionRequest, opts ...grpc.CallOption) (*UpdateDriverConnectionResponse, error)
 }
 
 type driverServiceClient struct {
@@ -59,12 +75,112 @@ func (c *driverServiceClient) UnregisterDriver(ctx context.Context, in *Register
 	return out, nil
 }
 
+func (c *driverServiceClient) GetDriver(ctx context.Context, in *GetDriverRequest, opts ...grpc.CallOption) (*GetDriverResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDriverResponse)
+	err := c.cc.Invoke(ctx, DriverService_GetDriver_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) ReportLocation(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ReportLocationRequest, ReportLocationResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DriverService_ServiceDesc.Streams[0], DriverService_ReportLocation_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReportLocationRequest, ReportLocationResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DriverService_ReportLocationClient = grpc.ClientStreamingClient[ReportLocationRequest, ReportLocationResponse]
+
+func (c *driverServiceClient) WatchDrivers(ctx context.Context, in *WatchDriversRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchDriversResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DriverService_ServiceDesc.Streams[1], DriverService_WatchDrivers_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchDriversRequest, WatchDriversResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DriverService_WatchDriversClient = grpc.ServerStreamingClient[WatchDriversResponse]
+
+func (c *driverServiceClient) GetDriverAvailability(ctx context.Context, in *GetDriverRequest, opts ...grpc.CallOption) (*GetDriverAvailabilityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDriverAvailabilityResponse)
+	err := c.cc.Invoke(ctx, DriverService_GetDriverAvailability_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) CreateDriverProfile(ctx context.Context, in *CreateDriverProfileRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverProfileResponse)
+	err := c.cc.Invoke(ctx, DriverService_CreateDriverProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) GetDriverProfile(ctx context.Context, in *GetDriverRequest, opts ...grpc.CallOption) (*DriverProfileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DriverProfileResponse)
+	err := c.cc.Invoke(ctx, DriverService_GetDriverProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) AddVehicle(ctx context.Context, in *AddVehicleRequest, opts ...grpc.CallOption) (*VehicleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VehicleResponse)
+	err := c.cc.Invoke(ctx, DriverService_AddVehicle_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *driverServiceClient) UpdateDriverConnection(ctx context.Context, in *UpdateDriverConnectionRequest, opts ...grpc.CallOption) (*UpdateDriverConnectionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateDriverConnectionResponse)
+	err := c.cc.Invoke(ctx, DriverService_UpdateDriverConnection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DriverServiceServer is the server API for DriverService service.
 // All implementations must embed UnimplementedDriverServiceServer
 // for forward compatibility.
 type DriverServiceServer interface {
 	RegisterDriver(context.Context, *RegisterDriverRequest) (*RegisterDriverResponse, error)
 	UnregisterDriver(context.Context, *RegisterDriverRequest) (*RegisterDriverResponse, error)
+	GetDriver(context.Context, *GetDriverRequest) (*GetDriverResponse, error)
+	ReportLocation(grpc.ClientStreamingServer[ReportLocationRequest, ReportLocationResponse]) error
+	WatchDrivers(*WatchDriversRequest, grpc.ServerStreamingServer[WatchDriversResponse]) error
+	GetDriverAvailability(context.Context, *GetDriverRequest) (*GetDriverAvailabilityResponse, error)
+	CreateDriverProfile(context.Context, *CreateDriverProfileRequest) (*DriverProfileResponse, error)
+	GetDriverProfile(context.Context, *GetDriverRequest) (*DriverProfileResponse, error)
+	AddVehicle(context.Context, *AddVehicleRequest) (*VehicleResponse, error)
+	UpdateDriverConnection(context.Context, *UpdateDriverConnectionRequest) (*UpdateDriverConnectionResponse, error)
 	mustEmbedUnimplementedDriverServiceServer()
 }
 
@@ -81,6 +197,30 @@ func (UnimplementedDriverServiceServer) RegisterDriver(context.Context, *Registe
 func (UnimplementedDriverServiceServer) UnregisterDriver(context.Context, *RegisterDriverRequest) (*RegisterDriverResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UnregisterDriver not implemented")
 }
+func (UnimplementedDriverServiceServer) GetDriver(context.Context, *GetDriverRequest) (*GetDriverResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDriver not implemented")
+}
+func (UnimplementedDriverServiceServer) ReportLocation(grpc.ClientStreamingServer[ReportLocationRequest, ReportLocationResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ReportLocation not implemented")
+}
+func (UnimplementedDriverServiceServer) WatchDrivers(*WatchDriversRequest, grpc.ServerStreamingServer[WatchDriversResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDrivers not implemented")
+}
+func (UnimplementedDriverServiceServer) GetDriverAvailability(context.Context, *GetDriverRequest) (*GetDriverAvailabilityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDriverAvailability not implemented")
+}
+func (UnimplementedDriverServiceServer) CreateDriverProfile(context.Context, *CreateDriverProfileRequest) (*DriverProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDriverProfile not implemented")
+}
+func (UnimplementedDriverServiceServer) GetDriverProfile(context.Context, *GetDriverRequest) (*DriverProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDriverProfile not implemented")
+}
+func (UnimplementedDriverServiceServer) AddVehicle(context.Context, *AddVehicleRequest) (*VehicleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddVehicle not implemented")
+}
+func (UnimplementedDriverServiceServer) UpdateDriverConnection(context.Context, *UpdateDriverConnectionRequest) (*UpdateDriverConnectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateDriverConnection not implemented")
+}
 func (UnimplementedDriverServiceServer) mustEmbedUnimplementedDriverServiceServer() {}
 func (UnimplementedDriverServiceServer) testEmbeddedByValue()                       {}
 
@@ -138,6 +278,132 @@ func _DriverService_UnregisterDriver_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DriverService_GetDriver_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDriverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).GetDriver(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_GetDriver_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).GetDriver(ctx, req.(*GetDriverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_ReportLocation_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DriverServiceServer).ReportLocation(&grpc.GenericServerStream[ReportLocationRequest, ReportLocationResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DriverService_ReportLocationServer = grpc.ClientStreamingServer[ReportLocationRequest, ReportLocationResponse]
+
+func _DriverService_WatchDrivers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchDriversRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DriverServiceServer).WatchDrivers(m, &grpc.GenericServerStream[WatchDriversRequest, WatchDriversResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DriverService_WatchDriversServer = grpc.ServerStreamingServer[WatchDriversResponse]
+
+func _DriverService_GetDriverAvailability_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDriverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).GetDriverAvailability(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_GetDriverAvailability_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).GetDriverAvailability(ctx, req.(*GetDriverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_CreateDriverProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDriverProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).CreateDriverProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_CreateDriverProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).CreateDriverProfile(ctx, req.(*CreateDriverProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_GetDriverProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDriverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).GetDriverProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_GetDriverProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).GetDriverProfile(ctx, req.(*GetDriverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_AddVehicle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddVehicleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).AddVehicle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_AddVehicle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).AddVehicle(ctx, req.(*AddVehicleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DriverService_UpdateDriverConnection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateDriverConnectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DriverServiceServer).UpdateDriverConnection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DriverService_UpdateDriverConnection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DriverServiceServer).UpdateDriverConnection(ctx, req.(*UpdateDriverConnectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DriverService_ServiceDesc is the grpc.ServiceDesc for DriverService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,7 +419,42 @@ var DriverService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UnregisterDriver",
 			Handler:    _DriverService_UnregisterDriver_Handler,
 		},
+		{
+			MethodName: "GetDriver",
+			Handler:    _DriverService_GetDriver_Handler,
+		},
+		{
+			MethodName: "GetDriverAvailability",
+			Handler:    _DriverService_GetDriverAvailability_Handler,
+		},
+		{
+			MethodName: "CreateDriverProfile",
+			Handler:    _DriverService_CreateDriverProfile_Handler,
+		},
+		{
+			MethodName: "GetDriverProfile",
+			Handler:    _DriverService_GetDriverProfile_Handler,
+		},
+		{
+			MethodName: "AddVehicle",
+			Handler:    _DriverService_AddVehicle_Handler,
+		},
+		{
+			MethodName: "UpdateDriverConnection",
+			Handler:    _DriverService_UpdateDriverConnection_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ReportLocation",
+			Handler:       _DriverService_ReportLocation_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchDrivers",
+			Handler:       _DriverService_WatchDrivers_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "driver.proto",
 }
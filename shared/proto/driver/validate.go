@@ -0,0 +1,45 @@
+package driver
+
+import "errors"
+
+// Validate methods check the fields the driver service can't do without. The gRPC
+// servers call them before any handler, see the shared grpcserver package.
+// ReportLocationRequest has none on purpose: invalid positions are counted as
+// rejected instead of ending the stream.
+
+var errDriverIDRequired = errors.New("driverID is required")
+
+func (x *RegisterDriverRequest) Validate() error {
+	if x.GetDriverID() == "" {
+		return errDriverIDRequired
+	}
+	return nil
+}
+
+func (x *GetDriverRequest) Validate() error {
+	if x.GetDriverID() == "" {
+		return errDriverIDRequired
+	}
+	return nil
+}
+
+func (x *UpdateDriverConnectionRequest) Validate() error {
+	if x.GetDriverID() == "" {
+		return errDriverIDRequired
+	}
+	return nil
+}
+
+func (x *CreateDriverProfileRequest) Validate() error {
+	if x.GetDriverID() == "" {
+		return errDriverIDRequired
+	}
+	return nil
+}
+
+func (x *AddVehicleRequest) Validate() error {
+	if x.GetDriverID() == "" {
+		return errDriverIDRequired
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package trip
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate methods check the fields the trip service can't do without. The gRPC
+// servers call them before any handler, see the shared grpcserver package.
+
+func (x *PreviewTripRequest) Validate() error {
+	if x.GetUserID() == "" {
+		return errors.New("userID is required")
+	}
+	if err := x.GetStartLocation().validate("startLocation"); err != nil {
+		return err
+	}
+	return x.GetEndLocation().validate("endLocation")
+}
+
+func (x *CreateTripRequest) Validate() error {
+	if x.GetRideFareID() == "" || x.GetUserID() == "" {
+		return errors.New("rideFareID and userID are required")
+	}
+	return nil
+}
+
+func (x *ListChatMessagesRequest) Validate() error {
+	if x.GetTripID() == "" || x.GetUserID() == "" {
+		return errors.New("tripID and userID are required")
+	}
+	return nil
+}
+
+func (x *Coordinate) validate(field string) error {
+	if x == nil {
+		return fmt.Errorf("%s is required", field)
+	}
+	if x.Latitude < -90 || x.Latitude > 90 || x.Longitude < -180 || x.Longitude > 180 {
+		return fmt.Errorf("%s is out of range", field)
+	}
+	return nil
+}
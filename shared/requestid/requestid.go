@@ -0,0 +1,23 @@
+/*
+Package requestid carries the ID of a request through contexts and across gRPC
+calls, so the logs of every service that handled a request can be related.
+*/
+package requestid
+
+import "context"
+
+// MetadataKey is the gRPC metadata key of the request ID, the lowercase X-Request-ID header
+const MetadataKey = "x-request-id"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the request ID
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestID)
+}
+
+// FromContext returns the request ID of a context, empty if it has none
+func FromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(contextKey{}).(string)
+	return requestID
+}
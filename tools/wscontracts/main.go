@@ -0,0 +1,250 @@
+// Command wscontracts generates the TypeScript types of the WebSocket messages in
+// web/src/contracts.ts from the message registry in shared/messaging, so the web
+// client and the services can't drift apart. It replaces the code between the
+// generated markers of the file; with -check it only reports whether it is up to date.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"ride-sharing/shared/contracts"
+	"ride-sharing/shared/messaging"
+	pbd "ride-sharing/shared/proto/driver"
+	pb "ride-sharing/shared/proto/trip"
+	"ride-sharing/shared/types"
+	"strings"
+	"time"
+)
+
+const (
+	beginMarker = "// BEGIN GENERATED WS CONTRACTS"
+	endMarker   = "// END GENERATED WS CONTRACTS"
+)
+
+// modelTypes are the domain models the web client declares itself in types.ts, with
+// fields of its own for the UI. Payloads refer to them by name.
+var modelTypes = map[reflect.Type]string{
+	reflect.TypeOf(pb.Trip{}):          "Trip",
+	reflect.TypeOf(pbd.Driver{}):       "Driver",
+	reflect.TypeOf(pbd.Location{}):     "Coordinate",
+	reflect.TypeOf(types.Coordinate{}): "Coordinate",
+}
+
+func main() {
+	out := flag.String("out", "web/src/contracts.ts", "TypeScript file to update")
+	check := flag.Bool("check", false, "only check that the file is up to date")
+	flag.Parse()
+
+	current, err := os.ReadFile(*out)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *out, err)
+	}
+
+	generated, err := generate(messaging.WSMessageSpecs())
+	if err != nil {
+		log.Fatalf("Failed to generate the WebSocket contracts: %v", err)
+	}
+
+	updated, err := replaceBetweenMarkers(current, generated)
+	if err != nil {
+		log.Fatalf("Failed to update %s: %v", *out, err)
+	}
+
+	if bytes.Equal(current, updated) {
+		return
+	}
+
+	if *check {
+		fmt.Fprintf(os.Stderr, "%s is out of date, run make generate-ws-contracts\n", *out)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*out, updated, 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+}
+
+// replaceBetweenMarkers swaps the generated section of a file, keeping the markers
+func replaceBetweenMarkers(file, generated []byte) ([]byte, error) {
+	begin := bytes.Index(file, []byte(beginMarker))
+	end := bytes.Index(file, []byte(endMarker))
+	if begin < 0 || end < begin {
+		return nil, fmt.Errorf("missing %q and %q markers", beginMarker, endMarker)
+	}
+
+	var updated bytes.Buffer
+	updated.Write(file[:begin+len(beginMarker)])
+	updated.WriteString("\n")
+	updated.Write(generated)
+	updated.Write(file[end:])
+	return updated.Bytes(), nil
+}
+
+// generator collects the TypeScript interfaces of the payload types, in the order they are first used
+type generator struct {
+	interfaces []string
+	names      map[string]reflect.Type
+}
+
+func generate(specs []messaging.WSMessageSpec) ([]byte, error) {
+	g := &generator{names: make(map[string]reflect.Type)}
+
+	var server, client []string
+	for _, spec := range specs {
+		member := fmt.Sprintf("  | { type: %q }\n", spec.Type)
+		if spec.Payload != nil {
+			dataType, err := g.tsType(reflect.TypeOf(spec.Payload))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", spec.Type, err)
+			}
+			member = fmt.Sprintf("  | { type: %q; data: %s }\n", spec.Type, dataType)
+		}
+		if spec.Doc != "" {
+			member = fmt.Sprintf("  // %s\n%s", spec.Doc, member)
+		}
+
+		if spec.Direction&messaging.WSServerToClient != 0 {
+			server = append(server, member)
+		}
+		if spec.Direction&messaging.WSClientToServer != 0 {
+			client = append(client, member)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by tools/wscontracts from the WebSocket message registry in\n")
+	out.WriteString("// shared/messaging/ws_messages.go. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "export const WS_PROTOCOL_VERSION = %d;\n\n", contracts.WSProtocolVersion)
+	out.WriteString(`// Envelope of every message. id stays the same when a message is delivered again,
+// correlationID is the id of the client message it answers.
+export interface WsEnvelope {
+  version: number;
+  id: string;
+  correlationID?: string;
+  timestamp: string;
+  // Messages with a seq must be acknowledged, and may be delivered again after a reconnect
+  seq?: number;
+}
+
+`)
+
+	for _, declaration := range g.interfaces {
+		out.WriteString(declaration)
+		out.WriteString("\n")
+	}
+
+	out.WriteString("// Messages sent from the server to the client via the websocket\n")
+	fmt.Fprintf(&out, "export type ServerWsMessage = (\n%s) & WsEnvelope;\n\n", strings.Join(server, ""))
+	out.WriteString("// Messages sent from the client to the server via the websocket. The envelope is\n")
+	out.WriteString("// optional, the server fills in what's missing.\n")
+	fmt.Fprintf(&out, "export type ClientWsMessage = (\n%s) & Partial<WsEnvelope>;\n\n", strings.Join(client, ""))
+
+	out.WriteString("export const SERVER_WS_MESSAGE_TYPES: ReadonlyArray<ServerWsMessage[\"type\"]> = [\n")
+	for _, spec := range specs {
+		if spec.Direction&messaging.WSServerToClient != 0 {
+			fmt.Fprintf(&out, "  %q,\n", spec.Type)
+		}
+	}
+	out.WriteString("];\n")
+
+	return out.Bytes(), nil
+}
+
+// tsType returns the TypeScript type of a Go type as encoding/json marshals it.
+// Structs become interfaces named after the Go type.
+func (g *generator) tsType(t reflect.Type) (string, error) {
+	if name, ok := modelTypes[t]; ok {
+		return name, nil
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string", nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.tsType(t.Elem())
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number", nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// base64 encoded
+			return "string", nil
+		}
+		elem, err := g.tsType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return elem + "[]", nil
+	case reflect.Map:
+		elem, err := g.tsType(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Record<string, %s>", elem), nil
+	case reflect.Interface:
+		return "unknown", nil
+	case reflect.Struct:
+		return g.tsInterface(t)
+	}
+
+	return "", fmt.Errorf("unsupported type %v", t)
+}
+
+// tsInterface declares the interface of a struct once and returns its name
+func (g *generator) tsInterface(t reflect.Type) (string, error) {
+	name := t.Name()
+	if seen, ok := g.names[name]; ok {
+		if seen != t {
+			return "", fmt.Errorf("%v and %v would both be named %s", seen, t, name)
+		}
+		return name, nil
+	}
+	for model, modelName := range modelTypes {
+		if modelName == name {
+			return "", fmt.Errorf("%v would shadow %s of %v", t, name, model)
+		}
+	}
+	g.names[name] = t
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		jsonName, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+
+		fieldType, err := g.tsType(field.Type)
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %w", name, field.Name, err)
+		}
+
+		// Pointers and slices without omitempty are always set by the services
+		optional := ""
+		if strings.Contains(options, "omitempty") {
+			optional = "?"
+		}
+		fields = append(fields, fmt.Sprintf("  %s%s: %s;\n", jsonName, optional, fieldType))
+	}
+
+	g.interfaces = append(g.interfaces, fmt.Sprintf("export interface %s {\n%s}\n", name, strings.Join(fields, "")))
+	return name, nil
+}
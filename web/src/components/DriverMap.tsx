@@ -12,6 +12,7 @@ import * as Geohash from 'ngeohash';
 import { RoutingControl } from "./RoutingControl";
 import { DriverCard } from "./DriverCard";
 import { TripEvents } from "../contracts";
+import { getUserID } from "../lib/auth";
 
 const START_LOCATION: Coordinate = {
   latitude: 20.5237,
@@ -38,7 +39,7 @@ const destinationMarker = new L.Icon({
 
 export const DriverMap = ({ packageSlug }: { packageSlug: CarPackageSlug }) => {
   const mapRef = useRef<L.Map>(null)
-  const userID = useMemo(() => crypto.randomUUID(), [])
+  const userID = useMemo(() => getUserID(), [])
   const [riderLocation, setRiderLocation] = useState<Coordinate>(START_LOCATION)
 
   const driverGeohash = useMemo(() =>
@@ -55,7 +56,6 @@ export const DriverMap = ({ packageSlug }: { packageSlug: CarPackageSlug }) => {
     resetTripStatus,
   } = useDriverStreamConnection({
     location: riderLocation,
-    geohash: driverGeohash,
     userID,
     packageSlug,
   })
@@ -5,14 +5,16 @@ import { useRiderStreamConnection } from '../hooks/useRiderStreamConnection';
 import { MapContainer, Marker, Popup, Rectangle, TileLayer } from 'react-leaflet'
 import L from 'leaflet';
 import { getGeohashBounds } from '../utils/geohash';
-import { useMemo, useRef, useState } from 'react';
+import { canCancelTrip } from '../utils/trip';
+import { useEffect, useMemo, useRef, useState } from 'react';
 import { MapClickHandler } from './MapClickHandler';
 import { Button } from './ui/button';
 import { RouteFare, RequestRideProps, TripPreview, HTTPTripStartResponse } from "../types";
 import { RoutingControl } from "./RoutingControl";
 import { API_URL } from '../constants';
+import { authHeaders, getUserID } from '../lib/auth';
 import { RiderTripOverview } from './RiderTripOverview';
-import { BackendEndpoints, HTTPTripPreviewRequestPayload, HTTPTripPreviewResponse, HTTPTripStartRequestPayload } from '../contracts';
+import { APIError, APIResponse, ApiErrorCodes, BackendEndpoints, HTTPTripPreviewRequestPayload, HTTPTripPreviewResponse, HTTPTripStartRequestPayload, RiderCommands } from '../contracts';
 
 const userMarker = new L.Icon({
     iconUrl: "https://upload.wikimedia.org/wikipedia/commons/thumb/e/ed/Map_pin_icon.svg/176px-Map_pin_icon.svg.png",
@@ -35,7 +37,7 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
     const [selectedCarPackage] = useState<RouteFare | null>(null)
     const [destination, setDestination] = useState<[number, number] | null>(null)
     const mapRef = useRef<L.Map>(null)
-    const userID = useMemo(() => crypto.randomUUID(), [])
+    const userID = useMemo(() => getUserID(), [])
     const debounceTimeoutRef = useRef<NodeJS.Timeout | null>(null);
 
     const location = {
@@ -46,11 +48,13 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
     const {
         drivers,
         error,
+        commandError,
         tripStatus,
         assignedDriver,
         paymentSession,
+        sendCommand,
         resetTripStatus
-    } = useRiderStreamConnection(location, userID);
+    } = useRiderStreamConnection(userID);
 
     console.log(tripStatus)
 
@@ -71,6 +75,9 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
                 destination: [e.latlng.lat, e.latlng.lng],
             })
             console.log(data)
+            if (!data) {
+                return
+            }
 
             const parsedRoute = data.route.geometry[0].coordinates
                 .map((coord) => [coord.longitude, coord.latitude] as [number, number])
@@ -88,7 +95,11 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
         }, 500);
     }
 
-    const requestRidePreview = async (props: RequestRideProps): Promise<HTTPTripPreviewResponse> => {
+    const reportError = (action: string, error?: APIError) => {
+        alert(`Failed to ${action}: ${error?.message ?? "unknown error"}${error?.requestID ? ` (request ${error.requestID})` : ""}`)
+    }
+
+    const requestRidePreview = async (props: RequestRideProps): Promise<HTTPTripPreviewResponse | null> => {
         const { pickup, destination } = props
         const payload = {
             userID: userID,
@@ -104,9 +115,14 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
 
         const response = await fetch(`${API_URL}${BackendEndpoints.PREVIEW_TRIP}`, {
             method: 'POST',
+            headers: authHeaders(),
             body: JSON.stringify(payload),
         })
-        const { data } = await response.json() as { data: HTTPTripPreviewResponse }
+        const { data, error } = await response.json() as APIResponse<HTTPTripPreviewResponse>
+        if (!response.ok || !data) {
+            reportError("preview the trip", error)
+            return null
+        }
         return data
     }
 
@@ -123,11 +139,16 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
 
         const response = await fetch(`${API_URL}${BackendEndpoints.START_TRIP}`, {
             method: 'POST',
+            headers: authHeaders(),
             body: JSON.stringify(payload),
         })
-        const data = await response.json() as HTTPTripStartResponse
+        const { data, error } = await response.json() as APIResponse<HTTPTripStartResponse>
+        if (!response.ok || !data) {
+            reportError("start the trip", error)
+            return
+        }
 
-        if (response.ok && trip) {
+        if (trip) {
             setTrip((prev) => ({
                 ...prev,
                 tripID: data.tripID,
@@ -138,7 +159,23 @@ export default function RiderMap({ onRouteSelected }: RiderMapProps) {
         return data
     }
 
+    useEffect(() => {
+        if (commandError) {
+            reportError("update the trip", { code: (commandError.code ?? ApiErrorCodes.BadRequest) as ApiErrorCodes, message: commandError.message ?? "" })
+        }
+        // eslint-disable-next-line react-hooks/exhaustive-deps
+    }, [commandError])
+
     const handleCancelTrip = () => {
+        // Trips which are still waiting for a driver or on their way are cancelled in
+        // trip-service as well, which tells the driver
+        if (trip && canCancelTrip(trip.tripID, tripStatus)) {
+            sendCommand({
+                type: RiderCommands.CancelTrip,
+                data: { tripID: trip.tripID },
+            })
+        }
+
         setTrip(null)
         setDestination(null)
         resetTripStatus()
@@ -1,2 +1,5 @@
 export const API_URL = process.env.NEXT_PUBLIC_API_URL ?? 'http://localhost:8081';
 export const WEBSOCKET_URL = process.env.NEXT_PUBLIC_WEBSOCKET_URL ?? 'ws://localhost:8081/ws';
+
+// The WebSocket subprotocol announcing that the next one is an access token
+export const ACCESS_TOKEN_SUBPROTOCOL = 'access_token';
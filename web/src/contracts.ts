@@ -5,6 +5,7 @@ import { Coordinate, Driver, Route, RouteFare, Trip } from "./types";
 export enum BackendEndpoints {
   PREVIEW_TRIP = "/trip/preview",
   START_TRIP = "/trip/start",
+  TRIP_CHAT = "/trip/chat",
   WS_DRIVERS = "/drivers",
   WS_RIDERS = "/riders",
 }
@@ -12,79 +13,273 @@ export enum BackendEndpoints {
 export enum TripEvents {
   NoDriversFound = "trip.event.no_drivers_found",
   DriverAssigned = "trip.event.driver_assigned",
+  Started = "trip.event.started",
   Completed = "trip.event.completed",
   Cancelled = "trip.event.cancelled",
   Created = "trip.event.created",
   DriverLocation = "driver.cmd.location",
+  AssignedDriverLocation = "trip.event.driver_location",
   DriverTripRequest = "driver.cmd.trip_request",
   DriverTripAccept = "driver.cmd.trip_accept",
   DriverTripDecline = "driver.cmd.trip_decline",
+  DriverTripStart = "driver.cmd.trip_start",
+  DriverTripComplete = "driver.cmd.trip_complete",
+  DriverTripOfferRevoked = "driver.cmd.trip_offer_revoked",
+  DriverTripAlreadyTaken = "driver.cmd.trip_already_taken",
+  DriverTripCancelled = "driver.cmd.trip_cancelled",
+  RouteUpdated = "trip.event.route_updated",
+  RiderCommandAck = "rider.event.command_ack",
+  RiderCommandError = "rider.event.command_error",
   DriverRegister = "driver.cmd.register",
   PaymentSessionCreated = "payment.event.session_created",
 }
 
-// Messages sent from the server to the client via the websocket
-export type ServerWsMessage =
-  | PaymentSessionCreatedRequest
-  | DriverAssignedRequest
-  | DriverLocationRequest
-  | DriverTripRequest
-  | DriverRegisterRequest
-  | TripCreatedRequest
-  | NoDriversFoundRequest;
+// Commands every client may send, whether rider or driver
+export enum ClientCommands {
+  Ack = "client.cmd.ack",
+  // Echoed back by the server, lets clients check the connection is alive
+  Heartbeat = "client.cmd.heartbeat",
+}
 
-// Messages sent from the client to the server via the websocket
-export type ClientWsMessage = DriverResponseToTripResponse
+// Commands riders send about their trip, answered with a RiderCommandAck or RiderCommandError
+export enum RiderCommands {
+  CancelTrip = "rider.cmd.cancel_trip",
+  UpdatePickup = "rider.cmd.update_pickup",
+  AddStop = "rider.cmd.add_stop",
+}
 
-interface TripCreatedRequest {
-  type: TripEvents.Created;
-  data: Trip;
+// Chat between a trip's rider and its assigned driver, open while the trip is active
+export enum ChatCommands {
+  Send = "chat.cmd.send",
+  // Marks the recipient's messages up to messageID as delivered or read
+  Receipt = "chat.cmd.receipt",
 }
 
-interface NoDriversFoundRequest {
-  type: TripEvents.NoDriversFound;
+export enum ChatEvents {
+  Message = "chat.event.message",
+  Receipt = "chat.event.receipt",
+  Error = "chat.event.error",
+  // The trip ended, no more messages can be sent
+  Closed = "chat.event.closed",
 }
 
-interface DriverRegisterRequest {
-  type: TripEvents.DriverRegister;
-  data: Driver;
+// Errors the server sends about client messages
+export enum ClientEvents {
+  // A message failed validation, correlationID is its id
+  Error = "client.event.error",
 }
-interface DriverTripRequest {
-  type: TripEvents.DriverTripRequest;
-  data: Trip;
+
+// BEGIN GENERATED WS CONTRACTS
+// Code generated by tools/wscontracts from the WebSocket message registry in
+// shared/messaging/ws_messages.go. DO NOT EDIT.
+
+export const WS_PROTOCOL_VERSION = 1;
+
+// Envelope of every message. id stays the same when a message is delivered again,
+// correlationID is the id of the client message it answers.
+export interface WsEnvelope {
+  version: number;
+  id: string;
+  correlationID?: string;
+  timestamp: string;
+  // Messages with a seq must be acknowledged, and may be delivered again after a reconnect
+  seq?: number;
 }
 
-export interface PaymentEventSessionCreatedData {
+export interface WSAckData {
+  seq: number;
+}
+
+export interface WSErrorData {
+  code: string;
+  message: string;
+  type?: string;
+}
+
+export interface WSDriverLocationData {
+  location: Coordinate;
+}
+
+export interface DriveTripResponseData {
+  driver: Driver;
   tripID: string;
-  sessionID: string;
-  amount: number;
-  currency: string;
+  riderID?: string;
+}
+
+export interface TripCreatedEvent {
+  trip: Trip;
+}
+
+export interface TripOfferClosedData {
+  tripID: string;
+  driverID: string;
+  reason: string;
+}
+
+export interface WSCancelTripData {
+  tripID: string;
+  reason?: string;
+}
+
+export interface WSUpdatePickupData {
+  tripID: string;
+  pickup: Coordinate;
+}
+
+export interface WSAddStopData {
+  tripID: string;
+  stop: Coordinate;
+}
+
+export interface WSCommandResultData {
+  command: string;
+  tripID?: string;
+  code?: string;
+  message?: string;
+}
+
+export interface TripDriverLocationEvent {
+  tripID: string;
+  driverID: string;
+  location: Coordinate;
+  heading: number;
+  distanceToPickupMeters?: number;
+  pickupEtaSeconds?: number;
+}
+
+export interface TripRouteUpdatedEvent {
+  tripID: string;
+  pickup?: Coordinate;
+  stops: Coordinate[];
+}
+
+export interface WSChatSendData {
+  tripID: string;
+  text: string;
+}
+
+export interface WSChatReceiptData {
+  tripID: string;
+  messageID: string;
+  status: string;
 }
 
-interface PaymentSessionCreatedRequest {
-  type: TripEvents.PaymentSessionCreated;
-  data: PaymentEventSessionCreatedData;
+export interface ChatMessageData {
+  id: string;
+  tripID: string;
+  senderID: string;
+  senderRole: string;
+  text: string;
+  sentAt: string;
+  deliveredAt?: string;
+  readAt?: string;
 }
 
-interface DriverAssignedRequest {
-  type: TripEvents.DriverAssigned;
-  data: Trip;
+export interface ChatReceiptData {
+  tripID: string;
+  messageID: string;
+  status: string;
+  at: string;
 }
 
-interface DriverLocationRequest {
-  type: TripEvents.DriverLocation;
-  data: Driver[];
+export interface ChatClosedData {
+  tripID: string;
+  tripStatus: string;
 }
 
-interface DriverResponseToTripResponse {
-  type: TripEvents.DriverTripAccept | TripEvents.DriverTripDecline;
-  data: {
-    tripID: string;
-    riderID: string;
-    driver: Driver;
-  };
+export interface PaymentEventSessionCreatedData {
+  tripID: string;
+  sessionID: string;
+  amount: number;
+  currency: string;
 }
 
+// Messages sent from the server to the client via the websocket
+export type ServerWsMessage = (
+  // Echoed back by the server, lets clients check the connection is alive
+  | { type: "client.cmd.heartbeat" }
+  // A client message failed validation
+  | { type: "client.event.error"; data: WSErrorData }
+  // The driver is registered, first message of every connection
+  | { type: "driver.cmd.register"; data: Driver }
+  | { type: "driver.cmd.trip_request"; data: TripCreatedEvent }
+  // Another driver accepted the trip first, or the rider cancelled it
+  | { type: "driver.cmd.trip_offer_revoked"; data: TripOfferClosedData }
+  // The driver's accept arrived after another driver's
+  | { type: "driver.cmd.trip_already_taken"; data: TripOfferClosedData }
+  // The rider cancelled the driver's trip
+  | { type: "driver.cmd.trip_cancelled"; data: TripOfferClosedData }
+  | { type: "rider.event.command_ack"; data: WSCommandResultData }
+  // trip-service refused a rider command
+  | { type: "rider.event.command_error"; data: WSCommandResultData }
+  | { type: "trip.event.no_drivers_found" }
+  | { type: "trip.event.driver_assigned"; data: Trip }
+  | { type: "trip.event.driver_location"; data: TripDriverLocationEvent }
+  | { type: "trip.event.started"; data: TripCreatedEvent }
+  | { type: "trip.event.completed"; data: TripCreatedEvent }
+  | { type: "trip.event.cancelled"; data: TripCreatedEvent }
+  // Sent to the rider and the assigned driver
+  | { type: "trip.event.route_updated"; data: TripRouteUpdatedEvent }
+  // Sent to the recipient and the sender, whose correlationID is the id of their chat.cmd.send
+  | { type: "chat.event.message"; data: ChatMessageData }
+  | { type: "chat.event.receipt"; data: ChatReceiptData }
+  // trip-service refused a chat command
+  | { type: "chat.event.error"; data: WSCommandResultData }
+  | { type: "chat.event.closed"; data: ChatClosedData }
+  | { type: "payment.event.session_created"; data: PaymentEventSessionCreatedData }
+) & WsEnvelope;
+
+// Messages sent from the client to the server via the websocket. The envelope is
+// optional, the server fills in what's missing.
+export type ClientWsMessage = (
+  // Acknowledges all messages up to and including seq
+  | { type: "client.cmd.ack"; data: WSAckData }
+  // Echoed back by the server, lets clients check the connection is alive
+  | { type: "client.cmd.heartbeat" }
+  | { type: "driver.cmd.location"; data: WSDriverLocationData }
+  | { type: "driver.cmd.trip_accept"; data: DriveTripResponseData }
+  | { type: "driver.cmd.trip_decline"; data: DriveTripResponseData }
+  // The driver picked up the rider
+  | { type: "driver.cmd.trip_start"; data: DriveTripResponseData }
+  // The driver dropped off the rider
+  | { type: "driver.cmd.trip_complete"; data: DriveTripResponseData }
+  | { type: "rider.cmd.cancel_trip"; data: WSCancelTripData }
+  | { type: "rider.cmd.update_pickup"; data: WSUpdatePickupData }
+  | { type: "rider.cmd.add_stop"; data: WSAddStopData }
+  // Allowed while the trip is accepted or in progress
+  | { type: "chat.cmd.send"; data: WSChatSendData }
+  // The recipient received or read the messages up to messageID
+  | { type: "chat.cmd.receipt"; data: WSChatReceiptData }
+) & Partial<WsEnvelope>;
+
+export const SERVER_WS_MESSAGE_TYPES: ReadonlyArray<ServerWsMessage["type"]> = [
+  "client.cmd.heartbeat",
+  "client.event.error",
+  "driver.cmd.register",
+  "driver.cmd.trip_request",
+  "driver.cmd.trip_offer_revoked",
+  "driver.cmd.trip_already_taken",
+  "driver.cmd.trip_cancelled",
+  "rider.event.command_ack",
+  "rider.event.command_error",
+  "trip.event.no_drivers_found",
+  "trip.event.driver_assigned",
+  "trip.event.driver_location",
+  "trip.event.started",
+  "trip.event.completed",
+  "trip.event.cancelled",
+  "trip.event.route_updated",
+  "chat.event.message",
+  "chat.event.receipt",
+  "chat.event.error",
+  "chat.event.closed",
+  "payment.event.session_created",
+];
+// END GENERATED WS CONTRACTS
+
+// The id of a command is the correlationID of the answer
+export type RiderCommandMessage = Extract<ClientWsMessage, { type: `rider.cmd.${string}` }>;
+
 export interface HTTPTripPreviewResponse {
   route: Route;
   rideFares: RouteFare[];
@@ -101,10 +296,43 @@ export interface HTTPTripPreviewRequestPayload {
   destination: Coordinate;
 }
 
+// Stable codes of API errors, mirrors the ErrCode constants in shared/contracts/http.go
+export enum ApiErrorCodes {
+  BadRequest = "BAD_REQUEST",
+  Unauthorized = "UNAUTHORIZED",
+  Forbidden = "FORBIDDEN",
+  NotFound = "NOT_FOUND",
+  Conflict = "CONFLICT",
+  RateLimited = "RATE_LIMITED",
+  Internal = "INTERNAL",
+  ServiceUnavailable = "SERVICE_UNAVAILABLE",
+  Timeout = "TIMEOUT",
+  FareNotOwned = "FARE_NOT_OWNED",
+  FareExpired = "FARE_EXPIRED",
+  ChatClosed = "CHAT_CLOSED",
+  MessageRejected = "MESSAGE_REJECTED",
+  UnknownMessageType = "UNKNOWN_MESSAGE_TYPE",
+  UnsupportedVersion = "UNSUPPORTED_VERSION",
+  InvalidPayload = "INVALID_PAYLOAD",
+}
+
+export interface APIError {
+  code: ApiErrorCodes;
+  message: string;
+  // Identifies the failed request in the gateway logs
+  requestID?: string;
+}
+
+export interface APIResponse<T> {
+  data?: T;
+  error?: APIError;
+}
+
 export function isValidTripEvent(event: string): event is TripEvents {
   return Object.values(TripEvents).includes(event as TripEvents);
 }
 
-export function isValidWsMessage(message: ServerWsMessage): message is ServerWsMessage {
-  return isValidTripEvent(message.type);
+export function isValidWsMessage(message: unknown): message is ServerWsMessage {
+  const type = (message as { type?: unknown } | null)?.type;
+  return typeof type === "string" && (SERVER_WS_MESSAGE_TYPES as ReadonlyArray<string>).includes(type);
 }
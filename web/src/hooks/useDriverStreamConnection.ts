@@ -1,21 +1,21 @@
-import { useEffect, useState } from 'react';
+import { useEffect, useRef, useState } from 'react';
 import { WEBSOCKET_URL } from "../constants";
+import { createMessageTracker } from '../utils/messageTracker';
+import { websocketProtocols } from '../lib/auth';
 import { Trip, Driver, CarPackageSlug } from '../types';
-import { ServerWsMessage, TripEvents, isValidWsMessage, isValidTripEvent, ClientWsMessage, BackendEndpoints } from '../contracts';
+import { TripEvents, isValidWsMessage, isValidTripEvent, ClientWsMessage, BackendEndpoints, ClientEvents, SERVER_WS_MESSAGE_TYPES } from '../contracts';
 
 interface useDriverConnectionProps {
   location: {
     latitude: number;
     longitude: number;
   };
-  geohash: string;
   userID: string;
   packageSlug: CarPackageSlug;
 }
 
 export const useDriverStreamConnection = ({
   location,
-  geohash,
   userID,
   packageSlug
 }: useDriverConnectionProps) => {
@@ -25,41 +25,56 @@ export const useDriverStreamConnection = ({
   const [ws, setWs] = useState<WebSocket | null>(null);
   const [driver, setDriver] = useState<Driver | null>(null);
 
+  // Survives reconnects, so replayed messages are recognized
+  const tracker = useRef(createMessageTracker());
+
   useEffect(() => {
     if (!userID) return;
 
-    const websocket = new WebSocket(`${WEBSOCKET_URL}${BackendEndpoints.WS_DRIVERS}?userID=${userID}&packageSlug=${packageSlug}`);
+    const websocket = new WebSocket(`${WEBSOCKET_URL}${BackendEndpoints.WS_DRIVERS}?userID=${userID}&packageSlug=${packageSlug}&lastSeq=${tracker.current.lastSeq()}`, websocketProtocols());
     setWs(websocket);
 
     websocket.onopen = () => {
       if (location) {
         // Send initial location
-        websocket.send(JSON.stringify({
+        const message: ClientWsMessage = {
           type: TripEvents.DriverLocation,
-          data: {
-            location,
-            geohash,
-          }
-        }));
+          data: { location },
+        };
+        websocket.send(JSON.stringify(message));
       }
     };
 
     websocket.onmessage = (event) => {
-      const message = JSON.parse(event.data) as ServerWsMessage;
+      const message: unknown = JSON.parse(event.data);
+
+      if (!isValidWsMessage(message)) {
+        setError(`Unknown message "${event.data}", allowed types are: ${SERVER_WS_MESSAGE_TYPES.join(', ')}`);
+        return;
+      }
 
-      if (!message || !isValidWsMessage(message)) {
-        setError(`Unknown message type "${message}", allowed types are: ${Object.values(TripEvents).join(', ')}`);
+      // Messages replayed after a reconnect were already handled
+      if (message.seq && !tracker.current.acknowledge(websocket, message.seq)) {
         return;
       }
 
       switch (message.type) {
         case TripEvents.DriverTripRequest:
-          const trip = (message.data?.trip) ?? message.data;
-          setRequestedTrip(trip);
+          setRequestedTrip(message.data.trip);
           break;
+        case TripEvents.DriverTripOfferRevoked:
+        case TripEvents.DriverTripAlreadyTaken:
+        case TripEvents.DriverTripCancelled:
+          // Another driver was faster or the rider cancelled, go back to waiting for a rider
+          setRequestedTrip(null);
+          setTripStatus(null);
+          return;
         case TripEvents.DriverRegister:
           setDriver(message.data);
           break;
+        case ClientEvents.Error:
+          setError(`The server rejected a message: ${message.data.message}`);
+          return;
       }
 
 
@@ -1,58 +1,70 @@
-import { useEffect, useState } from 'react';
+import { useEffect, useRef, useState } from 'react';
 import { WEBSOCKET_URL } from "../constants";
+import { createMessageTracker } from '../utils/messageTracker';
+import { websocketProtocols } from '../lib/auth';
 import { Trip } from '../types';
-import { Driver, Coordinate } from '../types';
-import { PaymentEventSessionCreatedData, TripEvents, ServerWsMessage, isValidWsMessage, BackendEndpoints } from '../contracts';
+import { Driver } from '../types';
+import { TripDriverLocationEvent, PaymentEventSessionCreatedData, TripEvents, ServerWsMessage, isValidWsMessage, BackendEndpoints, RiderCommandMessage, WSCommandResultData, WSErrorData, ClientEvents, SERVER_WS_MESSAGE_TYPES } from '../contracts';
 
-export function useRiderStreamConnection(location: Coordinate, userID: string) {
-  const [drivers, setDrivers] = useState<Driver[]>([]);
+export function useRiderStreamConnection(userID: string) {
+  const [drivers] = useState<Driver[]>([]);
   const [tripStatus, setTripStatus] = useState<TripEvents | null>(null);
   const [paymentSession, setPaymentSession] = useState<PaymentEventSessionCreatedData | null>(null);
   const [assignedDriver, setAssignedDriver] = useState<Trip["driver"] | null>(null);
+  const [assignedDriverLocation, setAssignedDriverLocation] = useState<TripDriverLocationEvent | null>(null);
   const [error, setError] = useState<string | null>(null);
+  // A command trip-service refused, or a message the gateway rejected
+  const [commandError, setCommandError] = useState<WSCommandResultData | WSErrorData | null>(null);
+  const [ws, setWs] = useState<WebSocket | null>(null);
+
+  // Survives reconnects, so replayed messages are recognized
+  const tracker = useRef(createMessageTracker());
 
   useEffect(() => {
     if (!userID) return;
 
-    const ws = new WebSocket(`${WEBSOCKET_URL}${BackendEndpoints.WS_RIDERS}?userID=${userID}`);
-
-    ws.onopen = () => {
-      // Send initial location
-      if (location) {
-        ws.send(JSON.stringify({
-          type: TripEvents.DriverLocation,
-          data: {
-            location,
-          }
-        }));
-      }
-    };
+    const ws = new WebSocket(`${WEBSOCKET_URL}${BackendEndpoints.WS_RIDERS}?userID=${userID}&lastSeq=${tracker.current.lastSeq()}`, websocketProtocols());
+    setWs(ws);
 
     ws.onmessage = (event) => {
-      const message = JSON.parse(event.data) as ServerWsMessage;
+      const message: unknown = JSON.parse(event.data);
+
+      if (!isValidWsMessage(message)) {
+        setError(`Unknown message "${event.data}", allowed types are: ${SERVER_WS_MESSAGE_TYPES.join(', ')}`);
+        return;
+      }
 
-      if (!message || !isValidWsMessage(message)) {
-        setError(`Unknown message type "${message}", allowed types are: ${Object.values(TripEvents).join(', ')}`);
+      // Messages replayed after a reconnect were already handled
+      if (message.seq && !tracker.current.acknowledge(ws, message.seq)) {
         return;
       }
 
       switch (message.type) {
-        case TripEvents.DriverLocation:
-          setDrivers(message.data);
-          break;
         case TripEvents.PaymentSessionCreated:
           setPaymentSession(message.data);
-          setTripStatus(message.type);
+          setTripStatus(TripEvents.PaymentSessionCreated);
           break;
         case TripEvents.DriverAssigned:
           setAssignedDriver(message.data.driver);
-          setTripStatus(message.type);
+          setTripStatus(TripEvents.DriverAssigned);
           break;
-        case TripEvents.Created:
-          setTripStatus(message.type);
+        case TripEvents.AssignedDriverLocation:
+          setAssignedDriverLocation(message.data);
+          setAssignedDriver((driver) => driver ? {
+            ...driver,
+            location: message.data.location,
+            heading: message.data.heading,
+          } : driver);
           break;
         case TripEvents.NoDriversFound:
-          setTripStatus(message.type);
+          setTripStatus(TripEvents.NoDriversFound);
+          break;
+        case TripEvents.Cancelled:
+          setTripStatus(TripEvents.Cancelled);
+          break;
+        case TripEvents.RiderCommandError:
+        case ClientEvents.Error:
+          setCommandError(message.data);
           break;
       }
     };
@@ -75,10 +87,21 @@ export function useRiderStreamConnection(location: Coordinate, userID: string) {
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [userID]);
 
+  const sendCommand = (command: RiderCommandMessage) => {
+    if (!ws || ws.readyState !== WebSocket.OPEN) {
+      return false;
+    }
+    setCommandError(null);
+    ws.send(JSON.stringify(command));
+    return true;
+  }
+
   const resetTripStatus = () => {
     setTripStatus(null);
+    setCommandError(null);
     setPaymentSession(null);
+    setAssignedDriverLocation(null);
   }
 
-  return { drivers, assignedDriver, error, tripStatus, paymentSession, resetTripStatus };
+  return { drivers, assignedDriver, assignedDriverLocation, error, commandError, tripStatus, paymentSession, sendCommand, resetTripStatus };
 }
@@ -0,0 +1,50 @@
+import { ACCESS_TOKEN_SUBPROTOCOL } from "../constants";
+
+const ACCESS_TOKEN_KEY = "accessToken";
+
+// Returns the token the gateway authenticates requests with. The sign-in page
+// redirects back with it in the URL fragment (#access_token=...), where it is
+// taken from and kept for the rest of the browser session.
+export function getAccessToken(): string | null {
+  if (typeof window === "undefined") return null;
+
+  const fragment = new URLSearchParams(window.location.hash.slice(1));
+  const token = fragment.get("access_token");
+  if (token) {
+    sessionStorage.setItem(ACCESS_TOKEN_KEY, token);
+    // Keep the token out of the history and of shared links
+    window.history.replaceState(null, "", window.location.pathname + window.location.search);
+    return token;
+  }
+  return sessionStorage.getItem(ACCESS_TOKEN_KEY);
+}
+
+// Returns the user the token was issued to, from its sub claim. The gateway
+// verifies the token; this only shows the same ID the gateway acts on.
+export function getTokenSubject(token: string): string | null {
+  try {
+    const payload = token.split(".")[1].replace(/-/g, "+").replace(/_/g, "/");
+    const claims = JSON.parse(atob(payload)) as { sub?: unknown };
+    return typeof claims.sub === "string" ? claims.sub : null;
+  } catch {
+    return null;
+  }
+}
+
+// The signed-in user, or a random ID when the gateway runs without authentication
+export function getUserID(): string {
+  const token = getAccessToken();
+  return (token && getTokenSubject(token)) || crypto.randomUUID();
+}
+
+// Headers authenticating an HTTP request to the gateway
+export function authHeaders(): Record<string, string> {
+  const token = getAccessToken();
+  return token ? { Authorization: `Bearer ${token}` } : {};
+}
+
+// WebSocket subprotocols carrying the token, since browsers can't set headers on WebSocket requests
+export function websocketProtocols(): string[] {
+  const token = getAccessToken();
+  return token ? [ACCESS_TOKEN_SUBPROTOCOL, token] : [];
+}
@@ -63,4 +63,5 @@ export interface Driver {
     name: string;
     profilePicture: string;
     carPlate: string;
+    heading?: number;
 }
@@ -0,0 +1,35 @@
+import { ClientCommands } from "../contracts";
+
+// How many message sequence numbers are remembered to spot replayed messages
+const MAX_SEEN_MESSAGES = 1000;
+
+export interface MessageTracker {
+  // Acknowledges a message and returns whether it wasn't seen before
+  acknowledge: (ws: WebSocket, seq: number) => boolean;
+  // The highest sequence number seen, to send when reconnecting
+  lastSeq: () => number;
+}
+
+export function createMessageTracker(): MessageTracker {
+  const seen = new Set<number>();
+  let last = 0;
+
+  return {
+    acknowledge: (ws, seq) => {
+      ws.send(JSON.stringify({ type: ClientCommands.Ack, data: { seq } }));
+
+      if (seen.has(seq)) {
+        return false;
+      }
+
+      seen.add(seq);
+      if (seen.size > MAX_SEEN_MESSAGES) {
+        // Sets iterate in insertion order, so this drops the oldest entry
+        seen.delete(seen.values().next().value as number);
+      }
+      last = Math.max(last, seq);
+      return true;
+    },
+    lastSeq: () => last,
+  };
+}
@@ -0,0 +1,22 @@
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { TripEvents } from "../contracts";
+import { canCancelTrip } from "./trip";
+
+test("cancels a trip waiting for a driver", () => {
+  assert.equal(canCancelTrip("trip-1", null), true)
+  assert.equal(canCancelTrip("trip-1", TripEvents.NoDriversFound), true)
+})
+
+test("cancels a trip on its way", () => {
+  assert.equal(canCancelTrip("trip-1", TripEvents.DriverAssigned), true)
+  assert.equal(canCancelTrip("trip-1", TripEvents.PaymentSessionCreated), true)
+})
+
+test("doesn't cancel previews or trips which started or ended", () => {
+  assert.equal(canCancelTrip("", null), false)
+  assert.equal(canCancelTrip(undefined, null), false)
+  for (const status of [TripEvents.Started, TripEvents.Completed, TripEvents.Cancelled]) {
+    assert.equal(canCancelTrip("trip-1", status), false, status)
+  }
+})
@@ -0,0 +1,13 @@
+import { TripEvents } from "../contracts";
+
+// Statuses after which the trip can't be cancelled anymore
+const FINAL_TRIP_STATUSES = [TripEvents.Started, TripEvents.Completed, TripEvents.Cancelled];
+
+// Whether the rider's cancel has to reach trip-service. Riders never see the created
+// event, so a trip waiting for a driver has no status yet and is cancelled as well.
+export function canCancelTrip(tripID: string | undefined, tripStatus: TripEvents | null): boolean {
+  if (!tripID) {
+    return false
+  }
+  return tripStatus === null || !FINAL_TRIP_STATUSES.includes(tripStatus)
+}